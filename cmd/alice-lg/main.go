@@ -6,16 +6,20 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"runtime"
 	"runtime/debug"
 	"runtime/pprof"
+	"syscall"
 	"time"
 
 	"github.com/alice-lg/alice-lg/pkg/config"
 	"github.com/alice-lg/alice-lg/pkg/http"
 	"github.com/alice-lg/alice-lg/pkg/store"
+	"github.com/alice-lg/alice-lg/pkg/store/backends/bolt"
 	"github.com/alice-lg/alice-lg/pkg/store/backends/memory"
 	"github.com/alice-lg/alice-lg/pkg/store/backends/postgres"
+	"github.com/alice-lg/alice-lg/pkg/store/backends/sqlite"
 
 	"github.com/jackc/pgx/v4/pgxpool"
 )
@@ -57,8 +61,58 @@ func startMemoryProfile(prefix string) {
 	}
 }
 
+// convertConfig reads an INI configuration and writes
+// the YAML representation to stdout.
+func convertConfig(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	res, err := config.ConvertINIToYAML(data)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(res)
+	return err
+}
+
+// checkConfig loads and validates the configuration
+// and prints the report. The exit code is returned.
+func checkConfig(filename, format string) int {
+	report, _ := config.CheckConfigFile(filename)
+	if format == "json" {
+		if err := report.WriteJSON(os.Stdout); err != nil {
+			log.Println(err)
+			return 1
+		}
+	} else {
+		report.Write(os.Stdout)
+	}
+	if report.Failed() {
+		return 1
+	}
+	return 0
+}
+
+// snapshotTask writes a snapshot of the memory backends
+func snapshotTask(
+	filename string,
+	neighbors *memory.NeighborsBackend,
+	routes *memory.RoutesBackend,
+) store.HousekeepingTask {
+	return func(_ context.Context) error {
+		if err := memory.SaveSnapshot(filename, neighbors, routes); err != nil {
+			return err
+		}
+		log.Println("Wrote snapshot:", filename)
+		return nil
+	}
+}
+
 func main() {
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(
+		context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Handle commandline parameters
 	configFilenameFlag := flag.String(
@@ -69,6 +123,30 @@ func main() {
 		"db-init", false,
 		"Initialize the database. Clears all data.",
 	)
+	dbMigrateFlag := flag.Bool(
+		"db-migrate", false,
+		"Apply pending database migrations and exit.",
+	)
+	dbStatusFlag := flag.Bool(
+		"db-status", false,
+		"Show the database schema version and pending migrations and exit.",
+	)
+	convertConfigFlag := flag.Bool(
+		"convert-config", false,
+		"Convert the configuration file to YAML and print it to stdout.",
+	)
+	checkConfigFlag := flag.Bool(
+		"check-config", false,
+		"Check the configuration and exit. Nothing is started.",
+	)
+	checkConfigFormatFlag := flag.String(
+		"check-config-format", "text",
+		"Output format of the configuration check: text or json",
+	)
+	loadSnapshotFlag := flag.String(
+		"load-snapshot", "",
+		"Serve the neighbors and routes from a snapshot `file`. The sources are not refreshed.",
+	)
 	memprofile := flag.String(
 		"memprofile", "", "write memory profile to `file`",
 	)
@@ -78,6 +156,20 @@ func main() {
 		go startMemoryProfile(*memprofile)
 	}
 
+	// Translate the configuration into the YAML format
+	if *convertConfigFlag {
+		if err := convertConfig(*configFilenameFlag); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Check the configuration without starting
+	// the server or stores.
+	if *checkConfigFlag {
+		os.Exit(checkConfig(*configFilenameFlag, *checkConfigFormatFlag))
+	}
+
 	// Load configuration
 	cfg, err := config.LoadConfig(*configFilenameFlag)
 	if err != nil {
@@ -89,11 +181,41 @@ func main() {
 
 	// Setup local routes store and use backend from configuration
 	var (
-		neighborsBackend store.NeighborsStoreBackend = memory.NewNeighborsBackend()
-		routesBackend    store.RoutesStoreBackend    = memory.NewRoutesBackend()
+		memoryNeighbors = memory.NewNeighborsBackend()
+		memoryRoutes    = memory.NewRoutesBackend()
+
+		neighborsBackend store.NeighborsStoreBackend = memoryNeighbors
+		routesBackend    store.RoutesStoreBackend    = memoryRoutes
 
 		pool *pgxpool.Pool
+
+		housekeepingTasks []store.HousekeepingTask
+		snapshotPath      string
 	)
+
+	// A snapshot is loaded read-only into the memory
+	// backends, regardless of the configured backend.
+	readOnly := *loadSnapshotFlag != ""
+	if readOnly {
+		cfg.Server.StoreBackend = "memory"
+		err := memory.LoadSnapshot(
+			*loadSnapshotFlag, memoryNeighbors, memoryRoutes)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Println("Loaded snapshot (read-only):", *loadSnapshotFlag)
+	} else if cfg.Server.StoreBackend == "memory" &&
+		cfg.Housekeeping.SnapshotPath != "" {
+		snapshotPath = cfg.Housekeeping.SnapshotPath
+		err := memory.LoadSnapshot(
+			snapshotPath, memoryNeighbors, memoryRoutes)
+		if err != nil && !os.IsNotExist(err) {
+			log.Println("error while restoring snapshot:", err)
+		}
+		housekeepingTasks = append(housekeepingTasks,
+			snapshotTask(snapshotPath, memoryNeighbors, memoryRoutes))
+	}
+
 	if cfg.Server.StoreBackend == "postgres" {
 		pool, err = postgres.Connect(ctx, cfg.Postgres)
 		if err != nil {
@@ -109,6 +231,23 @@ func main() {
 			log.Println("database initialized")
 			return
 		}
+		if *dbStatusFlag {
+			status := m.Status(ctx)
+			status.Log()
+			if status.Error != nil || !status.Migrated {
+				os.Exit(1)
+			}
+			return
+		}
+
+		// Apply pending migrations
+		if err := m.Migrate(ctx); err != nil {
+			log.Fatal(err)
+		}
+		if *dbMigrateFlag {
+			log.Println("database migrated")
+			return
+		}
 
 		go m.Start(ctx)
 
@@ -119,6 +258,61 @@ func main() {
 			log.Println("error while initializing routes backend:", err)
 		}
 	}
+	if cfg.Server.StoreBackend == "sqlite" {
+		db, err := sqlite.Open(ctx, cfg.Sqlite)
+		if err != nil {
+			log.Fatal(err)
+		}
+		m := sqlite.NewManager(db)
+
+		// Initialize db if required
+		if *dbInitFlag {
+			if err := m.Initialize(ctx); err != nil {
+				log.Fatal(err)
+			}
+			log.Println("database initialized")
+			return
+		}
+		if *dbStatusFlag {
+			status := m.Status(ctx)
+			status.Log()
+			if status.Error != nil || !status.Migrated {
+				os.Exit(1)
+			}
+			return
+		}
+		if err := m.Migrate(ctx); err != nil {
+			log.Fatal(err)
+		}
+		if *dbMigrateFlag {
+			log.Println("database migrated")
+			return
+		}
+
+		go m.Start(ctx)
+
+		neighborsBackend = sqlite.NewNeighborsBackend(db)
+		routesBackend = sqlite.NewRoutesBackend(db)
+	}
+	if cfg.Server.StoreBackend == "bolt" {
+		db, err := bolt.Open(cfg.Bolt)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer db.Close()
+
+		// Restore the neighbors and routes from the last run
+		neighbors := bolt.NewNeighborsBackend(db)
+		if err := neighbors.Init(ctx); err != nil {
+			log.Println("error while restoring neighbors:", err)
+		}
+		routes := bolt.NewRoutesBackend(db)
+		if err := routes.Init(ctx); err != nil {
+			log.Println("error while restoring routes:", err)
+		}
+		neighborsBackend = neighbors
+		routesBackend = routes
+	}
 
 	neighborsStore := store.NewNeighborsStore(cfg, neighborsBackend)
 	routesStore := store.NewRoutesStore(neighborsStore, cfg, routesBackend)
@@ -128,17 +322,32 @@ func main() {
 	log.Println("Using configuration:", cfg.File)
 
 	// Start stores
-	if cfg.Server.EnablePrefixLookup {
+	if cfg.Server.EnablePrefixLookup && !readOnly {
 		go neighborsStore.Start(ctx)
 		go routesStore.Start(ctx)
 	}
 
 	// Start the Housekeeping
-	go store.StartHousekeeping(ctx, cfg)
+	go store.StartHousekeeping(ctx, cfg, housekeepingTasks...)
+
+	// Discover sources from the inventory
+	if cfg.Discovery.Inventory != "" && !readOnly {
+		discovery := store.NewSourceDiscovery(cfg, neighborsStore, routesStore)
+		go discovery.Start(ctx)
+	}
 
 	// Start HTTP API
 	server := http.NewServer(cfg, pool, routesStore, neighborsStore)
 	go server.Start(ctx)
 
 	<-ctx.Done()
+	log.Println("Shutting down")
+
+	// Persist the memory backends
+	if snapshotPath != "" {
+		task := snapshotTask(snapshotPath, memoryNeighbors, memoryRoutes)
+		if err := task(context.Background()); err != nil {
+			log.Println("error while writing snapshot:", err)
+		}
+	}
 }
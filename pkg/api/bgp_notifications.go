@@ -0,0 +1,150 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+BGP NOTIFICATION error codes and subcodes
+
+From: https://www.iana.org/assignments/bgp-parameters/bgp-parameters.xhtml
+
+The texts follow the messages of the BIRD daemon, so
+a last error reported as text can be mapped back to the
+error code.
+*/
+
+// bgpErrors maps the error codes to the subcode texts.
+// The subcode 0 is the text of the error code.
+var bgpErrors = map[int]map[int]string{
+	1: {
+		0: "Invalid message header",
+		1: "Connection not synchronized",
+		2: "Bad message length",
+		3: "Bad message type",
+	},
+	2: {
+		0:  "Invalid OPEN message",
+		1:  "Unsupported version number",
+		2:  "Bad peer AS",
+		3:  "Bad BGP identifier",
+		4:  "Unsupported optional parameter",
+		5:  "Authentication failure",
+		6:  "Unacceptable hold time",
+		7:  "Required capability missing",
+		11: "Role mismatch",
+	},
+	3: {
+		0:  "Invalid UPDATE message",
+		1:  "Malformed attribute list",
+		2:  "Unrecognized well-known attribute",
+		3:  "Missing mandatory attribute",
+		4:  "Invalid attribute flags",
+		5:  "Invalid attribute length",
+		6:  "Invalid ORIGIN attribute",
+		7:  "AS routing loop",
+		8:  "Invalid NEXT_HOP attribute",
+		9:  "Optional attribute error",
+		10: "Invalid network field",
+		11: "Malformed AS_PATH",
+	},
+	4: {
+		0: "Hold timer expired",
+	},
+	5: {
+		0: "Finite state machine error",
+		1: "Unexpected message in OpenSent state",
+		2: "Unexpected message in OpenConfirm state",
+		3: "Unexpected message in Established state",
+	},
+	6: {
+		0:  "Cease",
+		1:  "Maximum number of prefixes reached",
+		2:  "Administrative shutdown",
+		3:  "Peer de-configured",
+		4:  "Administrative reset",
+		5:  "Connection rejected",
+		6:  "Other configuration change",
+		7:  "Connection collision resolution",
+		8:  "Out of Resources",
+		9:  "Hard reset",
+		10: "BFD session down",
+	},
+	7: {
+		0: "Invalid ROUTE-REFRESH message",
+		1: "Invalid ROUTE-REFRESH message length",
+	},
+}
+
+// BGPErrorText returns the text of a BGP error code
+// and subcode.
+func BGPErrorText(code, subcode int) string {
+	subcodes, ok := bgpErrors[code]
+	if !ok {
+		return fmt.Sprintf("Unknown error code %d", code)
+	}
+	if text, ok := subcodes[subcode]; ok {
+		return text
+	}
+	return fmt.Sprintf("%s (subcode %d)", subcodes[0], subcode)
+}
+
+// Directions of a BGP error
+const (
+	BGPErrorSent     = "sent"
+	BGPErrorReceived = "received"
+)
+
+// BGPError is the last error of a BGP session.
+// The code and subcode are 0 if unknown.
+type BGPError struct {
+	Direction string `json:"direction,omitempty"`
+	Code      int    `json:"code"`
+	Subcode   int    `json:"subcode"`
+	Text      string `json:"text"`
+}
+
+// NewBGPError creates an error from a code and subcode
+func NewBGPError(direction string, code, subcode int) *BGPError {
+	return &BGPError{
+		Direction: direction,
+		Code:      code,
+		Subcode:   subcode,
+		Text:      BGPErrorText(code, subcode),
+	}
+}
+
+// ParseBGPError decodes a textual error like
+// "Received: Hold timer expired". The code and subcode
+// are looked up by the text.
+func ParseBGPError(text string) *BGPError {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	bgpErr := &BGPError{Text: text}
+
+	msg := text
+	if prefix, rest, ok := strings.Cut(text, ":"); ok {
+		switch strings.ToLower(strings.TrimSpace(prefix)) {
+		case "received":
+			bgpErr.Direction = BGPErrorReceived
+			msg = rest
+		case "error", "sent", "bgp error":
+			bgpErr.Direction = BGPErrorSent
+			msg = rest
+		}
+	}
+	msg = strings.TrimSpace(msg)
+	for code, subcodes := range bgpErrors {
+		for subcode, t := range subcodes {
+			if strings.EqualFold(t, msg) {
+				bgpErr.Code = code
+				bgpErr.Subcode = subcode
+				return bgpErr
+			}
+		}
+	}
+	return bgpErr
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestBGPErrorText(t *testing.T) {
+	if text := BGPErrorText(6, 2); text != "Administrative shutdown" {
+		t.Error("unexpected text:", text)
+	}
+	if text := BGPErrorText(4, 0); text != "Hold timer expired" {
+		t.Error("unexpected text:", text)
+	}
+	if text := BGPErrorText(6, 42); text != "Cease (subcode 42)" {
+		t.Error("unexpected text:", text)
+	}
+	if text := BGPErrorText(23, 1); text != "Unknown error code 23" {
+		t.Error("unexpected text:", text)
+	}
+}
+
+func TestParseBGPError(t *testing.T) {
+	err := ParseBGPError("Received: Hold timer expired")
+	if err.Direction != BGPErrorReceived || err.Code != 4 ||
+		err.Subcode != 0 {
+		t.Error("unexpected error:", err)
+	}
+	err = ParseBGPError("Error: Maximum number of prefixes reached")
+	if err.Direction != BGPErrorSent || err.Code != 6 || err.Subcode != 1 {
+		t.Error("unexpected error:", err)
+	}
+	err = ParseBGPError("Socket: Connection refused")
+	if err.Code != 0 || err.Text != "Socket: Connection refused" {
+		t.Error("unexpected error:", err)
+	}
+	if ParseBGPError("") != nil {
+		t.Error("expected no error")
+	}
+}
@@ -79,6 +79,15 @@ type Meta struct {
 	ResultFromCache bool             `json:"result_from_cache"`
 	TTL             time.Time        `json:"ttl"`
 	StoreStatus     *StoreStatusMeta `json:"store_status,omitempty"`
+
+	// Stale is set when the source failed and the result
+	// is served from the store. The age is in seconds.
+	Stale    bool    `json:"stale,omitempty"`
+	StaleAge float64 `json:"stale_age,omitempty"`
+
+	// Warnings are issues with the data from the source,
+	// e.g. fields which could not be decoded.
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 // CacheStatus contains cache timing information.
@@ -94,6 +103,19 @@ type SourceStatus struct {
 	LastRefresh     time.Time     `json:"last_refresh"`
 	State           string        `json:"state"`
 	Initialized     bool          `json:"initialized"`
+	Stale           bool          `json:"stale"`
+
+	// The status of the neighbors, when the routes
+	// are refreshed neighbor by neighbor.
+	Neighbors map[string]*NeighborRefreshStatus `json:"neighbors,omitempty"`
+}
+
+// NeighborRefreshStatus is the status of the last
+// refresh of the routes of a neighbor.
+type NeighborRefreshStatus struct {
+	LastRefresh time.Time `json:"last_refresh"`
+	State       string    `json:"state"`
+	Error       string    `json:"error,omitempty"`
 }
 
 // StoreStatus is meta data for a store
@@ -125,13 +147,24 @@ type StatusResponse struct {
 	Status Status `json:"status"`
 }
 
+// SourceHealth is the state of the circuit
+// breaker of a source.
+type SourceHealth struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Latency             float64    `json:"latency_ms"`
+	LastError           string     `json:"last_error,omitempty"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+}
+
 // A RouteServer is a datasource with attributes.
 type RouteServer struct {
-	ID         string   `json:"id"`
-	Type       string   `json:"type"`
-	Name       string   `json:"name"`
-	Group      string   `json:"group"`
-	Blackholes []string `json:"blackholes"`
+	ID         string        `json:"id"`
+	Type       string        `json:"type"`
+	Name       string        `json:"name"`
+	Group      string        `json:"group"`
+	Blackholes []string      `json:"blackholes"`
+	Health     *SourceHealth `json:"health,omitempty"`
 
 	Order int `json:"-"`
 }
@@ -157,6 +190,13 @@ type RouteServersResponse struct {
 	RouteServers RouteServers `json:"routeservers"`
 }
 
+// A RefreshResponse confirms that the refresh
+// of a route server is scheduled.
+type RefreshResponse struct {
+	RouteserverID string `json:"routeserver_id"`
+	Scheduled     bool   `json:"scheduled"`
+}
+
 // A LookupRouteServer is a shorter representation of the
 // route server data source.
 type LookupRouteServer struct {
@@ -221,7 +261,12 @@ func (com ExtCommunity) String() string {
 		if i > 0 {
 			res += ":"
 		}
-		res += strconv.Itoa(v.(int))
+		switch v := v.(type) {
+		case int:
+			res += strconv.Itoa(v)
+		case string:
+			res += v // e.g. an IPv4 address
+		}
 	}
 	return res
 }
@@ -258,6 +303,13 @@ type BGPInfo struct {
 	ExtCommunities   ExtCommunities `json:"ext_communities"`
 	LocalPref        int            `json:"local_pref"`
 	Med              int            `json:"med"`
+
+	// Optional attributes, which are not provided
+	// by all sources.
+	NextHopLinkLocal *string `json:"next_hop_link_local,omitempty"`
+	AsSet            []int   `json:"as_set,omitempty"`
+	Aigp             uint64  `json:"aigp,omitempty"`
+	OnlyToCustomer   int     `json:"otc,omitempty"`
 }
 
 // HasCommunity checks for the presence of a BGP community.
@@ -26,10 +26,78 @@ type Neighbor struct {
 	LastError       string        `json:"last_error"`
 	RouteServerID   string        `json:"routeserver_id"`
 
+	// Optional: Normalized session details
+	Session *NeighborSession `json:"session,omitempty"`
+
 	// Original response
 	Details map[string]interface{} `json:"details"`
 }
 
+// NeighborTimers are the timers of a BGP session
+// in seconds. The hold and keepalive time are the
+// negotiated values if the session is established.
+type NeighborTimers struct {
+	HoldTime      int `json:"hold_time"`
+	KeepaliveTime int `json:"keepalive_time"`
+	ConnectRetry  int `json:"connect_retry,omitempty"`
+}
+
+// Modes of the add-path capability
+const (
+	AddPathReceive     = "receive"
+	AddPathSend        = "send"
+	AddPathSendReceive = "send/receive"
+)
+
+// AddPathCapability is the add-path mode of an AFI-SAFI.
+// The AFI-SAFI is empty if the backend does not provide it.
+type AddPathCapability struct {
+	AFISAFI string `json:"afi_safi,omitempty"`
+	Mode    string `json:"mode"`
+}
+
+// GracefulRestartCapability is the graceful restart
+// capability of a session.
+type GracefulRestartCapability struct {
+	RestartTime int      `json:"restart_time,omitempty"`
+	Restarting  bool     `json:"restarting"`
+	AFISAFIs    []string `json:"afi_safis,omitempty"`
+}
+
+// NeighborCapabilities are the negotiated capabilities
+// of a BGP session.
+type NeighborCapabilities struct {
+	AFISAFIs                 []string                   `json:"afi_safis,omitempty"`
+	RouteRefresh             bool                       `json:"route_refresh"`
+	EnhancedRouteRefresh     bool                       `json:"enhanced_route_refresh"`
+	FourOctetASN             bool                       `json:"four_octet_asn"`
+	ExtendedMessage          bool                       `json:"extended_message"`
+	AddPath                  []*AddPathCapability       `json:"add_path,omitempty"`
+	GracefulRestart          *GracefulRestartCapability `json:"graceful_restart,omitempty"`
+	LongLivedGracefulRestart bool                       `json:"long_lived_graceful_restart"`
+}
+
+// RouteLimit is an import or export limit of a session.
+// Routes is the current number of routes, if known.
+type RouteLimit struct {
+	Limit  int    `json:"limit"`
+	Routes int    `json:"routes"`
+	Action string `json:"action,omitempty"`
+}
+
+// NeighborSession contains the normalized details of a
+// BGP session. Fields the backend does not provide
+// are omitted.
+type NeighborSession struct {
+	Timers       *NeighborTimers       `json:"timers,omitempty"`
+	Capabilities *NeighborCapabilities `json:"capabilities,omitempty"`
+	LastError    *BGPError             `json:"last_error,omitempty"`
+	Flaps        int                   `json:"flaps,omitempty"`
+	ImportLimit  *RouteLimit           `json:"import_limit,omitempty"`
+	ExportLimit  *RouteLimit           `json:"export_limit,omitempty"`
+	BFDState     string                `json:"bfd_state,omitempty"`
+}
+
 // String encodes a neighbor as json. This is
 // more readable than the golang default representation.
 func (n *Neighbor) String() string {
@@ -100,6 +168,13 @@ func (res *NeighborsResponse) CacheTTL() time.Duration {
 	return res.Response.Meta.TTL.Sub(now)
 }
 
+// A NeighborResponse is a single neighbor with
+// caching information.
+type NeighborResponse struct {
+	Response
+	Neighbor *Neighbor `json:"neighbor"`
+}
+
 // NeighborsLookupResults is a mapping of lookup neighbors.
 // The sourceID is used as a key.
 type NeighborsLookupResults map[string]Neighbors
@@ -3,9 +3,27 @@ package api
 import (
 	"encoding/json"
 	"log"
+	"strings"
 	"time"
 )
 
+// AFI-SAFIs of routes
+const (
+	AFISAFIIPv4Unicast    = "ipv4-unicast"
+	AFISAFIIPv6Unicast    = "ipv6-unicast"
+	AFISAFIIPv4Flowspec   = "ipv4-flowspec"
+	AFISAFIIPv6Flowspec   = "ipv6-flowspec"
+	AFISAFIIPv4VPNUnicast = "l3vpn-ipv4-unicast"
+	AFISAFIIPv6VPNUnicast = "l3vpn-ipv6-unicast"
+)
+
+// FlowSpecRule is a component of a flowspec route,
+// e.g. the type "dport" with the value "= 80".
+type FlowSpecRule struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
 // Route is a prefix with BGP information.
 type Route struct {
 	// ID         string  `json:"id"`
@@ -21,9 +39,51 @@ type Route struct {
 	Primary    bool          `json:"primary"`
 	LearntFrom *string       `json:"learnt_from"`
 
+	// Optional: The table of the route and, for flowspec
+	// and VPN routes, the AFI-SAFI and route details.
+	Table              string          `json:"table,omitempty"`
+	AFISAFI            string          `json:"afi_safi,omitempty"`
+	RouteDistinguisher string          `json:"route_distinguisher,omitempty"`
+	FlowSpec           []*FlowSpecRule `json:"flowspec,omitempty"`
+
 	Details *json.RawMessage `json:"details"`
 }
 
+// AddressFamily returns the AFI-SAFI of the route.
+// Without an AFI-SAFI, the route is a unicast route.
+func (r *Route) AddressFamily() string {
+	if r.AFISAFI != "" {
+		return r.AFISAFI
+	}
+	if strings.Contains(r.Network, ":") {
+		return AFISAFIIPv6Unicast
+	}
+	return AFISAFIIPv4Unicast
+}
+
+// Key identifies the route among the routes of a
+// neighbor: VPN routes and flowspec rules with the same
+// network are distinguished by the route distinguisher
+// and the rules.
+func (r *Route) Key() string {
+	if r.RouteDistinguisher == "" && len(r.FlowSpec) == 0 {
+		return r.Network
+	}
+	var key strings.Builder
+	if r.RouteDistinguisher != "" {
+		key.WriteString(r.RouteDistinguisher)
+		key.WriteString(" ")
+	}
+	key.WriteString(r.Network)
+	for _, rule := range r.FlowSpec {
+		key.WriteString("; ")
+		key.WriteString(rule.Type)
+		key.WriteString(" ")
+		key.WriteString(rule.Value)
+	}
+	return key.String()
+}
+
 func (r *Route) String() string {
 	s, _ := json.Marshal(r)
 	return string(s)
@@ -186,6 +246,18 @@ func (r *LookupRoute) MatchLargeCommunity(community Community) bool {
 	return r.Route.BGP.HasLargeCommunity(community)
 }
 
+// GetNeighborID returns the ID of the neighbor,
+// which announced the route.
+func (r *LookupRoute) GetNeighborID() string {
+	if r.Route != nil && r.Route.NeighborID != nil {
+		return *r.Route.NeighborID
+	}
+	if r.Neighbor != nil {
+		return r.Neighbor.ID
+	}
+	return ""
+}
+
 // MatchNeighborQuery matches a neighbor query
 func (r *LookupRoute) MatchNeighborQuery(query *NeighborQuery) bool {
 	if r.RouteServer.ID != query.SourceID {
@@ -0,0 +1,18 @@
+package api
+
+// RoutesUpdate is the change of the routes of a
+// neighbor at a route server since the last refresh.
+type RoutesUpdate struct {
+	NeighborID string
+
+	// Routes are all routes of the neighbor
+	// after the update.
+	Routes LookupRoutes
+
+	// Added are new or changed routes
+	Added LookupRoutes
+
+	// Withdrawn are the IDs (the route keys) of
+	// the removed routes.
+	Withdrawn []string
+}
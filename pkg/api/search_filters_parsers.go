@@ -78,8 +78,14 @@ func parseExtCommunityValue(value string) (*SearchFilter, error) {
 	}
 	// TODO: Mixing strings and integers is not a good idea
 	community[0] = components[0]
-	community[1], _ = strconv.Atoi(components[1])
-	community[2], _ = strconv.Atoi(components[2])
+	for i, c := range components[1:] {
+		// The administrator can be an IPv4 address
+		if v, err := strconv.Atoi(c); err == nil {
+			community[i+1] = v
+		} else {
+			community[i+1] = c
+		}
+	}
 
 	return &SearchFilter{
 		Name:  community.String(),
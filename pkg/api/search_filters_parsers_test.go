@@ -60,6 +60,20 @@ func TestParseExtCommunityValue(t *testing.T) {
 
 }
 
+func TestParseExtCommunityValueIPv4(t *testing.T) {
+	filter, err := parseExtCommunityValue("rt:192.0.2.1:42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	com := filter.Value.(ExtCommunity)
+	if com[1] != "192.0.2.1" || com[2] != 42 {
+		t.Error("unexpected community:", com)
+	}
+	if filter.Name != "rt:192.0.2.1:42" {
+		t.Error("unexpected name:", filter.Name)
+	}
+}
+
 func TestPartialParseExtCommunityValue(t *testing.T) {
 	filter, err := parseExtCommunityValue("rt:23")
 	if err == nil {
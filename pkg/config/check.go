@@ -0,0 +1,498 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/sources/birdwatcher"
+	"github.com/alice-lg/alice-lg/pkg/sources/gobgp"
+)
+
+// Check levels
+const (
+	// CheckLevelOK indicates that there were no problems.
+	CheckLevelOK = "ok"
+
+	// CheckLevelWarning is used for problems which
+	// will not prevent alice from starting, but might
+	// lead to unexpected results.
+	CheckLevelWarning = "warning"
+
+	// CheckLevelError is used for invalid configurations.
+	CheckLevelError = "error"
+)
+
+// A CheckResult is a problem found in a section
+// of the configuration.
+type CheckResult struct {
+	Section string `json:"section"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// CheckReport is the result of a configuration check.
+type CheckReport struct {
+	File    string         `json:"file"`
+	Results []*CheckResult `json:"results"`
+}
+
+// NewCheckReport creates a new empty report
+func NewCheckReport(file string) *CheckReport {
+	return &CheckReport{
+		File:    file,
+		Results: []*CheckResult{},
+	}
+}
+
+func (r *CheckReport) add(section, level, format string, args ...interface{}) {
+	r.Results = append(r.Results, &CheckResult{
+		Section: section,
+		Level:   level,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Error adds an error to the report
+func (r *CheckReport) Error(section, format string, args ...interface{}) {
+	r.add(section, CheckLevelError, format, args...)
+}
+
+// Warning adds a warning to the report
+func (r *CheckReport) Warning(section, format string, args ...interface{}) {
+	r.add(section, CheckLevelWarning, format, args...)
+}
+
+// OK marks a section as checked without problems
+func (r *CheckReport) OK(section string) {
+	r.add(section, CheckLevelOK, "")
+}
+
+// Count returns the number of results with a level
+func (r *CheckReport) Count(level string) int {
+	n := 0
+	for _, res := range r.Results {
+		if res.Level == level {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed is true if the report contains errors
+func (r *CheckReport) Failed() bool {
+	return r.Count(CheckLevelError) > 0
+}
+
+// Write prints the report in a human readable form
+func (r *CheckReport) Write(w io.Writer) {
+	fmt.Fprintln(w, "Checking configuration:", r.File)
+	fmt.Fprintln(w)
+	for _, res := range r.Results {
+		level := fmt.Sprintf("[%s]", res.Level)
+		if res.Message == "" {
+			fmt.Fprintf(w, "%-10s %s\n", level, res.Section)
+			continue
+		}
+		fmt.Fprintf(w, "%-10s %s: %s\n", level, res.Section, res.Message)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%d error(s), %d warning(s)\n",
+		r.Count(CheckLevelError), r.Count(CheckLevelWarning))
+}
+
+// WriteJSON encodes the report as json
+func (r *CheckReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// CheckConfigFile loads and checks a configuration file.
+// Errors while loading the configuration are part
+// of the report.
+func CheckConfigFile(filename string) (*CheckReport, *Config) {
+	report := NewCheckReport(filename)
+	cfg, err := LoadConfig(filename)
+	if err != nil {
+		report.Error("config", "%s", err)
+		return report, nil
+	}
+	report.File = cfg.File
+	CheckConfig(report, cfg)
+	return report, cfg
+}
+
+// CheckConfig validates the sources, communities,
+// rpki, columns and theme of a loaded configuration.
+// Nothing is started or connected.
+func CheckConfig(report *CheckReport, cfg *Config) {
+	checkServer(report, cfg)
+	checkSources(report, cfg)
+	checkDiscovery(report, cfg)
+	checkCommunities(report, cfg)
+	checkRpki(report, cfg)
+	checkColumns(report, cfg)
+	checkTheme(report, cfg)
+}
+
+// Add an ok result if there was no problem in the section
+func checkDone(report *CheckReport, section string, n int) {
+	if len(report.Results) == n {
+		report.OK(section)
+	}
+}
+
+func checkServer(report *CheckReport, cfg *Config) {
+	n := len(report.Results)
+	if cfg.Server.Listen == "" {
+		report.Error("server", "listen_http is not set")
+	} else if _, _, err := net.SplitHostPort(cfg.Server.Listen); err != nil {
+		report.Error("server", "invalid listen_http: %s", err)
+	}
+	switch cfg.Server.StoreBackend {
+	case "memory", "postgres", "sqlite", "bolt":
+	default:
+		report.Error("server", "unknown store_backend: %q",
+			cfg.Server.StoreBackend)
+	}
+	switch cfg.Server.RoutesStoreRefreshStrategy {
+	case "", RoutesRefreshStrategyAll, RoutesRefreshStrategyNeighbors:
+	default:
+		report.Error("server", "unknown routes_store_refresh_strategy: %q",
+			cfg.Server.RoutesStoreRefreshStrategy)
+	}
+	checkDone(report, "server", n)
+
+	if cfg.Housekeeping.SnapshotPath != "" &&
+		cfg.Server.StoreBackend != "memory" {
+		report.Warning("housekeeping",
+			"snapshot_path is ignored with store_backend %q",
+			cfg.Server.StoreBackend)
+	}
+}
+
+// Check that the url has a scheme and host
+func checkURL(u string) error {
+	if u == "" {
+		return fmt.Errorf("api is not set")
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("unsupported url scheme in %q", u)
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("missing host in %q", u)
+	}
+	return nil
+}
+
+func checkSources(report *CheckReport, cfg *Config) {
+	if len(cfg.Sources) == 0 {
+		report.Warning("sources", "no sources configured")
+		return
+	}
+	for _, src := range cfg.Sources {
+		section := "source." + src.ID
+		n := len(report.Results)
+
+		if sourceTypeFromBackendType(src.Backend) == "" {
+			report.Error(section, "unknown backend: %q", src.Backend)
+			continue
+		}
+
+		switch src.Backend {
+		case SourceBackendBirdwatcher:
+			c := src.Birdwatcher
+			if err := checkURL(c.API); err != nil {
+				report.Error(section, "birdwatcher: %s", err)
+			}
+			if _, err := time.LoadLocation(c.Timezone); err != nil {
+				report.Error(section, "birdwatcher: invalid timezone: %s", err)
+			}
+			if c.DecodeMode != "" &&
+				c.DecodeMode != birdwatcher.DecodeModeLenient &&
+				c.DecodeMode != birdwatcher.DecodeModeStrict {
+				report.Error(section,
+					"birdwatcher: unknown decode_mode: %q", c.DecodeMode)
+			}
+		case SourceBackendGoBGP:
+			c := src.GoBGP
+			if _, _, err := net.SplitHostPort(c.Host); err != nil {
+				report.Error(section, "gobgp: invalid host: %s", err)
+			}
+			if err := gobgp.CheckTLS(c); err != nil {
+				report.Error(section, "gobgp: %s", err)
+			}
+			switch c.TableType {
+			case "", gobgp.TableTypeAdjIn, gobgp.TableTypeLocal:
+			default:
+				report.Error(section,
+					"gobgp: table_type must be %s or %s",
+					gobgp.TableTypeAdjIn, gobgp.TableTypeLocal)
+			}
+			if c.Streaming && c.TableType == gobgp.TableTypeLocal {
+				report.Error(section,
+					"gobgp: streaming requires table_type %s",
+					gobgp.TableTypeAdjIn)
+			}
+		case SourceBackendOpenBGPDStateServer, SourceBackendOpenBGPDBgplgd:
+			c := src.OpenBGPD
+			if err := checkURL(c.API); err != nil {
+				report.Error(section, "%s: %s", src.Backend, err)
+			}
+			reasons := []struct {
+				key       string
+				community string
+				reasons   api.BGPCommunityMap
+			}{
+				{"filtered_reason", c.FilteredReason, cfg.UI.RoutesRejections.Reasons},
+				{"ineligible_reason", c.IneligibleReason, cfg.UI.RoutesRejections.Reasons},
+				{"noexport_reason", c.NoexportReason, cfg.UI.RoutesNoexports.Reasons},
+			}
+			for _, r := range reasons {
+				if r.community == "" {
+					continue
+				}
+				if _, err := r.reasons.Lookup(r.community); err != nil {
+					report.Warning(section,
+						"%s: %s: %s is not a known reason",
+						src.Backend, r.key, r.community)
+				}
+			}
+		}
+		checkDone(report, section, n)
+	}
+}
+
+func checkDiscovery(report *CheckReport, cfg *Config) {
+	inventory := cfg.Discovery.Inventory
+	if inventory == "" {
+		return // Discovery is disabled
+	}
+	n := len(report.Results)
+	if strings.HasPrefix(inventory, "http://") ||
+		strings.HasPrefix(inventory, "https://") {
+		if err := checkURL(inventory); err != nil {
+			report.Error("discovery", "inventory: %s", err)
+		}
+	} else if _, err := os.Stat(inventory); err != nil {
+		report.Error("discovery", "inventory: %s", err)
+	}
+	if cfg.Discovery.Interval <= 0 {
+		report.Error("discovery", "interval must be positive")
+	}
+	checkDone(report, "discovery", n)
+}
+
+// Check that a community part is a number, a range
+// or a wildcard.
+func checkCommunityPart(p string) bool {
+	if p == "*" {
+		return true
+	}
+	for _, v := range strings.SplitN(p, "-", 2) {
+		if v == "*" {
+			continue
+		}
+		if _, err := strconv.ParseUint(v, 10, 32); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Walk the community map and check the keys and depth.
+func checkCommunityMap(
+	report *CheckReport,
+	section string,
+	m api.BGPCommunityMap,
+	path []string,
+) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := m[k]
+		p := append(path[:len(path):len(path)], k)
+		community := strings.Join(p, ":")
+
+		// Extended communities start with the type
+		isExt := len(path) == 0 && !checkCommunityPart(k)
+		if !isExt && !checkCommunityPart(k) {
+			report.Error(section, "invalid community: %s", community)
+			continue
+		}
+
+		switch v := v.(type) {
+		case api.BGPCommunityMap:
+			if len(p) >= 3 {
+				report.Error(section, "invalid community: %s:...", community)
+				continue
+			}
+			checkCommunityMap(report, section, v, p)
+		case string:
+			if len(p) < 2 {
+				report.Error(section, "invalid community: %s", community)
+			}
+		}
+	}
+}
+
+func checkCommunities(report *CheckReport, cfg *Config) {
+	maps := []struct {
+		section string
+		m       api.BGPCommunityMap
+	}{
+		{"bgp_communities", cfg.UI.BGPCommunities},
+		{"rejection_reasons", cfg.UI.RoutesRejections.Reasons},
+		{"noexport_reasons", cfg.UI.RoutesNoexports.Reasons},
+		{"rejection_candidates", cfg.UI.RoutesRejectCandidates.Communities},
+	}
+	for _, c := range maps {
+		n := len(report.Results)
+		checkCommunityMap(report, c.section, c.m, []string{})
+		checkDone(report, c.section, n)
+	}
+}
+
+func checkRpki(report *CheckReport, cfg *Config) {
+	n := len(report.Results)
+	rpki := cfg.UI.Rpki
+	if !rpki.Enabled {
+		checkDone(report, "rpki", n)
+		return
+	}
+	definitions := []struct {
+		key  string
+		comm [][]string
+		size int
+	}{
+		{"valid", rpki.Valid, 3},
+		{"unknown", rpki.Unknown, 3},
+		{"not_checked", rpki.NotChecked, 3},
+		{"invalid", rpki.Invalid, 4}, // The last part is a range
+	}
+	for _, def := range definitions {
+		for _, comm := range def.comm {
+			if len(comm) < 3 || len(comm) > def.size {
+				report.Error("rpki", "invalid %s community: %s",
+					def.key, strings.Join(comm, ":"))
+				continue
+			}
+			for _, p := range comm {
+				if !checkCommunityPart(p) {
+					report.Error("rpki", "invalid %s community: %s",
+						def.key, strings.Join(comm, ":"))
+					break
+				}
+			}
+		}
+	}
+	checkDone(report, "rpki", n)
+}
+
+// Get all json field names of a type
+func jsonFields(t reflect.Type) map[string]bool {
+	fields := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			for k := range jsonFields(ft) {
+				fields[k] = true
+			}
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}
+
+// Check that the columns reference a known field or widget.
+func checkColumnsDefinition(
+	report *CheckReport,
+	section string,
+	columns map[string]string,
+	order []string,
+	fields map[string]bool,
+	widgets map[string]bool,
+) {
+	n := len(report.Results)
+	for _, col := range order {
+		if _, ok := columns[col]; !ok {
+			report.Error(section, "column %s has no title", col)
+		}
+		if widgets[col] {
+			continue
+		}
+		field := strings.SplitN(col, ".", 2)[0]
+		if !fields[field] {
+			report.Warning(section, "unknown column: %s", col)
+		}
+	}
+	checkDone(report, section, n)
+}
+
+func checkColumns(report *CheckReport, cfg *Config) {
+	checkColumnsDefinition(report, "neighbors_columns",
+		cfg.UI.NeighborsColumns,
+		cfg.UI.NeighborsColumnsOrder,
+		jsonFields(reflect.TypeOf(api.Neighbor{})),
+		map[string]bool{
+			"Uptime":      true,
+			"Description": true,
+		})
+
+	routesWidgets := map[string]bool{
+		"flags":  true,
+		"Flags":  true,
+		"ASPath": true,
+		"asn":    true,
+	}
+	checkColumnsDefinition(report, "routes_columns",
+		cfg.UI.RoutesColumns,
+		cfg.UI.RoutesColumnsOrder,
+		jsonFields(reflect.TypeOf(api.Route{})),
+		routesWidgets)
+	checkColumnsDefinition(report, "lookup_columns",
+		cfg.UI.LookupColumns,
+		cfg.UI.LookupColumnsOrder,
+		jsonFields(reflect.TypeOf(api.LookupRoute{})),
+		routesWidgets)
+}
+
+func checkTheme(report *CheckReport, cfg *Config) {
+	n := len(report.Results)
+	path := cfg.UI.Theme.Path
+	if path != "" {
+		stat, err := os.Stat(path)
+		if err != nil {
+			report.Error("theme", "%s", err)
+		} else if !stat.IsDir() {
+			report.Error("theme", "%s is not a directory", path)
+		}
+	}
+	checkDone(report, "theme", n)
+}
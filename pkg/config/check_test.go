@@ -0,0 +1,181 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/sources/birdwatcher"
+	"github.com/alice-lg/alice-lg/pkg/sources/gobgp"
+)
+
+// Find a result in the report
+func findCheckResult(
+	report *CheckReport,
+	section string,
+	level string,
+) *CheckResult {
+	for _, res := range report.Results {
+		if res.Section == section && res.Level == level {
+			return res
+		}
+	}
+	return nil
+}
+
+func TestCheckConfigFile(t *testing.T) {
+	report, cfg := CheckConfigFile("testdata/alice.conf")
+	if cfg == nil {
+		t.Fatal("expected config to be loaded:", report.Results[0])
+	}
+	if !report.Failed() {
+		t.Error("expected check to fail")
+	}
+
+	// The theme path does not exist
+	if findCheckResult(report, "theme", CheckLevelError) == nil {
+		t.Error("expected theme error")
+	}
+	// GoBGP without TLS cert uses the system roots
+	if res := findCheckResult(report, "source.rs2-example", CheckLevelError); res != nil {
+		t.Error("unexpected error for rs2-example:", res)
+	}
+	if findCheckResult(report, "source.rs0-example-v4", CheckLevelOK) == nil {
+		t.Error("expected rs0-example-v4 to be ok")
+	}
+	// The testdata uses the old spelling of neighbors
+	if findCheckResult(report, "lookup_columns", CheckLevelWarning) == nil {
+		t.Error("expected warning for lookup columns")
+	}
+
+	buf := &bytes.Buffer{}
+	report.Write(buf)
+	t.Log(buf.String())
+}
+
+func TestCheckConfigFileLoadError(t *testing.T) {
+	report, cfg := CheckConfigFile("testdata/does-not-exist.conf")
+	if cfg != nil {
+		t.Error("did not expect a config")
+	}
+	if !report.Failed() {
+		t.Error("expected check to fail")
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	communities := api.BGPCommunityMap{}
+	communities.Set("23:42", "valid")
+	communities.Set("23:43:*", "valid large")
+	communities.Set("rt:23:42", "valid ext")
+	communities.Set("foo:bar", "invalid")
+	communities.Set("2342", "invalid")
+
+	cfg := &Config{
+		Server: ServerConfig{
+			Listen:       "127.0.0.1:7340",
+			StoreBackend: "memory",
+		},
+		UI: UIConfig{
+			BGPCommunities: communities,
+			Rpki: RpkiConfig{
+				Enabled:    true,
+				Valid:      [][]string{{"23", "1000", "1"}},
+				Unknown:    [][]string{{"23", "1000", "2"}},
+				NotChecked: [][]string{{"23", "1000"}},
+				Invalid:    [][]string{{"23", "1000", "4", "*"}},
+			},
+			RoutesColumns:      map[string]string{"network": "Network"},
+			RoutesColumnsOrder: []string{"network", "bgp.as_path"},
+		},
+		Sources: []*SourceConfig{
+			{
+				ID:      "rs1",
+				Backend: SourceBackendBirdwatcher,
+				Birdwatcher: birdwatcher.Config{
+					API:      "http://rs1.example.net:29184",
+					Timezone: "UTC",
+				},
+			},
+			{
+				ID:      "rs2",
+				Backend: SourceBackendBirdwatcher,
+				Birdwatcher: birdwatcher.Config{
+					API:      "rs2.example.net:29184",
+					Timezone: "Mars/Olympus_Mons",
+				},
+			},
+			{
+				ID:      "rs3",
+				Backend: SourceBackendGoBGP,
+				GoBGP: gobgp.Config{
+					Host:     "rs3.example.net:50051",
+					Insecure: true,
+				},
+			},
+			{
+				ID:      "rs4",
+				Backend: "quagga",
+			},
+			{
+				ID:      "rs5",
+				Backend: SourceBackendGoBGP,
+				GoBGP: gobgp.Config{
+					Host:    "rs5.example.net:50051",
+					TLSCert: "testdata/does-not-exist.crt",
+				},
+			},
+			{
+				ID:      "rs6",
+				Backend: SourceBackendGoBGP,
+				GoBGP: gobgp.Config{
+					Host: "rs6.example.net:50051",
+				},
+			},
+			{
+				ID:      "rs7",
+				Backend: SourceBackendGoBGP,
+				GoBGP: gobgp.Config{
+					Host:      "rs7.example.net:50051",
+					Insecure:  true,
+					Streaming: true,
+					TableType: gobgp.TableTypeLocal,
+				},
+			},
+		},
+	}
+
+	report := NewCheckReport("test")
+	CheckConfig(report, cfg)
+
+	expected := []struct {
+		section string
+		level   string
+		count   int
+	}{
+		{"server", CheckLevelOK, 1},
+		{"source.rs1", CheckLevelOK, 1},
+		{"source.rs2", CheckLevelError, 2},
+		{"source.rs3", CheckLevelOK, 1},
+		{"source.rs4", CheckLevelError, 1},
+		{"source.rs5", CheckLevelError, 1},
+		{"source.rs6", CheckLevelOK, 1},
+		{"source.rs7", CheckLevelError, 1},
+		{"bgp_communities", CheckLevelError, 2},
+		{"rpki", CheckLevelError, 1},
+		{"routes_columns", CheckLevelError, 1},
+		{"theme", CheckLevelOK, 1},
+	}
+	for _, e := range expected {
+		count := 0
+		for _, res := range report.Results {
+			if res.Section == e.section && res.Level == e.level {
+				count++
+			}
+		}
+		if count != e.count {
+			t.Errorf("expected %d %s results for %s, got %d",
+				e.count, e.level, e.section, count)
+		}
+	}
+}
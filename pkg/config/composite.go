@@ -0,0 +1,527 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/sources"
+)
+
+// ErrInvalidCompositeNeighbor is returned when the ID of
+// a neighbor of a composite source can not be resolved.
+var ErrInvalidCompositeNeighbor = errors.New("invalid composite neighbor")
+
+// CompositeConfig is the configuration of a
+// composite source: The IDs of the merged sources.
+type CompositeConfig struct {
+	Sources []string
+}
+
+// resolveCompositeSources checks the sources of the
+// composite sources. The type of a composite source
+// is the type of its first source.
+func resolveCompositeSources(srcs []*SourceConfig) error {
+	byID := make(map[string]*SourceConfig)
+	for _, src := range srcs {
+		byID[src.ID] = src
+	}
+	for _, src := range srcs {
+		if src.Backend != SourceBackendComposite {
+			continue
+		}
+		if len(src.Composite.Sources) == 0 {
+			return fmt.Errorf("source.%s: composite without sources", src.ID)
+		}
+		for _, id := range src.Composite.Sources {
+			member, ok := byID[id]
+			if !ok {
+				return fmt.Errorf(
+					"source.%s: unknown composite source: %s", src.ID, id)
+			}
+			if member.Backend == SourceBackendComposite {
+				return fmt.Errorf(
+					"source.%s: nested composite source: %s", src.ID, id)
+			}
+		}
+		src.Type = byID[src.Composite.Sources[0]].Type
+	}
+	return nil
+}
+
+// initCompositeSources creates the instances of the
+// composite sources. The sources are resolved by ID
+// with each request, so updated sources are used.
+func (cfg *Config) initCompositeSources(srcs []*SourceConfig) {
+	instancesLock.Lock()
+	defer instancesLock.Unlock()
+	for _, src := range srcs {
+		if src.Backend == SourceBackendComposite && src.instance == nil {
+			src.instance = &compositeSource{
+				id:      src.ID,
+				sources: src.Composite.Sources,
+				lookup:  cfg.SourceInstanceByID,
+			}
+		}
+	}
+}
+
+// The neighbor ID of a composite source encodes the
+// merged neighbors: <source>.<neighbor>,<source>.<neighbor>
+const (
+	compositeNeighborSep = ","
+	compositeSourceSep   = "."
+)
+
+// compositeNeighbor is a neighbor of a source
+type compositeNeighbor struct {
+	sourceID   string
+	neighborID string
+}
+
+// makeCompositeNeighborID encodes the neighbors
+func makeCompositeNeighborID(neighbors []compositeNeighbor) string {
+	ids := make([]string, 0, len(neighbors))
+	for _, n := range neighbors {
+		ids = append(ids, n.sourceID+compositeSourceSep+n.neighborID)
+	}
+	return strings.Join(ids, compositeNeighborSep)
+}
+
+// parseCompositeNeighborID decodes the neighbors. The
+// ID of a source can not contain the separator.
+func parseCompositeNeighborID(id string) ([]compositeNeighbor, error) {
+	neighbors := []compositeNeighbor{}
+	for _, token := range strings.Split(id, compositeNeighborSep) {
+		ids := strings.SplitN(token, compositeSourceSep, 2)
+		if len(ids) != 2 || ids[0] == "" || ids[1] == "" {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidCompositeNeighbor, id)
+		}
+		neighbors = append(neighbors, compositeNeighbor{
+			sourceID:   ids[0],
+			neighborID: ids[1],
+		})
+	}
+	return neighbors, nil
+}
+
+// A compositeSource merges the neighbors and routes of
+// multiple sources, e.g. the IPv4 and IPv6 daemons of a
+// route server. Neighbors are correlated by ASN and
+// description.
+type compositeSource struct {
+	id      string
+	sources []string
+	lookup  func(id string) sources.Source
+}
+
+// source resolves a source of the composite
+func (src *compositeSource) source(id string) (sources.Source, error) {
+	for _, sourceID := range src.sources {
+		if sourceID != id {
+			continue
+		}
+		if s := src.lookup(id); s != nil {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", sources.ErrSourceNotFound, id)
+}
+
+// mergeMeta merges the response meta of the sources.
+// The result is valid until the first TTL expires.
+func mergeMeta(meta, other *api.Meta) *api.Meta {
+	if meta == nil {
+		return other
+	}
+	if other == nil {
+		return meta
+	}
+	merged := *meta
+	if other.TTL.Before(merged.TTL) {
+		merged.TTL = other.TTL
+	}
+	if other.CacheStatus.CachedAt.Before(merged.CacheStatus.CachedAt) {
+		merged.CacheStatus = other.CacheStatus
+	}
+	merged.ResultFromCache = meta.ResultFromCache && other.ResultFromCache
+	merged.Stale = meta.Stale || other.Stale
+	if other.StaleAge > merged.StaleAge {
+		merged.StaleAge = other.StaleAge
+	}
+	if len(other.Warnings) > 0 {
+		merged.Warnings = append(
+			append([]string{}, meta.Warnings...), other.Warnings...)
+	}
+	return &merged
+}
+
+// ExpireCaches does nothing, the caches of the
+// sources are expired by the housekeeping.
+func (src *compositeSource) ExpireCaches() int {
+	return 0
+}
+
+// Status returns the status of the first available source
+func (src *compositeSource) Status(
+	ctx context.Context,
+) (*api.StatusResponse, error) {
+	var lastErr error
+	for _, id := range src.sources {
+		s, err := src.source(id)
+		if err != nil {
+			return nil, err
+		}
+		status, err := s.Status(ctx)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return status, nil
+	}
+	return nil, lastErr
+}
+
+// addWarnings adds the warnings about failed
+// sources to a copy of the meta.
+func addWarnings(meta *api.Meta, warnings []string) *api.Meta {
+	if len(warnings) == 0 {
+		return meta
+	}
+	if meta == nil {
+		meta = &api.Meta{}
+	}
+	merged := *meta
+	merged.Warnings = append(
+		append([]string{}, meta.Warnings...), warnings...)
+	return &merged
+}
+
+// collectNeighbors requests the neighbors from each
+// source. Failing sources are skipped with a warning,
+// unless all fail.
+func (src *compositeSource) collectNeighbors(
+	ctx context.Context,
+	summary bool,
+) (*api.Meta, map[string]api.Neighbors, error) {
+	var (
+		meta     *api.Meta
+		lastErr  error
+		warnings []string
+	)
+	neighbors := make(map[string]api.Neighbors)
+	for _, id := range src.sources {
+		s, err := src.source(id)
+		if err != nil {
+			return nil, nil, err
+		}
+		var res *api.NeighborsResponse
+		if summary {
+			res, err = s.NeighborsSummary(ctx)
+		} else {
+			res, err = s.Neighbors(ctx)
+		}
+		if err != nil {
+			log.Println("composite", src.id, "neighbors of", id, "failed:", err)
+			warnings = append(warnings,
+				fmt.Sprintf("neighbors of %s are unavailable: %s", id, err))
+			lastErr = err
+			continue
+		}
+		meta = mergeMeta(meta, res.Meta)
+		neighbors[id] = res.Neighbors
+	}
+	if len(neighbors) == 0 {
+		return nil, nil, lastErr
+	}
+	return addWarnings(meta, warnings), neighbors, nil
+}
+
+// neighborKey correlates the neighbors of the sources
+func neighborKey(n *api.Neighbor) string {
+	return strconv.Itoa(n.ASN) + "\x00" + n.Description
+}
+
+// mergedNeighbor is a neighbor of the composite
+// source with at most one neighbor of each source.
+type mergedNeighbor struct {
+	neighbor    *api.Neighbor
+	members     []compositeNeighbor
+	established bool
+}
+
+// hasSource checks if a neighbor of the source
+// is already merged.
+func (m *mergedNeighbor) hasSource(sourceID string) bool {
+	for _, member := range m.members {
+		if member.sourceID == sourceID {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeNeighbors merges the neighbors with the same key.
+// Only neighbors of different sources are merged: Neighbors
+// with the same key within a source remain separate.
+// The neighbors keep the order of the sources.
+func (src *compositeSource) mergeNeighbors(
+	bySource map[string]api.Neighbors,
+) api.Neighbors {
+	groups := make(map[string][]*mergedNeighbor)
+	all := []*mergedNeighbor{}
+	for _, id := range src.sources {
+		for _, n := range bySource[id] {
+			key := neighborKey(n)
+			var merged *mergedNeighbor
+			for _, g := range groups[key] {
+				if !g.hasSource(id) {
+					merged = g
+					break
+				}
+			}
+			if merged == nil {
+				merged = &mergedNeighbor{
+					neighbor: &api.Neighbor{
+						ASN:           n.ASN,
+						Description:   n.Description,
+						State:         n.State,
+						Uptime:        n.Uptime,
+						Session:       n.Session,
+						RouteServerID: src.id,
+						Details: map[string]interface{}{
+							"neighbors": map[string]string{},
+						},
+					},
+				}
+				groups[key] = append(groups[key], merged)
+				all = append(all, merged)
+			}
+			m := merged.neighbor
+			if len(merged.members) > 0 {
+				m.Address += ", "
+			}
+			merged.members = append(merged.members, compositeNeighbor{
+				sourceID:   id,
+				neighborID: n.ID,
+			})
+			m.Address += n.Address
+			m.RoutesReceived += n.RoutesReceived
+			m.RoutesFiltered += n.RoutesFiltered
+			m.RoutesExported += n.RoutesExported
+			m.RoutesPreferred += n.RoutesPreferred
+			m.RoutesAccepted += n.RoutesAccepted
+
+			// The session pair is up if one session is up.
+			// The session details and the uptime are of the
+			// established sessions.
+			if n.State == "up" || n.State == "established" {
+				m.State = n.State
+				if n.Session != nil {
+					m.Session = n.Session
+				}
+				if !merged.established || n.Uptime < m.Uptime {
+					m.Uptime = n.Uptime
+				}
+				merged.established = true
+			}
+			if n.LastError != "" {
+				m.LastError = n.LastError
+			}
+			m.Details["neighbors"].(map[string]string)[id] = n.ID
+		}
+	}
+
+	neighbors := make(api.Neighbors, 0, len(all))
+	for _, merged := range all {
+		n := merged.neighbor
+		n.ID = makeCompositeNeighborID(merged.members)
+		neighbors = append(neighbors, n)
+	}
+	sort.Sort(neighbors)
+	return neighbors
+}
+
+// Neighbors returns the merged neighbors
+func (src *compositeSource) Neighbors(
+	ctx context.Context,
+) (*api.NeighborsResponse, error) {
+	meta, neighbors, err := src.collectNeighbors(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	return &api.NeighborsResponse{
+		Response: api.Response{
+			Meta: meta,
+		},
+		Neighbors: src.mergeNeighbors(neighbors),
+	}, nil
+}
+
+// NeighborsSummary returns the merged neighbors summary
+func (src *compositeSource) NeighborsSummary(
+	ctx context.Context,
+) (*api.NeighborsResponse, error) {
+	meta, neighbors, err := src.collectNeighbors(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	return &api.NeighborsResponse{
+		Response: api.Response{
+			Meta: meta,
+		},
+		Neighbors: src.mergeNeighbors(neighbors),
+	}, nil
+}
+
+// NeighborsStatus returns the status of the merged neighbors
+func (src *compositeSource) NeighborsStatus(
+	ctx context.Context,
+) (*api.NeighborsStatusResponse, error) {
+	res, err := src.NeighborsSummary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := make(api.NeighborsStatus, 0, len(res.Neighbors))
+	for _, n := range res.Neighbors {
+		status = append(status, &api.NeighborStatus{
+			ID:    n.ID,
+			State: n.State,
+			Since: n.Uptime,
+		})
+	}
+	sort.Sort(status)
+	return &api.NeighborsStatusResponse{
+		Response: api.Response{
+			Meta: res.Meta,
+		},
+		Neighbors: status,
+	}, nil
+}
+
+// collectRoutes requests the routes of the merged
+// neighbor from the sources. Failing sources are
+// skipped with a warning, unless all fail.
+func (src *compositeSource) collectRoutes(
+	ctx context.Context,
+	neighborID string,
+	fetch func(sources.Source, string) (*api.RoutesResponse, error),
+) (*api.RoutesResponse, error) {
+	neighbors, err := parseCompositeNeighborID(neighborID)
+	if err != nil {
+		return nil, err
+	}
+	result := &api.RoutesResponse{
+		Imported:    api.Routes{},
+		Filtered:    api.Routes{},
+		NotExported: api.Routes{},
+	}
+	var (
+		lastErr  error
+		warnings []string
+		answered int
+	)
+	for _, n := range neighbors {
+		s, err := src.source(n.sourceID)
+		if err != nil {
+			return nil, err
+		}
+		res, err := fetch(s, n.neighborID)
+		if err != nil {
+			log.Println("composite", src.id, "routes of", n.sourceID, "failed:", err)
+			warnings = append(warnings,
+				fmt.Sprintf("routes of %s are unavailable: %s", n.sourceID, err))
+			lastErr = err
+			continue
+		}
+		answered++
+		result.Meta = mergeMeta(result.Meta, res.Meta)
+		result.Imported = append(result.Imported, res.Imported...)
+		result.Filtered = append(result.Filtered, res.Filtered...)
+		result.NotExported = append(result.NotExported, res.NotExported...)
+	}
+	if answered == 0 {
+		return nil, lastErr
+	}
+	result.Meta = addWarnings(result.Meta, warnings)
+	sort.Sort(result.Imported)
+	sort.Sort(result.Filtered)
+	sort.Sort(result.NotExported)
+	return result, nil
+}
+
+// Routes returns the merged routes of a neighbor
+func (src *compositeSource) Routes(
+	ctx context.Context,
+	neighborID string,
+) (*api.RoutesResponse, error) {
+	return src.collectRoutes(ctx, neighborID,
+		func(s sources.Source, id string) (*api.RoutesResponse, error) {
+			return s.Routes(ctx, id)
+		})
+}
+
+// RoutesReceived returns the merged received routes
+func (src *compositeSource) RoutesReceived(
+	ctx context.Context,
+	neighborID string,
+) (*api.RoutesResponse, error) {
+	return src.collectRoutes(ctx, neighborID,
+		func(s sources.Source, id string) (*api.RoutesResponse, error) {
+			return s.RoutesReceived(ctx, id)
+		})
+}
+
+// RoutesFiltered returns the merged filtered routes
+func (src *compositeSource) RoutesFiltered(
+	ctx context.Context,
+	neighborID string,
+) (*api.RoutesResponse, error) {
+	return src.collectRoutes(ctx, neighborID,
+		func(s sources.Source, id string) (*api.RoutesResponse, error) {
+			return s.RoutesFiltered(ctx, id)
+		})
+}
+
+// RoutesNotExported returns the merged routes not exported
+func (src *compositeSource) RoutesNotExported(
+	ctx context.Context,
+	neighborID string,
+) (*api.RoutesResponse, error) {
+	return src.collectRoutes(ctx, neighborID,
+		func(s sources.Source, id string) (*api.RoutesResponse, error) {
+			return s.RoutesNotExported(ctx, id)
+		})
+}
+
+// AllRoutes returns the routes of all sources. The
+// stores refresh the sources of the composite instead.
+func (src *compositeSource) AllRoutes(
+	ctx context.Context,
+) (*api.RoutesResponse, error) {
+	result := &api.RoutesResponse{
+		Imported:    api.Routes{},
+		Filtered:    api.Routes{},
+		NotExported: api.Routes{},
+	}
+	for _, id := range src.sources {
+		s, err := src.source(id)
+		if err != nil {
+			return nil, err
+		}
+		res, err := s.AllRoutes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		result.Meta = mergeMeta(result.Meta, res.Meta)
+		result.Imported = append(result.Imported, res.Imported...)
+		result.Filtered = append(result.Filtered, res.Filtered...)
+		result.NotExported = append(result.NotExported, res.NotExported...)
+	}
+	return result, nil
+}
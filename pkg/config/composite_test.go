@@ -0,0 +1,225 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-ini/ini"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/sources"
+)
+
+// compositeTestSource serves neighbors and received routes
+type compositeTestSource struct {
+	*sources.FailedSource
+	neighbors api.Neighbors
+	routes    map[string]api.Routes
+	routesErr error
+}
+
+func (src *compositeTestSource) NeighborsSummary(
+	context.Context,
+) (*api.NeighborsResponse, error) {
+	return &api.NeighborsResponse{
+		Response:  api.Response{Meta: &api.Meta{}},
+		Neighbors: src.neighbors,
+	}, nil
+}
+
+func (src *compositeTestSource) RoutesReceived(
+	_ context.Context,
+	neighborID string,
+) (*api.RoutesResponse, error) {
+	if src.routesErr != nil {
+		return nil, src.routesErr
+	}
+	return &api.RoutesResponse{
+		Response: api.Response{Meta: &api.Meta{}},
+		Imported: src.routes[neighborID],
+	}, nil
+}
+
+func TestResolveCompositeSources(t *testing.T) {
+	parsed, err := ini.Load([]byte(`
+[source.rs1]
+name = rs1
+[source.rs1.composite]
+sources = rs1-v4, rs1-v6
+
+[source.rs1-v4]
+[source.rs1-v4.birdwatcher]
+api = http://rs1:29184/
+type = single_table
+
+[source.rs1-v6]
+[source.rs1-v6.birdwatcher]
+api = http://rs1:29186/
+type = single_table
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srcs, err := getSources(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs1 := srcs[0]
+	if rs1.Backend != SourceBackendComposite || rs1.Type != SourceTypeBird {
+		t.Error("unexpected composite source:", rs1.Backend, rs1.Type)
+	}
+	if len(rs1.Composite.Sources) != 2 || rs1.Composite.Sources[1] != "rs1-v6" {
+		t.Error("unexpected sources:", rs1.Composite.Sources)
+	}
+
+	invalid := []string{
+		"[source.rs1]\n[source.rs1.composite]\nsources = rs2\n",
+		"[source.rs1]\n[source.rs1.composite]\nsources = rs1\n",
+		"[source.rs1]\n[source.rs1.composite]\n",
+	}
+	for _, conf := range invalid {
+		parsed, err := ini.Load([]byte(conf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := getSources(parsed); err == nil {
+			t.Error("expected error for:", conf)
+		}
+	}
+}
+
+func TestCompositeSource(t *testing.T) {
+	failed := sources.NewFailedSource(errors.New("unavailable"))
+	v4 := &SourceConfig{ID: "rs1-v4", instance: &compositeTestSource{
+		FailedSource: failed,
+		neighbors: api.Neighbors{
+			{ID: "R1", ASN: 65001, Description: "AS1", State: "down",
+				Address: "192.0.2.1", RoutesAccepted: 2,
+				Uptime: time.Minute},
+			{ID: "R2", ASN: 65002, Description: "AS2", State: "up",
+				Address: "192.0.2.2"},
+		},
+		routes: map[string]api.Routes{
+			"R1": {{Network: "198.51.100.0/24"}},
+		},
+	}}
+	v6Instance := &compositeTestSource{
+		FailedSource: failed,
+		neighbors: api.Neighbors{
+			{ID: "R1_6", ASN: 65001, Description: "AS1", State: "up",
+				Address: "2001:db8::1", RoutesAccepted: 3,
+				Uptime:  time.Hour,
+				Session: &api.NeighborSession{Flaps: 1}},
+		},
+		routes: map[string]api.Routes{
+			"R1_6": {{Network: "2001:db8:1::/48"}},
+		},
+	}
+	v6 := &SourceConfig{ID: "rs1-v6", instance: v6Instance}
+	rs1 := &SourceConfig{
+		ID:        "rs1",
+		Backend:   SourceBackendComposite,
+		Composite: CompositeConfig{Sources: []string{"rs1-v4", "rs1-v6"}},
+	}
+	cfg := &Config{}
+	cfg.SetSources([]*SourceConfig{rs1, v4, v6})
+
+	ctx := context.Background()
+	src := cfg.SourceInstanceByID("rs1")
+	res, err := src.NeighborsSummary(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Neighbors) != 2 {
+		t.Fatal("expected 2 merged neighbors, got:", res.Neighbors)
+	}
+	n := res.Neighbors[0]
+	if n.ID != "rs1-v4.R1,rs1-v6.R1_6" || n.RouteServerID != "rs1" {
+		t.Error("unexpected neighbor:", n)
+	}
+	if n.Address != "192.0.2.1, 2001:db8::1" || n.State != "up" ||
+		n.RoutesAccepted != 5 {
+		t.Error("unexpected neighbor:", n)
+	}
+	if n.Session == nil || n.Session.Flaps != 1 {
+		t.Error("expected session of the established neighbor:", n.Session)
+	}
+	if n.Uptime != time.Hour {
+		t.Error("expected uptime of the established neighbor:", n.Uptime)
+	}
+
+	routes, err := src.RoutesReceived(ctx, n.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes.Imported) != 2 ||
+		routes.Imported[0].Network != "198.51.100.0/24" {
+		t.Error("unexpected routes:", routes.Imported)
+	}
+
+	// The routes of the answering sources are returned
+	v6Instance.routesErr = errors.New("timeout")
+	routes, err = src.RoutesReceived(ctx, n.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes.Imported) != 1 || len(routes.Meta.Warnings) != 1 {
+		t.Error("unexpected routes:", routes.Imported, routes.Meta.Warnings)
+	}
+	if _, err := src.RoutesReceived(ctx, "rs1-v6.R1_6"); err == nil {
+		t.Error("expected an error if all sources fail")
+	}
+	v6Instance.routesErr = nil
+
+	// The neighbors status is the status of the merged neighbors
+	status, err := src.NeighborsStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Neighbors) != 2 {
+		t.Error("unexpected status:", status.Neighbors)
+	}
+
+	// Neighbors of other sources are rejected
+	if _, err := src.RoutesReceived(ctx, "rs2.R1"); !errors.Is(
+		err, sources.ErrSourceNotFound) {
+		t.Error("expected source not found, got:", err)
+	}
+	if _, err := src.RoutesReceived(ctx, "R1"); !errors.Is(
+		err, ErrInvalidCompositeNeighbor) {
+		t.Error("expected invalid neighbor, got:", err)
+	}
+}
+
+func TestCompositeMergeNeighborsSameSource(t *testing.T) {
+	src := &compositeSource{
+		id:      "rs1",
+		sources: []string{"rs1-v4", "rs1-v6"},
+	}
+	// Two sessions of the same ASN and description
+	// on the IPv4 route server.
+	neighbors := src.mergeNeighbors(map[string]api.Neighbors{
+		"rs1-v4": {
+			{ID: "R1", ASN: 65001, Description: "AS1", Address: "192.0.2.1"},
+			{ID: "R2", ASN: 65001, Description: "AS1", Address: "192.0.2.2"},
+		},
+		"rs1-v6": {
+			{ID: "R1_6", ASN: 65001, Description: "AS1", Address: "2001:db8::1"},
+		},
+	})
+	if len(neighbors) != 2 {
+		t.Fatal("expected 2 merged neighbors, got:", neighbors)
+	}
+	ids := map[string]string{}
+	for _, n := range neighbors {
+		ids[n.ID] = n.Address
+	}
+	if ids["rs1-v4.R1,rs1-v6.R1_6"] != "192.0.2.1, 2001:db8::1" {
+		t.Error("unexpected merged neighbors:", ids)
+	}
+	if ids["rs1-v4.R2"] != "192.0.2.2" {
+		t.Error("unexpected merged neighbors:", ids)
+	}
+}
@@ -6,11 +6,15 @@ package config
 
 import (
 	"bytes"
+	"encoding/csv"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-ini/ini"
@@ -45,6 +49,10 @@ const (
 
 	// SourceTypeOpenBGPD is used for an OpenBGPD source.
 	SourceTypeOpenBGPD = "openbgpd"
+
+	// SourceTypeComposite is used for a composite source,
+	// until the type of its sources is resolved.
+	SourceTypeComposite = "composite"
 )
 
 const (
@@ -63,6 +71,10 @@ const (
 	// SourceBackendOpenBGPDBgplgd is used when the openbgpd
 	// state is exported through the bgplgd.
 	SourceBackendOpenBGPDBgplgd = "openbgpd-bgplgd"
+
+	// SourceBackendComposite is used when the source
+	// merges the neighbors and routes of other sources.
+	SourceBackendComposite = "composite"
 )
 
 const (
@@ -78,39 +90,96 @@ const (
 	// DefaultRoutesStoreQueryLimit is the default limit for
 	// prefixes returned from the store.
 	DefaultRoutesStoreQueryLimit = 200000
+
+	// DefaultRoutesStoreNeighborRefreshParallelism is the
+	// default number of neighbors of a source refreshed
+	// at the same time.
+	DefaultRoutesStoreNeighborRefreshParallelism = 4
+
+	// DefaultRefreshJitter is the maximum random delay in
+	// seconds before a refresh, so not all sources are
+	// refreshed at once.
+	DefaultRefreshJitter = 30
+
+	// DefaultBreakerFailureThreshold is the number of
+	// consecutive failures after which the circuit breaker
+	// of a source opens.
+	DefaultBreakerFailureThreshold = 5
+
+	// DefaultBreakerOpenTimeout is the time in seconds
+	// after which an open circuit breaker probes the source.
+	DefaultBreakerOpenTimeout = 30
+)
+
+// Routes store refresh strategies
+const (
+	// RoutesRefreshStrategyAll fetches all routes of
+	// a source at once.
+	RoutesRefreshStrategyAll = "all"
+
+	// RoutesRefreshStrategyNeighbors fetches the routes
+	// neighbor by neighbor. Only failed neighbors are retried.
+	RoutesRefreshStrategyNeighbors = "neighbors"
 )
 
 // A ServerConfig holds the runtime configuration
 // for the backend.
 type ServerConfig struct {
-	Listen                            string `ini:"listen_http"`
-	HTTPTimeout                       int    `ini:"http_timeout"`
-	EnablePrefixLookup                bool   `ini:"enable_prefix_lookup"`
-	PrefixLookupCommunityFilterCutoff int    `ini:"prefix_lookup_community_filter_cutoff"`
-	NeighborsStoreRefreshInterval     int    `ini:"neighbors_store_refresh_interval"`
-	NeighborsStoreRefreshParallelism  int    `ini:"neighbors_store_refresh_parallelism"`
-	RoutesStoreRefreshInterval        int    `ini:"routes_store_refresh_interval"`
-	RoutesStoreRefreshParallelism     int    `ini:"routes_store_refresh_parallelism"`
-	RoutesStoreQueryLimit             uint   `ini:"routes_store_query_limit"`
-	StoreBackend                      string `ini:"store_backend"`
-	DefaultAsn                        int    `ini:"asn"`
-	EnableNeighborsStatusRefresh      bool   `ini:"enable_neighbors_status_refresh"`
-	StreamParserThrottle              int    `ini:"stream_parser_throttle"`
+	Listen                            string `ini:"listen_http" yaml:"listen_http"`
+	HTTPTimeout                       int    `ini:"http_timeout" yaml:"http_timeout"`
+	EnablePrefixLookup                bool   `ini:"enable_prefix_lookup" yaml:"enable_prefix_lookup"`
+	PrefixLookupCommunityFilterCutoff int    `ini:"prefix_lookup_community_filter_cutoff" yaml:"prefix_lookup_community_filter_cutoff"`
+	NeighborsStoreRefreshInterval     int    `ini:"neighbors_store_refresh_interval" yaml:"neighbors_store_refresh_interval"`
+	NeighborsStoreRefreshParallelism  int    `ini:"neighbors_store_refresh_parallelism" yaml:"neighbors_store_refresh_parallelism"`
+	RoutesStoreRefreshInterval        int    `ini:"routes_store_refresh_interval" yaml:"routes_store_refresh_interval"`
+	RoutesStoreRefreshParallelism     int    `ini:"routes_store_refresh_parallelism" yaml:"routes_store_refresh_parallelism"`
+	RoutesStoreQueryLimit             uint   `ini:"routes_store_query_limit" yaml:"routes_store_query_limit"`
+	RoutesStoreRefreshStrategy        string `ini:"routes_store_refresh_strategy" yaml:"routes_store_refresh_strategy"`
+	RoutesStoreNeighborParallelism    int    `ini:"routes_store_neighbor_refresh_parallelism" yaml:"routes_store_neighbor_refresh_parallelism"`
+	StoreBackend                      string `ini:"store_backend" yaml:"store_backend"`
+	DefaultAsn                        int    `ini:"asn" yaml:"asn"`
+	EnableNeighborsStatusRefresh      bool   `ini:"enable_neighbors_status_refresh" yaml:"enable_neighbors_status_refresh"`
+	StreamParserThrottle              int    `ini:"stream_parser_throttle" yaml:"stream_parser_throttle"`
+	RefreshJitter                     int    `ini:"refresh_jitter" yaml:"refresh_jitter"`
+	AdminToken                        string `ini:"admin_token" yaml:"admin_token"`
+	EnableStoreFallback               bool   `ini:"enable_store_fallback" yaml:"enable_store_fallback"`
 }
 
 // PostgresConfig is the configuration for the database
 // connection when the postgres backend is used.
 type PostgresConfig struct {
-	URL      string `ini:"url"`
-	MaxConns int32  `ini:"max_connections"`
-	MinConns int32  `ini:"min_connections"`
+	URL      string `ini:"url" yaml:"url"`
+	MaxConns int32  `ini:"max_connections" yaml:"max_connections"`
+	MinConns int32  `ini:"min_connections" yaml:"min_connections"`
+}
+
+// SqliteConfig is the configuration for the database
+// file when the sqlite backend is used.
+type SqliteConfig struct {
+	Path string `ini:"path" yaml:"path"`
+}
+
+// BoltConfig is the configuration for the database
+// file when the bolt backend is used.
+type BoltConfig struct {
+	Path string `ini:"path" yaml:"path"`
 }
 
 // HousekeepingConfig describes the housekeeping interval
-// and flags.
+// and flags. With the memory backend, a snapshot of the
+// stores is written to the snapshot path.
 type HousekeepingConfig struct {
-	Interval           int  `ini:"interval"`
-	ForceReleaseMemory bool `ini:"force_release_memory"`
+	Interval           int    `ini:"interval" yaml:"interval"`
+	ForceReleaseMemory bool   `ini:"force_release_memory" yaml:"force_release_memory"`
+	SnapshotPath       string `ini:"snapshot_path" yaml:"snapshot_path"`
+}
+
+// DiscoveryConfig configures the discovery of sources
+// from an inventory. The inventory is either a local file
+// or an http(s) url and is read every interval minutes.
+type DiscoveryConfig struct {
+	Inventory string `ini:"inventory" yaml:"inventory"`
+	Interval  int    `ini:"interval" yaml:"interval"`
 }
 
 // RejectionsConfig holds rejection reasons
@@ -171,15 +240,15 @@ type UIConfig struct {
 
 // ThemeConfig describes a theme configuration
 type ThemeConfig struct {
-	Path     string `ini:"path"`
-	BasePath string `ini:"url_base"` // Optional, default: /theme
+	Path     string `ini:"path" yaml:"path"`
+	BasePath string `ini:"url_base" yaml:"url_base"` // Optional, default: /theme
 }
 
 // PaginationConfig holds settings for route pagination
 type PaginationConfig struct {
-	RoutesFilteredPageSize    int `ini:"routes_filtered_page_size"`
-	RoutesAcceptedPageSize    int `ini:"routes_accepted_page_size"`
-	RoutesNotExportedPageSize int `ini:"routes_not_exported_page_size"`
+	RoutesFilteredPageSize    int `ini:"routes_filtered_page_size" yaml:"routes_filtered_page_size"`
+	RoutesAcceptedPageSize    int `ini:"routes_accepted_page_size" yaml:"routes_accepted_page_size"`
+	RoutesNotExportedPageSize int `ini:"routes_not_exported_page_size" yaml:"routes_not_exported_page_size"`
 }
 
 // A SourceConfig is a generic source configuration
@@ -192,12 +261,23 @@ type SourceConfig struct {
 	// Blackhole IPs
 	Blackholes []string
 
+	// Refresh schedule: The interval overrides the refresh
+	// interval of the stores, if set. No refresh happens
+	// within a maintenance window.
+	RefreshInterval time.Duration
+	Maintenance     []*MaintenanceWindow
+
+	// Calls to the source pass a circuit breaker,
+	// unless the failure threshold is 0.
+	Breaker sources.BreakerConfig
+
 	// Source configurations
 	Type        string
 	Backend     string
 	Birdwatcher birdwatcher.Config
 	GoBGP       gobgp.Config
 	OpenBGPD    openbgpd.Config
+	Composite   CompositeConfig
 
 	// Source instance
 	instance sources.Source
@@ -207,15 +287,41 @@ type SourceConfig struct {
 type Config struct {
 	Server       ServerConfig
 	Postgres     *PostgresConfig
+	Sqlite       *SqliteConfig
+	Bolt         *BoltConfig
 	Housekeeping HousekeepingConfig
+	Discovery    DiscoveryConfig
 	UI           UIConfig
 	Sources      []*SourceConfig
 	File         string
+
+	// The sources can be updated at runtime
+	// when discovery is enabled.
+	sourcesLock sync.RWMutex
+
+	// The parsed configuration is required for
+	// resolving templates of discovered sources.
+	parsed *ini.File
+}
+
+// GetSources returns the currently configured sources
+func (cfg *Config) GetSources() []*SourceConfig {
+	cfg.sourcesLock.RLock()
+	defer cfg.sourcesLock.RUnlock()
+	return cfg.Sources
+}
+
+// SetSources replaces the configured sources
+func (cfg *Config) SetSources(sources []*SourceConfig) {
+	cfg.initCompositeSources(sources)
+	cfg.sourcesLock.Lock()
+	defer cfg.sourcesLock.Unlock()
+	cfg.Sources = sources
 }
 
 // SourceByID returns a source from the config by id
 func (cfg *Config) SourceByID(id string) *SourceConfig {
-	for _, sourceConfig := range cfg.Sources {
+	for _, sourceConfig := range cfg.GetSources() {
 		if sourceConfig.ID == id {
 			return sourceConfig
 		}
@@ -249,6 +355,8 @@ func sourceBackendTypeFromConfig(section *ini.Section) (string, error) {
 		return SourceBackendOpenBGPDBgplgd, nil
 	} else if strings.HasSuffix(name, "openbgpd-state-server") {
 		return SourceBackendOpenBGPDStateServer, nil
+	} else if strings.HasSuffix(name, "composite") {
+		return SourceBackendComposite, nil
 	}
 
 	return "", ErrSourceTypeUnknown
@@ -266,6 +374,8 @@ func sourceTypeFromBackendType(t string) string {
 		return SourceTypeOpenBGPD
 	case SourceBackendOpenBGPDBgplgd:
 		return SourceTypeOpenBGPD
+	case SourceBackendComposite:
+		return SourceTypeComposite
 	default:
 		return ""
 	}
@@ -708,177 +818,479 @@ func getUIConfig(config *ini.File) (UIConfig, error) {
 	return uiConfig, nil
 }
 
-func getSources(config *ini.File) ([]*SourceConfig, error) {
-	sources := []*SourceConfig{}
+// Get the backend configuration section of a source.
+// The section is nil if there is no backend configured.
+func getSourceBackendSection(section *ini.Section) (*ini.Section, error) {
+	sections := section.ChildSections()
+	if len(sections) == 0 {
+		return nil, nil
+	}
+	if len(sections) > 1 {
+		// The source is ambiguous
+		return nil, fmt.Errorf("%s has ambigous backends", section.Name())
+	}
+	return sections[0], nil
+}
 
-	order := 0
-	sourceSections := config.ChildSections("source")
-	for _, section := range sourceSections {
-		if !isSourceBase(section) {
+// Merge the keys of the sections into a new section.
+// Keys of later sections take precedence.
+func mergeSections(
+	scratch *ini.File,
+	name string,
+	sections ...*ini.Section,
+) (*ini.Section, error) {
+	merged, err := scratch.NewSection(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, section := range sections {
+		if section == nil {
 			continue
 		}
+		for _, key := range section.Keys() {
+			merged.Key(key.Name()).SetValue(key.Value())
+		}
+	}
+	return merged, nil
+}
 
-		// Derive source-id from name
-		sourceID := section.Name()[len("source:"):]
+// Get a source template and its backend configuration
+func getSourceTemplate(
+	config *ini.File,
+	name string,
+) (*ini.Section, *ini.Section, error) {
+	template, err := config.GetSection("source_template." + name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unknown source template: %s", name)
+	}
+	backend, err := getSourceBackendSection(template)
+	if err != nil {
+		return nil, nil, err
+	}
+	return template, backend, nil
+}
 
-		// Try to get child configs and determine
-		// Source type
-		sourceConfigSections := section.ChildSections()
-		if len(sourceConfigSections) == 0 {
+// Resolve the inherited settings from a template. Base and
+// backend configuration of the source are merged with
+// the template.
+func resolveSourceTemplate(
+	config *ini.File,
+	scratch *ini.File,
+	section *ini.Section,
+) (*ini.Section, *ini.Section, error) {
+	backend, err := getSourceBackendSection(section)
+	if err != nil {
+		return nil, nil, err
+	}
+	inherit := section.Key("inherit").MustString("")
+	if inherit == "" {
+		if backend == nil {
 			// This source has no configured backend
-			return nil, fmt.Errorf("%s has no backend configuration", section.Name())
+			return nil, nil, fmt.Errorf(
+				"%s has no backend configuration", section.Name())
 		}
+		return section, backend, nil
+	}
 
-		if len(sourceConfigSections) > 1 {
-			// The source is ambiguous
-			return nil, fmt.Errorf("%s has ambigous backends", section.Name())
-		}
+	template, templateBackend, err := getSourceTemplate(config, inherit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", section.Name(), err)
+	}
+	if backend == nil && templateBackend == nil {
+		return nil, nil, fmt.Errorf(
+			"%s has no backend configuration", section.Name())
+	}
 
-		// Configure backend
-		backendConfig := sourceConfigSections[0]
-		backendType, err := sourceBackendTypeFromConfig(backendConfig)
-		if err != nil {
-			return nil, fmt.Errorf("%s has an unsupported backend", section.Name())
+	// Determine the backend from the source or template
+	var backendName string
+	if backend != nil {
+		backendName = backend.Name()[len(section.Name())+1:]
+	}
+	if templateBackend != nil {
+		name := templateBackend.Name()[len(template.Name())+1:]
+		if backendName != "" && backendName != name {
+			return nil, nil, fmt.Errorf(
+				"%s has a different backend than template %s",
+				section.Name(), inherit)
 		}
+		backendName = name
+	}
 
-		sourceType := sourceTypeFromBackendType(backendType)
-
-		// Make config
-		sourceName := section.Key("name").MustString("Unknown Source")
-		sourceGroup := section.Key("group").MustString("")
-		sourceBlackholes := decoders.TrimmedCSVStringList(
-			section.Key("blackholes").MustString(""))
-
-		srcCfg := &SourceConfig{
-			ID:         sourceID,
-			Order:      order,
-			Name:       sourceName,
-			Group:      sourceGroup,
-			Blackholes: sourceBlackholes,
-			Backend:    backendType,
-			Type:       sourceType,
-		}
+	base, err := mergeSections(scratch, section.Name(), template, section)
+	if err != nil {
+		return nil, nil, err
+	}
+	backend, err = mergeSections(
+		scratch, section.Name()+"."+backendName, templateBackend, backend)
+	if err != nil {
+		return nil, nil, err
+	}
+	return base, backend, nil
+}
 
-		// Register route server ID with pool
-		pools.RouteServers.Acquire(sourceID)
+// Read the records of a source list:
+// id, name, group, api
+func readSourceList(section *ini.Section) ([][]string, error) {
+	r := csv.NewReader(strings.NewReader(section.Body()))
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = 4
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", section.Name(), err)
+	}
+	return records, nil
+}
 
-		// Set backend
-		switch backendType {
-		case SourceBackendBirdwatcher:
-			sourceType := backendConfig.Key("type").MustString("")
-			mainTable := backendConfig.Key("main_table").MustString("master")
-			peerTablePrefix := backendConfig.Key("peer_table_prefix").MustString("T")
-			pipeProtocolPrefix := backendConfig.Key("pipe_protocol_prefix").MustString("M")
-
-			if sourceType != "single_table" &&
-				sourceType != "multi_table" {
-				log.Fatal("Configuration error (birdwatcher source) unknown birdwatcher type:", sourceType)
-			}
+// Generate sources from a source list. Each line
+// of the section body is: id, name, group, api
+// The template is derived from the section name.
+func getSourceListSources(
+	config *ini.File,
+	scratch *ini.File,
+	section *ini.Section,
+) ([][2]*ini.Section, error) {
+	name := section.Name()[len("source_list."):]
+	template, templateBackend, err := getSourceTemplate(config, name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", section.Name(), err)
+	}
+	if templateBackend == nil {
+		return nil, fmt.Errorf(
+			"%s: template has no backend configuration", section.Name())
+	}
+	backendName := templateBackend.Name()[len(template.Name())+1:]
 
-			c := birdwatcher.Config{
-				ID:   srcCfg.ID,
-				Name: srcCfg.Name,
+	// The api is the host for GoBGP sources
+	apiKey := "api"
+	if backendName == SourceBackendGoBGP {
+		apiKey = "host"
+	}
 
-				Timezone:        "UTC",
-				ServerTime:      "2006-01-02T15:04:05.999999999Z07:00",
-				ServerTimeShort: "2006-01-02",
-				ServerTimeExt:   "Mon, 02 Jan 2006 15:04:05 -0700",
+	records, err := readSourceList(section)
+	if err != nil {
+		return nil, err
+	}
 
-				Type:               sourceType,
-				MainTable:          mainTable,
-				PeerTablePrefix:    peerTablePrefix,
-				PipeProtocolPrefix: pipeProtocolPrefix,
-			}
+	sources := make([][2]*ini.Section, 0, len(records))
+	for _, rec := range records {
+		id := strings.TrimSpace(rec[0])
+		if id == "" {
+			return nil, fmt.Errorf("%s: source without id", section.Name())
+		}
+		sourceName := "source." + id
+		if _, err := scratch.GetSection(sourceName); err == nil {
+			return nil, fmt.Errorf("%s: duplicate source %s", section.Name(), id)
+		}
+		base, err := mergeSections(scratch, sourceName, template)
+		if err != nil {
+			return nil, err
+		}
+		base.Key("name").SetValue(strings.TrimSpace(rec[1]))
+		base.Key("group").SetValue(strings.TrimSpace(rec[2]))
 
-			if err := backendConfig.MapTo(&c); err != nil {
-				return nil, err
-			}
-			srcCfg.Birdwatcher = c
-
-			log.Println("Adding birdwatcher source",
-				c.Name, "of type", sourceType,
-				"with peer_table_prefix", peerTablePrefix,
-				"and pipe_protocol_prefix", pipeProtocolPrefix)
-			if c.AltPipeProtocolSuffix != "" {
-				log.Println(
-					"Alt pipe protocol prefix:", c.AltPipeProtocolPrefix,
-					"suffix:", c.AltPipeProtocolSuffix,
-				)
-			}
+		backend, err := mergeSections(
+			scratch, sourceName+"."+backendName, templateBackend)
+		if err != nil {
+			return nil, err
+		}
+		backend.Key(apiKey).SetValue(strings.TrimSpace(rec[3]))
 
-		case SourceBackendGoBGP:
-			c := gobgp.Config{
-				ID:   srcCfg.ID,
-				Name: srcCfg.Name,
-			}
+		sources = append(sources, [2]*ini.Section{base, backend})
+	}
+	return sources, nil
+}
 
-			if err := backendConfig.MapTo(&c); err != nil {
-				return nil, err
-			}
-			// Update defaults:
-			//  - processing_timeout
-			if c.ProcessingTimeout == 0 {
-				c.ProcessingTimeout = 300
-			}
+// Get all sources from the config. Sources are either
+// configured in a [source.ID] section, optionally inheriting
+// settings from a [source_template.NAME] section, or
+// generated from a [source_list.NAME] section.
+func getSources(config *ini.File) ([]*SourceConfig, error) {
+	sources := []*SourceConfig{}
+	scratch := ini.Empty()
+	ids := map[string]bool{}
 
-			srcCfg.GoBGP = c
+	for _, section := range config.Sections() {
+		name := section.Name()
 
-		case SourceBackendOpenBGPDStateServer:
-			// Get cache TTL and reject communities from the config
-			cacheTTL := time.Second * time.Duration(backendConfig.Key("cache_ttl").MustInt(300))
-			routesCacheSize := backendConfig.Key("routes_cache_size").MustInt(1024)
-			rc, err := getRoutesRejections(config)
+		// Collect the base and backend configurations
+		var sections [][2]*ini.Section
+		if strings.HasPrefix(name, "source.") && isSourceBase(section) {
+			base, backend, err := resolveSourceTemplate(config, scratch, section)
 			if err != nil {
 				return nil, err
 			}
-			rejectComms := rc.Reasons.Communities()
-
-			c := openbgpd.Config{
-				ID:                srcCfg.ID,
-				Name:              srcCfg.Name,
-				CacheTTL:          cacheTTL,
-				RoutesCacheSize:   routesCacheSize,
-				RejectCommunities: rejectComms,
-			}
-			if err := backendConfig.MapTo(&c); err != nil {
+			sections = [][2]*ini.Section{{base, backend}}
+		} else if strings.HasPrefix(name, "source_list.") {
+			listSections, err := getSourceListSources(config, scratch, section)
+			if err != nil {
 				return nil, err
 			}
-			srcCfg.OpenBGPD = c
+			sections = listSections
+		}
 
-		case SourceBackendOpenBGPDBgplgd:
-			// Get cache TTL from the config
-			cacheTTL := time.Second * time.Duration(backendConfig.Key("cache_ttl").MustInt(300))
-			routesCacheSize := backendConfig.Key("routes_cache_size").MustInt(1024)
-			rc, err := getRoutesRejections(config)
-			if err != nil {
+		for _, s := range sections {
+			// Templates and source lists are expanded, apply
+			// the overrides to the generated sections.
+			if err := applySourceEnvOverrides(os.Environ(), s[0], s[1]); err != nil {
 				return nil, err
 			}
-			rejectComms := rc.Reasons.Communities()
-
-			c := openbgpd.Config{
-				ID:                srcCfg.ID,
-				Name:              srcCfg.Name,
-				CacheTTL:          cacheTTL,
-				RoutesCacheSize:   routesCacheSize,
-				RejectCommunities: rejectComms,
-			}
-			if err := backendConfig.MapTo(&c); err != nil {
+			srcCfg, err := getSourceConfig(config, s[0], s[1], len(sources))
+			if err != nil {
 				return nil, err
 			}
-			srcCfg.OpenBGPD = c
+			for _, src := range expandSourceVRFs(srcCfg) {
+				if ids[src.ID] {
+					return nil, fmt.Errorf("duplicate source: %s", src.ID)
+				}
+				ids[src.ID] = true
+				if err := src.checkInstance(); err != nil {
+					log.Println(
+						"source", src.ID, "is unavailable:", err)
+				}
+				src.Order = len(sources)
+				sources = append(sources, src)
+			}
 		}
+	}
 
-		// Add to list of sources
-		sources = append(sources, srcCfg)
-		order++
+	if err := resolveCompositeSources(sources); err != nil {
+		return nil, err
 	}
 
 	return sources, nil
 }
 
+// expandSourceVRFs creates a source for each VRF
+// of a GoBGP source. The ID and the name of the source
+// are suffixed with the name of the VRF.
+func expandSourceVRFs(src *SourceConfig) []*SourceConfig {
+	vrfs := decoders.TrimmedCSVStringList(src.GoBGP.VRFs)
+	if src.Backend != SourceBackendGoBGP || len(vrfs) == 0 {
+		return []*SourceConfig{src}
+	}
+	expanded := make([]*SourceConfig, 0, len(vrfs))
+	for _, vrf := range vrfs {
+		vrfSrc := *src
+		vrfSrc.ID = src.ID + "-" + vrf
+		vrfSrc.Name = src.Name + " (" + vrf + ")"
+		vrfSrc.GoBGP.ID = vrfSrc.ID
+		vrfSrc.GoBGP.Name = vrfSrc.Name
+		vrfSrc.GoBGP.VRF = vrf
+		vrfSrc.GoBGP.VRFs = ""
+		expanded = append(expanded, &vrfSrc)
+	}
+	return expanded
+}
+
+// Make the source config from the base and
+// backend configuration.
+func getSourceConfig(
+	config *ini.File,
+	section *ini.Section,
+	backendConfig *ini.Section,
+	order int,
+) (*SourceConfig, error) {
+	// Derive source-id from name
+	sourceID := section.Name()[len("source:"):]
+
+	// Configure backend
+	backendType, err := sourceBackendTypeFromConfig(backendConfig)
+	if err != nil {
+		return nil, fmt.Errorf("%s has an unsupported backend", section.Name())
+	}
+
+	sourceType := sourceTypeFromBackendType(backendType)
+
+	// Make config
+	sourceName := section.Key("name").MustString("Unknown Source")
+	sourceGroup := section.Key("group").MustString("")
+	sourceBlackholes := decoders.TrimmedCSVStringList(
+		section.Key("blackholes").MustString(""))
+	refreshInterval := time.Duration(
+		section.Key("refresh_interval").MustInt(0)) * time.Minute
+	maintenance, err := ParseMaintenanceWindows(
+		decoders.TrimmedCSVStringList(
+			section.Key("maintenance").MustString("")))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", section.Name(), err)
+	}
+	breaker := sources.BreakerConfig{
+		FailureThreshold: section.Key("breaker_failure_threshold").MustInt(
+			DefaultBreakerFailureThreshold),
+		OpenTimeout: time.Second * time.Duration(
+			section.Key("breaker_open_timeout").MustInt(
+				DefaultBreakerOpenTimeout)),
+	}
+
+	srcCfg := &SourceConfig{
+		ID:              sourceID,
+		Order:           order,
+		Name:            sourceName,
+		Group:           sourceGroup,
+		Blackholes:      sourceBlackholes,
+		RefreshInterval: refreshInterval,
+		Maintenance:     maintenance,
+		Breaker:         breaker,
+		Backend:         backendType,
+		Type:            sourceType,
+	}
+
+	// Register route server ID with pool
+	pools.RouteServers.Acquire(sourceID)
+
+	// Set backend
+	switch backendType {
+	case SourceBackendBirdwatcher:
+		sourceType := backendConfig.Key("type").MustString("")
+		mainTable := backendConfig.Key("main_table").MustString("master")
+		peerTablePrefix := backendConfig.Key("peer_table_prefix").MustString("T")
+		pipeProtocolPrefix := backendConfig.Key("pipe_protocol_prefix").MustString("M")
+
+		if sourceType != "single_table" &&
+			sourceType != "multi_table" {
+			return nil, fmt.Errorf(
+				"%s has an unknown birdwatcher type: %q",
+				section.Name(), sourceType)
+		}
+
+		c := birdwatcher.Config{
+			ID:   srcCfg.ID,
+			Name: srcCfg.Name,
+
+			Timezone:        "UTC",
+			ServerTime:      "2006-01-02T15:04:05.999999999Z07:00",
+			ServerTimeShort: "2006-01-02",
+			ServerTimeExt:   "Mon, 02 Jan 2006 15:04:05 -0700",
+			DecodeMode:      birdwatcher.DecodeModeLenient,
+
+			Type:               sourceType,
+			MainTable:          mainTable,
+			PeerTablePrefix:    peerTablePrefix,
+			PipeProtocolPrefix: pipeProtocolPrefix,
+		}
+
+		if err := backendConfig.MapTo(&c); err != nil {
+			return nil, err
+		}
+		srcCfg.Birdwatcher = c
+
+		log.Println("Adding birdwatcher source",
+			c.Name, "of type", sourceType,
+			"with peer_table_prefix", peerTablePrefix,
+			"and pipe_protocol_prefix", pipeProtocolPrefix)
+		if c.AltPipeProtocolSuffix != "" {
+			log.Println(
+				"Alt pipe protocol prefix:", c.AltPipeProtocolPrefix,
+				"suffix:", c.AltPipeProtocolSuffix,
+			)
+		}
+
+	case SourceBackendGoBGP:
+		c := gobgp.Config{
+			ID:   srcCfg.ID,
+			Name: srcCfg.Name,
+		}
+
+		if err := backendConfig.MapTo(&c); err != nil {
+			return nil, err
+		}
+		// Update defaults:
+		//  - processing_timeout
+		if c.ProcessingTimeout == 0 {
+			c.ProcessingTimeout = 300
+		}
+		//  - table_type
+		if c.TableType == "" {
+			c.TableType = gobgp.TableTypeAdjIn
+		}
+
+		srcCfg.GoBGP = c
+
+	case SourceBackendOpenBGPDStateServer:
+		// Get cache TTL and reject communities from the config
+		cacheTTL := time.Second * time.Duration(backendConfig.Key("cache_ttl").MustInt(300))
+		routesCacheSize := backendConfig.Key("routes_cache_size").MustInt(1024)
+		rc, err := getRoutesRejections(config)
+		if err != nil {
+			return nil, err
+		}
+		rejectComms := rc.Reasons.Communities()
+
+		c := openbgpd.Config{
+			ID:                srcCfg.ID,
+			Name:              srcCfg.Name,
+			CacheTTL:          cacheTTL,
+			RoutesCacheSize:   routesCacheSize,
+			RejectCommunities: rejectComms,
+		}
+		if err := backendConfig.MapTo(&c); err != nil {
+			return nil, err
+		}
+		srcCfg.OpenBGPD = c
+
+	case SourceBackendOpenBGPDBgplgd:
+		// Get cache TTL from the config
+		cacheTTL := time.Second * time.Duration(backendConfig.Key("cache_ttl").MustInt(300))
+		routesCacheSize := backendConfig.Key("routes_cache_size").MustInt(1024)
+		rc, err := getRoutesRejections(config)
+		if err != nil {
+			return nil, err
+		}
+		rejectComms := rc.Reasons.Communities()
+		nc, err := getRoutesNoexports(config)
+		if err != nil {
+			return nil, err
+		}
+		noexportComms := nc.Reasons.Communities()
+
+		c := openbgpd.Config{
+			ID:                  srcCfg.ID,
+			Name:                srcCfg.Name,
+			CacheTTL:            cacheTTL,
+			RoutesCacheSize:     routesCacheSize,
+			RejectCommunities:   rejectComms,
+			NoexportCommunities: noexportComms,
+		}
+		if err := backendConfig.MapTo(&c); err != nil {
+			return nil, err
+		}
+		srcCfg.OpenBGPD = c
+
+	case SourceBackendComposite:
+		srcCfg.Composite = CompositeConfig{
+			Sources: decoders.TrimmedCSVStringList(
+				backendConfig.Key("sources").MustString("")),
+		}
+	}
+
+	return srcCfg, nil
+}
+
+// Match source list section headers
+var matchSourceListSection = regexp.MustCompile(
+	`(?m)^\s*\[(source_list\.[^\]]+)\]`)
+
+// getUnparseableSections returns the sections which
+// are parsed with our own parsers. This includes the
+// source lists.
+func getUnparseableSections(data []byte) []string {
+	sections := []string{
+		"bgp_communities",
+		"blackhole_communities",
+		"rejection_reasons",
+		"noexport_reasons",
+		"rpki",
+	}
+	for _, m := range matchSourceListSection.FindAllSubmatch(data, -1) {
+		sections = append(sections, string(m[1]))
+	}
+	return sections
+}
+
 // preprocessConfig parses the variables in the config
 // and applies it to the rest of the config.
-func preprocessConfig(data []byte) []byte {
+func preprocessConfig(data []byte) ([]byte, error) {
 	lines := bytes.Split(data, []byte("\n"))
 	config := make([][]byte, 0, len(lines))
 
@@ -886,7 +1298,9 @@ func preprocessConfig(data []byte) []byte {
 	for _, line := range lines {
 		l := strings.TrimSpace(string(line))
 		if strings.HasPrefix(l, "$") {
-			expMap.AddExpr(l[1:])
+			if err := expMap.AddExpr(l[1:]); err != nil {
+				return nil, err
+			}
 			continue
 		}
 		config = append(config, line)
@@ -898,13 +1312,14 @@ func preprocessConfig(data []byte) []byte {
 		l := string(line)
 		exp, err := expMap.Expand(l)
 		if err != nil {
-			log.Fatal("Error expanding expression in config:", l, err)
+			return nil, fmt.Errorf(
+				"error expanding expression in config: %s: %w", l, err)
 		}
 		for _, e := range exp {
 			configLines = append(configLines, e)
 		}
 	}
-	return []byte(strings.Join(configLines, "\n"))
+	return []byte(strings.Join(configLines, "\n")), nil
 }
 
 // LoadConfig reads a configuration from a file.
@@ -920,23 +1335,36 @@ func LoadConfig(file string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
-	configData = preprocessConfig(configData)
+
+	// The YAML configuration is translated into
+	// the INI representation.
+	if isYAMLConfigFile(file) {
+		configData, err = yamlToINI(configData)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	configData, err = preprocessConfig(configData)
+	if err != nil {
+		return nil, err
+	}
 
 	// Load configuration, but handle bgp communities section
 	// with our own parser
 	parsedConfig, err := ini.LoadSources(ini.LoadOptions{
-		UnparseableSections: []string{
-			"bgp_communities",
-			"blackhole_communities",
-			"rejection_reasons",
-			"noexport_reasons",
-			"rpki",
-		},
+		UnparseableSections: getUnparseableSections(configData),
 	}, configData)
 	if err != nil {
 		return nil, err
 	}
 
+	// Apply overrides from the environment and
+	// read secrets referenced in the config.
+	applyEnvOverrides(parsedConfig, os.Environ())
+	if err := resolveSecretRefs(parsedConfig); err != nil {
+		return nil, err
+	}
+
 	// Map sections
 	server := ServerConfig{
 		HTTPTimeout:                       DefaultHTTPTimeout,
@@ -945,6 +1373,10 @@ func LoadConfig(file string) (*Config, error) {
 		RoutesStoreRefreshParallelism:     1,
 		NeighborsStoreRefreshParallelism:  1,
 		RoutesStoreQueryLimit:             DefaultRoutesStoreQueryLimit,
+		RoutesStoreRefreshStrategy:        RoutesRefreshStrategyAll,
+		RoutesStoreNeighborParallelism:    DefaultRoutesStoreNeighborRefreshParallelism,
+		RefreshJitter:                     DefaultRefreshJitter,
+		EnableStoreFallback:               true,
 	}
 	if err := parsedConfig.Section("server").MapTo(&server); err != nil {
 		return nil, err
@@ -962,11 +1394,38 @@ func LoadConfig(file string) (*Config, error) {
 		}
 	}
 
+	sqlite := &SqliteConfig{}
+	if err := parsedConfig.Section("sqlite").MapTo(sqlite); err != nil {
+		return nil, err
+	}
+	if server.StoreBackend == "sqlite" {
+		if sqlite.Path == "" {
+			sqlite.Path = "alice-lg.db"
+		}
+	}
+
+	bolt := &BoltConfig{}
+	if err := parsedConfig.Section("bolt").MapTo(bolt); err != nil {
+		return nil, err
+	}
+	if server.StoreBackend == "bolt" {
+		if bolt.Path == "" {
+			bolt.Path = "alice-lg.bolt"
+		}
+	}
+
 	housekeeping := HousekeepingConfig{}
 	if err := parsedConfig.Section("housekeeping").MapTo(&housekeeping); err != nil {
 		return nil, err
 	}
 
+	discovery := DiscoveryConfig{
+		Interval: 5,
+	}
+	if err := parsedConfig.Section("discovery").MapTo(&discovery); err != nil {
+		return nil, err
+	}
+
 	// Get all sources
 	sources, err := getSources(parsedConfig)
 	if err != nil {
@@ -979,45 +1438,108 @@ func LoadConfig(file string) (*Config, error) {
 		return nil, err
 	}
 
-	// Update stream parser throttle on all birdwatcher sources
-	for _, src := range sources {
-		if src.Backend == SourceBackendBirdwatcher {
-			src.Birdwatcher.StreamParserThrottle = server.StreamParserThrottle
-		}
-	}
+	setStreamParserThrottle(sources, server.StreamParserThrottle)
 
 	config := &Config{
 		Server:       server,
 		Postgres:     psql,
+		Sqlite:       sqlite,
+		Bolt:         bolt,
 		Housekeeping: housekeeping,
+		Discovery:    discovery,
 		UI:           ui,
 		Sources:      sources,
 		File:         file,
+		parsed:       parsedConfig,
 	}
+	config.initCompositeSources(sources)
 
 	return config, nil
 }
 
-// GetInstance gets a source instance from config
+// Update stream parser throttle on all birdwatcher
+// and openbgpd sources
+func setStreamParserThrottle(sources []*SourceConfig, throttle int) {
+	for _, src := range sources {
+		switch src.Backend {
+		case SourceBackendBirdwatcher:
+			src.Birdwatcher.StreamParserThrottle = throttle
+		case SourceBackendOpenBGPDStateServer, SourceBackendOpenBGPDBgplgd:
+			src.OpenBGPD.StreamParserThrottle = throttle
+		}
+	}
+}
+
+// instancesLock guards the creation of the source
+// instances, which are requested concurrently.
+var instancesLock sync.Mutex
+
+// GetInstance gets a source instance from config.
+// If the source can not be created, all requests
+// fail with the error.
 func (cfg *SourceConfig) GetInstance() sources.Source {
+	instancesLock.Lock()
+	defer instancesLock.Unlock()
 	if cfg.instance != nil {
 		return cfg.instance
 	}
 
-	var instance sources.Source
+	instance, err := cfg.newInstance()
+	if err != nil {
+		log.Println("could not create source", cfg.ID+":", err)
+		instance = sources.NewFailedSource(err)
+	}
+	if cfg.Breaker.FailureThreshold > 0 {
+		instance = sources.NewBreaker(cfg.Name, instance, cfg.Breaker)
+	}
+
+	cfg.instance = instance
+	return instance
+}
+
+// Instance returns the source instance if it was
+// already created, or nil. Unlike GetInstance, no
+// instance is created.
+func (cfg *SourceConfig) Instance() sources.Source {
+	instancesLock.Lock()
+	defer instancesLock.Unlock()
+	return cfg.instance
+}
+
+// CloseInstance closes the source instance, if it was
+// created and holds connections or goroutines. This is
+// required when a source is replaced or removed.
+func (cfg *SourceConfig) CloseInstance() error {
+	if closer, ok := cfg.Instance().(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// newInstance creates the source instance of the backend
+func (cfg *SourceConfig) newInstance() (sources.Source, error) {
 	switch cfg.Backend {
 	case SourceBackendBirdwatcher:
-		instance = birdwatcher.NewBirdwatcher(cfg.Birdwatcher)
+		return birdwatcher.NewBirdwatcher(cfg.Birdwatcher), nil
 	case SourceBackendGoBGP:
-		instance = gobgp.NewGoBGP(cfg.GoBGP)
+		return gobgp.NewGoBGP(cfg.GoBGP)
 	case SourceBackendOpenBGPDStateServer:
-		instance = openbgpd.NewStateServerSource(&cfg.OpenBGPD)
+		return openbgpd.NewStateServerSource(&cfg.OpenBGPD), nil
 	case SourceBackendOpenBGPDBgplgd:
-		instance = openbgpd.NewBgplgdSource(&cfg.OpenBGPD)
+		return openbgpd.NewBgplgdSource(&cfg.OpenBGPD), nil
+	case SourceBackendComposite:
+		return nil, fmt.Errorf("composite source %s is not initialized", cfg.ID)
 	}
+	return nil, fmt.Errorf("unsupported backend: %s", cfg.Backend)
+}
 
-	cfg.instance = instance
-	return instance
+// checkInstance checks if the source instance can be
+// created, without creating it.
+func (cfg *SourceConfig) checkInstance() error {
+	if cfg.Backend == SourceBackendGoBGP {
+		return gobgp.CheckTLS(cfg.GoBGP)
+	}
+	return nil
 }
 
 // Get configuration file with fallbacks
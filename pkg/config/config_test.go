@@ -1,8 +1,12 @@
 package config
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/alice-lg/alice-lg/pkg/sources"
 	"github.com/alice-lg/alice-lg/pkg/sources/birdwatcher"
 	"github.com/alice-lg/alice-lg/pkg/sources/gobgp"
 )
@@ -21,6 +25,10 @@ func TestLoadConfigs(t *testing.T) {
 		t.Error("Listen string not present.")
 	}
 
+	if !config.Server.EnableStoreFallback {
+		t.Error("Store fallback should be enabled by default.")
+	}
+
 	if len(config.UI.RoutesColumns) == 0 {
 		t.Error("Route columns settings missing")
 	}
@@ -172,6 +180,47 @@ func TestBlackholeParsing(t *testing.T) {
 	}
 }
 
+func TestSourceRefreshSchedule(t *testing.T) {
+	config, err := LoadConfig("testdata/alice.conf")
+	if err != nil {
+		t.Fatal("Could not load test config:", err)
+	}
+	rs1 := config.Sources[0]
+	if rs1.RefreshInterval != 10*time.Minute {
+		t.Error("unexpected refresh interval:", rs1.RefreshInterval)
+	}
+	if len(rs1.Maintenance) != 2 {
+		t.Fatal("expected two maintenance windows:", rs1.Maintenance)
+	}
+	sunday := time.Date(2023, 1, 1, 3, 0, 0, 0, time.UTC)
+	if !rs1.InMaintenance(sunday) {
+		t.Error("expected source to be in maintenance")
+	}
+	if rs1.InMaintenance(sunday.Add(24 * time.Hour)) {
+		t.Error("expected source not to be in maintenance")
+	}
+	if config.Sources[1].RefreshInterval != 0 {
+		t.Error("expected default refresh interval")
+	}
+}
+
+func TestSourceBreakerConfig(t *testing.T) {
+	config, err := LoadConfig("testdata/alice.conf")
+	if err != nil {
+		t.Fatal("Could not load test config:", err)
+	}
+	rs1 := config.Sources[0]
+	if rs1.Breaker.FailureThreshold != DefaultBreakerFailureThreshold {
+		t.Error("unexpected failure threshold:", rs1.Breaker.FailureThreshold)
+	}
+	if rs1.Breaker.OpenTimeout != 30*time.Second {
+		t.Error("unexpected open timeout:", rs1.Breaker.OpenTimeout)
+	}
+	if _, ok := rs1.GetInstance().(*sources.Breaker); !ok {
+		t.Error("expected the source to be wrapped")
+	}
+}
+
 func TestRpkiConfig(t *testing.T) {
 	config, err := LoadConfig("testdata/alice.conf")
 	if err != nil {
@@ -262,3 +311,144 @@ func TestGetBlackholeCommunities(t *testing.T) {
 	}
 	t.Log(comms)
 }
+
+func TestSourceTemplates(t *testing.T) {
+	config, err := LoadConfig("testdata/source_templates.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Sources) != 4 {
+		t.Fatal("expected 4 sources, got:", len(config.Sources))
+	}
+
+	// Inherited source
+	rs1 := config.Sources[0]
+	if rs1.ID != "rs1-fra-v4" || rs1.Name != "rs1.fra (IPv4)" {
+		t.Error("unexpected source:", rs1.ID, rs1.Name)
+	}
+	if rs1.Group != "FRA" || rs1.Blackholes[0] != "10.23.6.666" {
+		t.Error("expected group and blackholes from template:", rs1.Group, rs1.Blackholes)
+	}
+	if rs1.Birdwatcher.API != "http://rs1.fra.example.net:29184/" {
+		t.Error("unexpected api:", rs1.Birdwatcher.API)
+	}
+	if rs1.Birdwatcher.MainTable != "master" {
+		t.Error("expected main_table to be overridden:", rs1.Birdwatcher.MainTable)
+	}
+	if rs1.Birdwatcher.NeighborsRefreshTimeout != 2 {
+		t.Error("expected neighbors_refresh_timeout from template")
+	}
+	if rs1.Birdwatcher.Timezone != "UTC" {
+		t.Error("expected default timezone:", rs1.Birdwatcher.Timezone)
+	}
+
+	// Generated sources
+	rs2 := config.Sources[1]
+	if rs2.ID != "rs2-fra-v4" || rs2.Order != 1 {
+		t.Error("unexpected source:", rs2.ID, rs2.Order)
+	}
+	if rs2.Birdwatcher.API != "http://rs2.fra.example.net:29184/" {
+		t.Error("unexpected api:", rs2.Birdwatcher.API)
+	}
+	if rs2.Birdwatcher.MainTable != "master4" {
+		t.Error("unexpected main table:", rs2.Birdwatcher.MainTable)
+	}
+	rs3 := config.Sources[2]
+	if rs3.Name != "rs1.ams, (IPv4)" || rs3.Group != "AMS" {
+		t.Error("unexpected source:", rs3.Name, rs3.Group)
+	}
+	rs4 := config.Sources[3]
+	if rs4.Backend != SourceBackendGoBGP {
+		t.Error("unexpected backend:", rs4.Backend)
+	}
+	if rs4.GoBGP.Host != "rs3.fra.example.net:50051" || !rs4.GoBGP.Insecure {
+		t.Error("unexpected gobgp config:", rs4.GoBGP)
+	}
+	if rs4.GoBGP.ProcessingTimeout != 300 {
+		t.Error("expected default processing timeout")
+	}
+}
+
+func TestExpandSourceVRFs(t *testing.T) {
+	src := &SourceConfig{
+		ID:      "rs1",
+		Name:    "rs1.example.net",
+		Backend: SourceBackendGoBGP,
+		GoBGP: gobgp.Config{
+			ID:   "rs1",
+			Name: "rs1.example.net",
+			VRFs: "lan1, lan2",
+		},
+	}
+	sources := expandSourceVRFs(src)
+	if len(sources) != 2 {
+		t.Fatal("expected a source per vrf:", sources)
+	}
+	lan2 := sources[1]
+	if lan2.ID != "rs1-lan2" || lan2.GoBGP.ID != "rs1-lan2" {
+		t.Error("unexpected id:", lan2.ID)
+	}
+	if lan2.Name != "rs1.example.net (lan2)" {
+		t.Error("unexpected name:", lan2.Name)
+	}
+	if lan2.GoBGP.VRF != "lan2" || lan2.GoBGP.VRFs != "" {
+		t.Error("unexpected vrf config:", lan2.GoBGP)
+	}
+
+	// Sources without VRFs are not expanded
+	src.GoBGP.VRFs = ""
+	if sources := expandSourceVRFs(src); len(sources) != 1 || sources[0] != src {
+		t.Error("unexpected sources:", sources)
+	}
+}
+
+func TestGetInstanceFailed(t *testing.T) {
+	src := &SourceConfig{
+		ID:      "rs1",
+		Backend: SourceBackendGoBGP,
+		GoBGP: gobgp.Config{
+			Host:    "rs1.example.net:50051",
+			TLSCert: "testdata/does-not-exist.crt",
+		},
+	}
+	if err := src.checkInstance(); !errors.Is(err, gobgp.ErrInvalidTLSConfig) {
+		t.Error("expected tls error, got:", err)
+	}
+	instance := src.GetInstance()
+	if _, ok := instance.(*sources.FailedSource); !ok {
+		t.Fatal("expected failed source, got:", instance)
+	}
+	_, err := instance.Status(context.Background())
+	if !errors.Is(err, gobgp.ErrInvalidTLSConfig) {
+		t.Error("expected tls error, got:", err)
+	}
+}
+
+func TestGetInstanceConcurrent(t *testing.T) {
+	src := &SourceConfig{
+		ID:      "rs1",
+		Backend: SourceBackendBirdwatcher,
+		Birdwatcher: birdwatcher.Config{
+			API: "http://rs1.example.net:29184",
+		},
+	}
+	// Instance does not create the instance
+	if src.Instance() != nil {
+		t.Error("did not expect an instance")
+	}
+	instances := make(chan sources.Source, 10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			instances <- src.GetInstance()
+		}()
+	}
+	first := <-instances
+	for i := 1; i < 10; i++ {
+		if instance := <-instances; instance != first {
+			t.Error("expected a single instance")
+		}
+	}
+	if src.Instance() != first {
+		t.Error("expected the created instance")
+	}
+}
@@ -0,0 +1,644 @@
+package config
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-ini/ini"
+	"gopkg.in/yaml.v3"
+
+	"github.com/alice-lg/alice-lg/pkg/decoders"
+)
+
+// The YAML configuration is an alternative representation
+// of the INI configuration:
+//
+//	variables:
+//	  ASN01: 1111
+//	server:
+//	  listen_http: 127.0.0.1:7340
+//	bgp_communities:
+//	  "1:23": some tag
+//	routes_columns:
+//	  network: Network
+//	sources:
+//	  - id: rs1-example
+//	    name: rs1.example.com
+//	    birdwatcher:
+//	      api: http://rs1.example.com:29184/
+//	      type: multi_table
+//
+// The document is validated against a strict schema and then
+// translated into the INI representation, so both formats
+// share the same defaults and post processing.
+
+// ErrYAMLInvalid is returned when the YAML configuration
+// does not match the expected structure.
+var ErrYAMLInvalid = errors.New("invalid yaml configuration")
+
+// yamlError creates an error with line information
+func yamlError(node *yaml.Node, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	return fmt.Errorf("%w: line %d: %s", ErrYAMLInvalid, node.Line, msg)
+}
+
+// Sections which are parsed by our own parsers and
+// are passed as body to the INI file.
+var yamlRawSections = map[string]bool{
+	"bgp_communities":       true,
+	"blackhole_communities": true,
+	"rejection_reasons":     true,
+	"noexport_reasons":      true,
+	"rpki":                  true,
+}
+
+// yamlStringList accepts a single value or a list of values.
+type yamlStringList []string
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface
+func (l *yamlStringList) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*l = yamlStringList{node.Value}
+	case yaml.SequenceNode:
+		values := make(yamlStringList, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return yamlError(item, "expected a scalar value")
+			}
+			values = append(values, item.Value)
+		}
+		*l = values
+	default:
+		return yamlError(node, "expected a value or a list of values")
+	}
+	return nil
+}
+
+// yamlStringMap is a mapping of scalar keys and values,
+// e.g. the bgp communities or columns. The order of the
+// keys is preserved.
+type yamlStringMap []string
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface
+func (m *yamlStringMap) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return yamlError(node, "expected a mapping")
+	}
+	keys := make(yamlStringMap, 0, len(node.Content)/2)
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Kind != yaml.ScalarNode || value.Kind != yaml.ScalarNode {
+			return yamlError(key, "expected a key with a scalar value")
+		}
+		keys = append(keys, key.Value)
+	}
+	*m = keys
+	return nil
+}
+
+// The yaml schema of the configuration. The document is
+// decoded into this structure only for validation.
+type yamlConfig struct {
+	Variables    yamlStringMap       `yaml:"variables"`
+	Server       *ServerConfig       `yaml:"server"`
+	Postgres     *PostgresConfig     `yaml:"postgres"`
+	Sqlite       *SqliteConfig       `yaml:"sqlite"`
+	Bolt         *BoltConfig         `yaml:"bolt"`
+	Housekeeping *HousekeepingConfig `yaml:"housekeeping"`
+	Discovery    *DiscoveryConfig    `yaml:"discovery"`
+	Theme        *ThemeConfig        `yaml:"theme"`
+	Pagination   *PaginationConfig   `yaml:"pagination"`
+
+	RejectionReasons    yamlStringMap `yaml:"rejection_reasons"`
+	RejectionCandidates *struct {
+		Communities yamlStringList `yaml:"communities"`
+	} `yaml:"rejection_candidates"`
+	Noexport *struct {
+		LoadOnDemand bool `yaml:"load_on_demand"`
+	} `yaml:"noexport"`
+	NoexportReasons      yamlStringMap  `yaml:"noexport_reasons"`
+	BlackholeCommunities yamlStringList `yaml:"blackhole_communities"`
+	BGPCommunities       yamlStringMap  `yaml:"bgp_communities"`
+	Rpki                 *struct {
+		Enabled    bool           `yaml:"enabled"`
+		Valid      yamlStringList `yaml:"valid"`
+		Unknown    yamlStringList `yaml:"unknown"`
+		NotChecked yamlStringList `yaml:"not_checked"`
+		Invalid    yamlStringList `yaml:"invalid"`
+	} `yaml:"rpki"`
+
+	NeighborsColumns yamlStringMap `yaml:"neighbors_columns"`
+	RoutesColumns    yamlStringMap `yaml:"routes_columns"`
+	LookupColumns    yamlStringMap `yaml:"lookup_columns"`
+
+	Sources         []*yamlSourceConfig               `yaml:"sources"`
+	SourceTemplates []*yamlSourceConfig               `yaml:"source_templates"`
+	SourceLists     map[string][]*yamlSourceListEntry `yaml:"source_lists"`
+}
+
+// yamlSourceListEntry is a source generated from a template.
+// For GoBGP sources the api is the host.
+type yamlSourceListEntry struct {
+	ID    string `yaml:"id"`
+	Name  string `yaml:"name"`
+	Group string `yaml:"group"`
+	API   string `yaml:"api"`
+}
+
+// yamlSourceConfig is the schema of a source with
+// exactly one backend configuration.
+type yamlSourceConfig struct {
+	ID         string         `yaml:"id"`
+	Inherit    string         `yaml:"inherit"`
+	Name       string         `yaml:"name"`
+	Group      string         `yaml:"group"`
+	Blackholes yamlStringList `yaml:"blackholes"`
+
+	RefreshInterval int            `yaml:"refresh_interval"`
+	Maintenance     yamlStringList `yaml:"maintenance"`
+
+	BreakerFailureThreshold int `yaml:"breaker_failure_threshold"`
+	BreakerOpenTimeout      int `yaml:"breaker_open_timeout"`
+
+	Birdwatcher *struct {
+		API                     string         `yaml:"api"`
+		Timezone                string         `yaml:"timezone"`
+		ServerTime              string         `yaml:"servertime"`
+		ServerTimeShort         string         `yaml:"servertime_short"`
+		ServerTimeExt           string         `yaml:"servertime_ext"`
+		ShowLastReboot          bool           `yaml:"show_last_reboot"`
+		DecodeMode              string         `yaml:"decode_mode"`
+		Type                    string         `yaml:"type"`
+		MainTable               string         `yaml:"main_table"`
+		PeerTablePrefix         string         `yaml:"peer_table_prefix"`
+		PipeProtocolPrefix      string         `yaml:"pipe_protocol_prefix"`
+		AltPipeProtocolPrefix   string         `yaml:"alt_pipe_protocol_prefix"`
+		AltPipeProtocolSuffix   string         `yaml:"alt_pipe_protocol_suffix"`
+		NeighborsRefreshTimeout int            `yaml:"neighbors_refresh_timeout"`
+		ChannelTables           yamlStringList `yaml:"channel_tables"`
+	} `yaml:"birdwatcher"`
+
+	GoBGP *struct {
+		Host              string         `yaml:"host"`
+		Insecure          bool           `yaml:"insecure"`
+		ProcessingTimeout int            `yaml:"processing_timeout"`
+		TLSCert           string         `yaml:"tls_crt"`
+		TLSCommonName     string         `yaml:"tls_common_name"`
+		TLSClientCert     string         `yaml:"tls_client_crt"`
+		TLSClientKey      string         `yaml:"tls_client_key"`
+		AuthToken         string         `yaml:"auth_token"`
+		Streaming         bool           `yaml:"streaming"`
+		VRF               string         `yaml:"vrf"`
+		VRFs              yamlStringList `yaml:"vrfs"`
+		TableType         string         `yaml:"table_type"`
+	} `yaml:"gobgp"`
+
+	OpenBGPDStateServer *yamlOpenBGPDConfig `yaml:"openbgpd-state-server"`
+	OpenBGPDBgplgd      *yamlOpenBGPDConfig `yaml:"openbgpd-bgplgd"`
+
+	Composite *struct {
+		Sources yamlStringList `yaml:"sources"`
+	} `yaml:"composite"`
+}
+
+type yamlOpenBGPDConfig struct {
+	API             string `yaml:"api"`
+	CacheTTL        int    `yaml:"cache_ttl"`
+	RoutesCacheSize int    `yaml:"routes_cache_size"`
+
+	FilteredReason   string `yaml:"filtered_reason"`
+	IneligibleReason string `yaml:"ineligible_reason"`
+	NoexportReason   string `yaml:"noexport_reason"`
+}
+
+// isYAMLConfigFile checks the file extension of the
+// configuration file.
+func isYAMLConfigFile(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// Encode a value for the INI file. Values with
+// comment characters are quoted.
+func iniValue(v string) string {
+	if strings.ContainsAny(v, "#;") || strings.TrimSpace(v) != v {
+		return "`" + v + "`"
+	}
+	return v
+}
+
+// Write the key value pairs of a mapping node
+// as INI section.
+func yamlWriteSection(buf *bytes.Buffer, name string, node *yaml.Node) error {
+	fmt.Fprintf(buf, "\n[%s]\n", name)
+	raw := yamlRawSections[name]
+
+	// Sections with a list of values, e.g. blackhole_communities
+	if node.Kind == yaml.SequenceNode {
+		for _, item := range node.Content {
+			fmt.Fprintln(buf, item.Value)
+		}
+		return nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return yamlError(node, "section %s must be a mapping", name)
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		switch value.Kind {
+		case yaml.ScalarNode:
+			if raw {
+				fmt.Fprintf(buf, "%s = %s\n", key, value.Value)
+			} else {
+				fmt.Fprintf(buf, "%s = %s\n", key, iniValue(value.Value))
+			}
+		case yaml.SequenceNode:
+			values := make([]string, 0, len(value.Content))
+			for _, item := range value.Content {
+				values = append(values, item.Value)
+			}
+			if raw {
+				// Repeated keys, as used in the rpki section
+				for _, v := range values {
+					fmt.Fprintf(buf, "%s = %s\n", key, v)
+				}
+			} else {
+				fmt.Fprintf(buf, "%s = %s\n",
+					key, iniValue(strings.Join(values, ", ")))
+			}
+		default:
+			return yamlError(value, "unexpected value for %s.%s", name, key)
+		}
+	}
+	return nil
+}
+
+// Write the sources as [source.ID] and [source.ID.backend]
+// sections.
+func yamlWriteSources(buf *bytes.Buffer, prefix string, node *yaml.Node) error {
+	if node.Kind != yaml.SequenceNode {
+		return yamlError(node, "%ss must be a list", prefix)
+	}
+	ids := map[string]bool{}
+	for _, src := range node.Content {
+		var (
+			id          string
+			inherit     bool
+			backend     string
+			base        = &yaml.Node{Kind: yaml.MappingNode}
+			backendNode *yaml.Node
+		)
+		for i := 0; i < len(src.Content); i += 2 {
+			key, value := src.Content[i], src.Content[i+1]
+			switch key.Value {
+			case "id":
+				id = value.Value
+			case "inherit":
+				inherit = true
+				base.Content = append(base.Content, key, value)
+			case SourceBackendBirdwatcher,
+				SourceBackendGoBGP,
+				SourceBackendOpenBGPDStateServer,
+				SourceBackendOpenBGPDBgplgd,
+				SourceBackendComposite:
+				if backendNode != nil {
+					return yamlError(key, "source %s has ambigous backends", id)
+				}
+				backend = key.Value
+				backendNode = value
+			default:
+				base.Content = append(base.Content, key, value)
+			}
+		}
+		if id == "" {
+			return yamlError(src, "source without id")
+		}
+		if strings.ContainsAny(id, ".[] ") {
+			return yamlError(src, "invalid source id: %s", id)
+		}
+		if ids[id] {
+			return yamlError(src, "duplicate source id: %s", id)
+		}
+		ids[id] = true
+		// The backend can be inherited from a template
+		if backendNode == nil && !inherit && prefix == "source" {
+			return yamlError(src, "source %s has no backend configuration", id)
+		}
+
+		if err := yamlWriteSection(buf, prefix+"."+id, base); err != nil {
+			return err
+		}
+		if backendNode == nil {
+			continue
+		}
+		if err := yamlWriteSection(
+			buf, prefix+"."+id+"."+backend, backendNode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write the source lists as [source_list.TEMPLATE] sections
+// with one line per source.
+func yamlWriteSourceLists(buf *bytes.Buffer, node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return yamlError(node, "source_lists must be a mapping")
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		template, list := node.Content[i].Value, node.Content[i+1]
+		fmt.Fprintf(buf, "\n[source_list.%s]\n", template)
+		w := csv.NewWriter(buf)
+		for _, src := range list.Content {
+			entry := &yamlSourceListEntry{}
+			if err := src.Decode(entry); err != nil {
+				return err
+			}
+			if err := w.Write([]string{
+				entry.ID, entry.Name, entry.Group, entry.API,
+			}); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// yamlToINI validates the YAML configuration and translates
+// it into the INI representation. Variables are passed
+// to the preprocessor.
+func yamlToINI(data []byte) ([]byte, error) {
+	// Validate the document against the schema
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	schema := &yamlConfig{}
+	if err := dec.Decode(schema); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	if len(doc.Content) == 0 {
+		return buf.Bytes(), nil // Empty document
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, yamlError(root, "expected a mapping")
+	}
+
+	// Variables must be defined first
+	for i := 0; i < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if key.Value != "variables" {
+			continue
+		}
+		for j := 0; j < len(value.Content); j += 2 {
+			fmt.Fprintf(buf, "$%s = %s\n",
+				value.Content[j].Value, value.Content[j+1].Value)
+		}
+	}
+
+	for i := 0; i < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if value.Tag == "!!null" {
+			continue // Empty section
+		}
+		var err error
+		switch key.Value {
+		case "variables":
+			continue
+		case "sources":
+			err = yamlWriteSources(buf, "source", value)
+		case "source_templates":
+			err = yamlWriteSources(buf, "source_template", value)
+		case "source_lists":
+			err = yamlWriteSourceLists(buf, value)
+		default:
+			err = yamlWriteSection(buf, key.Value, value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Make a scalar node. The tag is resolved
+// when the node is encoded.
+func yamlScalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+}
+
+// Make a sequence node from a list of values.
+func yamlSequence(values []string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, v := range values {
+		node.Content = append(node.Content, yamlScalar(v))
+	}
+	return node
+}
+
+// Append a key value pair to a mapping node
+func yamlAppend(node *yaml.Node, key string, value *yaml.Node) {
+	node.Content = append(node.Content, yamlScalar(key), value)
+}
+
+// Get the non empty and non comment lines of
+// a section body.
+func iniBodyLines(section *ini.Section) []string {
+	lines := []string{}
+	for _, line := range strings.Split(section.Body(), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Convert the key value pairs of a section
+func iniSectionToYAML(section *ini.Section) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range section.Keys() {
+		yamlAppend(node, key.Name(), yamlScalar(key.Value()))
+	}
+	return node
+}
+
+// Convert a raw section body with key = value lines.
+// Repeated keys will be a list.
+func iniRawSectionToYAML(section *ini.Section, repeated bool) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	lists := map[string]*yaml.Node{}
+	for _, line := range iniBodyLines(section) {
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(kv[0])
+		value := strings.TrimSpace(kv[1])
+		if !repeated || key == "enabled" {
+			yamlAppend(node, key, yamlScalar(value))
+			continue
+		}
+		list, ok := lists[key]
+		if !ok {
+			list = &yaml.Node{Kind: yaml.SequenceNode}
+			lists[key] = list
+			yamlAppend(node, key, list)
+		}
+		list.Content = append(list.Content, yamlScalar(value))
+	}
+	return node
+}
+
+// Convert a source or source template with
+// the backend configuration.
+func iniSourceToYAML(section *ini.Section) (*yaml.Node, error) {
+	name := section.Name()
+	src := &yaml.Node{Kind: yaml.MappingNode}
+	yamlAppend(src, "id", yamlScalar(name[strings.Index(name, ".")+1:]))
+	for _, key := range section.Keys() {
+		if key.Name() == "blackholes" {
+			yamlAppend(src, "blackholes", yamlSequence(
+				decoders.TrimmedCSVStringList(key.Value())))
+			continue
+		}
+		yamlAppend(src, key.Name(), yamlScalar(key.Value()))
+	}
+	for _, backend := range section.ChildSections() {
+		backendType, err := sourceBackendTypeFromConfig(backend)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"%s has an unsupported backend", name)
+		}
+		yamlAppend(src, backendType, iniSectionToYAML(backend))
+	}
+	return src, nil
+}
+
+// Convert the lines of a source list
+func iniSourceListToYAML(section *ini.Section) (*yaml.Node, error) {
+	records, err := readSourceList(section)
+	if err != nil {
+		return nil, err
+	}
+	list := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, rec := range records {
+		src := &yaml.Node{Kind: yaml.MappingNode}
+		for i, key := range []string{"id", "name", "group", "api"} {
+			yamlAppend(src, key, yamlScalar(strings.TrimSpace(rec[i])))
+		}
+		list.Content = append(list.Content, src)
+	}
+	return list, nil
+}
+
+// ConvertINIToYAML translates an INI configuration
+// into the YAML configuration format. Variables
+// and placeholders are preserved.
+func ConvertINIToYAML(data []byte) ([]byte, error) {
+	parsed, err := ini.LoadSources(ini.LoadOptions{
+		UnparseableSections: getUnparseableSections(data),
+	}, data)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &yaml.Node{Kind: yaml.MappingNode}
+
+	// Variables are part of the default section
+	variables := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range parsed.Section(ini.DefaultSection).Keys() {
+		if strings.HasPrefix(key.Name(), "$") {
+			yamlAppend(variables, key.Name()[1:], yamlScalar(key.Value()))
+		}
+	}
+	if len(variables.Content) > 0 {
+		yamlAppend(root, "variables", variables)
+	}
+
+	sources := &yaml.Node{Kind: yaml.SequenceNode}
+	templates := &yaml.Node{Kind: yaml.SequenceNode}
+	lists := &yaml.Node{Kind: yaml.MappingNode}
+	for _, section := range parsed.Sections() {
+		name := section.Name()
+		switch {
+		case name == ini.DefaultSection:
+			continue
+		case strings.HasPrefix(name, "source."):
+			if !isSourceBase(section) {
+				continue
+			}
+			src, err := iniSourceToYAML(section)
+			if err != nil {
+				return nil, err
+			}
+			sources.Content = append(sources.Content, src)
+		case strings.HasPrefix(name, "source_template."):
+			if !isSourceBase(section) {
+				continue
+			}
+			src, err := iniSourceToYAML(section)
+			if err != nil {
+				return nil, err
+			}
+			templates.Content = append(templates.Content, src)
+		case strings.HasPrefix(name, "source_list."):
+			list, err := iniSourceListToYAML(section)
+			if err != nil {
+				return nil, err
+			}
+			yamlAppend(lists, name[len("source_list."):], list)
+		case name == "blackhole_communities":
+			yamlAppend(root, name, yamlSequence(iniBodyLines(section)))
+		case name == "rpki":
+			yamlAppend(root, name, iniRawSectionToYAML(section, true))
+		case yamlRawSections[name]:
+			yamlAppend(root, name, iniRawSectionToYAML(section, false))
+		case name == "rejection_candidates":
+			node := &yaml.Node{Kind: yaml.MappingNode}
+			yamlAppend(node, "communities", yamlSequence(
+				decoders.TrimmedCSVStringList(section.Key("communities").String())))
+			yamlAppend(root, name, node)
+		default:
+			yamlAppend(root, name, iniSectionToYAML(section))
+		}
+	}
+	if len(templates.Content) > 0 {
+		yamlAppend(root, "source_templates", templates)
+	}
+	if len(sources.Content) > 0 {
+		yamlAppend(root, "sources", sources)
+	}
+	if len(lists.Content) > 0 {
+		yamlAppend(root, "source_lists", lists)
+	}
+
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
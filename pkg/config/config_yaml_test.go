@@ -0,0 +1,223 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadYAMLConfig(t *testing.T) {
+	config, err := LoadConfig("testdata/alice.yaml")
+	if err != nil {
+		t.Fatal("Could not load test config:", err)
+	}
+
+	if config.Server.Listen != "127.0.0.1:7340" {
+		t.Error("unexpected listen:", config.Server.Listen)
+	}
+	if config.Server.PrefixLookupCommunityFilterCutoff != 123 {
+		t.Error("Expected PrefixLookupCommunityFilterCutoff to be 123")
+	}
+	if config.Server.HTTPTimeout != DefaultHTTPTimeout {
+		t.Error("expected default http timeout, got:",
+			config.Server.HTTPTimeout)
+	}
+	if config.Postgres.MinConns != 10 {
+		t.Error("unexpected min conns:", config.Postgres.MinConns)
+	}
+	if config.Postgres.MaxConns != 128 {
+		t.Error("expected default max conns:", config.Postgres.MaxConns)
+	}
+
+	// Communities with expanded variables
+	label, err := config.UI.BGPCommunities.Lookup("1111:911:1002")
+	if err != nil {
+		t.Error(err)
+	}
+	if label != "Redistribute to switch02.dc01" {
+		t.Error("unexpected label:", label)
+	}
+	if _, err := config.UI.RoutesRejections.Reasons.Lookup("2222:65666:1"); err != nil {
+		t.Error(err)
+	}
+	if config.UI.Rpki.Valid[0][0] != "1111" && config.UI.Rpki.Valid[0][0] != "2222" {
+		t.Error("unexpected rpki valid:", config.UI.Rpki.Valid)
+	}
+	if _, err := config.UI.RoutesRejectCandidates.Communities.Lookup("23:42:46"); err != nil {
+		t.Error(err)
+	}
+
+	// Column order is preserved
+	if config.UI.NeighborsColumnsOrder[0] != "address" ||
+		config.UI.NeighborsColumnsOrder[6] != "routes_filtered" {
+		t.Error("unexpected columns order:", config.UI.NeighborsColumnsOrder)
+	}
+
+	// Sources
+	if len(config.Sources) != 5 {
+		t.Fatal("expected 5 sources, got:", len(config.Sources))
+	}
+	rs1 := config.Sources[0]
+	if rs1.Backend != SourceBackendBirdwatcher {
+		t.Error("unexpected backend:", rs1.Backend)
+	}
+	if len(rs1.Blackholes) != 2 || rs1.Blackholes[1] != "10.23.6.665" {
+		t.Error("unexpected blackholes:", rs1.Blackholes)
+	}
+	rs2 := config.Sources[1]
+	if rs2.Birdwatcher.AltPipeProtocolSuffix != "_lg" {
+		t.Error("unexpected alt_pipe_suffix:", rs2.Birdwatcher.AltPipeProtocolSuffix)
+	}
+	if rs2.Birdwatcher.StreamParserThrottle != 2342 {
+		t.Error("Unexpected StreamParserThrottle", rs2.Birdwatcher.StreamParserThrottle)
+	}
+	if rs2.Birdwatcher.ServerTimeShort != "02.01.2006" {
+		t.Error("unexpected servertime_short:", rs2.Birdwatcher.ServerTimeShort)
+	}
+	if config.Sources[2].GoBGP.Host != "rs2.example.com:50051" {
+		t.Error("unexpected gobgp host:", config.Sources[2].GoBGP.Host)
+	}
+	if config.Sources[3].Backend != SourceBackendOpenBGPDStateServer {
+		t.Error("unexpected backend:", config.Sources[3].Backend)
+	}
+	if config.Sources[3].OpenBGPD.RoutesCacheSize != 1024 {
+		t.Error("unexpected cache size:", config.Sources[3].OpenBGPD)
+	}
+}
+
+func TestYAMLConfigValidation(t *testing.T) {
+	tests := []struct {
+		doc  string
+		line string
+	}{
+		{
+			doc:  "server:\n  listen_http: 127.0.0.1:7340\n  listen: foo\n",
+			line: "line 3",
+		},
+		{
+			doc:  "server:\n  http_timeout: soon\n",
+			line: "line 2",
+		},
+		{
+			doc:  "sources:\n  - id: rs1\n    name: rs1\n",
+			line: "line 2",
+		},
+		{
+			doc: "sources:\n  - id: rs1\n    gobgp:\n      host: rs1:50051\n" +
+				"    birdwatcher:\n      api: http://rs1\n",
+			line: "line 5",
+		},
+		{
+			doc: "sources:\n  - id: rs1\n    gobgp:\n      host: rs1:50051\n" +
+				"  - id: rs1\n    gobgp:\n      host: rs1:50051\n",
+			line: "line 5",
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := yamlToINI([]byte(tt.doc))
+		if err == nil {
+			t.Error("expected an error for:", tt.doc)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.line) {
+			t.Error("expected error in", tt.line, "got:", err)
+		}
+	}
+
+	// Errors from the semantic validation can be identified
+	_, err := yamlToINI([]byte("sources:\n  - name: rs1\n"))
+	if !errors.Is(err, ErrYAMLInvalid) {
+		t.Error("unexpected error:", err)
+	}
+}
+
+func TestConvertINIToYAML(t *testing.T) {
+	conf := `
+$ASN01 = 1111
+
+[server]
+listen_http = 127.0.0.1:7340
+asn = 1111
+
+[rpki]
+enabled = true
+valid = {{ASN*}}:1000:1
+invalid = {{ASN*}}:1000:4-*
+invalid = {{ASN*}}:1000:5
+
+[blackhole_communities]
+65535:666
+
+[routes_columns]
+network = Network
+flags =
+
+[source.rs1]
+name = rs1.example.com
+blackholes = 10.23.6.666, 10.23.6.665
+
+[source.rs1.gobgp]
+host = rs1.example.com:50051
+insecure = true
+`
+	data, err := ConvertINIToYAML([]byte(conf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Log(string(data))
+
+	// The result should be valid and equivalent
+	res, err := yamlToINI(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"$ASN01 = 1111",
+		"[server]\nlisten_http = 127.0.0.1:7340\nasn = 1111\n",
+		"invalid = {{ASN*}}:1000:4-*\ninvalid = {{ASN*}}:1000:5\n",
+		"[blackhole_communities]\n65535:666\n",
+		"[routes_columns]\nnetwork = Network\nflags = \n",
+		"[source.rs1]\nname = rs1.example.com\nblackholes = 10.23.6.666, 10.23.6.665\n",
+		"[source.rs1.gobgp]\nhost = rs1.example.com:50051\ninsecure = true\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(string(res), e) {
+			t.Errorf("expected %q in:\n%s", e, res)
+		}
+	}
+}
+
+func TestConvertSourceTemplates(t *testing.T) {
+	data, err := os.ReadFile("testdata/source_templates.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := ConvertINIToYAML(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "alice.yaml")
+	if err := os.WriteFile(filename, res, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(filename)
+	if err != nil {
+		t.Fatal(err, string(res))
+	}
+	if len(config.Sources) != 4 {
+		t.Fatal("expected 4 sources, got:", len(config.Sources))
+	}
+	if config.Sources[0].Birdwatcher.MainTable != "master" {
+		t.Error("unexpected main table:", config.Sources[0].Birdwatcher)
+	}
+	if config.Sources[2].Name != "rs1.ams, (IPv4)" {
+		t.Error("unexpected name:", config.Sources[2].Name)
+	}
+	if config.Sources[3].GoBGP.Host != "rs3.fra.example.net:50051" {
+		t.Error("unexpected host:", config.Sources[3].GoBGP.Host)
+	}
+}
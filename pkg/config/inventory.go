@@ -0,0 +1,132 @@
+package config
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/go-ini/ini"
+	"gopkg.in/yaml.v3"
+)
+
+// The inventory is a list of sources in the format of
+// the YAML configuration. As JSON is a subset of YAML,
+// JSON inventories are supported as well:
+//
+//	[
+//	  {"id": "rs1-example", "name": "rs1.example.com",
+//	   "birdwatcher": {"api": "http://rs1.example.com:29184/",
+//	                   "type": "multi_table"}},
+//	  {"id": "rs2-example", "inherit": "bird-v4", ...}
+//	]
+//
+// The sources can also be wrapped in a mapping with
+// a `sources` key. Templates of the configuration can
+// be inherited.
+
+// yamlInventory is the schema of the inventory
+type yamlInventory struct {
+	Sources []*yamlSourceConfig `yaml:"sources"`
+}
+
+// Get the node with the list of sources from the
+// inventory document.
+func inventorySources(data []byte) (*yaml.Node, error) {
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal(data, doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil // Empty inventory
+	}
+	root := doc.Content[0]
+	switch root.Kind {
+	case yaml.SequenceNode:
+		return root, nil
+	case yaml.MappingNode:
+		for i := 0; i < len(root.Content); i += 2 {
+			key, value := root.Content[i], root.Content[i+1]
+			if key.Value != "sources" {
+				return nil, yamlError(key, "unknown key: %s", key.Value)
+			}
+			if value.Tag == "!!null" {
+				return nil, nil
+			}
+			return value, nil
+		}
+		return nil, nil
+	}
+	return nil, yamlError(root, "expected a list of sources")
+}
+
+// ParseInventory reads the sources from an inventory.
+// The sources are validated like the sources of the
+// YAML configuration and may inherit templates and
+// rejection reasons from the configuration.
+func (cfg *Config) ParseInventory(data []byte) ([]*SourceConfig, error) {
+	node, err := inventorySources(data)
+	if err != nil {
+		return nil, err
+	}
+	if node == nil {
+		return []*SourceConfig{}, nil
+	}
+	if node.Kind != yaml.SequenceNode {
+		return nil, yamlError(node, "sources must be a list")
+	}
+
+	// Validate the sources against the schema
+	wrapped, err := yaml.Marshal(map[string]*yaml.Node{"sources": node})
+	if err != nil {
+		return nil, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(wrapped))
+	dec.KnownFields(true)
+	if err := dec.Decode(&yamlInventory{}); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := yamlWriteSources(buf, "source", node); err != nil {
+		return nil, err
+	}
+	inventory, err := ini.Load(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	// Add the templates, rejection and no-export
+	// reasons from the configuration.
+	if cfg.parsed != nil {
+		for _, section := range cfg.parsed.Sections() {
+			name := section.Name()
+			if strings.HasPrefix(name, "source_template.") {
+				if _, err := mergeSections(inventory, name, section); err != nil {
+					return nil, err
+				}
+			}
+			if name == "rejection_reasons" || name == "noexport_reasons" {
+				if _, err := inventory.NewRawSection(name, section.Body()); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	sources, err := getSources(inventory)
+	if err != nil {
+		return nil, err
+	}
+	setStreamParserThrottle(sources, cfg.Server.StreamParserThrottle)
+	return sources, nil
+}
+
+// Matches checks if the source configurations are
+// equal, ignoring the instance and the order.
+func (cfg *SourceConfig) Matches(other *SourceConfig) bool {
+	a, b := *cfg, *other
+	a.instance, b.instance = nil, nil
+	a.Order, b.Order = 0, 0
+	return reflect.DeepEqual(a, b)
+}
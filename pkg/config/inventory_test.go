@@ -0,0 +1,112 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestParseInventory(t *testing.T) {
+	cfg, err := LoadConfig("testdata/source_templates.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile("testdata/inventory.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources, err := cfg.ParseInventory(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != 2 {
+		t.Fatal("expected 2 sources, got:", len(sources))
+	}
+
+	// The template is inherited
+	rs4 := sources[0]
+	if rs4.ID != "rs4-fra-v4" || rs4.Group != "FRA" {
+		t.Error("unexpected source:", rs4.ID, rs4.Group)
+	}
+	if rs4.Birdwatcher.MainTable != "master4" {
+		t.Error("unexpected main table:", rs4.Birdwatcher.MainTable)
+	}
+	if rs4.Birdwatcher.API != "http://rs4.fra.example.net:29184/" {
+		t.Error("unexpected api:", rs4.Birdwatcher.API)
+	}
+
+	rs5 := sources[1]
+	if rs5.Backend != SourceBackendOpenBGPDBgplgd {
+		t.Error("unexpected backend:", rs5.Backend)
+	}
+
+	// The rejection and no-export reasons are
+	// taken from the configuration
+	if len(rs5.OpenBGPD.RejectCommunities) != 1 {
+		t.Error("unexpected reject communities:",
+			rs5.OpenBGPD.RejectCommunities)
+	}
+	if len(rs5.OpenBGPD.NoexportCommunities) != 1 {
+		t.Error("unexpected no-export communities:",
+			rs5.OpenBGPD.NoexportCommunities)
+	}
+
+	// Parsing the inventory again yields equal sources
+	again, err := cfg.ParseInventory(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !again[0].Matches(rs4) {
+		t.Error("expected sources to match")
+	}
+	if again[1].Matches(rs4) {
+		t.Error("did not expect sources to match")
+	}
+}
+
+func TestParseInventoryYAML(t *testing.T) {
+	cfg := &Config{}
+	sources, err := cfg.ParseInventory([]byte(`
+sources:
+  - id: rs1
+    name: rs1.example.net
+    gobgp:
+      host: rs1.example.net:50051
+      insecure: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != 1 || sources[0].GoBGP.Host != "rs1.example.net:50051" {
+		t.Error("unexpected sources:", sources)
+	}
+
+	// Empty inventories are valid
+	sources, err = cfg.ParseInventory([]byte(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != 0 {
+		t.Error("expected no sources")
+	}
+}
+
+func TestParseInventoryInvalid(t *testing.T) {
+	cfg := &Config{}
+	invalid := []string{
+		`[{"id": "rs1", "birdwatcher": {"api": "http://rs1", "foo": 1}}]`,
+		`[{"id": "rs1", "name": "no backend"}]`,
+		`{"routeservers": []}`,
+		`[{"id": "rs1", "inherit": "unknown"}]`,
+	}
+	for _, inv := range invalid {
+		if _, err := cfg.ParseInventory([]byte(inv)); err == nil {
+			t.Error("expected an error for:", inv)
+		}
+	}
+
+	_, err := cfg.ParseInventory([]byte(`{"routeservers": []}`))
+	if !errors.Is(err, ErrYAMLInvalid) {
+		t.Error("unexpected error:", err)
+	}
+}
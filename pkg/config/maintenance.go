@@ -0,0 +1,138 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A MaintenanceWindow is a period of time in which the
+// refresh of a source is paused. Windows are either daily,
+// e.g. `02:00-04:00`, or weekly, e.g. `Sun 02:00-04:00`.
+// The time is in UTC and a window may span midnight.
+type MaintenanceWindow struct {
+	Weekday *time.Weekday
+	Start   time.Duration
+	End     time.Duration
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Parse the time of the day: HH:MM
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day: %q", s)
+	}
+	return time.Duration(t.Hour())*time.Hour +
+		time.Duration(t.Minute())*time.Minute, nil
+}
+
+// ParseMaintenanceWindow parses a window in the format
+// `[weekday] HH:MM-HH:MM`.
+func ParseMaintenanceWindow(s string) (*MaintenanceWindow, error) {
+	w := &MaintenanceWindow{}
+	fields := strings.Fields(s)
+	if len(fields) == 2 {
+		name := strings.ToLower(fields[0])
+		if len(name) > 3 {
+			name = name[:3]
+		}
+		day, ok := weekdays[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday in %q", s)
+		}
+		w.Weekday = &day
+		fields = fields[1:]
+	}
+	if len(fields) != 1 {
+		return nil, fmt.Errorf("invalid maintenance window: %q", s)
+	}
+	times := strings.SplitN(fields[0], "-", 2)
+	if len(times) != 2 {
+		return nil, fmt.Errorf("invalid maintenance window: %q", s)
+	}
+	start, err := parseTimeOfDay(times[0])
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseTimeOfDay(times[1])
+	if err != nil {
+		return nil, err
+	}
+	if start == end {
+		return nil, fmt.Errorf("empty maintenance window: %q", s)
+	}
+	w.Start = start
+	w.End = end
+	return w, nil
+}
+
+// ParseMaintenanceWindows parses a list of windows
+func ParseMaintenanceWindows(windows []string) ([]*MaintenanceWindow, error) {
+	result := make([]*MaintenanceWindow, 0, len(windows))
+	for _, s := range windows {
+		w, err := ParseMaintenanceWindow(s)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, w)
+	}
+	return result, nil
+}
+
+// Contains checks if the time is within the window
+func (w *MaintenanceWindow) Contains(t time.Time) bool {
+	t = t.UTC()
+	day := t.Truncate(24 * time.Hour)
+	offset := t.Sub(day)
+	if w.Start < w.End {
+		return w.matchDay(t.Weekday()) &&
+			offset >= w.Start && offset < w.End
+	}
+	// The window spans midnight and started
+	// either today or yesterday.
+	if offset >= w.Start {
+		return w.matchDay(t.Weekday())
+	}
+	if offset < w.End {
+		return w.matchDay((t.Weekday() + 6) % 7)
+	}
+	return false
+}
+
+// Check if the window starts at the weekday
+func (w *MaintenanceWindow) matchDay(day time.Weekday) bool {
+	return w.Weekday == nil || *w.Weekday == day
+}
+
+// String formats the window
+func (w *MaintenanceWindow) String() string {
+	fmtTime := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	s := fmtTime(w.Start) + "-" + fmtTime(w.End)
+	if w.Weekday != nil {
+		s = w.Weekday.String()[:3] + " " + s
+	}
+	return s
+}
+
+// InMaintenance checks if the time is within any
+// maintenance window of the source.
+func (cfg *SourceConfig) InMaintenance(t time.Time) bool {
+	for _, w := range cfg.Maintenance {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMaintenanceWindow(t *testing.T) {
+	w, err := ParseMaintenanceWindow("Sunday 02:00-04:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *w.Weekday != time.Sunday {
+		t.Error("unexpected weekday:", w.Weekday)
+	}
+	if w.Start != 2*time.Hour || w.End != 4*time.Hour+30*time.Minute {
+		t.Error("unexpected window:", w.Start, w.End)
+	}
+	if w.String() != "Sun 02:00-04:30" {
+		t.Error("unexpected string:", w)
+	}
+
+	for _, s := range []string{"", "02:00", "Foo 02:00-04:00", "25:00-26:00", "02:00-02:00"} {
+		if _, err := ParseMaintenanceWindow(s); err == nil {
+			t.Error("expected error for:", s)
+		}
+	}
+}
+
+func TestMaintenanceWindowContains(t *testing.T) {
+	at := func(s string) time.Time {
+		t, _ := time.Parse("2006-01-02 15:04", s)
+		return t
+	}
+	daily, _ := ParseMaintenanceWindow("23:30-00:15")
+	weekly, _ := ParseMaintenanceWindow("Sun 23:00-01:00")
+
+	// 2023-01-01 is a Sunday
+	tests := []struct {
+		w        *MaintenanceWindow
+		t        time.Time
+		expected bool
+	}{
+		{daily, at("2023-01-03 23:45"), true},
+		{daily, at("2023-01-04 00:10"), true},
+		{daily, at("2023-01-04 00:15"), false},
+		{daily, at("2023-01-04 12:00"), false},
+		{weekly, at("2023-01-01 23:10"), true},
+		{weekly, at("2023-01-02 00:30"), true},
+		{weekly, at("2023-01-02 23:10"), false},
+		{weekly, at("2023-01-01 00:30"), false},
+	}
+	for _, test := range tests {
+		if test.w.Contains(test.t) != test.expected {
+			t.Error("unexpected result for", test.w, "at", test.t)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-ini/ini"
+)
+
+// EnvPrefix is the prefix of environment variables
+// overriding configuration keys. The variable name is
+// derived from the section and key:
+//
+//	[server] listen_http             -> ALICE_SERVER_LISTEN_HTTP
+//	[source.rs1.birdwatcher] api     -> ALICE_SOURCE_RS1_BIRDWATCHER_API
+const EnvPrefix = "ALICE_"
+
+// SecretFilePrefix marks a value as reference to a file.
+// The value is replaced with the content of the file,
+// e.g. `url = @file:/run/secrets/pg`.
+const SecretFilePrefix = "@file:"
+
+// Sections which can be overridden, even if they
+// are not present in the config file.
+var envOverrideSections = []string{
+	"server",
+	"postgres",
+	"sqlite",
+	"bolt",
+	"housekeeping",
+	"discovery",
+	"theme",
+	"pagination",
+	"noexport",
+	"rejection_candidates",
+}
+
+// envName normalizes a section name or key for use in
+// an environment variable name.
+func envName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// isRawSection checks if the section is parsed by
+// our own parsers. Keys can not be overridden.
+func isRawSection(name string) bool {
+	return yamlRawSections[name] || strings.HasPrefix(name, "source_list.")
+}
+
+// applyEnvOverrides sets the configuration keys from
+// the environment. The section with the longest matching
+// name is used, the remainder is the key.
+func applyEnvOverrides(config *ini.File, environ []string) {
+	sections := map[string]string{}
+	for _, name := range envOverrideSections {
+		sections[envName(name)] = name
+	}
+	for _, section := range config.Sections() {
+		name := section.Name()
+		if name == ini.DefaultSection || isRawSection(name) {
+			continue
+		}
+		sections[envName(name)] = name
+	}
+	overrideSections(environ, sections, config.Section)
+}
+
+// applySourceEnvOverrides sets the keys of the base and
+// backend configuration of a source from the environment.
+// This is required for sources generated from templates
+// or source lists, which are not in the config file.
+func applySourceEnvOverrides(
+	environ []string,
+	base *ini.Section,
+	backend *ini.Section,
+) error {
+	sections := map[string]string{
+		envName(base.Name()):    base.Name(),
+		envName(backend.Name()): backend.Name(),
+	}
+	overrideSections(environ, sections, func(name string) *ini.Section {
+		if name == base.Name() {
+			return base
+		}
+		return backend
+	})
+	if err := resolveSectionSecretRefs(base); err != nil {
+		return err
+	}
+	return resolveSectionSecretRefs(backend)
+}
+
+// overrideSections sets the keys of the sections, mapped
+// by their variable names, from the environment.
+func overrideSections(
+	environ []string,
+	sections map[string]string,
+	getSection func(name string) *ini.Section,
+) {
+	for _, env := range environ {
+		kv := strings.SplitN(env, "=", 2)
+		if len(kv) != 2 || !strings.HasPrefix(kv[0], EnvPrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(kv[0], EnvPrefix)
+
+		// Find section with the longest prefix
+		section := ""
+		for prefix, s := range sections {
+			if !strings.HasPrefix(name, prefix+"_") {
+				continue
+			}
+			if len(s) > len(section) {
+				section = s
+			}
+		}
+		if section == "" {
+			continue // Not a config key
+		}
+		s := getSection(section)
+		key := overrideKey(s, name[len(envName(section))+1:])
+		s.Key(key).SetValue(kv[1])
+	}
+}
+
+// overrideKey finds the key in the section matching
+// the variable name case-insensitively and keeps its
+// spelling, e.g. `Uptime` in the neighbors_columns.
+// New keys are lower case.
+func overrideKey(section *ini.Section, name string) string {
+	for _, key := range section.KeyStrings() {
+		if envName(key) == name {
+			return key
+		}
+	}
+	return strings.ToLower(name)
+}
+
+// resolveSecretRefs replaces values referencing a
+// file with the content of the file.
+func resolveSecretRefs(config *ini.File) error {
+	for _, section := range config.Sections() {
+		if isRawSection(section.Name()) {
+			continue
+		}
+		if err := resolveSectionSecretRefs(section); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveSectionSecretRefs replaces the values of a
+// section referencing a file.
+func resolveSectionSecretRefs(section *ini.Section) error {
+	for _, key := range section.Keys() {
+		value := key.Value()
+		if !strings.HasPrefix(value, SecretFilePrefix) {
+			continue
+		}
+		filename := strings.TrimPrefix(value, SecretFilePrefix)
+		secret, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf(
+				"[%s] %s: could not read secret: %w",
+				section.Name(), key.Name(), err)
+		}
+		key.SetValue(strings.TrimRight(string(secret), "\r\n"))
+	}
+	return nil
+}
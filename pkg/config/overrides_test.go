@@ -0,0 +1,162 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-ini/ini"
+)
+
+func TestEnvName(t *testing.T) {
+	if n := envName("source.rs0-example-v4.birdwatcher"); n != "SOURCE_RS0_EXAMPLE_V4_BIRDWATCHER" {
+		t.Error("unexpected env name:", n)
+	}
+}
+
+func TestApplyEnvOverrides(t *testing.T) {
+	config, err := ini.Load([]byte(`
+[server]
+listen_http = 127.0.0.1:7340
+
+[source.rs1]
+name = rs1
+
+[source.rs1.birdwatcher]
+api = http://rs1.example.net
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applyEnvOverrides(config, []string{
+		"ALICE_SERVER_LISTEN_HTTP=[::]:7340",
+		"ALICE_SOURCE_RS1_BIRDWATCHER_API=http://rs1.example.com",
+		"ALICE_SOURCE_RS1_NAME=route server 1",
+		"ALICE_POSTGRES_URL=postgres://alice@db/alice",
+		"ALICE_UNKNOWN_KEY=foo",
+		"HOME=/root",
+	})
+
+	expected := []struct {
+		section string
+		key     string
+		value   string
+	}{
+		{"server", "listen_http", "[::]:7340"},
+		{"source.rs1.birdwatcher", "api", "http://rs1.example.com"},
+		{"source.rs1", "name", "route server 1"},
+		{"postgres", "url", "postgres://alice@db/alice"},
+	}
+	for _, e := range expected {
+		v := config.Section(e.section).Key(e.key).String()
+		if v != e.value {
+			t.Errorf("expected [%s] %s = %s, got: %s",
+				e.section, e.key, e.value, v)
+		}
+	}
+	if config.HasSection("unknown") {
+		t.Error("unexpected section")
+	}
+}
+
+func TestApplyEnvOverridesKeyCase(t *testing.T) {
+	config, err := ini.Load([]byte(`
+[neighbors_columns]
+Uptime = Since
+Description = Description
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	applyEnvOverrides(config, []string{
+		"ALICE_NEIGHBORS_COLUMNS_UPTIME=Up since",
+		"ALICE_NEIGHBORS_COLUMNS_ASN=ASN",
+	})
+
+	section := config.Section("neighbors_columns")
+	if v := section.Key("Uptime").String(); v != "Up since" {
+		t.Error("unexpected value:", v)
+	}
+	if section.HasKey("uptime") {
+		t.Error("the key should keep its spelling")
+	}
+	if v := section.Key("asn").String(); v != "ASN" {
+		t.Error("unexpected value:", v)
+	}
+	keys := section.KeyStrings()
+	if len(keys) != 3 || keys[0] != "Uptime" {
+		t.Error("unexpected keys:", keys)
+	}
+}
+
+func TestResolveSecretRefs(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "pg")
+	if err := os.WriteFile(secret, []byte("postgres://secret@db/alice\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := ini.Load([]byte("[postgres]\nurl = @file:" + secret + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := resolveSecretRefs(config); err != nil {
+		t.Fatal(err)
+	}
+	url := config.Section("postgres").Key("url").String()
+	if url != "postgres://secret@db/alice" {
+		t.Error("unexpected url:", url)
+	}
+
+	// Missing secrets are an error
+	config, _ = ini.Load([]byte("[postgres]\nurl = @file:/does/not/exist\n"))
+	if err := resolveSecretRefs(config); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestLoadConfigEnvOverrides(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "pg")
+	if err := os.WriteFile(secret, []byte("postgres://secret@db/alice"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ALICE_POSTGRES_URL", "@file:"+secret)
+	t.Setenv("ALICE_SERVER_HTTP_TIMEOUT", "42")
+	t.Setenv("ALICE_SOURCE_RS1_EXAMPLE_V6_BIRDWATCHER_API", "http://rs1.example.net:29186/")
+
+	config, err := LoadConfig("testdata/alice.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Postgres.URL != "postgres://secret@db/alice" {
+		t.Error("unexpected postgres url:", config.Postgres.URL)
+	}
+	if config.Server.HTTPTimeout != 42 {
+		t.Error("unexpected http timeout:", config.Server.HTTPTimeout)
+	}
+	if api := config.Sources[1].Birdwatcher.API; api != "http://rs1.example.net:29186/" {
+		t.Error("unexpected api:", api)
+	}
+}
+
+func TestLoadConfigEnvOverridesSourceTemplates(t *testing.T) {
+	t.Setenv("ALICE_SOURCE_RS1_FRA_V4_BIRDWATCHER_API", "http://rs1.fra.example.com:29184/")
+	t.Setenv("ALICE_SOURCE_RS2_FRA_V4_BIRDWATCHER_API", "http://rs2.fra.example.com:29184/")
+	t.Setenv("ALICE_SOURCE_RS3_FRA_NAME", "rs3.fra (GoBGP)")
+
+	config, err := LoadConfig("testdata/source_templates.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs1 := config.SourceByID("rs1-fra-v4")
+	if api := rs1.Birdwatcher.API; api != "http://rs1.fra.example.com:29184/" {
+		t.Error("unexpected api of template source:", api)
+	}
+	rs2 := config.SourceByID("rs2-fra-v4")
+	if api := rs2.Birdwatcher.API; api != "http://rs2.fra.example.com:29184/" {
+		t.Error("unexpected api of listed source:", api)
+	}
+	rs3 := config.SourceByID("rs3-fra")
+	if rs3.Name != "rs3.fra (GoBGP)" {
+		t.Error("unexpected name of listed source:", rs3.Name)
+	}
+}
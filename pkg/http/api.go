@@ -25,11 +25,15 @@ import (
 //     List         /api/v1/routeservers
 //     Status       /api/v1/routeservers/:id/status
 //     Neighbors    /api/v1/routeservers/:id/neighbors
+//     Neighbor     /api/v1/routeservers/:id/neighbors/:neighborId
 //     Routes       /api/v1/routeservers/:id/neighbors/:neighborId/routes
 //
 //   Querying
 //     LookupPrefix   /api/v1/lookup/prefix?q=<prefix>
 //     LookupNeighbor /api/v1/lookup/neighbor?asn=1235
+//
+//   Administration (requires the admin token)
+//     Refresh      POST /api/v1/routeservers/:id/refresh
 
 type response interface{}
 
@@ -104,6 +108,8 @@ func (s *Server) apiRegisterEndpoints(
 		endpoint(s.apiRouteServerStatusShow))
 	router.GET("/api/v1/routeservers/:id/neighbors",
 		endpoint(s.apiNeighborsList))
+	router.GET("/api/v1/routeservers/:id/neighbors/:neighborId",
+		endpoint(s.apiNeighborShow))
 	// router.GET("/api/v1/routeservers/:id/neighbors/:neighborId/routes",
 	// 	endpoint(s.apiRoutesList))
 	router.GET("/api/v1/routeservers/:id/neighbors/:neighborId/routes/received",
@@ -121,5 +127,11 @@ func (s *Server) apiRegisterEndpoints(
 			endpoint(s.apiLookupNeighborsGlobal))
 	}
 
+	// Administration
+	if s.cfg.Server.AdminToken != "" {
+		router.POST("/api/v1/routeservers/:id/refresh",
+			endpoint(s.adminEndpoint(s.apiRouteServerRefresh)))
+	}
+
 	return nil
 }
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+)
+
+// Require the admin token as bearer token
+// in the authorization header.
+func (s *Server) adminEndpoint(wrapped apiEndpoint) apiEndpoint {
+	token := []byte(s.cfg.Server.AdminToken)
+	return func(
+		ctx context.Context,
+		req *http.Request,
+		params httprouter.Params,
+	) (response, error) {
+		auth := req.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") {
+			return nil, &ErrAccessDenied{}
+		}
+		bearer := []byte(strings.TrimPrefix(auth, "Bearer "))
+		if subtle.ConstantTimeCompare(bearer, token) != 1 {
+			return nil, &ErrAccessDenied{}
+		}
+		return wrapped(ctx, req, params)
+	}
+}
+
+// Handle a refresh request for a route server. The
+// neighbors and routes are refreshed as soon as possible.
+func (s *Server) apiRouteServerRefresh(
+	ctx context.Context,
+	_req *http.Request,
+	params httprouter.Params,
+) (response, error) {
+	rsID, err := validateSourceID(params.ByName("id"))
+	if err != nil {
+		return nil, err
+	}
+	// Both stores must accept the refresh, otherwise
+	// only the neighbors would be refreshed.
+	if err := s.neighborsStore.CanRefreshSource(rsID); err != nil {
+		return nil, err
+	}
+	if err := s.routesStore.CanRefreshSource(rsID); err != nil {
+		return nil, err
+	}
+	if err := s.neighborsStore.RefreshSource(rsID); err != nil {
+		return nil, err
+	}
+	if err := s.routesStore.RefreshSource(rsID); err != nil {
+		return nil, err
+	}
+	return api.RefreshResponse{
+		RouteserverID: rsID,
+		Scheduled:     true,
+	}, nil
+}
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/alice-lg/alice-lg/pkg/config"
+	"github.com/alice-lg/alice-lg/pkg/sources"
+	"github.com/alice-lg/alice-lg/pkg/store"
+	"github.com/alice-lg/alice-lg/pkg/store/backends/memory"
+)
+
+func TestAdminEndpoint(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			AdminToken: "s3cr3t",
+		},
+	}
+	s := &Server{cfg: cfg}
+	handler := s.adminEndpoint(func(
+		context.Context,
+		*http.Request,
+		httprouter.Params,
+	) (response, error) {
+		return "ok", nil
+	})
+
+	tests := []struct {
+		auth string
+		ok   bool
+	}{
+		{"", false},
+		{"s3cr3t", false},
+		{"Bearer foo", false},
+		{"Bearer s3cr3t", true},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", "/api/v1/routeservers/rs1/refresh", nil)
+		if test.auth != "" {
+			req.Header.Set("Authorization", test.auth)
+		}
+		res, err := handler(context.Background(), req, nil)
+		if test.ok && (err != nil || res != "ok") {
+			t.Error("expected access with", test.auth, "got:", err)
+		}
+		if !test.ok {
+			if _, status := apiErrorResponse("rs1", err); status != http.StatusUnauthorized {
+				t.Error("expected access denied with", test.auth, "got:", status)
+			}
+		}
+	}
+}
+
+func TestApiErrorResponseSourceBusy(t *testing.T) {
+	err := fmt.Errorf("refresh: %w", sources.ErrSourceBusy)
+	res, status := apiErrorResponse("rs1", err)
+	if status != http.StatusConflict || res.Tag != TagSourceBusy {
+		t.Error("unexpected response:", res, status)
+	}
+	res, status = apiErrorResponse("rs1", sources.ErrSourceUnavailable)
+	if status != http.StatusServiceUnavailable || res.Tag != TagSourceUnavailable {
+		t.Error("unexpected response:", res, status)
+	}
+}
+
+func TestApiRouteServerRefreshNotRunning(t *testing.T) {
+	cfg := &config.Config{
+		Sources: []*config.SourceConfig{
+			{ID: "rs1", Name: "rs1"},
+		},
+	}
+	neighbors := store.NewNeighborsStore(cfg, memory.NewNeighborsBackend())
+	routes := store.NewRoutesStore(neighbors, cfg, memory.NewRoutesBackend())
+	s := &Server{
+		cfg:            cfg,
+		neighborsStore: neighbors,
+		routesStore:    routes,
+	}
+
+	params := httprouter.Params{{Key: "id", Value: "rs1"}}
+	req := httptest.NewRequest("POST", "/api/v1/routeservers/rs1/refresh", nil)
+	res, err := s.apiRouteServerRefresh(context.Background(), req, params)
+	if !errors.Is(err, store.ErrStoreNotRunning) {
+		t.Fatal("expected store not running, got:", res, err)
+	}
+	body, status := apiErrorResponse("rs1", err)
+	if status != http.StatusServiceUnavailable || body.Tag != TagStoreUnavailable {
+		t.Error("unexpected response:", body, status)
+	}
+}
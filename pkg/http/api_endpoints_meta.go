@@ -17,7 +17,20 @@ func (s *Server) apiStatusShow(
 	_params httprouter.Params,
 ) (response, error) {
 	status, err := CollectAppStatus(ctx, s.pool, s.routesStore, s.neighborsStore)
-	return status, err
+	if err != nil {
+		return nil, err
+	}
+	for _, src := range s.cfg.GetSources() {
+		health := sourceHealth(src)
+		if health == nil {
+			continue
+		}
+		if status.Sources == nil {
+			status.Sources = make(map[string]*api.SourceHealth)
+		}
+		status.Sources[src.ID] = health
+	}
+	return status, nil
 }
 
 // Handle Config Endpoint
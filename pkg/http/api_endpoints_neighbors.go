@@ -4,11 +4,13 @@ import (
 	"context"
 	"net/http"
 	"sort"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 
 	"github.com/alice-lg/alice-lg/pkg/api"
 	"github.com/alice-lg/alice-lg/pkg/config"
+	"github.com/alice-lg/alice-lg/pkg/store"
 )
 
 // Handle get neighbors on routeserver
@@ -21,51 +23,161 @@ func (s *Server) apiNeighborsList(
 	if err != nil {
 		return nil, err
 	}
+	neighborsResponse, err := s.getNeighbors(ctx, rsID)
+	if err != nil {
+		return nil, err
+	}
 
-	var neighborsResponse *api.NeighborsResponse
+	// Sort result
+	sort.Sort(&neighborsResponse.Neighbors)
+	return neighborsResponse, nil
+}
 
+// getNeighbors retrieves the neighbors of a route server.
+func (s *Server) getNeighbors(
+	ctx context.Context,
+	rsID string,
+) (*api.NeighborsResponse, error) {
 	// Try to fetch neighbors from store, only fall back
 	// to RS query if store is not ready yet.
 	// The stored neighbors response includes details like
 	// the number of filtered routes which might be lacking
 	// from the summary.
-	if s.neighborsStore.IsInitialized(rsID) {
-		status, err := s.neighborsStore.GetStatus(rsID)
-		if err != nil {
-			return nil, err
-		}
-		neighbors, err := s.neighborsStore.GetNeighborsAt(ctx, rsID)
-		if err != nil {
-			return nil, err
-		}
-		// Make response
-		neighborsResponse = &api.NeighborsResponse{
-			Response: api.Response{
-				Meta: &api.Meta{
-					Version: config.Version,
-					CacheStatus: api.CacheStatus{
-						OrigTTL:  0,
-						CachedAt: status.LastRefresh,
-					},
-					ResultFromCache: true, // you bet!
-					TTL:             s.neighborsStore.SourceCacheTTL(ctx, rsID),
-				},
-			},
-			Neighbors: neighbors,
-		}
-	} else {
+	if !s.neighborsStore.IsInitialized(rsID) {
 		source := s.cfg.SourceInstanceByID(rsID)
 		if source == nil {
 			return nil, ErrSourceNotFound
 		}
-		neighborsResponse, err = source.NeighborsSummary(ctx)
+		neighborsResponse, err := source.NeighborsSummary(ctx)
 		if err != nil {
 			s.logSourceError("neighbors", rsID, err)
 			return nil, err
 		}
+		return neighborsResponse, nil
 	}
 
-	// Sort result
-	sort.Sort(&neighborsResponse.Neighbors)
-	return neighborsResponse, nil
+	status, err := s.neighborsStore.GetStatus(rsID)
+	if err != nil {
+		return nil, err
+	}
+	neighbors, err := s.neighborsStore.GetNeighborsAt(ctx, rsID)
+	if err != nil {
+		// The refresh of the neighbors status failed
+		s.logSourceError("neighbors", rsID, err)
+		if !s.useStoreFallback(err) {
+			return nil, err
+		}
+		return s.fallbackNeighbors(ctx, rsID)
+	}
+	// Make response
+	ttl := s.neighborsStore.SourceCacheTTL(ctx, rsID)
+	return &api.NeighborsResponse{
+		Response: api.Response{
+			Meta: storedNeighborsMeta(status, ttl, time.Now().UTC()),
+		},
+		Neighbors: neighbors,
+	}, nil
+}
+
+// storedNeighborsMeta creates the meta of the neighbors
+// served from the store. The neighbors are stale, when
+// the last refresh of the source failed.
+func storedNeighborsMeta(
+	status *store.Status,
+	ttl time.Time,
+	now time.Time,
+) *api.Meta {
+	meta := &api.Meta{
+		Version: config.Version,
+		CacheStatus: api.CacheStatus{
+			OrigTTL:  0,
+			CachedAt: status.LastRefresh,
+		},
+		ResultFromCache: true, // you bet!
+		TTL:             ttl,
+	}
+	if status.State == store.StateError {
+		meta.Stale = true
+		meta.StaleAge = now.Sub(status.LastRefresh).Seconds()
+	}
+	return meta
+}
+
+// Handle get a single neighbor on routeserver. The
+// neighbor is retrieved like the list of neighbors,
+// only the session details are requested from the source.
+func (s *Server) apiNeighborShow(
+	ctx context.Context,
+	req *http.Request,
+	params httprouter.Params,
+) (response, error) {
+	rsID, err := validateSourceID(params.ByName("id"))
+	if err != nil {
+		return nil, err
+	}
+	neighborID := params.ByName("neighborId")
+
+	neighborsResponse, err := s.getNeighbors(ctx, rsID)
+	if err != nil {
+		return nil, err
+	}
+	var neighbor *api.Neighbor
+	for _, n := range neighborsResponse.Neighbors {
+		if n.ID == neighborID {
+			nb := *n // The neighbor is shared with the store
+			neighbor = &nb
+			break
+		}
+	}
+	if neighbor == nil {
+		return nil, ErrNeighborNotFound
+	}
+
+	meta := &api.Meta{}
+	if neighborsResponse.Meta != nil {
+		m := *neighborsResponse.Meta
+		meta = &m
+	}
+	session, err := s.neighborSession(ctx, rsID, neighborID)
+	if err != nil {
+		s.logSourceError("neighbor", rsID, neighborID, err)
+		if !s.useStoreFallback(err) {
+			return nil, err
+		}
+		// Keep the stored session details
+		meta.Warnings = append(append([]string{}, meta.Warnings...),
+			"session details are not current: "+err.Error())
+	} else if session != nil {
+		neighbor.Session = session
+	}
+
+	return &api.NeighborResponse{
+		Response: api.Response{
+			Meta: meta,
+		},
+		Neighbor: neighbor,
+	}, nil
+}
+
+// neighborSession requests the current session
+// details of a neighbor from the source.
+func (s *Server) neighborSession(
+	ctx context.Context,
+	rsID string,
+	neighborID string,
+) (*api.NeighborSession, error) {
+	source := s.cfg.SourceInstanceByID(rsID)
+	if source == nil {
+		return nil, ErrSourceNotFound
+	}
+	neighborsResponse, err := source.Neighbors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range neighborsResponse.Neighbors {
+		if n.ID == neighborID {
+			return n.Session, nil
+		}
+	}
+	return nil, nil
 }
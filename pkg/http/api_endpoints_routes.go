@@ -60,11 +60,19 @@ func (s *Server) apiRoutesListReceived(
 	result, err := source.RoutesReceived(ctx, neighborID)
 	if err != nil {
 		s.logSourceError("routes_received", rsID, neighborID, err)
-		return nil, err
+		if !s.useStoreFallback(err) {
+			return nil, err
+		}
+		stored, fallbackErr := s.fallbackRoutes(ctx, rsID, neighborID, "imported")
+		if fallbackErr != nil {
+			return nil, err
+		}
+		result = stored
 	}
 
 	// Filter routes based on criteria if present
 	allRoutes := apiQueryFilterNextHopGateway(req, "q", result.Imported)
+	allRoutes = apiQueryFilterRouteFamily(req, allRoutes)
 	routes := api.Routes{}
 
 	// Apply other (commmunity) filters
@@ -138,11 +146,19 @@ func (s *Server) apiRoutesListFiltered(
 	result, err := source.RoutesFiltered(ctx, neighborID)
 	if err != nil {
 		s.logSourceError("routes_filtered", rsID, neighborID, err)
-		return nil, err
+		if !s.useStoreFallback(err) {
+			return nil, err
+		}
+		stored, fallbackErr := s.fallbackRoutes(ctx, rsID, neighborID, "filtered")
+		if fallbackErr != nil {
+			return nil, err
+		}
+		result = stored
 	}
 
 	// Filter routes based on criteria if present
 	allRoutes := apiQueryFilterNextHopGateway(req, "q", result.Filtered)
+	allRoutes = apiQueryFilterRouteFamily(req, allRoutes)
 	routes := api.Routes{}
 
 	// Apply other (commmunity) filters
@@ -221,6 +237,7 @@ func (s *Server) apiRoutesListNotExported(
 
 	// Filter routes based on criteria if present
 	allRoutes := apiQueryFilterNextHopGateway(req, "q", result.NotExported)
+	allRoutes = apiQueryFilterRouteFamily(req, allRoutes)
 	routes := api.Routes{}
 
 	// Apply other (commmunity) filters
@@ -9,8 +9,19 @@ import (
 
 	"github.com/alice-lg/alice-lg/pkg/api"
 	"github.com/alice-lg/alice-lg/pkg/config"
+	"github.com/alice-lg/alice-lg/pkg/sources"
 )
 
+// sourceHealth returns the state of the circuit breaker
+// of a source, if the source has one. Instances are not
+// created for reporting the health.
+func sourceHealth(src *config.SourceConfig) *api.SourceHealth {
+	if b, ok := src.Instance().(*sources.Breaker); ok {
+		return b.Health()
+	}
+	return nil
+}
+
 // Handle RouteServers List
 func (s *Server) apiRouteServersList(
 	ctx context.Context,
@@ -20,7 +31,7 @@ func (s *Server) apiRouteServersList(
 	// Get list of sources from config,
 	routeservers := api.RouteServers{}
 
-	sources := s.cfg.Sources
+	sources := s.cfg.GetSources()
 	for _, source := range sources {
 		routeservers = append(routeservers, api.RouteServer{
 			ID:         source.ID,
@@ -29,6 +40,7 @@ func (s *Server) apiRouteServersList(
 			Group:      source.Group,
 			Blackholes: source.Blackholes,
 			Order:      source.Order,
+			Health:     sourceHealth(source),
 		})
 	}
 
@@ -96,6 +96,9 @@ func (s *Server) apiLookupPrefixGlobal(
 		}
 	}
 
+	// Filter by table and AFI-SAFI
+	routes = apiQueryFilterLookupRouteFamily(req, routes)
+
 	// Split routes
 	// TODO: Refactor at neighbors store
 	totalResults := len(routes)
@@ -6,11 +6,14 @@ package http
 // to internal IP addresses.
 
 import (
+	"errors"
 	"net/http"
 	"net/url"
 	"strings"
 
 	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/sources"
+	"github.com/alice-lg/alice-lg/pkg/store"
 )
 
 // ErrResourceNotFoundError is a 404 error
@@ -22,6 +25,15 @@ func (err *ErrResourceNotFoundError) Error() string {
 	return "resource not found"
 }
 
+// ErrAccessDenied is a 401 error
+type ErrAccessDenied struct{}
+
+// Error implements the error interface and returns
+// the error message
+func (err *ErrAccessDenied) Error() string {
+	return "access denied"
+}
+
 // ErrTimeout will be sent if the request took too long
 type ErrTimeout string
 
@@ -32,7 +44,8 @@ func (err ErrTimeout) Error() string {
 
 // Variables
 var (
-	ErrSourceNotFound = &ErrResourceNotFoundError{}
+	ErrSourceNotFound   = &ErrResourceNotFoundError{}
+	ErrNeighborNotFound = &ErrResourceNotFoundError{}
 )
 
 // Error tags
@@ -42,6 +55,10 @@ const (
 	TagConnectionTimeout = "CONNECTION_TIMEOUT"
 	TagResourceNotFound  = "NOT_FOUND"
 	TagValidationError   = "VALIDATION_ERROR"
+	TagAccessDenied      = "ACCESS_DENIED"
+	TagSourceBusy        = "SOURCE_BUSY"
+	TagSourceUnavailable = "SOURCE_UNAVAILABLE"
+	TagStoreUnavailable  = "STORE_UNAVAILABLE"
 )
 
 // Error codes
@@ -49,8 +66,12 @@ const (
 	CodeGeneric           = 42
 	CodeConnectionRefused = 100
 	CodeConnectionTimeout = 101
+	CodeSourceUnavailable = 102
+	CodeStoreUnavailable  = 103
 	CodeValidationError   = 400
+	CodeAccessDenied      = 401
 	CodeResourceNotFound  = 404
+	CodeSourceBusy        = 409
 )
 
 // Error status codes
@@ -58,6 +79,9 @@ const (
 	StatusError            = http.StatusInternalServerError
 	StatusResourceNotFound = http.StatusNotFound
 	StatusValidationError  = http.StatusBadRequest
+	StatusAccessDenied     = http.StatusUnauthorized
+	StatusSourceBusy       = http.StatusConflict
+	StatusUnavailable      = http.StatusServiceUnavailable
 	TimeoutError           = http.StatusGatewayTimeout
 )
 
@@ -76,6 +100,23 @@ func apiErrorResponse(
 		tag = TagValidationError
 		code = CodeValidationError
 		status = StatusValidationError
+	} else if errors.Is(err, sources.ErrSourceBusy) ||
+		errors.Is(err, store.ErrSourceMaintenance) {
+		tag = TagSourceBusy
+		code = CodeSourceBusy
+		status = StatusSourceBusy
+	} else if errors.Is(err, sources.ErrSourceUnavailable) {
+		tag = TagSourceUnavailable
+		code = CodeSourceUnavailable
+		status = StatusUnavailable
+	} else if errors.Is(err, store.ErrStoreNotRunning) {
+		tag = TagStoreUnavailable
+		code = CodeStoreUnavailable
+		status = StatusUnavailable
+	} else if errors.Is(err, sources.ErrSourceNotFound) {
+		tag = TagResourceNotFound
+		code = CodeResourceNotFound
+		status = StatusResourceNotFound
 	} else {
 
 		switch e := err.(type) {
@@ -87,6 +128,10 @@ func apiErrorResponse(
 			tag = TagResourceNotFound
 			code = CodeResourceNotFound
 			status = StatusResourceNotFound
+		case *ErrAccessDenied:
+			tag = TagAccessDenied
+			code = CodeAccessDenied
+			status = StatusAccessDenied
 		case *url.Error:
 			if strings.Contains(message, "connection refused") {
 				tag = TagConnectionRefused
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/config"
+)
+
+// errNeighborNotStored is returned when the neighbor
+// is unknown to the neighbors store.
+var errNeighborNotStored = errors.New("neighbor not in store")
+
+// useStoreFallback checks if the response can be served
+// from the store after a request to the source failed.
+// Canceled requests are not retried.
+func (s *Server) useStoreFallback(err error) bool {
+	return err != nil &&
+		s.cfg.Server.EnableStoreFallback &&
+		!errors.Is(err, context.Canceled)
+}
+
+// staleMeta creates the meta of a response served
+// from the store, because the source failed.
+func staleMeta(cachedAt time.Time, now time.Time) *api.Meta {
+	return &api.Meta{
+		Version: config.Version,
+		CacheStatus: api.CacheStatus{
+			CachedAt: cachedAt,
+		},
+		ResultFromCache: true,
+		TTL:             now,
+		Stale:           true,
+		StaleAge:        now.Sub(cachedAt).Seconds(),
+	}
+}
+
+// fallbackNeighbors retrieves the neighbors of a
+// source from the store.
+func (s *Server) fallbackNeighbors(
+	ctx context.Context,
+	rsID string,
+) (*api.NeighborsResponse, error) {
+	neighbors, cachedAt, err := s.neighborsStore.GetStoredNeighborsAt(ctx, rsID)
+	if err != nil {
+		return nil, err
+	}
+	log.Println("serving stale neighbors of", rsID, "from store")
+	return &api.NeighborsResponse{
+		Response: api.Response{
+			Meta: staleMeta(cachedAt, time.Now().UTC()),
+		},
+		Neighbors: neighbors,
+	}, nil
+}
+
+// fallbackRoutes retrieves the routes of a neighbor
+// with a state (imported or filtered) from the store.
+// The neighbor must be known to the neighbors store.
+func (s *Server) fallbackRoutes(
+	ctx context.Context,
+	rsID string,
+	neighborID string,
+	state string,
+) (*api.RoutesResponse, error) {
+	neighbors, err := s.neighborsStore.GetNeighborsMapAt(ctx, rsID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := neighbors[neighborID]; !ok {
+		return nil, errNeighborNotStored
+	}
+	routes, cachedAt, err := s.routesStore.GetStoredRoutesAt(
+		ctx, rsID, neighborID, state)
+	if err != nil {
+		return nil, err
+	}
+	log.Println(
+		"serving stale", state, "routes of", neighborID,
+		"on", rsID, "from store")
+	res := &api.RoutesResponse{
+		Response: api.Response{
+			Meta: staleMeta(cachedAt, time.Now().UTC()),
+		},
+	}
+	if state == "filtered" {
+		res.Filtered = routes
+	} else {
+		res.Imported = routes
+	}
+	return res, nil
+}
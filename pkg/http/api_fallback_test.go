@@ -0,0 +1,67 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alice-lg/alice-lg/pkg/config"
+	"github.com/alice-lg/alice-lg/pkg/store"
+)
+
+func TestUseStoreFallback(t *testing.T) {
+	s := &Server{cfg: &config.Config{
+		Server: config.ServerConfig{
+			EnableStoreFallback: true,
+		},
+	}}
+	if s.useStoreFallback(nil) {
+		t.Error("fallback without error")
+	}
+	if !s.useStoreFallback(errors.New("timeout")) {
+		t.Error("expected fallback")
+	}
+	err := fmt.Errorf("request: %w", context.Canceled)
+	if s.useStoreFallback(err) {
+		t.Error("fallback for canceled request")
+	}
+	s.cfg.Server.EnableStoreFallback = false
+	if s.useStoreFallback(errors.New("timeout")) {
+		t.Error("fallback is disabled")
+	}
+}
+
+func TestStaleMeta(t *testing.T) {
+	now := time.Now().UTC()
+	meta := staleMeta(now.Add(-90*time.Second), now)
+	if !meta.Stale || !meta.ResultFromCache {
+		t.Error("expected stale result from cache:", meta)
+	}
+	if meta.StaleAge != 90 {
+		t.Error("unexpected age:", meta.StaleAge)
+	}
+}
+
+func TestStoredNeighborsMeta(t *testing.T) {
+	now := time.Now().UTC()
+	status := &store.Status{
+		LastRefresh: now.Add(-120 * time.Second),
+		State:       store.StateReady,
+	}
+	meta := storedNeighborsMeta(status, now, now)
+	if meta.Stale || !meta.ResultFromCache {
+		t.Error("expected fresh result from cache:", meta)
+	}
+
+	// The last refresh failed
+	status.State = store.StateError
+	meta = storedNeighborsMeta(status, now, now)
+	if !meta.Stale {
+		t.Error("expected stale result:", meta)
+	}
+	if meta.StaleAge != 120 {
+		t.Error("unexpected age:", meta.StaleAge)
+	}
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -58,6 +59,55 @@ func apiQueryFilterNextHopGateway(
 	return results
 }
 
+// apiQueryMatchRouteFamily checks if the route matches
+// the table and afi_safi parameters of the query.
+func apiQueryMatchRouteFamily(query url.Values, r *api.Route) bool {
+	if table := query.Get("table"); table != "" && r.Table != table {
+		return false
+	}
+	if afiSafi := query.Get("afi_safi"); afiSafi != "" &&
+		r.AddressFamily() != strings.ToLower(afiSafi) {
+		return false
+	}
+	return true
+}
+
+// apiQueryFilterRouteFamily filters the routes by
+// table and AFI-SAFI.
+func apiQueryFilterRouteFamily(
+	req *http.Request, routes api.Routes,
+) api.Routes {
+	query := req.URL.Query()
+	if query.Get("table") == "" && query.Get("afi_safi") == "" {
+		return routes
+	}
+	results := make(api.Routes, 0, len(routes))
+	for _, r := range routes {
+		if apiQueryMatchRouteFamily(query, r) {
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+// apiQueryFilterLookupRouteFamily filters the lookup
+// routes by table and AFI-SAFI.
+func apiQueryFilterLookupRouteFamily(
+	req *http.Request, routes api.LookupRoutes,
+) api.LookupRoutes {
+	query := req.URL.Query()
+	if query.Get("table") == "" && query.Get("afi_safi") == "" {
+		return routes
+	}
+	results := make(api.LookupRoutes, 0, len(routes))
+	for _, r := range routes {
+		if apiQueryMatchRouteFamily(query, r.Route) {
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
 // QueryString wraps the q parameter from the query.
 // Extract the value and additional filters from the string
 type QueryString string
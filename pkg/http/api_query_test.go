@@ -73,3 +73,29 @@ func TestApiQueryFilterNextHopGateway(t *testing.T) {
 		t.Error("Expected 142.23.0.0/16 to match criteria, got:", filtered[0])
 	}
 }
+
+func TestApiQueryFilterRouteFamily(t *testing.T) {
+	routes := makeQueryRoutes()
+	routes[0].Table = "master4"
+	routes[1].Table = "flowtab4"
+	routes[1].AFISAFI = api.AFISAFIIPv4Flowspec
+	routes[2].Table = "master4"
+
+	u, _ := url.Parse("http://alice/api?afi_safi=ipv4-unicast")
+	filtered := apiQueryFilterRouteFamily(&http.Request{URL: u}, routes)
+	if len(filtered) != 2 || filtered[1].Network != "123.43.0.0/16" {
+		t.Error("unexpected routes:", filtered)
+	}
+
+	u, _ = url.Parse("http://alice/api?table=flowtab4")
+	filtered = apiQueryFilterRouteFamily(&http.Request{URL: u}, routes)
+	if len(filtered) != 1 || filtered[0].Network != "142.23.0.0/16" {
+		t.Error("unexpected routes:", filtered)
+	}
+
+	u, _ = url.Parse("http://alice/api?table=master4&afi_safi=ipv4-flowspec")
+	filtered = apiQueryFilterRouteFamily(&http.Request{URL: u}, routes)
+	if len(filtered) != 0 {
+		t.Error("unexpected routes:", filtered)
+	}
+}
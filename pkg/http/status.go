@@ -17,6 +17,9 @@ type AppStatus struct {
 	Routes    *api.RoutesStoreStats    `json:"routes"`
 	Neighbors *api.NeighborsStoreStats `json:"neighbors"`
 	Postgres  *postgres.Status         `json:"postgres"`
+
+	// The health of the sources with a circuit breaker
+	Sources map[string]*api.SourceHealth `json:"sources,omitempty"`
 }
 
 // CollectAppStatus initializes the application
@@ -0,0 +1,46 @@
+package pools
+
+import (
+	"github.com/alice-lg/alice-lg/pkg/api"
+)
+
+// AcquireRoute replaces the attributes of a route with
+// the pooled values. Routes decoded from a persistent
+// storage must be acquired, as lookups compare the
+// pointers of the neighbor ids.
+func AcquireRoute(route *api.Route) {
+	if route.NeighborID != nil {
+		route.NeighborID = Neighbors.Acquire(*route.NeighborID)
+	}
+	if route.Interface != nil {
+		route.Interface = Interfaces.Acquire(*route.Interface)
+	}
+	if route.Gateway != nil {
+		route.Gateway = Gateways4.Acquire(*route.Gateway)
+	}
+	route.Type = Types.Acquire(route.Type)
+
+	bgp := route.BGP
+	if bgp == nil {
+		return
+	}
+	if bgp.Origin != nil {
+		bgp.Origin = Origins.Acquire(*bgp.Origin)
+	}
+	if bgp.NextHop != nil {
+		bgp.NextHop = Gateways4.Acquire(*bgp.NextHop)
+	}
+	bgp.AsPath = ASPaths.Acquire(bgp.AsPath)
+	bgp.Communities = CommunitiesSets.Acquire(bgp.Communities)
+	bgp.LargeCommunities = LargeCommunitiesSets.Acquire(bgp.LargeCommunities)
+
+	// Numbers in decoded extended communities are floats
+	for _, comm := range bgp.ExtCommunities {
+		for i, v := range comm {
+			if f, ok := v.(float64); ok {
+				comm[i] = int(f)
+			}
+		}
+	}
+	bgp.ExtCommunities = ExtCommunitiesSets.Acquire(bgp.ExtCommunities)
+}
@@ -0,0 +1,49 @@
+package pools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+)
+
+func TestAcquireRoute(t *testing.T) {
+	data := []byte(`{
+		"neighbor_id": "ID23_AS2342",
+		"network": "10.0.0.0/8",
+		"gateway": "192.168.1.1",
+		"type": ["BGP", "univ"],
+		"bgp": {
+			"origin": "IGP",
+			"as_path": [2342, 23],
+			"communities": [[2342, 1]],
+			"ext_communities": [["rt", 2342, 1]]
+		}
+	}`)
+	r1 := &api.Route{}
+	r2 := &api.Route{}
+	if err := json.Unmarshal(data, r1); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, r2); err != nil {
+		t.Fatal(err)
+	}
+	AcquireRoute(r1)
+	AcquireRoute(r2)
+
+	if r1.NeighborID != r2.NeighborID {
+		t.Error("expected neighbor ids to be pooled")
+	}
+	if r1.NeighborID != Neighbors.Get("ID23_AS2342") {
+		t.Error("unexpected neighbor id pointer")
+	}
+	if r1.BGP.Origin != r2.BGP.Origin {
+		t.Error("expected origins to be pooled")
+	}
+	if &r1.BGP.AsPath[0] != &r2.BGP.AsPath[0] {
+		t.Error("expected as paths to be pooled")
+	}
+	if !r1.BGP.HasExtCommunity(api.ExtCommunity{"rt", 2342, 1}) {
+		t.Error("expected ext community to match")
+	}
+}
@@ -5,8 +5,13 @@ package birdwatcher
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+
+	"github.com/alice-lg/alice-lg/pkg/sources"
 )
 
 // ClientResponse is a json key value mapping
@@ -28,7 +33,9 @@ func NewClient(api string) *Client {
 
 // GetEndpoint makes an API request and returns the
 // response. The response body will be parsed further
-// downstream.
+// downstream. A response with an error status is
+// returned as error, including the error message
+// of birdwatcher.
 func (c *Client) GetEndpoint(
 	ctx context.Context,
 	endpoint string,
@@ -40,7 +47,23 @@ func (c *Client) GetEndpoint(
 		return nil, err
 	}
 
-	return client.Do(req)
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := sources.CheckResponseStatus(res); err != nil {
+		defer res.Body.Close()
+		var body struct {
+			Error string `json:"error"`
+		}
+		var statusErr *sources.StatusError
+		payload, _ := ioutil.ReadAll(io.LimitReader(res.Body, 4096))
+		if errors.As(err, &statusErr) && json.Unmarshal(payload, &body) == nil {
+			statusErr.Message = body.Error
+		}
+		return nil, fmt.Errorf("unexpected status: %w", err)
+	}
+	return res, nil
 }
 
 // GetJSON makes an API request.
@@ -0,0 +1,34 @@
+package birdwatcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alice-lg/alice-lg/pkg/sources"
+)
+
+func TestClientStatusError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(`{"error": "bird is not running"}`))
+		}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+	_, err := client.GetJSON(context.Background(), "/status")
+	var statusErr *sources.StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatal("expected status error, got:", err)
+	}
+	if statusErr.StatusCode != http.StatusBadGateway ||
+		statusErr.Message != "bird is not running" {
+		t.Error("unexpected status error:", statusErr)
+	}
+	if !sources.IsFailure(err) {
+		t.Error("expected error to count as failure")
+	}
+}
@@ -12,6 +12,7 @@ type Config struct {
 	ServerTimeShort string `ini:"servertime_short"`
 	ServerTimeExt   string `ini:"servertime_ext"`
 	ShowLastReboot  bool   `ini:"show_last_reboot"`
+	DecodeMode      string `ini:"decode_mode"`
 
 	Type                    string `ini:"type"`
 	MainTable               string `ini:"main_table"`
@@ -21,5 +22,9 @@ type Config struct {
 	AltPipeProtocolSuffix   string `ini:"alt_pipe_protocol_suffix"`
 	NeighborsRefreshTimeout int    `ini:"neighbors_refresh_timeout"`
 
+	// ChannelTables are the comma separated tables of
+	// additional BIRD 2 channels, e.g. flowspec or VPN.
+	ChannelTables string `ini:"channel_tables"`
+
 	StreamParserThrottle int
 }
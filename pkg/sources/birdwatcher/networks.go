@@ -0,0 +1,99 @@
+package birdwatcher
+
+import (
+	"strings"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+)
+
+// BIRD 2 networks besides plain prefixes:
+//
+//   flow4 { dst 192.0.2.0/24; proto 6; dport = 80; }
+//   65000:100 192.0.2.0/24
+//
+// are flowspec rules and VPN routes with a
+// route distinguisher.
+
+// flowSpecTypes are the flowspec components consisting
+// of more than one word.
+var flowSpecTypes = []string{
+	"icmp type",
+	"icmp code",
+	"tcp flags",
+}
+
+// decodeFlowSpec decodes the rules of a flowspec network
+func decodeFlowSpec(rules string) []*api.FlowSpecRule {
+	flowSpec := []*api.FlowSpecRule{}
+	for _, rule := range strings.Split(rules, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		ruleType := ""
+		for _, t := range flowSpecTypes {
+			if strings.HasPrefix(rule, t+" ") {
+				ruleType = t
+				break
+			}
+		}
+		if ruleType == "" {
+			ruleType = strings.SplitN(rule, " ", 2)[0]
+		}
+		flowSpec = append(flowSpec, &api.FlowSpecRule{
+			Type:  ruleType,
+			Value: strings.TrimSpace(strings.TrimPrefix(rule, ruleType)),
+		})
+	}
+	return flowSpec
+}
+
+// decodeNetwork sets the network of the route. The network
+// is the prefix only: For flowspec rules, the network is the
+// destination prefix, if present. The route distinguisher
+// and the flowspec rules are part of the route key.
+func decodeNetwork(route *api.Route, network string) {
+	route.Network = network
+
+	// Flowspec: flow4 { ... }
+	if strings.HasPrefix(network, "flow4 {") ||
+		strings.HasPrefix(network, "flow6 {") {
+		route.AFISAFI = api.AFISAFIIPv4Flowspec
+		if strings.HasPrefix(network, "flow6") {
+			route.AFISAFI = api.AFISAFIIPv6Flowspec
+		}
+		rules := strings.TrimSuffix(
+			strings.TrimSpace(network[len("flow4 {"):]), "}")
+		route.FlowSpec = decodeFlowSpec(rules)
+		route.Network = ""
+		for _, r := range route.FlowSpec {
+			if r.Type == "dst" {
+				// The destination may include an offset (IPv6)
+				route.Network = strings.SplitN(r.Value, " ", 2)[0]
+				break
+			}
+		}
+		return
+	}
+
+	// VPN: <route distinguisher> <prefix>
+	tokens := strings.Fields(network)
+	if len(tokens) != 2 ||
+		!strings.Contains(tokens[0], ":") ||
+		!strings.Contains(tokens[1], "/") {
+		return
+	}
+	route.RouteDistinguisher = tokens[0]
+	route.Network = tokens[1]
+	route.AFISAFI = api.AFISAFIIPv4VPNUnicast
+	if strings.Contains(tokens[1], ":") {
+		route.AFISAFI = api.AFISAFIIPv6VPNUnicast
+	}
+}
+
+// setRoutesTable sets the table of the routes
+func setRoutesTable(routes api.Routes, table string) {
+	for _, r := range routes {
+		r.Table = table
+	}
+}
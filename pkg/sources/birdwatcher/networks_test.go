@@ -0,0 +1,80 @@
+package birdwatcher
+
+import (
+	"testing"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+)
+
+func TestDecodeNetwork(t *testing.T) {
+	r := &api.Route{}
+	decodeNetwork(r, "192.0.2.0/24")
+	if r.Network != "192.0.2.0/24" || r.AFISAFI != "" {
+		t.Error("unexpected route:", r)
+	}
+	if r.AddressFamily() != api.AFISAFIIPv4Unicast {
+		t.Error("unexpected afi safi:", r.AddressFamily())
+	}
+
+	r = &api.Route{}
+	decodeNetwork(r, "65000:100 2001:db8::/32")
+	if r.Network != "2001:db8::/32" || r.RouteDistinguisher != "65000:100" {
+		t.Error("unexpected route:", r)
+	}
+	if r.AFISAFI != api.AFISAFIIPv6VPNUnicast {
+		t.Error("unexpected afi safi:", r.AFISAFI)
+	}
+
+	r = &api.Route{}
+	decodeNetwork(r,
+		"flow4 { dst 192.0.2.0/24; proto 6; dport > 24 && < 30; tcp flags 0x3/0x3; }")
+	if r.Network != "192.0.2.0/24" || r.AFISAFI != api.AFISAFIIPv4Flowspec {
+		t.Error("unexpected route:", r)
+	}
+	expected := []api.FlowSpecRule{
+		{Type: "dst", Value: "192.0.2.0/24"},
+		{Type: "proto", Value: "6"},
+		{Type: "dport", Value: "> 24 && < 30"},
+		{Type: "tcp flags", Value: "0x3/0x3"},
+	}
+	if len(r.FlowSpec) != len(expected) {
+		t.Fatal("unexpected flowspec:", r.FlowSpec)
+	}
+	for i, rule := range r.FlowSpec {
+		if *rule != expected[i] {
+			t.Error("expected", expected[i], "got:", *rule)
+		}
+	}
+
+	// Without destination there is no network
+	r = &api.Route{}
+	decodeNetwork(r, "flow6 { src 2001:db8::/32 offset 0; }")
+	if r.Network != "" || r.AFISAFI != api.AFISAFIIPv6Flowspec {
+		t.Error("unexpected route:", r)
+	}
+	if r.Key() != "; src 2001:db8::/32 offset 0" {
+		t.Error("unexpected route key:", r.Key())
+	}
+}
+
+func TestDecodeNetworkKeys(t *testing.T) {
+	networks := []string{
+		"65000:100 192.0.2.0/24",
+		"65000:200 192.0.2.0/24",
+		"192.0.2.0/24",
+		"flow4 { dst 192.0.2.0/24; proto 6; }",
+		"flow4 { dst 192.0.2.0/24; proto 17; }",
+	}
+	keys := map[string]bool{}
+	for _, network := range networks {
+		r := &api.Route{}
+		decodeNetwork(r, network)
+		if r.Network != "192.0.2.0/24" {
+			t.Error("unexpected network:", r.Network)
+		}
+		keys[r.Key()] = true
+	}
+	if len(keys) != len(networks) {
+		t.Error("expected unique route keys:", keys)
+	}
+}
@@ -102,47 +102,6 @@ func parseCacheStatus(
 	return status, nil
 }
 
-// Parse birdwatcher status
-func parseBirdwatcherStatus(bird ClientResponse, config Config) (api.Status, error) {
-	birdStatus := bird["status"].(map[string]interface{})
-
-	// Get special fields
-	serverTime, _ := parseServerTime(
-		birdStatus["current_server"],
-		config.ServerTimeShort,
-		config.Timezone,
-	)
-
-	lastReboot, _ := parseServerTime(
-		birdStatus["last_reboot"],
-		config.ServerTimeShort,
-		config.Timezone,
-	)
-
-	if !config.ShowLastReboot {
-		lastReboot = time.Time{}
-	}
-
-	lastReconfig, _ := parseServerTime(
-		birdStatus["last_reconfig"],
-		config.ServerTimeExt,
-		config.Timezone,
-	)
-
-	// Make status response
-	status := api.Status{
-		ServerTime:   serverTime,
-		LastReboot:   lastReboot,
-		LastReconfig: lastReconfig,
-		Backend:      "bird",
-		Version:      decoders.String(birdStatus["version"], "unknown"),
-		Message:      decoders.String(birdStatus["message"], "unknown"),
-		RouterID:     decoders.String(birdStatus["router_id"], "unknown"),
-	}
-
-	return status, nil
-}
-
 // Parse neighbor uptime
 func parseRelativeServerTime(uptime interface{}, config Config) time.Duration {
 	serverTime, _ := parseServerTime(uptime, config.ServerTimeShort, config.Timezone)
@@ -193,6 +152,7 @@ func parseNeighbors(bird ClientResponse, config Config) (api.Neighbors, error) {
 
 			RouteServerID: rsID,
 
+			Session: decodeNeighborSession(protocol),
 			Details: protocol,
 		}
 
@@ -345,7 +305,6 @@ func parseRouteData(
 
 		NeighborID: pools.Neighbors.Acquire(
 			decoders.String(rdata["from_protocol"], "unknown neighbor")),
-		Network: decoders.String(rdata["network"], "unknown net"),
 		Interface: pools.Interfaces.Acquire(
 			decoders.String(rdata["interface"], "unknown interface")),
 		Metric:     decoders.Int(rdata["metric"], -1),
@@ -358,6 +317,7 @@ func parseRouteData(
 
 		Details: &details,
 	}
+	decodeNetwork(route, decoders.String(rdata["network"], "unknown net"))
 	return route
 }
 
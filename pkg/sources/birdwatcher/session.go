@@ -0,0 +1,134 @@
+package birdwatcher
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/decoders"
+)
+
+// The session details of a BGP protocol are
+// key value pairs of `show protocols all`:
+//
+//   "hold_timer": "146/180",
+//   "keepalive_timer": "8/60",
+//   "neighbor_caps": "refresh restart-able AS4 add-path-rx",
+//   "route_limit": "139/16000",
+//   "action": "restart",
+//   "last_error": "Received: Hold timer expired"
+
+// decodeTimer decodes the configured time of a timer
+// like "146/180" or "146.532/180".
+func decodeTimer(value interface{}) (int, bool) {
+	_, total, ok := strings.Cut(decoders.String(value, ""), "/")
+	if !ok {
+		return 0, false
+	}
+	t, err := strconv.ParseFloat(strings.TrimSpace(total), 64)
+	if err != nil {
+		return 0, false
+	}
+	return int(t), true
+}
+
+// decodeRouteLimit decodes a limit like "139/16000"
+// or a plain limit without the number of routes.
+func decodeRouteLimit(value interface{}) *api.RouteLimit {
+	if limit, ok := value.(float64); ok {
+		return &api.RouteLimit{Limit: int(limit)}
+	}
+	routes, limit, ok := strings.Cut(decoders.String(value, ""), "/")
+	if !ok {
+		return nil
+	}
+	return &api.RouteLimit{
+		Limit:  decoders.IntFromString(strings.TrimSpace(limit), 0),
+		Routes: decoders.IntFromString(strings.TrimSpace(routes), 0),
+	}
+}
+
+// decodeCapabilities decodes the capabilities of
+// the neighbor.
+func decodeCapabilities(caps string) *api.NeighborCapabilities {
+	if caps == "" {
+		return nil
+	}
+	c := &api.NeighborCapabilities{}
+	addPathRx, addPathTx := false, false
+	for _, name := range strings.Fields(caps) {
+		switch name {
+		case "refresh":
+			c.RouteRefresh = true
+		case "enhanced-refresh":
+			c.EnhancedRouteRefresh = true
+		case "AS4":
+			c.FourOctetASN = true
+		case "ext-messages":
+			c.ExtendedMessage = true
+		case "restart-able", "restart-aware":
+			c.GracefulRestart = &api.GracefulRestartCapability{}
+		case "llgr-able", "llgr-aware":
+			c.LongLivedGracefulRestart = true
+		case "add-path-rx":
+			addPathRx = true
+		case "add-path-tx":
+			addPathTx = true
+		}
+	}
+	switch {
+	case addPathRx && addPathTx:
+		c.AddPath = []*api.AddPathCapability{{Mode: api.AddPathSendReceive}}
+	case addPathRx:
+		c.AddPath = []*api.AddPathCapability{{Mode: api.AddPathReceive}}
+	case addPathTx:
+		c.AddPath = []*api.AddPathCapability{{Mode: api.AddPathSend}}
+	}
+	return c
+}
+
+// decodeNeighborSession decodes the session details
+// of a BGP protocol. The BFD state is only present
+// if provided by birdwatcher.
+func decodeNeighborSession(
+	protocol map[string]interface{},
+) *api.NeighborSession {
+	session := &api.NeighborSession{
+		Capabilities: decodeCapabilities(
+			decoders.String(protocol["neighbor_caps"], "")),
+		LastError: api.ParseBGPError(
+			decoders.String(protocol["last_error"], "")),
+		BFDState: decoders.String(protocol["bfd"], ""),
+	}
+
+	holdTime, hasHold := decodeTimer(protocol["hold_timer"])
+	keepalive, hasKeepalive := decodeTimer(protocol["keepalive_timer"])
+	if hasHold || hasKeepalive {
+		session.Timers = &api.NeighborTimers{
+			HoldTime:      holdTime,
+			KeepaliveTime: keepalive,
+		}
+	}
+
+	// BIRD 1.x: route limit, BIRD 2: import limit
+	session.ImportLimit = decodeRouteLimit(protocol["route_limit"])
+	if session.ImportLimit == nil {
+		session.ImportLimit = decodeRouteLimit(protocol["import_limit"])
+	}
+	session.ExportLimit = decodeRouteLimit(protocol["export_limit"])
+	action := decoders.String(protocol["action"], "")
+	for _, limit := range []*api.RouteLimit{
+		session.ImportLimit, session.ExportLimit,
+	} {
+		if limit != nil {
+			limit.Action = action
+		}
+	}
+
+	if session.Timers == nil && session.Capabilities == nil &&
+		session.LastError == nil && session.ImportLimit == nil &&
+		session.ExportLimit == nil && session.BFDState == "" {
+		return nil
+	}
+	return session
+}
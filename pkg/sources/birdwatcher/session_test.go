@@ -0,0 +1,72 @@
+package birdwatcher
+
+import (
+	"testing"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+)
+
+func TestDecodeNeighborSession(t *testing.T) {
+	bird, err := parseTestResponse(APIResponseNeighbors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	neighbors, err := parseNeighbors(bird, testDecodeConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var session *api.NeighborSession
+	for _, n := range neighbors {
+		if n.ID == "ID103_AS25074_194.9.117.1" {
+			session = n.Session
+		}
+	}
+	if session == nil {
+		t.Fatal("expected session details")
+	}
+	if session.Timers.HoldTime != 180 || session.Timers.KeepaliveTime != 60 {
+		t.Error("unexpected timers:", session.Timers)
+	}
+	caps := session.Capabilities
+	if !caps.RouteRefresh || !caps.FourOctetASN || caps.GracefulRestart != nil {
+		t.Error("unexpected capabilities:", caps)
+	}
+	limit := session.ImportLimit
+	if limit.Limit != 16000 || limit.Routes != 139 || limit.Action != "restart" {
+		t.Error("unexpected import limit:", limit)
+	}
+	if session.ExportLimit != nil || session.LastError != nil {
+		t.Error("unexpected session:", session)
+	}
+}
+
+func TestDecodeNeighborSessionBIRD2(t *testing.T) {
+	session := decodeNeighborSession(map[string]interface{}{
+		"hold_timer":      "3.624/240",
+		"keepalive_timer": "12.871/80",
+		"neighbor_caps":   "refresh enhanced-refresh restart-aware llgr-aware AS4 add-path-rx add-path-tx ext-messages",
+		"import_limit":    float64(1000),
+		"last_error":      "Received: Administrative shutdown",
+	})
+	if session.Timers.HoldTime != 240 || session.Timers.KeepaliveTime != 80 {
+		t.Error("unexpected timers:", session.Timers)
+	}
+	caps := session.Capabilities
+	if !caps.EnhancedRouteRefresh || !caps.ExtendedMessage ||
+		!caps.LongLivedGracefulRestart || caps.GracefulRestart == nil {
+		t.Error("unexpected capabilities:", caps)
+	}
+	if len(caps.AddPath) != 1 || caps.AddPath[0].Mode != api.AddPathSendReceive {
+		t.Error("unexpected add-path:", caps.AddPath)
+	}
+	if session.ImportLimit.Limit != 1000 {
+		t.Error("unexpected import limit:", session.ImportLimit)
+	}
+	if session.LastError.Code != 6 || session.LastError.Subcode != 2 {
+		t.Error("unexpected last error:", session.LastError)
+	}
+
+	if decodeNeighborSession(map[string]interface{}{}) != nil {
+		t.Error("expected no session details")
+	}
+}
@@ -3,11 +3,13 @@ package birdwatcher
 import (
 	"context"
 	"fmt"
+	"log"
 	"sort"
 	"time"
 
 	"github.com/alice-lg/alice-lg/pkg/api"
 	"github.com/alice-lg/alice-lg/pkg/caches"
+	"github.com/alice-lg/alice-lg/pkg/decoders"
 	"github.com/alice-lg/alice-lg/pkg/sources"
 )
 
@@ -147,12 +149,12 @@ func (b *GenericBirdwatcher) filterRoutesByDuplicates(
 
 	routesMap := make(map[string]*api.Route) // for O(1) access
 	for _, route := range routes {
-		routesMap[route.Network] = route
+		routesMap[route.Key()] = route
 	}
 
 	// Remove routes from "routes" that are contained within filterRoutes
 	for _, filterRoute := range filterRoutes {
-		delete(routesMap, filterRoute.Network)
+		delete(routesMap, filterRoute.Key())
 	}
 
 	for _, route := range routesMap {
@@ -166,6 +168,74 @@ func (b *GenericBirdwatcher) filterRoutesByDuplicates(
 	return routes
 }
 
+// channelTables returns the tables of the additional channels
+func (b *GenericBirdwatcher) channelTables() []string {
+	return decoders.TrimmedCSVStringList(b.config.ChannelTables)
+}
+
+// fetchTableRoutes fetches routes from an endpoint
+// and sets the table of the routes.
+func (b *GenericBirdwatcher) fetchTableRoutes(
+	ctx context.Context,
+	endpoint string,
+	table string,
+) (*api.Meta, api.Routes, error) {
+	res, err := b.client.GetEndpoint(ctx, endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	meta, routes, err := parseRoutesResponseStream(res.Body, b.config)
+	if err != nil {
+		return nil, nil, err
+	}
+	setRoutesTable(routes, table)
+	return meta, routes, nil
+}
+
+// fetchChannelRoutes fetches the routes of a neighbor
+// from the channel tables. The routes are looked up
+// by the address of the neighbor.
+func (b *GenericBirdwatcher) fetchChannelRoutes(
+	ctx context.Context,
+	src sources.Source,
+	neighborID string,
+) (api.Routes, error) {
+	tables := b.channelTables()
+	if len(tables) == 0 {
+		return api.Routes{}, nil
+	}
+	res, err := src.Neighbors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	address := ""
+	for _, n := range res.Neighbors {
+		if n.ID == neighborID {
+			address = n.Address
+			break
+		}
+	}
+	if address == "" {
+		return nil, fmt.Errorf("invalid neighbor")
+	}
+
+	routes := api.Routes{}
+	for _, table := range tables {
+		_, tableRoutes, err := b.fetchTableRoutes(
+			ctx, "/routes/table/"+table+"/peer/"+address, table)
+		if err != nil {
+			log.Println("WARNING Could not retrieve routes of table",
+				table, "for", neighborID, ":", err)
+			return nil, err
+		}
+		routes = append(routes, tableRoutes...)
+	}
+	sort.Sort(routes)
+	return routes, nil
+}
+
 func (b *GenericBirdwatcher) fetchProtocolsShort(ctx context.Context) (
 	*api.Meta,
 	map[string]interface{},
@@ -207,19 +277,13 @@ func (b *GenericBirdwatcher) ExpireCaches() int {
 
 // Status retrievs the current backend status
 func (b *GenericBirdwatcher) Status(ctx context.Context) (*api.StatusResponse, error) {
-	bird, err := b.client.GetJSON(ctx, "/status")
-	if err != nil {
-		return nil, err
-	}
-
-	// Use api status from first request
-	apiStatus, err := parseAPIStatus(bird, b.config)
+	res, err := b.client.GetEndpoint(ctx, "/status")
 	if err != nil {
 		return nil, err
 	}
+	defer res.Body.Close()
 
-	// Parse the status
-	birdStatus, err := parseBirdwatcherStatus(bird, b.config)
+	apiStatus, birdStatus, err := decodeStatusResponse(res.Body, b.config)
 	if err != nil {
 		return nil, err
 	}
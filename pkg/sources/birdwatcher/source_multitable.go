@@ -1,8 +1,11 @@
 package birdwatcher
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"sort"
 	"strings"
@@ -73,28 +76,42 @@ func (src *MultiTableBirdwatcher) parseProtocolToTableTree(
 	return response
 }
 
+// fetchProtocols retrieves all protocols. The meta and
+// the BGP neighbors are decoded with the typed decoder.
+// The protocols are returned as well, for the lookup
+// of the tables and pipes of the neighbors.
 func (src *MultiTableBirdwatcher) fetchProtocols(ctx context.Context) (
 	*api.Meta,
+	api.Neighbors,
 	map[string]interface{},
 	error,
 ) {
 	// Query birdwatcher
-	bird, err := src.client.GetJSON(ctx, "/protocols")
+	res, err := src.client.GetEndpoint(ctx, "/protocols")
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+	defer res.Body.Close()
+	payload, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
-	// Use api status from first request
-	apiStatus, err := parseAPIStatus(bird, src.config)
+	apiStatus, neighbors, err := decodeNeighborsResponse(
+		bytes.NewReader(payload), src.config)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
-	if _, ok := bird["protocols"]; !ok {
-		return nil, nil, fmt.Errorf("failed to fetch protocols")
+	bird := make(ClientResponse)
+	if err := json.Unmarshal(payload, &bird); err != nil {
+		return nil, nil, nil, err
+	}
+	if _, ok := bird["protocols"].(map[string]interface{}); !ok {
+		return nil, nil, nil, fmt.Errorf("failed to fetch protocols")
 	}
 
-	return apiStatus, bird, nil
+	return apiStatus, neighbors, bird, nil
 }
 
 func (src *MultiTableBirdwatcher) fetchReceivedRoutes(
@@ -102,7 +119,7 @@ func (src *MultiTableBirdwatcher) fetchReceivedRoutes(
 	neighborID string,
 ) (*api.Meta, api.Routes, error) {
 	// Query birdwatcher
-	_, birdProtocols, err := src.fetchProtocols(ctx)
+	_, _, birdProtocols, err := src.fetchProtocols(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -118,34 +135,19 @@ func (src *MultiTableBirdwatcher) fetchReceivedRoutes(
 	pipe := src.getMasterPipeName(table)
 
 	qryURL := "/routes/peer/" + peer
+	routesTable := src.config.MainTable
 	if src.isAltSession(pipe) {
 		qryURL = "/routes/table/" + table + "/peer/" + peer
+		routesTable = table
 	}
 
 	// Query birdwatcher
-	bird, err := src.client.GetJSON(ctx, qryURL)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// Use api status from first request
-	apiStatus, err := parseAPIStatus(bird, src.config)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// Parse the routes
-	received, err := parseRoutes(bird, src.config, true)
+	apiStatus, received, err := src.fetchTableRoutes(ctx, qryURL, routesTable)
 	if err != nil {
 		log.Println("WARNING Could not retrieve received routes:", err)
 		log.Println("Is the 'routes_peer' module active in birdwatcher?")
-		return apiStatus, nil, err
-	}
-
-	for k := range bird {
-		delete(bird, k)
+		return nil, nil, err
 	}
-	bird = nil
 
 	return apiStatus, received, nil
 }
@@ -179,6 +181,7 @@ func (src *MultiTableBirdwatcher) fetchFilteredRoutesStream(
 
 	// If there is no pipe to master, there is nothing left to do
 	if pipeName == "" {
+		setRoutesTable(filtered, table)
 		return meta, filtered, nil
 	}
 
@@ -205,6 +208,7 @@ func (src *MultiTableBirdwatcher) fetchFilteredRoutesStream(
 	}
 
 	filtered = append(filtered, pipeFiltered...)
+	setRoutesTable(filtered, table)
 
 	return meta, filtered, nil
 }
@@ -212,73 +216,22 @@ func (src *MultiTableBirdwatcher) fetchFilteredRoutesStream(
 func (src *MultiTableBirdwatcher) fetchFilteredRoutes(
 	ctx context.Context,
 	neighborID string,
-	keepDetails bool,
 ) (*api.Meta, api.Routes, error) {
 	// Query birdwatcher
-	_, birdProtocols, err := src.fetchProtocols(ctx)
+	_, _, birdProtocols, err := src.fetchProtocols(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
-
 	protocols := birdProtocols["protocols"].(map[string]interface{})
 
-	if _, ok := protocols[neighborID]; !ok {
-		return nil, nil, fmt.Errorf("invalid Neighbor")
-	}
-
-	// Stage 1 filters
-	birdFiltered, err := src.client.GetJSON(ctx, "/routes/filtered/"+neighborID)
+	apiStatus, filtered, err := src.fetchFilteredRoutesStream(
+		ctx, protocols, neighborID)
 	if err != nil {
-		log.Println("WARNING Could not retrieve filtered routes:", err)
-		log.Println("Is the 'routes_filtered' module active in birdwatcher?")
 		return nil, nil, err
 	}
 
-	// Use api status from first request
-	apiStatus, err := parseAPIStatus(birdFiltered, src.config)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// Parse the routes
-	filtered := parseRoutesData(
-		birdFiltered["routes"].([]interface{}), src.config, keepDetails)
-
-	// Stage 2 filters
-	table := protocols[neighborID].(map[string]interface{})["table"].(string)
-	pipeName := src.getMasterPipeName(table)
-
-	// If there is no pipe to master, there is nothing left to do
-	if pipeName == "" {
-		return apiStatus, filtered, nil
-	}
-
-	// Check if this is an alternative session and query the alt pipe instead
-	if src.isAltSession(pipeName) {
-		pipeName = src.getAltPipeName(pipeName)
-	}
-
-	// Query birdwatcher
-	birdPipeFiltered, err := src.client.GetJSON(
-		ctx, "/routes/pipe/filtered?table="+table+"&pipe="+pipeName)
-	if err != nil {
-		log.Println("WARNING Could not retrieve filtered routes:", err)
-		log.Println("Is the 'pipe_filtered' module active in birdwatcher?")
-		return apiStatus, nil, err
-	}
-
-	// Parse the routes
-	pipeFiltered := parseRoutesData(
-		birdPipeFiltered["routes"].([]interface{}), src.config, keepDetails)
-
 	// Sort routes for deterministic ordering
-	filtered = append(filtered, pipeFiltered...)
-
-	if !keepDetails {
-		// Yes this is not the right variable name to convey this...
-		sort.Sort(filtered)
-	}
-
+	sort.Sort(filtered)
 	return apiStatus, filtered, nil
 }
 
@@ -287,7 +240,7 @@ func (src *MultiTableBirdwatcher) fetchNotExportedRoutes(
 	neighborID string,
 ) (*api.Meta, api.Routes, error) {
 	// Query birdwatcher
-	apiStatus, birdProtocols, err := src.fetchProtocols(ctx)
+	apiStatus, _, birdProtocols, err := src.fetchProtocols(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -309,18 +262,12 @@ func (src *MultiTableBirdwatcher) fetchNotExportedRoutes(
 	}
 
 	// Query birdwatcher
-	bird, _ := src.client.GetJSON(ctx, "/routes/noexport/"+pipeName)
-
-	// Use api status from first request
-	apiStatus, err = parseAPIStatus(bird, src.config)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	notExported, err := parseRoutes(bird, src.config, true)
+	apiStatus, notExported, err := src.fetchTableRoutes(
+		ctx, "/routes/noexport/"+pipeName, table)
 	if err != nil {
 		log.Println("WARNING Could not retrieve routes not exported:", err)
 		log.Println("Is the 'routes_noexport' module active in birdwatcher?")
+		return nil, nil, err
 	}
 
 	return apiStatus, notExported, nil
@@ -358,7 +305,7 @@ func (src *MultiTableBirdwatcher) fetchRequiredRoutes(
 	}
 
 	// Second: get routes filtered
-	_, filteredRoutes, err := src.fetchFilteredRoutes(ctx, neighborID, true)
+	_, filteredRoutes, err := src.fetchFilteredRoutes(ctx, neighborID)
 	if err != nil {
 		return nil, err
 	}
@@ -374,6 +321,13 @@ func (src *MultiTableBirdwatcher) fetchRequiredRoutes(
 		importedRoutes = src.filterRoutesByDuplicates(receivedRoutes, filteredRoutes)
 	}
 
+	// Third: get routes from the other channels
+	channelRoutes, err := src.fetchChannelRoutes(ctx, src, neighborID)
+	if err != nil {
+		return nil, err
+	}
+	importedRoutes = append(importedRoutes, channelRoutes...)
+
 	response = &api.RoutesResponse{
 		Response: api.Response{
 			Meta: apiStatus,
@@ -400,14 +354,7 @@ func (src *MultiTableBirdwatcher) Neighbors(
 	}
 
 	// Query birdwatcher
-	apiStatus, birdProtocols, err := src.fetchProtocols(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	// Parse the neighbors
-	neighbors, err := parseNeighbors(
-		src.filterProtocolsBgp(birdProtocols), src.config)
+	apiStatus, neighbors, birdProtocols, err := src.fetchProtocols(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -496,8 +443,8 @@ func (src *MultiTableBirdwatcher) Neighbors(
 						return nil, err
 					}
 
-					if _, ok := count["routes"]; ok {
-						filtered[protocol.(map[string]interface{})["protocol"].(string)] = int(count["routes"].(float64))
+					if n, ok := count["routes"].(float64); ok {
+						filtered[protocol.(map[string]interface{})["protocol"].(string)] = int(n)
 					}
 				}
 			}
@@ -649,7 +596,7 @@ func (src *MultiTableBirdwatcher) AllRoutes(
 	ctx context.Context,
 ) (*api.RoutesResponse, error) {
 	// Query birdwatcher
-	_, birdProtocols, err := src.fetchProtocols(ctx)
+	_, _, birdProtocols, err := src.fetchProtocols(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -657,15 +604,20 @@ func (src *MultiTableBirdwatcher) AllRoutes(
 	mainTable := src.GenericBirdwatcher.config.MainTable
 
 	// Fetch received routes first
-	res, err := src.client.GetEndpoint(ctx, "/routes/table/"+mainTable)
+	meta, imported, err := src.fetchTableRoutes(
+		ctx, "/routes/table/"+mainTable, mainTable)
 	if err != nil {
 		return nil, err
 	}
-	defer res.Body.Close()
 
-	meta, imported, err := parseRoutesResponseStream(res.Body, src.config)
-	if err != nil {
-		return nil, err
+	// Routes of the other channels
+	for _, table := range src.channelTables() {
+		_, routes, err := src.fetchTableRoutes(
+			ctx, "/routes/table/"+table, table)
+		if err != nil {
+			return nil, err
+		}
+		imported = append(imported, routes...)
 	}
 
 	response := &api.RoutesResponse{
@@ -1,6 +1,10 @@
 package birdwatcher
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -23,3 +27,70 @@ func TestGetMasterPipeName(t *testing.T) {
 	}
 
 }
+
+// testMultiTableResponses are the responses of a multi table
+// birdwatcher with a neighbor and the pipe of its table
+var testMultiTableResponses = map[string]string{
+	"/protocols": `{"api":{"Version":"2.0.0","result_from_cache":false,"cache_status":{"cached_at":{"date":""}}},"protocols":{
+		"R1":{"bird_protocol":"BGP","protocol":"R1","table":"T1","neighbor_address":"192.0.2.1","neighbor_as":"AS65001",
+			"state":"up","description":"AS1","routes":{"imported":1,"filtered":0,"exported":0,"preferred":1}},
+		"M1":{"bird_protocol":"Pipe","protocol":"M1","table":"master","state":"up","routes":{"imported":1}}}}`,
+	"/routes/peer/192.0.2.1": `{"api":{"Version":"2.0.0","result_from_cache":false,"cache_status":{"cached_at":{"date":""}}},"routes":[
+		{"network":"10.0.0.0/24","gateway":"192.0.2.1","from_protocol":"R1","metric":"high","bgp":{"as_path":[65001],"local_pref":100}}]}`,
+	"/routes/filtered/R1":   `{"api":{"Version":"2.0.0","result_from_cache":false,"cache_status":{"cached_at":{"date":""}}},"routes":[]}`,
+	"/routes/pipe/filtered": `{"api":{"Version":"2.0.0","result_from_cache":false,"cache_status":{"cached_at":{"date":""}}},"routes":[]}`,
+}
+
+func TestMultiTableDecodeModes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			res, ok := testMultiTableResponses[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte(res))
+		}))
+	defer srv.Close()
+
+	config := testDecodeConfig
+	config.API = srv.URL
+	config.Type = "multi_table"
+	config.MainTable = "master"
+	config.PeerTablePrefix = "T"
+	config.PipeProtocolPrefix = "M"
+	ctx := context.Background()
+
+	// Invalid fields are reported as warnings
+	src := NewBirdwatcher(config)
+	neighbors, err := src.Neighbors(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(neighbors.Neighbors) != 1 || neighbors.Neighbors[0].ID != "R1" {
+		t.Fatal("expected only the BGP neighbor:", neighbors.Neighbors)
+	}
+	if len(neighbors.Meta.Warnings) != 1 {
+		t.Error("unexpected warnings:", neighbors.Meta.Warnings)
+	}
+	routes, err := src.RoutesReceived(ctx, "R1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes.Imported) != 1 || routes.Imported[0].Metric != -1 {
+		t.Fatal("unexpected routes:", routes.Imported)
+	}
+	if len(routes.Meta.Warnings) != 1 {
+		t.Error("unexpected warnings:", routes.Meta.Warnings)
+	}
+
+	// In strict mode the requests fail
+	config.DecodeMode = DecodeModeStrict
+	src = NewBirdwatcher(config)
+	if _, err := src.Neighbors(ctx); !errors.Is(err, ErrInvalidField) {
+		t.Error("expected invalid field error, got:", err)
+	}
+	if _, err := src.RoutesReceived(ctx, "R1"); !errors.Is(err, ErrInvalidField) {
+		t.Error("expected invalid field error, got:", err)
+	}
+}
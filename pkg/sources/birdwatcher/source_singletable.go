@@ -16,36 +16,21 @@ func (src *SingleTableBirdwatcher) fetchReceivedRoutes(
 	ctx context.Context,
 	neighborID string,
 ) (*api.Meta, api.Routes, error) {
-	res, err := src.client.GetEndpoint(ctx, "/routes/protocol/"+neighborID)
-	if err != nil {
-		return nil, nil, err
-	}
-	defer res.Body.Close()
-
-	meta, routes, err := parseRoutesResponseStream(res.Body, src.config)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	return meta, routes, nil
+	return src.fetchTableRoutes(
+		ctx, "/routes/protocol/"+neighborID, src.config.MainTable)
 }
 
 func (src *SingleTableBirdwatcher) fetchFilteredRoutes(
 	ctx context.Context,
 	neighborID string,
 ) (*api.Meta, api.Routes, error) {
-	res, err := src.client.GetEndpoint(ctx, "/routes/filtered/"+neighborID)
+	meta, routes, err := src.fetchTableRoutes(
+		ctx, "/routes/filtered/"+neighborID, src.config.MainTable)
 	if err != nil {
 		log.Println("WARNING Could not retrieve filtered routes:", err)
 		log.Println("Is the 'routes_filtered' module active in birdwatcher?")
 		return nil, nil, err
 	}
-	defer res.Body.Close()
-
-	meta, routes, err := parseRoutesResponseStream(res.Body, src.config)
-	if err != nil {
-		return nil, nil, err
-	}
 
 	return meta, routes, nil
 }
@@ -54,18 +39,13 @@ func (src *SingleTableBirdwatcher) fetchNotExportedRoutes(
 	ctx context.Context,
 	neighborID string,
 ) (*api.Meta, api.Routes, error) {
-	res, err := src.client.GetEndpoint(ctx, "/routes/noexport/"+neighborID)
+	meta, routes, err := src.fetchTableRoutes(
+		ctx, "/routes/noexport/"+neighborID, src.config.MainTable)
 	if err != nil {
 		log.Println("WARNING Could not retrieve routes not exported:", err)
 		log.Println("Is the 'routes_noexport' module active in birdwatcher?")
 		return nil, nil, err
 	}
-	defer res.Body.Close()
-
-	meta, routes, err := parseRoutesResponseStream(res.Body, src.config)
-	if err != nil {
-		return nil, nil, err
-	}
 
 	return meta, routes, nil
 }
@@ -117,6 +97,13 @@ func (src *SingleTableBirdwatcher) fetchRequiredRoutes(
 		importedRoutes = src.filterRoutesByDuplicates(receivedRoutes, filteredRoutes)
 	}
 
+	// Third: get routes from the other channels
+	channelRoutes, err := src.fetchChannelRoutes(ctx, src, neighborID)
+	if err != nil {
+		return nil, err
+	}
+	importedRoutes = append(importedRoutes, channelRoutes...)
+
 	response = &api.RoutesResponse{
 		Response: api.Response{
 			Meta: apiStatus,
@@ -142,19 +129,13 @@ func (src *SingleTableBirdwatcher) Neighbors(
 	}
 
 	// Query birdwatcher
-	bird, err := src.client.GetJSON(ctx, "/protocols/bgp")
-	if err != nil {
-		return nil, err
-	}
-
-	// Use api status from first request
-	apiStatus, err := parseAPIStatus(bird, src.config)
+	res, err := src.client.GetEndpoint(ctx, "/protocols/bgp")
 	if err != nil {
 		return nil, err
 	}
+	defer res.Body.Close()
 
-	// Parse the neighbors
-	neighbors, err := parseNeighbors(bird, src.config)
+	apiStatus, neighbors, err := decodeNeighborsResponse(res.Body, src.config)
 	if err != nil {
 		return nil, err
 	}
@@ -299,27 +280,33 @@ func (src *SingleTableBirdwatcher) AllRoutes(
 	mainTable := src.GenericBirdwatcher.config.MainTable
 
 	// Routes received
-	res, err := src.client.GetEndpoint(ctx, "/routes/table/"+mainTable)
-	if err != nil {
-		return nil, err
-	}
-	defer res.Body.Close()
-
-	meta, birdImported, err := parseRoutesResponseStream(res.Body, src.config)
+	meta, birdImported, err := src.fetchTableRoutes(
+		ctx, "/routes/table/"+mainTable, mainTable)
 	if err != nil {
 		return nil, err
 	}
 
 	// Routes filtered
-	res, err = src.client.GetEndpoint(ctx, "/routes/table/"+mainTable+"/filtered")
+	_, birdFiltered, err := src.fetchTableRoutes(
+		ctx, "/routes/table/"+mainTable+"/filtered", mainTable)
 	if err != nil {
 		return nil, err
 	}
-	defer res.Body.Close()
 
-	_, birdFiltered, err := parseRoutesResponseStream(res.Body, src.config)
-	if err != nil {
-		return nil, err
+	// Routes of the other channels
+	for _, table := range src.channelTables() {
+		_, imported, err := src.fetchTableRoutes(
+			ctx, "/routes/table/"+table, table)
+		if err != nil {
+			return nil, err
+		}
+		_, filtered, err := src.fetchTableRoutes(
+			ctx, "/routes/table/"+table+"/filtered", table)
+		if err != nil {
+			return nil, err
+		}
+		birdImported = append(birdImported, imported...)
+		birdFiltered = append(birdFiltered, filtered...)
 	}
 
 	response := &api.RoutesResponse{
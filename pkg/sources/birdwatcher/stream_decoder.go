@@ -2,16 +2,23 @@ package birdwatcher
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"time"
 
 	"github.com/alice-lg/alice-lg/pkg/api"
 )
 
+// parseRoutesResponseStream decodes a routes response
+// route by route into the typed representation.
 func parseRoutesResponseStream(
 	body io.Reader,
 	config Config,
 ) (*api.Meta, api.Routes, error) {
+	d, err := newTypedDecoder(config)
+	if err != nil {
+		return nil, nil, err
+	}
 	dec := json.NewDecoder(body)
 	meta := &api.Meta{}
 	routes := api.Routes{}
@@ -29,30 +36,27 @@ func parseRoutesResponseStream(
 
 		// Parse API meta data
 		if t == "api" {
-			api := make(map[string]interface{})
-			if err := dec.Decode(&api); err != nil {
+			info := birdAPI{}
+			if err := dec.Decode(&info); err != nil {
+				if err := d.unmarshalError("api", err); err != nil {
+					return nil, nil, err
+				}
+			}
+			if err := d.apiMeta(meta, &info); err != nil {
 				return nil, nil, err
 			}
-			cacheStatus, _ := parseCacheStatus(api, config)
-			meta.Version = api["Version"].(string)
-			meta.ResultFromCache = api["result_from_cache"].(bool)
-			meta.CacheStatus = cacheStatus
 		}
 
 		if t == "ttl" {
-			var ttlD string
-			if err := dec.Decode(&ttlD); err != nil {
-				return nil, nil, err
+			var ttl string
+			if err := dec.Decode(&ttl); err != nil {
+				if err := d.unmarshalError("ttl", err); err != nil {
+					return nil, nil, err
+				}
 			}
-			ttl, err := parseServerTime(
-				ttlD,
-				config.ServerTime,
-				config.Timezone,
-			)
-			if err != nil {
+			if err := d.ttlMeta(meta, ttl); err != nil {
 				return nil, nil, err
 			}
-			meta.TTL = ttl
 		}
 
 		// Route data
@@ -63,20 +67,27 @@ func parseRoutesResponseStream(
 				break
 			}
 
-			for dec.More() {
-				rdata := make(map[string]interface{})
+			for i := 0; dec.More(); i++ {
+				field := fmt.Sprintf("routes[%d]", i)
+				rdata := birdRoute{}
 				if err := dec.Decode(&rdata); err != nil {
-					return nil, nil, err
+					if err := d.unmarshalError(field, err); err != nil {
+						return nil, nil, err
+					}
 				}
 
 				// Wait a bit, so our CPUs do not go up in flames.
 				time.Sleep(throttle)
 
-				route := parseRouteData(rdata, config, false)
+				route, err := d.route(field, &rdata)
+				if err != nil {
+					return nil, nil, err
+				}
 				routes = append(routes, route)
 			}
 		}
 	}
 
+	d.finish(meta)
 	return meta, routes, nil
 }
@@ -0,0 +1,480 @@
+package birdwatcher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/pools"
+)
+
+// Decode modes: In lenient mode invalid fields are
+// replaced with a fallback and a warning is added to
+// the response meta. In strict mode the response fails.
+const (
+	DecodeModeLenient = "lenient"
+	DecodeModeStrict  = "strict"
+)
+
+// ErrInvalidField is returned in strict mode when
+// a field of a response can not be decoded.
+var ErrInvalidField = errors.New("invalid field")
+
+// maxDecodeWarnings limits the warnings of a response
+const maxDecodeWarnings = 100
+
+// A typedDecoder converts typed birdwatcher responses
+// and collects the decode warnings.
+type typedDecoder struct {
+	config   Config
+	strict   bool
+	location *time.Location
+
+	warnings []string
+	dropped  int
+}
+
+// newTypedDecoder creates a decoder for a response
+func newTypedDecoder(config Config) (*typedDecoder, error) {
+	location, err := time.LoadLocation(config.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	return &typedDecoder{
+		config:   config,
+		strict:   config.DecodeMode == DecodeModeStrict,
+		location: location,
+	}, nil
+}
+
+// warn adds a warning for a field. In strict
+// mode the warning is returned as error.
+func (d *typedDecoder) warn(field, format string, args ...interface{}) error {
+	msg := field + ": " + fmt.Sprintf(format, args...)
+	if d.strict {
+		return fmt.Errorf("%w: %s", ErrInvalidField, msg)
+	}
+	if len(d.warnings) >= maxDecodeWarnings {
+		d.dropped++
+		return nil
+	}
+	d.warnings = append(d.warnings, msg)
+	return nil
+}
+
+// unmarshalError converts a type mismatch into a warning.
+// The other fields of the value are decoded anyway.
+func (d *typedDecoder) unmarshalError(field string, err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		return err
+	}
+	if typeErr.Field != "" {
+		field += "." + typeErr.Field
+	}
+	return d.warn(field, "expected %s, got %s", typeErr.Type, typeErr.Value)
+}
+
+// int returns the value of a number or the fallback
+func (d *typedDecoder) int(field string, v flexInt, fallback int) (int, error) {
+	if v.invalid != nil {
+		return fallback, d.warn(field, "invalid number %s", v.invalid)
+	}
+	if !v.set {
+		return fallback, nil
+	}
+	return v.value, nil
+}
+
+// time parses a server time. Empty values are the zero time.
+func (d *typedDecoder) time(field, value, layout string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation(layout, value, d.location)
+	if err != nil {
+		return time.Time{}, d.warn(field, "invalid time %q", value)
+	}
+	return t.UTC(), nil
+}
+
+// meta creates the response meta from the api info
+func (d *typedDecoder) meta(res *birdResponse) (*api.Meta, error) {
+	if res.API == nil {
+		// Try to retrieve the real error from server
+		if res.Error != "" {
+			return nil, errors.New(res.Error)
+		}
+		return nil, fmt.Errorf("invalid API response received from server")
+	}
+	meta := &api.Meta{}
+	if err := d.apiMeta(meta, res.API); err != nil {
+		return nil, err
+	}
+	if err := d.ttlMeta(meta, res.TTL); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// apiMeta sets the version and cache status of the meta
+func (d *typedDecoder) apiMeta(meta *api.Meta, info *birdAPI) error {
+	cachedAt, err := d.time(
+		"api.cache_status.cached_at.date",
+		info.CacheStatus.CachedAt.Date,
+		d.config.ServerTime)
+	if err != nil {
+		return err
+	}
+	meta.Version = info.Version
+	meta.ResultFromCache = info.ResultFromCache
+	meta.CacheStatus = api.CacheStatus{
+		CachedAt: cachedAt,
+	}
+	return nil
+}
+
+// ttlMeta sets the ttl of the meta
+func (d *typedDecoder) ttlMeta(meta *api.Meta, ttl string) error {
+	t, err := d.time("ttl", ttl, d.config.ServerTime)
+	if err != nil {
+		return err
+	}
+	meta.TTL = t
+	return nil
+}
+
+// finish adds the collected warnings to the meta
+func (d *typedDecoder) finish(meta *api.Meta) {
+	if len(d.warnings) == 0 {
+		return
+	}
+	meta.Warnings = d.warnings
+	if d.dropped > 0 {
+		meta.Warnings = append(meta.Warnings,
+			fmt.Sprintf("%d more warnings", d.dropped))
+	}
+}
+
+// status converts the bird status
+func (d *typedDecoder) status(s *birdStatus) (api.Status, error) {
+	serverTime, err := d.time(
+		"status.current_server", s.CurrentServer, d.config.ServerTimeShort)
+	if err != nil {
+		return api.Status{}, err
+	}
+	lastReboot, err := d.time(
+		"status.last_reboot", s.LastReboot, d.config.ServerTimeShort)
+	if err != nil {
+		return api.Status{}, err
+	}
+	if !d.config.ShowLastReboot {
+		lastReboot = time.Time{}
+	}
+	lastReconfig, err := d.time(
+		"status.last_reconfig", s.LastReconfig, d.config.ServerTimeExt)
+	if err != nil {
+		return api.Status{}, err
+	}
+	return api.Status{
+		ServerTime:   serverTime,
+		LastReboot:   lastReboot,
+		LastReconfig: lastReconfig,
+		Backend:      "bird",
+		Version:      stringOr(s.Version, "unknown"),
+		Message:      stringOr(s.Message, "unknown"),
+		RouterID:     stringOr(s.RouterID, "unknown"),
+	}, nil
+}
+
+// neighbor converts a protocol into a neighbor
+func (d *typedDecoder) neighbor(
+	id string,
+	p *birdProtocol,
+	details map[string]interface{},
+) (*api.Neighbor, error) {
+	field := "protocols." + id
+	stateChanged, err := d.time(
+		field+".state_changed", p.StateChanged, d.config.ServerTimeShort)
+	if err != nil {
+		return nil, err
+	}
+	asn, err := d.int(field+".neighbor_as", p.NeighborAS, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	routes := p.Routes
+	if routes == nil {
+		routes = &birdProtocolRoutes{}
+	}
+	counts := []struct {
+		name  string
+		value flexInt
+		count int
+	}{
+		{name: "imported", value: routes.Imported},
+		{name: "filtered", value: routes.Filtered},
+		{name: "exported", value: routes.Exported},
+		{name: "preferred", value: routes.Preferred},
+	}
+	for i, c := range counts {
+		counts[i].count, err = d.int(field+".routes."+c.name, c.value, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	imported, filtered := counts[0].count, counts[1].count
+
+	return &api.Neighbor{
+		ID: id,
+
+		Address:     stringOr(p.NeighborAddress, "error"),
+		ASN:         asn,
+		State:       strings.ToLower(stringOr(p.State, "unknown")),
+		Description: stringOr(p.Description, "no description"),
+
+		RoutesReceived:  imported + filtered,
+		RoutesAccepted:  imported,
+		RoutesFiltered:  filtered,
+		RoutesExported:  counts[2].count,
+		RoutesPreferred: counts[3].count,
+
+		Uptime:    time.Since(stateChanged),
+		LastError: p.LastError,
+
+		RouteServerID: d.config.ID,
+
+		Session: decodeNeighborSession(details),
+		Details: details,
+	}, nil
+}
+
+// route converts a route
+func (d *typedDecoder) route(field string, r *birdRoute) (*api.Route, error) {
+	gwpool := pools.Gateways4
+
+	age, err := d.time(field+".age", r.Age, d.config.ServerTimeShort)
+	if err != nil {
+		return nil, err
+	}
+	metric, err := d.int(field+".metric", r.Metric, -1)
+	if err != nil {
+		return nil, err
+	}
+	bgpInfo, err := d.bgp(field+".bgp", r.BGP)
+	if err != nil {
+		return nil, err
+	}
+
+	gateway := stringOr(r.Gateway, "unknown gateway")
+	learntFrom := stringOr(r.LearntFrom, gateway)
+
+	var details json.RawMessage
+	route := &api.Route{
+		NeighborID: pools.Neighbors.Acquire(
+			stringOr(r.FromProtocol, "unknown neighbor")),
+		Interface: pools.Interfaces.Acquire(
+			stringOr(r.Interface, "unknown interface")),
+		Metric:     metric,
+		Primary:    r.Primary,
+		LearntFrom: gwpool.Acquire(learntFrom),
+		Gateway:    gwpool.Acquire(gateway),
+		Age:        time.Since(age),
+		Type:       pools.Types.Acquire(r.Type),
+		BGP:        bgpInfo,
+
+		Details: &details,
+	}
+	decodeNetwork(route, stringOr(r.Network, "unknown net"))
+	return route, nil
+}
+
+// bgp converts the BGP attributes of a route
+func (d *typedDecoder) bgp(field string, b *birdBGP) (*api.BGPInfo, error) {
+	if b == nil {
+		// Info is missing
+		return &api.BGPInfo{}, nil
+	}
+
+	asPath := make([]int, 0, len(b.ASPath))
+	for i, asn := range b.ASPath {
+		if asn.invalid != nil {
+			err := d.warn(
+				fmt.Sprintf("%s.as_path[%d]", field, i),
+				"invalid number %s", asn.invalid)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		asPath = append(asPath, asn.value)
+	}
+	localPref, err := d.int(field+".local_pref", b.LocalPref, 0)
+	if err != nil {
+		return nil, err
+	}
+	med, err := d.int(field+".med", b.Med, 0)
+	if err != nil {
+		return nil, err
+	}
+	extCommunities, err := d.extCommunities(
+		field+".ext_communities", b.ExtCommunities)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.BGPInfo{
+		Origin:    pools.Origins.Acquire(stringOr(b.Origin, "unknown")),
+		AsPath:    pools.ASPaths.Acquire(asPath),
+		NextHop:   pools.Gateways4.Acquire(stringOr(b.NextHop, "unknown")),
+		LocalPref: localPref,
+		Med:       med,
+		Communities: pools.CommunitiesSets.Acquire(
+			communities(b.Communities)),
+		ExtCommunities: pools.ExtCommunitiesSets.Acquire(extCommunities),
+		LargeCommunities: pools.LargeCommunitiesSets.Acquire(
+			communities(b.LargeCommunities)),
+	}, nil
+}
+
+// extCommunities converts the extended communities
+// of the form (type, value, value).
+func (d *typedDecoder) extCommunities(
+	field string,
+	data [][]string,
+) (api.ExtCommunities, error) {
+	comms := make(api.ExtCommunities, 0, len(data))
+	for i, c := range data {
+		f := fmt.Sprintf("%s[%d]", field, i)
+		if len(c) != 3 {
+			if err := d.warn(f, "malformed ext community %v", c); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		val1, err1 := strconv.Atoi(c[1])
+		val2, err2 := strconv.Atoi(c[2])
+		if err1 != nil || err2 != nil {
+			if err := d.warn(f, "malformed ext community %v", c); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		comms = append(comms, api.ExtCommunity{c[0], val1, val2})
+	}
+	return comms, nil
+}
+
+// communities converts the communities
+func communities(data [][]int) api.Communities {
+	comms := make(api.Communities, 0, len(data))
+	for _, c := range data {
+		comms = append(comms, api.Community(c))
+	}
+	return comms
+}
+
+// stringOr returns the fallback for empty strings
+func stringOr(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+// decodeStatusResponse decodes the response of /status
+func decodeStatusResponse(
+	body io.Reader,
+	config Config,
+) (*api.Meta, api.Status, error) {
+	d, err := newTypedDecoder(config)
+	if err != nil {
+		return nil, api.Status{}, err
+	}
+	res := birdStatusResponse{}
+	if err := json.NewDecoder(body).Decode(&res); err != nil {
+		if err := d.unmarshalError("", err); err != nil {
+			return nil, api.Status{}, err
+		}
+	}
+	meta, err := d.meta(&res.birdResponse)
+	if err != nil {
+		return nil, api.Status{}, err
+	}
+	if res.Status == nil {
+		return nil, api.Status{}, fmt.Errorf("status missing in response")
+	}
+	status, err := d.status(res.Status)
+	if err != nil {
+		return nil, api.Status{}, err
+	}
+	d.finish(meta)
+	return meta, status, nil
+}
+
+// decodeNeighborsResponse decodes a protocols response
+// into neighbors. The details of a neighbor are the
+// undecoded protocol. Protocols other than BGP, like
+// the pipes of a multi table source, are skipped.
+func decodeNeighborsResponse(
+	body io.Reader,
+	config Config,
+) (*api.Meta, api.Neighbors, error) {
+	d, err := newTypedDecoder(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	res := birdProtocolsResponse{}
+	if err := json.NewDecoder(body).Decode(&res); err != nil {
+		if err := d.unmarshalError("", err); err != nil {
+			return nil, nil, err
+		}
+	}
+	meta, err := d.meta(&res.birdResponse)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.Protocols == nil {
+		return nil, nil, fmt.Errorf("failed to fetch protocols")
+	}
+
+	ids := make([]string, 0, len(res.Protocols))
+	for id := range res.Protocols {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	neighbors := make(api.Neighbors, 0, len(ids))
+	for _, id := range ids {
+		raw := res.Protocols[id]
+		p := birdProtocol{}
+		if err := json.Unmarshal(raw, &p); err != nil {
+			if err := d.unmarshalError("protocols."+id, err); err != nil {
+				return nil, nil, err
+			}
+		}
+		if p.BirdProtocol != "" && p.BirdProtocol != "BGP" {
+			continue
+		}
+		details := make(map[string]interface{})
+		if err := json.Unmarshal(raw, &details); err != nil {
+			details = nil
+		}
+		neighbor, err := d.neighbor(id, &p, details)
+		if err != nil {
+			return nil, nil, err
+		}
+		neighbors = append(neighbors, neighbor)
+	}
+	sort.Sort(neighbors)
+
+	d.finish(meta)
+	return meta, neighbors, nil
+}
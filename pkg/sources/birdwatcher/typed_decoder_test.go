@@ -0,0 +1,259 @@
+package birdwatcher
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+)
+
+// testDecodeConfig is the config used for decoding
+// the test responses
+var testDecodeConfig = Config{
+	Timezone:        "UTC",
+	ServerTime:      "2006-01-02T15:04:05.999999999Z07:00",
+	ServerTimeShort: "2006-01-02 15:04:05",
+	ServerTimeExt:   "Mon, 02 Jan 2006 15:04:05 -0700",
+}
+
+// APIResponseRoutesInvalid has a route with invalid fields
+const APIResponseRoutesInvalid = `
+{"api":{"Version":"2.0.0","result_from_cache":false,"cache_status":{"orig_ttl":0,"cached_at":{"date":"","timezone_type":"","timezone":""}}},"routes":[
+  {"network":"192.0.2.0/24","gateway":"198.51.100.1","from_protocol":"R1","metric":"high","type":["BGP"],"primary":"yes",
+   "bgp":{"as_path":["65001","AS65002"],"local_pref":"100","med":"n/a","next_hop":"198.51.100.1","communities":[[65001,1]],"ext_communities":[["rt","65000"]]}},
+  {"network":"203.0.113.0/24","gateway":"198.51.100.2","from_protocol":"R2","metric":100,"bgp":{"as_path":[65002],"local_pref":200}}
+]}`
+
+func TestDecodeRoutesResponse(t *testing.T) {
+	bird, _ := parseTestResponse(APIResponseRoutes)
+	expected, err := parseRoutes(bird, testDecodeConfig, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta, routes, err := parseRoutesResponseStream(
+		strings.NewReader(APIResponseRoutes), testDecodeConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Version != "1.7.11" || meta.Warnings != nil {
+		t.Error("unexpected meta:", meta)
+	}
+	if len(routes) != len(expected) {
+		t.Fatal("expected", len(expected), "routes, got:", len(routes))
+	}
+	for i, r := range routes {
+		e := expected[i]
+		if r.Network != e.Network || r.Gateway != e.Gateway ||
+			r.NeighborID != e.NeighborID || r.Metric != e.Metric ||
+			r.Primary != e.Primary {
+			t.Error("unexpected route:", r, "expected:", e)
+		}
+		if !reflect.DeepEqual(r.BGP, e.BGP) {
+			t.Error("unexpected bgp info:", r.BGP, "expected:", e.BGP)
+		}
+	}
+}
+
+func TestDecodeNeighborsResponse(t *testing.T) {
+	bird, _ := parseTestResponse(APIResponseNeighbors)
+	expected, err := parseNeighbors(bird, testDecodeConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta, neighbors, err := decodeNeighborsResponse(
+		strings.NewReader(APIResponseNeighbors), testDecodeConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.ResultFromCache {
+		t.Error("expected result from cache")
+	}
+	if len(neighbors) != len(expected) {
+		t.Fatal("expected", len(expected), "neighbors, got:", len(neighbors))
+	}
+	for i, n := range neighbors {
+		e := expected[i]
+		if n.ID != e.ID || n.Address != e.Address || n.ASN != e.ASN ||
+			n.State != e.State || n.Description != e.Description ||
+			n.RoutesReceived != e.RoutesReceived ||
+			n.RoutesFiltered != e.RoutesFiltered ||
+			n.RoutesExported != e.RoutesExported {
+			t.Error("unexpected neighbor:", n, "expected:", e)
+		}
+		if n.Details["neighbor_address"] != e.Details["neighbor_address"] {
+			t.Error("expected details to be the protocol:", n.Details)
+		}
+	}
+}
+
+func TestDecodeStatusResponse(t *testing.T) {
+	body := `{"api":{"Version":"2.0.0","result_from_cache":false,"cache_status":{"cached_at":{"date":""}}},
+	  "status":{"current_server":"2021-01-01 12:00:00","last_reboot":"yesterday","router_id":"192.0.2.1","version":"2.0.7"}}`
+
+	meta, status, err := decodeStatusResponse(
+		strings.NewReader(body), testDecodeConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.RouterID != "192.0.2.1" || status.Version != "2.0.7" {
+		t.Error("unexpected status:", status)
+	}
+	if status.ServerTime.Year() != 2021 {
+		t.Error("unexpected server time:", status.ServerTime)
+	}
+	if len(meta.Warnings) != 1 ||
+		!strings.HasPrefix(meta.Warnings[0], "status.last_reboot:") {
+		t.Error("unexpected warnings:", meta.Warnings)
+	}
+}
+
+func TestDecodeModes(t *testing.T) {
+	meta, routes, err := parseRoutesResponseStream(
+		strings.NewReader(APIResponseRoutesInvalid), testDecodeConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 2 {
+		t.Fatal("expected 2 routes, got:", len(routes))
+	}
+
+	// Invalid fields are replaced with the fallback
+	r := routes[0]
+	if r.Metric != -1 || r.BGP.LocalPref != 100 || r.BGP.Med != 0 {
+		t.Error("unexpected route:", r, r.BGP)
+	}
+	if !reflect.DeepEqual(r.BGP.AsPath, []int{65001}) {
+		t.Error("unexpected as path:", r.BGP.AsPath)
+	}
+	if len(r.BGP.ExtCommunities) != 0 {
+		t.Error("unexpected ext communities:", r.BGP.ExtCommunities)
+	}
+	if routes[1].Metric != 100 || routes[1].BGP.LocalPref != 200 {
+		t.Error("unexpected route:", routes[1], routes[1].BGP)
+	}
+
+	expected := []string{
+		"routes[0].primary",
+		"routes[0].metric",
+		"routes[0].bgp.as_path[1]",
+		"routes[0].bgp.med",
+		"routes[0].bgp.ext_communities[0]",
+	}
+	if len(meta.Warnings) != len(expected) {
+		t.Fatal("unexpected warnings:", meta.Warnings)
+	}
+	for i, w := range meta.Warnings {
+		if !strings.HasPrefix(w, expected[i]+":") {
+			t.Error("expected warning for", expected[i], "got:", w)
+		}
+	}
+
+	// Strict mode fails with the first invalid field
+	config := testDecodeConfig
+	config.DecodeMode = DecodeModeStrict
+	_, _, err = parseRoutesResponseStream(
+		strings.NewReader(APIResponseRoutesInvalid), config)
+	if !errors.Is(err, ErrInvalidField) {
+		t.Error("expected invalid field error, got:", err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "routes[0].primary") {
+		t.Error("unexpected error:", err)
+	}
+}
+
+func TestDecodeWarningsLimit(t *testing.T) {
+	d, err := newTypedDecoder(testDecodeConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxDecodeWarnings+5; i++ {
+		d.warn("field", "invalid")
+	}
+	meta := &api.Meta{}
+	d.finish(meta)
+	if len(meta.Warnings) != maxDecodeWarnings+1 {
+		t.Error("unexpected number of warnings:", len(meta.Warnings))
+	}
+	if meta.Warnings[maxDecodeWarnings] != "5 more warnings" {
+		t.Error("unexpected last warning:", meta.Warnings[maxDecodeWarnings])
+	}
+}
+
+// benchmarkRoutesResponse repeats the routes of the
+// test response n times.
+func benchmarkRoutesResponse(b *testing.B, n int) string {
+	bird, err := parseTestResponse(APIResponseRoutes)
+	if err != nil {
+		b.Fatal(err)
+	}
+	route, err := json.Marshal(bird["routes"].([]interface{})[0])
+	if err != nil {
+		b.Fatal(err)
+	}
+	routes := make([]string, n)
+	for i := range routes {
+		routes[i] = string(route)
+	}
+	return `{"api":{"Version":"1.7.11","result_from_cache":false,` +
+		`"cache_status":{"cached_at":{"date":""}}},` +
+		`"routes":[` + strings.Join(routes, ",") + `]}`
+}
+
+func BenchmarkParseRoutes(b *testing.B) {
+	body := benchmarkRoutesResponse(b, 1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bird, err := parseTestResponse(body)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := parseAPIStatus(bird, testDecodeConfig); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := parseRoutes(bird, testDecodeConfig, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeRoutes(b *testing.B) {
+	body := benchmarkRoutesResponse(b, 1000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, err := parseRoutesResponseStream(
+			strings.NewReader(body), testDecodeConfig)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseNeighbors(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		bird, err := parseTestResponse(APIResponseNeighbors)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := parseAPIStatus(bird, testDecodeConfig); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := parseNeighbors(bird, testDecodeConfig); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeNeighbors(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_, _, err := decodeNeighborsResponse(
+			strings.NewReader(APIResponseNeighbors), testDecodeConfig)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
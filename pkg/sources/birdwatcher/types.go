@@ -0,0 +1,124 @@
+package birdwatcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// Typed birdwatcher responses
+
+// A flexInt is a number, which birdwatcher encodes
+// either as a number or as a string. Invalid values
+// are kept for the decode warnings.
+type flexInt struct {
+	value   int
+	set     bool
+	invalid json.RawMessage
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// It never fails, so the other fields are decoded.
+func (v *flexInt) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	s := string(bytes.Trim(data, `"`))
+	if n, err := strconv.Atoi(s); err == nil {
+		v.value, v.set = n, true
+		return nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		v.value, v.set = int(f), true
+		return nil
+	}
+	v.invalid = append(json.RawMessage{}, data...)
+	return nil
+}
+
+// birdAPI is the api info included in every response
+type birdAPI struct {
+	Version         string `json:"Version"`
+	ResultFromCache bool   `json:"result_from_cache"`
+	CacheStatus     struct {
+		CachedAt struct {
+			Date string `json:"date"`
+		} `json:"cached_at"`
+	} `json:"cache_status"`
+}
+
+// birdResponse are the common fields of all responses
+type birdResponse struct {
+	API   *birdAPI `json:"api"`
+	TTL   string   `json:"ttl"`
+	Error string   `json:"error"`
+}
+
+// birdStatus is the status of the bird daemon
+type birdStatus struct {
+	CurrentServer string `json:"current_server"`
+	LastReboot    string `json:"last_reboot"`
+	LastReconfig  string `json:"last_reconfig"`
+	Version       string `json:"version"`
+	Message       string `json:"message"`
+	RouterID      string `json:"router_id"`
+}
+
+// birdStatusResponse is the response of /status
+type birdStatusResponse struct {
+	birdResponse
+	Status *birdStatus `json:"status"`
+}
+
+// birdProtocolRoutes are the route counts of a protocol
+type birdProtocolRoutes struct {
+	Imported  flexInt `json:"imported"`
+	Filtered  flexInt `json:"filtered"`
+	Exported  flexInt `json:"exported"`
+	Preferred flexInt `json:"preferred"`
+}
+
+// birdProtocol is a BGP protocol
+type birdProtocol struct {
+	BirdProtocol    string              `json:"bird_protocol"`
+	NeighborAddress string              `json:"neighbor_address"`
+	NeighborAS      flexInt             `json:"neighbor_as"`
+	State           string              `json:"state"`
+	StateChanged    string              `json:"state_changed"`
+	Description     string              `json:"description"`
+	LastError       string              `json:"last_error"`
+	Routes          *birdProtocolRoutes `json:"routes"`
+}
+
+// birdProtocolsResponse is the response of /protocols/bgp.
+// The protocols are decoded one by one.
+type birdProtocolsResponse struct {
+	birdResponse
+	Protocols map[string]json.RawMessage `json:"protocols"`
+}
+
+// birdBGP are the BGP attributes of a route
+type birdBGP struct {
+	Origin           string     `json:"origin"`
+	NextHop          string     `json:"next_hop"`
+	ASPath           []flexInt  `json:"as_path"`
+	LocalPref        flexInt    `json:"local_pref"`
+	Med              flexInt    `json:"med"`
+	Communities      [][]int    `json:"communities"`
+	LargeCommunities [][]int    `json:"large_communities"`
+	ExtCommunities   [][]string `json:"ext_communities"`
+}
+
+// birdRoute is a route
+type birdRoute struct {
+	Network      string   `json:"network"`
+	Gateway      string   `json:"gateway"`
+	Interface    string   `json:"interface"`
+	FromProtocol string   `json:"from_protocol"`
+	LearntFrom   string   `json:"learnt_from"`
+	Age          string   `json:"age"`
+	Metric       flexInt  `json:"metric"`
+	Primary      bool     `json:"primary"`
+	Type         []string `json:"type"`
+	BGP          *birdBGP `json:"bgp"`
+}
@@ -0,0 +1,345 @@
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+)
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// BreakerConfig configures the circuit breaker of a
+// source. The breaker opens after FailureThreshold
+// consecutive failures. After the OpenTimeout the
+// source is probed.
+type BreakerConfig struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+}
+
+// IsUnavailable checks if the error indicates that the
+// source could not be reached, e.g. because of a timeout
+// or a refused connection.
+func IsUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	if s, ok := status.FromError(err); ok {
+		return s.Code() == codes.Unavailable ||
+			s.Code() == codes.DeadlineExceeded
+	}
+	return false
+}
+
+// StatusError is returned when a source responds
+// with a status other than 2xx.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+// Error implements the error interface
+func (err *StatusError) Error() string {
+	if err.Message != "" {
+		return err.Status + ": " + err.Message
+	}
+	return err.Status
+}
+
+// CheckResponseStatus returns a StatusError if
+// the response status is not 2xx.
+func CheckResponseStatus(res *http.Response) error {
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+	return &StatusError{
+		StatusCode: res.StatusCode,
+		Status:     res.Status,
+	}
+}
+
+// IsFailure checks if the error counts as a failure of
+// the source: The source is unavailable, responded with
+// an error status or the response could not be decoded.
+// Other errors, like an unknown neighbor, do not.
+func IsFailure(err error) bool {
+	if IsUnavailable(err) {
+		return true
+	}
+	var (
+		statusErr *StatusError
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	return errors.As(err, &statusErr) ||
+		errors.As(err, &syntaxErr) ||
+		errors.As(err, &typeErr) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// A Breaker wraps a source and tracks the failures and
+// latency of the calls. While the breaker is open, calls
+// fail with ErrSourceUnavailable without reaching the source.
+type Breaker struct {
+	name   string
+	source Source
+	config BreakerConfig
+
+	state     string
+	failures  int
+	openedAt  time.Time
+	latency   time.Duration
+	lastError error
+	sync.Mutex
+}
+
+// NewBreaker creates a circuit breaker for a source
+func NewBreaker(name string, src Source, config BreakerConfig) *Breaker {
+	return &Breaker{
+		name:   name,
+		source: src,
+		config: config,
+		state:  BreakerClosed,
+	}
+}
+
+// Source returns the wrapped source
+func (b *Breaker) Source() Source {
+	return b.source
+}
+
+// Health returns the state of the breaker
+func (b *Breaker) Health() *api.SourceHealth {
+	b.Lock()
+	defer b.Unlock()
+	health := &api.SourceHealth{
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		Latency:             float64(b.latency) / float64(time.Millisecond),
+	}
+	if b.lastError != nil {
+		health.LastError = b.lastError.Error()
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		health.OpenedAt = &openedAt
+	}
+	return health
+}
+
+// allow checks if a call may pass the breaker. After
+// the open timeout, the source is probed.
+func (b *Breaker) allow(ctx context.Context) error {
+	b.Lock()
+	if b.state == BreakerClosed {
+		b.Unlock()
+		return nil
+	}
+	if b.state == BreakerHalfOpen ||
+		time.Since(b.openedAt) < b.config.OpenTimeout {
+		b.Unlock()
+		return ErrSourceUnavailable
+	}
+	b.state = BreakerHalfOpen
+	b.Unlock()
+
+	start := time.Now()
+	_, err := b.source.Status(ctx)
+	b.done(start, err)
+	b.Lock()
+	defer b.Unlock()
+	if b.state != BreakerClosed {
+		return ErrSourceUnavailable
+	}
+	return nil
+}
+
+// done records the result of a call. The breaker is
+// only closed by a successful call.
+func (b *Breaker) done(start time.Time, err error) {
+	b.Lock()
+	defer b.Unlock()
+	if errors.Is(err, context.Canceled) {
+		// The caller is gone, the source might be fine.
+		if b.state == BreakerHalfOpen {
+			b.state = BreakerOpen
+		}
+		return
+	}
+	if !IsFailure(err) {
+		// The source responded
+		d := time.Since(start)
+		if b.latency == 0 {
+			b.latency = d
+		} else {
+			b.latency = (7*b.latency + d) / 8
+		}
+		if err != nil {
+			// An error like an unknown neighbor neither
+			// counts as failure nor closes the breaker.
+			if b.state == BreakerHalfOpen {
+				b.state = BreakerOpen
+				b.openedAt = time.Now()
+			}
+			return
+		}
+		if b.state != BreakerClosed {
+			log.Println("Circuit breaker of", b.name, "closed")
+		}
+		b.state = BreakerClosed
+		b.failures = 0
+		b.lastError = nil
+		return
+	}
+
+	b.failures++
+	b.lastError = err
+	if b.state == BreakerHalfOpen ||
+		b.failures >= b.config.FailureThreshold {
+		if b.state == BreakerClosed {
+			log.Println(
+				"Circuit breaker of", b.name, "opened after",
+				b.failures, "consecutive failures:", err)
+		}
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// Call the source through the breaker
+func breakerCall[T any](
+	ctx context.Context,
+	b *Breaker,
+	fn func() (T, error),
+) (T, error) {
+	if err := b.allow(ctx); err != nil {
+		var none T
+		return none, err
+	}
+	start := time.Now()
+	res, err := fn()
+	b.done(start, err)
+	return res, err
+}
+
+// Close closes the source, if it can be closed
+func (b *Breaker) Close() error {
+	if closer, ok := b.source.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// ExpireCaches passes through to the source
+func (b *Breaker) ExpireCaches() int {
+	return b.source.ExpireCaches()
+}
+
+// Status implements the Source interface
+func (b *Breaker) Status(
+	ctx context.Context,
+) (*api.StatusResponse, error) {
+	return breakerCall(ctx, b, func() (*api.StatusResponse, error) {
+		return b.source.Status(ctx)
+	})
+}
+
+// Neighbors implements the Source interface
+func (b *Breaker) Neighbors(
+	ctx context.Context,
+) (*api.NeighborsResponse, error) {
+	return breakerCall(ctx, b, func() (*api.NeighborsResponse, error) {
+		return b.source.Neighbors(ctx)
+	})
+}
+
+// NeighborsSummary implements the Source interface
+func (b *Breaker) NeighborsSummary(
+	ctx context.Context,
+) (*api.NeighborsResponse, error) {
+	return breakerCall(ctx, b, func() (*api.NeighborsResponse, error) {
+		return b.source.NeighborsSummary(ctx)
+	})
+}
+
+// NeighborsStatus implements the Source interface
+func (b *Breaker) NeighborsStatus(
+	ctx context.Context,
+) (*api.NeighborsStatusResponse, error) {
+	return breakerCall(ctx, b, func() (*api.NeighborsStatusResponse, error) {
+		return b.source.NeighborsStatus(ctx)
+	})
+}
+
+// Routes implements the Source interface
+func (b *Breaker) Routes(
+	ctx context.Context,
+	neighborID string,
+) (*api.RoutesResponse, error) {
+	return breakerCall(ctx, b, func() (*api.RoutesResponse, error) {
+		return b.source.Routes(ctx, neighborID)
+	})
+}
+
+// RoutesReceived implements the Source interface
+func (b *Breaker) RoutesReceived(
+	ctx context.Context,
+	neighborID string,
+) (*api.RoutesResponse, error) {
+	return breakerCall(ctx, b, func() (*api.RoutesResponse, error) {
+		return b.source.RoutesReceived(ctx, neighborID)
+	})
+}
+
+// RoutesFiltered implements the Source interface
+func (b *Breaker) RoutesFiltered(
+	ctx context.Context,
+	neighborID string,
+) (*api.RoutesResponse, error) {
+	return breakerCall(ctx, b, func() (*api.RoutesResponse, error) {
+		return b.source.RoutesFiltered(ctx, neighborID)
+	})
+}
+
+// RoutesNotExported implements the Source interface
+func (b *Breaker) RoutesNotExported(
+	ctx context.Context,
+	neighborID string,
+) (*api.RoutesResponse, error) {
+	return breakerCall(ctx, b, func() (*api.RoutesResponse, error) {
+		return b.source.RoutesNotExported(ctx, neighborID)
+	})
+}
+
+// AllRoutes implements the Source interface
+func (b *Breaker) AllRoutes(
+	ctx context.Context,
+) (*api.RoutesResponse, error) {
+	return breakerCall(ctx, b, func() (*api.RoutesResponse, error) {
+		return b.source.AllRoutes(ctx)
+	})
+}
@@ -0,0 +1,168 @@
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+)
+
+var errTestUnreachable = &net.OpError{
+	Op:  "dial",
+	Err: errors.New("connection refused"),
+}
+
+// The testSource fails while it is down. Neighbors
+// fails with err, if set.
+type testSource struct {
+	Source
+	down  bool
+	err   error
+	calls int
+}
+
+func (src *testSource) Status(
+	ctx context.Context,
+) (*api.StatusResponse, error) {
+	src.calls++
+	if src.down {
+		return nil, errTestUnreachable
+	}
+	return &api.StatusResponse{}, nil
+}
+
+func (src *testSource) Neighbors(
+	ctx context.Context,
+) (*api.NeighborsResponse, error) {
+	src.calls++
+	if src.down {
+		return nil, errTestUnreachable
+	}
+	if src.err != nil {
+		return nil, src.err
+	}
+	return &api.NeighborsResponse{}, nil
+}
+
+func TestIsUnavailable(t *testing.T) {
+	if !IsUnavailable(errTestUnreachable) {
+		t.Error("expected connection error to be unavailable")
+	}
+	if !IsUnavailable(context.DeadlineExceeded) {
+		t.Error("expected timeout to be unavailable")
+	}
+	if IsUnavailable(errors.New("neighbor not found")) {
+		t.Error("unexpected unavailable")
+	}
+}
+
+func TestIsFailure(t *testing.T) {
+	failures := []error{
+		errTestUnreachable,
+		&StatusError{StatusCode: 502, Status: "502 Bad Gateway"},
+		fmt.Errorf("rib: %w", &StatusError{StatusCode: 404, Status: "404 Not Found"}),
+		json.Unmarshal([]byte("<html>"), &struct{}{}),
+		io.ErrUnexpectedEOF,
+	}
+	for _, err := range failures {
+		if !IsFailure(err) {
+			t.Error("expected failure:", err)
+		}
+	}
+	if IsFailure(errors.New("neighbor not found")) {
+		t.Error("unexpected failure")
+	}
+}
+
+func TestBreakerStatusErrors(t *testing.T) {
+	ctx := context.Background()
+	src := &testSource{
+		err: &StatusError{StatusCode: 503, Status: "503 Service Unavailable"},
+	}
+	b := NewBreaker("rs1", src, BreakerConfig{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+	})
+	for i := 0; i < 2; i++ {
+		if _, err := b.Neighbors(ctx); err == nil {
+			t.Error("expected an error")
+		}
+	}
+	if b.Health().State != BreakerOpen {
+		t.Fatal("expected breaker to be open:", b.Health())
+	}
+
+	// Only a successful call closes the half-open breaker
+	b.state = BreakerHalfOpen
+	b.done(time.Now(), errors.New("neighbor not found"))
+	if health := b.Health(); health.State != BreakerOpen ||
+		health.ConsecutiveFailures != 2 {
+		t.Error("expected breaker to stay open:", health)
+	}
+	b.state = BreakerHalfOpen
+	b.done(time.Now(), nil)
+	if b.Health().State != BreakerClosed {
+		t.Error("expected breaker to be closed:", b.Health())
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	ctx := context.Background()
+	src := &testSource{down: true}
+	b := NewBreaker("rs1", src, BreakerConfig{
+		FailureThreshold: 3,
+		OpenTimeout:      time.Minute,
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, err := b.Neighbors(ctx); !errors.Is(err, errTestUnreachable) {
+			t.Error("expected source error, got:", err)
+		}
+	}
+	if b.Health().State != BreakerOpen {
+		t.Fatal("expected breaker to be open:", b.Health())
+	}
+
+	// Calls are short circuited
+	if _, err := b.Neighbors(ctx); !errors.Is(err, ErrSourceUnavailable) {
+		t.Error("expected source to be unavailable, got:", err)
+	}
+	if src.calls != 3 {
+		t.Error("unexpected calls:", src.calls)
+	}
+
+	// Probe after the timeout: The source is still down
+	b.openedAt = time.Now().Add(-2 * time.Minute)
+	if _, err := b.Neighbors(ctx); !errors.Is(err, ErrSourceUnavailable) {
+		t.Error("expected source to be unavailable, got:", err)
+	}
+	if src.calls != 4 || b.Health().State != BreakerOpen {
+		t.Error("expected a failed probe:", src.calls, b.Health())
+	}
+
+	// The source is back
+	src.down = false
+	b.openedAt = time.Now().Add(-2 * time.Minute)
+	if _, err := b.Neighbors(ctx); err != nil {
+		t.Fatal(err)
+	}
+	health := b.Health()
+	if health.State != BreakerClosed || health.ConsecutiveFailures != 0 {
+		t.Error("expected breaker to be closed:", health)
+	}
+	if src.calls != 6 {
+		t.Error("expected probe and call:", src.calls)
+	}
+
+	// The latency is reported in milliseconds
+	b.latency = 1500 * time.Microsecond
+	if health := b.Health(); health.Latency != 1.5 {
+		t.Error("unexpected latency:", health.Latency)
+	}
+}
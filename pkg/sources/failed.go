@@ -0,0 +1,87 @@
+package sources
+
+import (
+	"context"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+)
+
+// A FailedSource is used in place of a source which
+// could not be created, e.g. because of invalid TLS
+// material. All calls fail with the error.
+type FailedSource struct {
+	err error
+}
+
+// NewFailedSource creates a source failing with the error
+func NewFailedSource(err error) *FailedSource {
+	return &FailedSource{err: err}
+}
+
+// ExpireCaches does nothing
+func (src *FailedSource) ExpireCaches() int {
+	return 0
+}
+
+// Status fails with the error
+func (src *FailedSource) Status(
+	context.Context,
+) (*api.StatusResponse, error) {
+	return nil, src.err
+}
+
+// Neighbors fails with the error
+func (src *FailedSource) Neighbors(
+	context.Context,
+) (*api.NeighborsResponse, error) {
+	return nil, src.err
+}
+
+// NeighborsSummary fails with the error
+func (src *FailedSource) NeighborsSummary(
+	context.Context,
+) (*api.NeighborsResponse, error) {
+	return nil, src.err
+}
+
+// NeighborsStatus fails with the error
+func (src *FailedSource) NeighborsStatus(
+	context.Context,
+) (*api.NeighborsStatusResponse, error) {
+	return nil, src.err
+}
+
+// Routes fails with the error
+func (src *FailedSource) Routes(
+	context.Context, string,
+) (*api.RoutesResponse, error) {
+	return nil, src.err
+}
+
+// RoutesReceived fails with the error
+func (src *FailedSource) RoutesReceived(
+	context.Context, string,
+) (*api.RoutesResponse, error) {
+	return nil, src.err
+}
+
+// RoutesFiltered fails with the error
+func (src *FailedSource) RoutesFiltered(
+	context.Context, string,
+) (*api.RoutesResponse, error) {
+	return nil, src.err
+}
+
+// RoutesNotExported fails with the error
+func (src *FailedSource) RoutesNotExported(
+	context.Context, string,
+) (*api.RoutesResponse, error) {
+	return nil, src.err
+}
+
+// AllRoutes fails with the error
+func (src *FailedSource) AllRoutes(
+	context.Context,
+) (*api.RoutesResponse, error) {
+	return nil, src.err
+}
@@ -0,0 +1,85 @@
+package gobgp
+
+import (
+	"encoding/binary"
+
+	"github.com/osrg/gobgp/pkg/packet/bgp"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+)
+
+// The Only-To-Customer attribute (RFC 9234) is not
+// known to the BGP packet library and is decoded from
+// the unknown attribute.
+const attrTypeOnlyToCustomer bgp.BGPAttrType = 35
+
+// extCommunityType maps the sub type of an extended
+// community to the names used by the other sources.
+func extCommunityType(subType bgp.ExtendedCommunityAttrSubType) string {
+	switch subType {
+	case bgp.EC_SUBTYPE_ROUTE_TARGET:
+		return "rt"
+	case bgp.EC_SUBTYPE_ROUTE_ORIGIN:
+		return "ro"
+	}
+	return ""
+}
+
+// decodeGenericExtCommunity decodes an extended
+// community into the high and low 32 bits.
+func decodeGenericExtCommunity(
+	community bgp.ExtendedCommunityInterface,
+) (api.ExtCommunity, bool) {
+	buf, err := community.Serialize()
+	if err != nil || len(buf) != 8 {
+		return nil, false
+	}
+	return api.ExtCommunity{
+		"generic",
+		int(binary.BigEndian.Uint32(buf[0:4])),
+		int(binary.BigEndian.Uint32(buf[4:8])),
+	}, true
+}
+
+// decodeExtCommunity decodes an extended community.
+// Route targets and route origins are decoded as
+// (rt|ro, administrator, value), all other types as
+// (generic, high, low).
+func decodeExtCommunity(
+	community bgp.ExtendedCommunityInterface,
+) (api.ExtCommunity, bool) {
+	switch c := community.(type) {
+	case *bgp.TwoOctetAsSpecificExtended:
+		if t := extCommunityType(c.SubType); t != "" {
+			return api.ExtCommunity{t, int(c.AS), int(c.LocalAdmin)}, true
+		}
+	case *bgp.FourOctetAsSpecificExtended:
+		if t := extCommunityType(c.SubType); t != "" {
+			return api.ExtCommunity{t, int(c.AS), int(c.LocalAdmin)}, true
+		}
+	case *bgp.IPv4AddressSpecificExtended:
+		if t := extCommunityType(c.SubType); t != "" {
+			return api.ExtCommunity{t, c.IPv4.String(), int(c.LocalAdmin)}, true
+		}
+	}
+	return decodeGenericExtCommunity(community)
+}
+
+// decodeAigp returns the accumulated IGP metric
+func decodeAigp(attr *bgp.PathAttributeAigp) uint64 {
+	for _, tlv := range attr.Values {
+		if m, ok := tlv.(*bgp.AigpTLVIgpMetric); ok {
+			return m.Metric
+		}
+	}
+	return 0
+}
+
+// decodeOnlyToCustomer returns the ASN of the
+// Only-To-Customer attribute.
+func decodeOnlyToCustomer(attr *bgp.PathAttributeUnknown) (int, bool) {
+	if attr.Type != attrTypeOnlyToCustomer || len(attr.Value) != 4 {
+		return 0, false
+	}
+	return int(binary.BigEndian.Uint32(attr.Value)), true
+}
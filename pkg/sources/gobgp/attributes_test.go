@@ -0,0 +1,95 @@
+package gobgp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/osrg/gobgp/pkg/packet/bgp"
+
+	"github.com/alice-lg/alice-lg/pkg/sources/gobgp/apiutil"
+)
+
+func TestDecodeExtCommunity(t *testing.T) {
+	tests := []struct {
+		community bgp.ExtendedCommunityInterface
+		expect    string
+	}{
+		{bgp.NewTwoOctetAsSpecificExtended(
+			bgp.EC_SUBTYPE_ROUTE_TARGET, 65000, 100, true), "rt:65000:100"},
+		{bgp.NewTwoOctetAsSpecificExtended(
+			bgp.EC_SUBTYPE_ROUTE_ORIGIN, 65000, 100, true), "ro:65000:100"},
+		{bgp.NewFourOctetAsSpecificExtended(
+			bgp.EC_SUBTYPE_ROUTE_TARGET, 4200000000, 23, true), "rt:4200000000:23"},
+		{bgp.NewIPv4AddressSpecificExtended(
+			bgp.EC_SUBTYPE_ROUTE_ORIGIN, "192.0.2.1", 42, true), "ro:192.0.2.1:42"},
+		{bgp.NewOpaqueExtended(
+			true, []byte{0x0c, 0, 0, 0, 0, 0, 0x08}), "generic:51118080:8"},
+	}
+	for _, test := range tests {
+		com, ok := decodeExtCommunity(test.community)
+		if !ok {
+			t.Error("could not decode:", test.community)
+			continue
+		}
+		if com.String() != test.expect {
+			t.Error("expected", test.expect, "got:", com.String())
+		}
+	}
+}
+
+func TestParsePathAttributes(t *testing.T) {
+	peer := testPeer("2001:db8::1", 64500, true)
+	nlri := bgp.NewIPv6AddrPrefix(32, "2001:db8::")
+	mpReach := bgp.NewPathAttributeMpReachNLRI(
+		"2001:db8::1", []bgp.AddrPrefixInterface{nlri})
+	mpReach.LinkLocalNexthop = net.ParseIP("fe80::1")
+	otc := bgp.NewPathAttributeUnknown(
+		bgp.BGP_ATTR_FLAG_OPTIONAL|bgp.BGP_ATTR_FLAG_TRANSITIVE,
+		attrTypeOnlyToCustomer, []byte{0, 0, 0xfb, 0xf4})
+
+	attrs := []bgp.PathAttributeInterface{
+		bgp.NewPathAttributeOrigin(bgp.BGP_ORIGIN_ATTR_TYPE_IGP),
+		bgp.NewPathAttributeAsPath([]bgp.AsPathParamInterface{
+			bgp.NewAs4PathParam(bgp.BGP_ASPATH_ATTR_TYPE_SEQ, []uint32{64500, 64501}),
+			bgp.NewAs4PathParam(bgp.BGP_ASPATH_ATTR_TYPE_SET, []uint32{64510, 64511}),
+		}),
+		mpReach,
+		bgp.NewPathAttributeExtendedCommunities([]bgp.ExtendedCommunityInterface{
+			bgp.NewFourOctetAsSpecificExtended(
+				bgp.EC_SUBTYPE_ROUTE_TARGET, 4200000000, 23, true),
+		}),
+		bgp.NewPathAttributeAigp([]bgp.AigpTLVInterface{
+			bgp.NewAigpTLVIgpMetric(1000),
+		}),
+		otc,
+	}
+	path := apiutil.NewPath(nlri, false, attrs, time.Now())
+	path.NeighborIp = peer.State.NeighborAddress
+	path.SourceAsn = peer.State.PeerAs
+
+	gobgp := &GoBGP{}
+	route, err := gobgp.parsePathIntoRoute(path, nlri.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *route.Gateway != "2001:db8::1" || *route.BGP.NextHop != "2001:db8::1" {
+		t.Error("unexpected next hop:", *route.Gateway)
+	}
+	if route.BGP.NextHopLinkLocal == nil || *route.BGP.NextHopLinkLocal != "fe80::1" {
+		t.Error("unexpected link local next hop:", route.BGP.NextHopLinkLocal)
+	}
+	if len(route.BGP.AsPath) != 2 || len(route.BGP.AsSet) != 2 {
+		t.Error("unexpected as path:", route.BGP.AsPath, route.BGP.AsSet)
+	}
+	if len(route.BGP.ExtCommunities) != 1 ||
+		route.BGP.ExtCommunities[0].String() != "rt:4200000000:23" {
+		t.Error("unexpected ext communities:", route.BGP.ExtCommunities)
+	}
+	if route.BGP.Aigp != 1000 {
+		t.Error("unexpected aigp:", route.BGP.Aigp)
+	}
+	if route.BGP.OnlyToCustomer != 64500 {
+		t.Error("unexpected otc:", route.BGP.OnlyToCustomer)
+	}
+}
@@ -1,5 +1,13 @@
 package gobgp
 
+// Table types: The received routes are either read from
+// the adj-in table of the peer or from the local RIB
+// (the global table or the table of the VRF).
+const (
+	TableTypeAdjIn = "adj_in"
+	TableTypeLocal = "local"
+)
+
 // Config is a GoBGP source config
 type Config struct {
 	ID   string
@@ -11,4 +19,22 @@ type Config struct {
 	ProcessingTimeout int    `ini:"processing_timeout"`
 	TLSCert           string `ini:"tls_crt"`
 	TLSCommonName     string `ini:"tls_common_name"`
+
+	// The client certificate and key for mutual TLS
+	TLSClientCert string `ini:"tls_client_crt"`
+	TLSClientKey  string `ini:"tls_client_key"`
+
+	// AuthToken is sent as bearer token with each call
+	AuthToken string `ini:"auth_token"`
+
+	// Streaming subscribes to the peer and table monitor
+	// streams instead of listing the tables on each request.
+	Streaming bool `ini:"streaming"`
+
+	// The neighbors and routes are limited to the VRF.
+	// Each of the comma separated VRFs is exposed as
+	// a route server.
+	VRF       string `ini:"vrf"`
+	VRFs      string `ini:"vrfs"`
+	TableType string `ini:"table_type"`
 }
@@ -0,0 +1,182 @@
+package gobgp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/alice-lg/alice-lg/pkg/sources"
+	gobgpapi "github.com/osrg/gobgp/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Connection errors
+var (
+	// ErrInvalidTLSConfig is returned when the TLS
+	// material of a source can not be loaded.
+	ErrInvalidTLSConfig = errors.New("invalid tls config")
+
+	// ErrNotConnected is returned when the connection
+	// to the GoBGP daemon is not established.
+	ErrNotConnected = errors.New("not connected")
+)
+
+// Reconnect backoff of the connection
+var connectBackoff = backoff.Config{
+	BaseDelay:  1 * time.Second,
+	Multiplier: 1.6,
+	Jitter:     0.2,
+	MaxDelay:   60 * time.Second,
+}
+
+// tokenCredentials adds a bearer token to each call
+type tokenCredentials struct {
+	token  string
+	secure bool
+}
+
+// GetRequestMetadata implements the PerRPCCredentials interface
+func (c *tokenCredentials) GetRequestMetadata(
+	context.Context, ...string,
+) (map[string]string, error) {
+	return map[string]string{
+		"authorization": "Bearer " + c.token,
+	}, nil
+}
+
+// RequireTransportSecurity implements the PerRPCCredentials
+// interface. The token is only sent over an insecure
+// connection if the source is configured as insecure.
+func (c *tokenCredentials) RequireTransportSecurity() bool {
+	return c.secure
+}
+
+// tlsConfig creates the TLS config of the connection.
+// The server certificate is verified with the CA certificate
+// (tls_crt) or the system roots. With a client certificate
+// and key, the client authenticates to the server (mTLS).
+func tlsConfig(config Config) (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName: config.TLSCommonName,
+	}
+	if config.TLSCert != "" {
+		pem, err := os.ReadFile(config.TLSCert)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTLSConfig, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf(
+				"%w: no certificates in %s", ErrInvalidTLSConfig, config.TLSCert)
+		}
+		cfg.RootCAs = pool
+	}
+	if config.TLSClientCert != "" || config.TLSClientKey != "" {
+		if config.TLSClientCert == "" || config.TLSClientKey == "" {
+			return nil, fmt.Errorf(
+				"%w: tls_client_crt and tls_client_key are required for mTLS",
+				ErrInvalidTLSConfig)
+		}
+		cert, err := tls.LoadX509KeyPair(
+			config.TLSClientCert, config.TLSClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTLSConfig, err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// dialOptions creates the options for the connection
+func dialOptions(config Config) ([]grpc.DialOption, error) {
+	opts := []grpc.DialOption{
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff: connectBackoff,
+		}),
+	}
+	if config.Insecure {
+		opts = append(opts,
+			grpc.WithTransportCredentials(insecure.NewCredentials()))
+	} else {
+		cfg, err := tlsConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts,
+			grpc.WithTransportCredentials(credentials.NewTLS(cfg)))
+	}
+	if config.AuthToken != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(&tokenCredentials{
+			token:  config.AuthToken,
+			secure: !config.Insecure,
+		}))
+	}
+	return opts, nil
+}
+
+// getClient returns the client. The connection is
+// established with the first call and reconnects
+// with a backoff when it is lost.
+func (gobgp *GoBGP) getClient() (gobgpapi.GobgpApiClient, error) {
+	gobgp.connLock.Lock()
+	defer gobgp.connLock.Unlock()
+	if gobgp.closed {
+		return nil, sources.ErrSourceClosed
+	}
+	if gobgp.client != nil {
+		return gobgp.client, nil
+	}
+	conn, err := grpc.Dial(gobgp.config.Host, gobgp.dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotConnected, err)
+	}
+	go gobgp.logConnState(conn)
+	gobgp.conn = conn
+	gobgp.client = gobgpapi.NewGobgpApiClient(conn)
+	return gobgp.client, nil
+}
+
+// connState returns the state of the connection
+func (gobgp *GoBGP) connState() (connectivity.State, bool) {
+	gobgp.connLock.Lock()
+	defer gobgp.connLock.Unlock()
+	if gobgp.conn == nil {
+		return connectivity.Idle, false
+	}
+	return gobgp.conn.GetState(), true
+}
+
+// logConnState logs when the connection is
+// established or fails.
+func (gobgp *GoBGP) logConnState(conn *grpc.ClientConn) {
+	state := conn.GetState()
+	for conn.WaitForStateChange(context.Background(), state) {
+		state = conn.GetState()
+		switch state {
+		case connectivity.Ready, connectivity.TransientFailure:
+			log.Println(
+				"gobgp:", gobgp.config.Name, "connection to",
+				gobgp.config.Host, "is", state)
+		case connectivity.Shutdown:
+			return
+		}
+	}
+}
+
+// CheckTLS loads the TLS material of the config
+func CheckTLS(config Config) error {
+	if config.Insecure {
+		return nil
+	}
+	_, err := tlsConfig(config)
+	return err
+}
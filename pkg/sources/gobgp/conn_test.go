@@ -0,0 +1,227 @@
+package gobgp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gobgpapi "github.com/osrg/gobgp/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/alice-lg/alice-lg/pkg/sources"
+)
+
+// writeTestCert creates a self signed certificate for
+// localhost, usable for the server and the client.
+func writeTestCert(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+		KeyUsage: x509.KeyUsageDigitalSignature |
+			x509.KeyUsageCertSign,
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageServerAuth,
+			x509.ExtKeyUsageClientAuth,
+		},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	err = os.WriteFile(certFile, pem.EncodeToMemory(
+		&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(
+		&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// startTCPServer starts the fake daemon on a tcp port
+func startTCPServer(t *testing.T, opts ...grpc.ServerOption) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer(opts...)
+	gobgpapi.RegisterGobgpApiServer(s, &testServer{})
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+func TestTLSConfig(t *testing.T) {
+	certFile, keyFile := writeTestCert(t)
+
+	cfg, err := tlsConfig(Config{
+		TLSCert:       certFile,
+		TLSCommonName: "localhost",
+		TLSClientCert: certFile,
+		TLSClientKey:  keyFile,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.RootCAs == nil || len(cfg.Certificates) != 1 {
+		t.Error("unexpected tls config:", cfg)
+	}
+
+	invalid := []Config{
+		{TLSCert: "/does/not/exist"},
+		{TLSCert: keyFile},
+		{TLSClientCert: certFile},
+		{TLSClientCert: certFile, TLSClientKey: certFile},
+	}
+	for _, c := range invalid {
+		if _, err := NewGoBGP(c); !errors.Is(err, ErrInvalidTLSConfig) {
+			t.Error("expected tls error for", c, "got:", err)
+		}
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	certFile, keyFile := writeTestCert(t)
+	serverCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(serverCert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	host := startTCPServer(t, grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	})))
+
+	config := Config{
+		Name:              "rs1",
+		Host:              host,
+		ProcessingTimeout: 5,
+		TLSCert:           certFile,
+		TLSCommonName:     "localhost",
+		TLSClientCert:     certFile,
+		TLSClientKey:      keyFile,
+	}
+	gobgp, err := NewGoBGP(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := gobgp.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Status.RouterID != "192.0.2.254" {
+		t.Error("unexpected status:", status.Status)
+	}
+
+	// Without the client certificate the handshake fails
+	config.TLSClientCert = ""
+	config.TLSClientKey = ""
+	config.ProcessingTimeout = 1
+	gobgp, err = NewGoBGP(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gobgp.Status(context.Background()); err == nil {
+		t.Error("expected status to fail without client cert")
+	}
+}
+
+func TestAuthToken(t *testing.T) {
+	var auth []string
+	host := startTCPServer(t, grpc.UnaryInterceptor(func(
+		ctx context.Context,
+		req interface{},
+		_ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		auth = md.Get("authorization")
+		return handler(ctx, req)
+	}))
+
+	gobgp, err := NewGoBGP(Config{
+		Name:              "rs1",
+		Host:              host,
+		Insecure:          true,
+		ProcessingTimeout: 5,
+		AuthToken:         "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gobgp.Status(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(auth) != 1 || auth[0] != "Bearer secret" {
+		t.Error("unexpected authorization:", auth)
+	}
+}
+
+func TestLazyConnect(t *testing.T) {
+	// Nothing listens on the port
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := lis.Addr().String()
+	lis.Close()
+
+	gobgp, err := NewGoBGP(Config{
+		Name:              "rs1",
+		Host:              host,
+		Insecure:          true,
+		ProcessingTimeout: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, dialed := gobgp.connState(); dialed {
+		t.Error("expected connection to be established on first use")
+	}
+	_, err = gobgp.Status(context.Background())
+	if !errors.Is(err, ErrNotConnected) {
+		t.Error("expected not connected error, got:", err)
+	}
+	if !sources.IsUnavailable(err) {
+		t.Error("expected error to indicate unavailability:", err)
+	}
+}
@@ -2,6 +2,7 @@ package gobgp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -52,6 +53,24 @@ func (gobgp *GoBGP) lookupNeighbor(
 	return nil, fmt.Errorf("could not lookup neighbor")
 }
 
+// peerInVRF checks if the peer belongs to the
+// VRF of the source. Without VRF, all peers are used.
+func (gobgp *GoBGP) peerInVRF(peer *gobgpapi.Peer) bool {
+	if gobgp.config.VRF == "" {
+		return true
+	}
+	return peer.Conf != nil && peer.Conf.Vrf == gobgp.config.VRF
+}
+
+// localTable returns the table type and name of the
+// local RIB: The table of the VRF or the global table.
+func (gobgp *GoBGP) localTable() (gobgpapi.TableType, string) {
+	if gobgp.config.VRF != "" {
+		return gobgpapi.TableType_VRF, gobgp.config.VRF
+	}
+	return gobgpapi.TableType_GLOBAL, ""
+}
+
 // GetNeighbors retrievs all neighbors and returns
 // a list of peers.
 func (gobgp *GoBGP) GetNeighbors(
@@ -61,7 +80,11 @@ func (gobgp *GoBGP) GetNeighbors(
 		ctx, time.Second*time.Duration(gobgp.config.ProcessingTimeout))
 	defer cancel()
 
-	peerStream, err := gobgp.client.ListPeer(
+	client, err := gobgp.getClient()
+	if err != nil {
+		return nil, err
+	}
+	peerStream, err := client.ListPeer(
 		ctx, &gobgpapi.ListPeerRequest{EnableAdvertised: true})
 	if err != nil {
 		return nil, err
@@ -73,6 +96,11 @@ func (gobgp *GoBGP) GetNeighbors(
 		peer, err := peerStream.Recv()
 		if err == io.EOF {
 			break
+		} else if err != nil {
+			return nil, err
+		}
+		if !gobgp.peerInVRF(peer.Peer) {
+			continue
 		}
 		peers = append(peers, peer.Peer)
 	}
@@ -110,6 +138,20 @@ func (gobgp *GoBGP) parsePathIntoRoute(
 		case *bgp.PathAttributeNextHop:
 			route.Gateway = pools.Gateways4.Acquire(attr.Value.String())
 			route.BGP.NextHop = pools.Gateways4.Acquire(attr.Value.String())
+		case *bgp.PathAttributeMpReachNLRI:
+			if attr.Nexthop == nil || attr.Nexthop.IsUnspecified() {
+				continue
+			}
+			gateways := pools.Gateways4
+			if attr.Nexthop.To4() == nil {
+				gateways = pools.Gateways6
+			}
+			route.Gateway = gateways.Acquire(attr.Nexthop.String())
+			route.BGP.NextHop = gateways.Acquire(attr.Nexthop.String())
+			if attr.LinkLocalNexthop != nil && !attr.LinkLocalNexthop.IsUnspecified() {
+				route.BGP.NextHopLinkLocal = pools.Gateways6.Acquire(
+					attr.LinkLocalNexthop.String())
+			}
 		case *bgp.PathAttributeLocalPref:
 			route.BGP.LocalPref = int(attr.Value)
 		case *bgp.PathAttributeOrigin:
@@ -124,6 +166,10 @@ func (gobgp *GoBGP) parsePathIntoRoute(
 		case *bgp.PathAttributeAsPath:
 			for _, aspth := range attr.Value {
 				for _, as := range aspth.GetAS() {
+					if aspth.GetType() == bgp.BGP_ASPATH_ATTR_TYPE_SET {
+						route.BGP.AsSet = append(route.BGP.AsSet, int(as))
+						continue
+					}
 					route.BGP.AsPath = append(route.BGP.AsPath, int(as))
 				}
 			}
@@ -137,14 +183,17 @@ func (gobgp *GoBGP) parsePathIntoRoute(
 			}
 		case *bgp.PathAttributeExtendedCommunities:
 			for _, community := range attr.Value {
-				if apiComm, ok := community.(*bgp.TwoOctetAsSpecificExtended); ok {
+				if apiComm, ok := decodeExtCommunity(community); ok {
 					route.BGP.ExtCommunities = append(
-						route.BGP.ExtCommunities,
-						api.ExtCommunity{
-							apiComm.AS,
-							apiComm.LocalAdmin})
+						route.BGP.ExtCommunities, apiComm)
 				}
 			}
+		case *bgp.PathAttributeAigp:
+			route.BGP.Aigp = decodeAigp(attr)
+		case *bgp.PathAttributeUnknown:
+			if asn, ok := decodeOnlyToCustomer(attr); ok {
+				route.BGP.OnlyToCustomer = asn
+			}
 		case *bgp.PathAttributeLargeCommunities:
 			for _, community := range attr.Values {
 				route.BGP.LargeCommunities = append(
@@ -158,6 +207,9 @@ func (gobgp *GoBGP) parsePathIntoRoute(
 	}
 
 	route.BGP.AsPath = pools.ASPaths.Acquire(route.BGP.AsPath)
+	if route.BGP.AsSet != nil {
+		route.BGP.AsSet = pools.ASPaths.Acquire(route.BGP.AsSet)
+	}
 	route.BGP.Communities = pools.CommunitiesSets.Acquire(route.BGP.Communities)
 	route.BGP.ExtCommunities = pools.ExtCommunitiesSets.Acquire(route.BGP.ExtCommunities)
 	route.BGP.LargeCommunities = pools.LargeCommunitiesSets.Acquire(route.BGP.LargeCommunities)
@@ -179,34 +231,28 @@ func (gobgp *GoBGP) GetRoutes(
 		ctx, time.Second*time.Duration(gobgp.config.ProcessingTimeout))
 	defer cancel()
 
-	for _, family := range families {
-
-		pathStream, err := gobgp.client.ListPath(ctx, &gobgpapi.ListPathRequest{
-			Name:           peer.State.NeighborAddress,
-			TableType:      tableType,
-			Family:         &family,
-			EnableFiltered: true,
-		})
+	name := peer.State.NeighborAddress
+	local := tableType == gobgpapi.TableType_ADJ_IN &&
+		gobgp.config.TableType == TableTypeLocal
+	if local {
+		tableType, name = gobgp.localTable()
+	}
 
+	for _, family := range families {
+		rib, err := gobgp.listDestinations(ctx, name, tableType, family)
 		if err != nil {
-			log.Print(err)
-			continue
-		}
-
-		rib := make([]*gobgpapi.Destination, 0)
-		for {
-			_path, err := pathStream.Recv()
-			if err == io.EOF {
-				break
-			} else if err != nil {
+			if errors.Is(err, errListPathFailed) {
 				log.Print(err)
-				return err
+				continue
 			}
-			rib = append(rib, _path.Destination)
+			return err
 		}
 
 		for _, destination := range rib {
 			for _, path := range destination.Paths {
+				if local && path.NeighborIp != peer.State.NeighborAddress {
+					continue // The path was received from another peer
+				}
 				route, err := gobgp.parsePathIntoRoute(path, destination.Prefix)
 				if err != nil {
 					log.Println(err)
@@ -224,3 +270,86 @@ func (gobgp *GoBGP) GetRoutes(
 
 	return nil
 }
+
+// GetTableRoutes retrieves the routes of all peers
+// from the local RIB.
+func (gobgp *GoBGP) GetTableRoutes(
+	ctx context.Context,
+	response *api.RoutesResponse,
+) error {
+	ctx, cancel := context.WithTimeout(
+		ctx, time.Second*time.Duration(gobgp.config.ProcessingTimeout))
+	defer cancel()
+
+	peers, err := gobgp.GetNeighbors(ctx)
+	if err != nil {
+		return err
+	}
+	addrs := make(map[string]bool, len(peers))
+	for _, peer := range peers {
+		addrs[peer.State.NeighborAddress] = true
+	}
+
+	tableType, name := gobgp.localTable()
+	for _, family := range families {
+		rib, err := gobgp.listDestinations(ctx, name, tableType, family)
+		if err != nil {
+			return err
+		}
+		for _, destination := range rib {
+			for _, path := range destination.Paths {
+				if !addrs[path.NeighborIp] {
+					continue
+				}
+				route, err := gobgp.parsePathIntoRoute(path, destination.Prefix)
+				if err != nil {
+					log.Println(err)
+					continue
+				}
+				response.Imported = append(response.Imported, route)
+			}
+		}
+	}
+	return nil
+}
+
+// errListPathFailed is returned when the request
+// for listing the paths could not be made.
+var errListPathFailed = errors.New("list path failed")
+
+// listDestinations retrieves the destinations of a
+// family from a table. The name is the address of the
+// peer or the name of the VRF.
+func (gobgp *GoBGP) listDestinations(
+	ctx context.Context,
+	name string,
+	tableType gobgpapi.TableType,
+	family gobgpapi.Family,
+) ([]*gobgpapi.Destination, error) {
+	client, err := gobgp.getClient()
+	if err != nil {
+		return nil, err
+	}
+	pathStream, err := client.ListPath(ctx, &gobgpapi.ListPathRequest{
+		Name:           name,
+		TableType:      tableType,
+		Family:         &family,
+		EnableFiltered: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errListPathFailed, err)
+	}
+
+	rib := make([]*gobgpapi.Destination, 0)
+	for {
+		_path, err := pathStream.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			log.Print(err)
+			return nil, err
+		}
+		rib = append(rib, _path.Destination)
+	}
+	return rib, nil
+}
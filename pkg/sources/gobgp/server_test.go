@@ -0,0 +1,261 @@
+package gobgp
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	gobgpapi "github.com/osrg/gobgp/api"
+	"github.com/osrg/gobgp/pkg/packet/bgp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/alice-lg/alice-lg/pkg/sources/gobgp/apiutil"
+)
+
+// testServer is a fake in-process GoBGP daemon. The
+// peers and paths are listed and updates are sent
+// through the monitor streams.
+type testServer struct {
+	gobgpapi.GobgpApiServer // Not implemented methods panic
+
+	peers []*gobgpapi.Peer
+	paths []*gobgpapi.Path
+
+	// The local RIBs by VRF name. The global
+	// table has no name.
+	tables map[string][]*gobgpapi.Path
+
+	peerUpdates chan *gobgpapi.Peer
+	pathUpdates chan *gobgpapi.Path
+	drop        chan struct{}
+
+	listPeerCalls int
+	sync.Mutex
+}
+
+// startTestServer starts the fake daemon and
+// returns a client connected to it.
+func startTestServer(t *testing.T) (*testServer, gobgpapi.GobgpApiClient) {
+	srv := &testServer{
+		peerUpdates: make(chan *gobgpapi.Peer),
+		pathUpdates: make(chan *gobgpapi.Path),
+		drop:        make(chan struct{}),
+	}
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	gobgpapi.RegisterGobgpApiServer(s, srv)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.DialContext(
+		context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return srv, gobgpapi.NewGobgpApiClient(conn)
+}
+
+// testPeer creates a peer
+func testPeer(addr string, asn uint32, up bool) *gobgpapi.Peer {
+	state := gobgpapi.PeerState_IDLE
+	if up {
+		state = gobgpapi.PeerState_ESTABLISHED
+	}
+	return &gobgpapi.Peer{
+		Conf: &gobgpapi.PeerConf{
+			Description: "peer " + addr,
+		},
+		State: &gobgpapi.PeerState{
+			NeighborAddress: addr,
+			PeerAs:          asn,
+			SessionState:    state,
+		},
+	}
+}
+
+// testPath creates an IPv4 path received from a peer
+func testPath(
+	peer *gobgpapi.Peer,
+	prefix string,
+	length uint8,
+	filtered bool,
+) *gobgpapi.Path {
+	attrs := []bgp.PathAttributeInterface{
+		bgp.NewPathAttributeOrigin(bgp.BGP_ORIGIN_ATTR_TYPE_IGP),
+		bgp.NewPathAttributeNextHop(peer.State.NeighborAddress),
+	}
+	path := apiutil.NewPath(
+		bgp.NewIPAddrPrefix(length, prefix), false, attrs, time.Now())
+	path.Family = &families[0]
+	path.NeighborIp = peer.State.NeighborAddress
+	path.SourceAsn = peer.State.PeerAs
+	path.Filtered = filtered
+	return path
+}
+
+// dropStreams closes all monitor streams
+func (srv *testServer) dropStreams() {
+	srv.Lock()
+	defer srv.Unlock()
+	close(srv.drop)
+	srv.drop = make(chan struct{})
+}
+
+func (srv *testServer) dropped() chan struct{} {
+	srv.Lock()
+	defer srv.Unlock()
+	return srv.drop
+}
+
+func (srv *testServer) GetBgp(
+	context.Context,
+	*gobgpapi.GetBgpRequest,
+) (*gobgpapi.GetBgpResponse, error) {
+	return &gobgpapi.GetBgpResponse{
+		Global: &gobgpapi.Global{RouterId: "192.0.2.254"},
+	}, nil
+}
+
+func (srv *testServer) ListPeer(
+	_ *gobgpapi.ListPeerRequest,
+	stream gobgpapi.GobgpApi_ListPeerServer,
+) error {
+	srv.Lock()
+	srv.listPeerCalls++
+	peers := srv.peers
+	srv.Unlock()
+	for _, peer := range peers {
+		if err := stream.Send(&gobgpapi.ListPeerResponse{Peer: peer}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (srv *testServer) ListPath(
+	req *gobgpapi.ListPathRequest,
+	stream gobgpapi.GobgpApi_ListPathServer,
+) error {
+	srv.Lock()
+	paths := srv.paths
+	local := req.TableType == gobgpapi.TableType_GLOBAL ||
+		req.TableType == gobgpapi.TableType_VRF
+	if local {
+		paths = srv.tables[req.Name]
+	}
+	srv.Unlock()
+	for _, path := range paths {
+		if path.Family.Afi != req.Family.Afi {
+			continue
+		}
+		if !local && path.NeighborIp != req.Name {
+			continue
+		}
+		nlri, err := apiutil.GetNativeNlri(path)
+		if err != nil {
+			return err
+		}
+		if len(req.Prefixes) > 0 && req.Prefixes[0].Prefix != nlri.String() {
+			continue
+		}
+		if err := stream.Send(&gobgpapi.ListPathResponse{
+			Destination: &gobgpapi.Destination{
+				Prefix: nlri.String(),
+				Paths:  []*gobgpapi.Path{path},
+			},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (srv *testServer) MonitorPeer(
+	_ *gobgpapi.MonitorPeerRequest,
+	stream gobgpapi.GobgpApi_MonitorPeerServer,
+) error {
+	drop := srv.dropped()
+	for {
+		select {
+		case peer := <-srv.peerUpdates:
+			if err := stream.Send(&gobgpapi.MonitorPeerResponse{Peer: peer}); err != nil {
+				return err
+			}
+		case <-drop:
+			return status.Error(codes.Unavailable, "stream lost")
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// updateAdjIn applies a path update to the adj-in
+// table, which is listed with the filtered flag.
+func (srv *testServer) updateAdjIn(path *gobgpapi.Path) {
+	srv.Lock()
+	defer srv.Unlock()
+	nlri, _ := apiutil.GetNativeNlri(path)
+	paths := make([]*gobgpapi.Path, 0, len(srv.paths)+1)
+	for _, p := range srv.paths {
+		n, _ := apiutil.GetNativeNlri(p)
+		if p.NeighborIp == path.NeighborIp &&
+			p.Identifier == path.Identifier &&
+			n.String() == nlri.String() {
+			continue
+		}
+		paths = append(paths, p)
+	}
+	if !path.IsWithdraw {
+		paths = append(paths, path)
+	}
+	srv.paths = paths
+}
+
+func (srv *testServer) MonitorTable(
+	_ *gobgpapi.MonitorTableRequest,
+	stream gobgpapi.GobgpApi_MonitorTableServer,
+) error {
+	drop := srv.dropped()
+	for {
+		select {
+		case path := <-srv.pathUpdates:
+			srv.updateAdjIn(path)
+			// The monitor stream sends the paths
+			// before the import policy is applied.
+			prePolicy := *path
+			prePolicy.Filtered = false
+			if err := stream.Send(&gobgpapi.MonitorTableResponse{
+				Path: &prePolicy,
+			}); err != nil {
+				return err
+			}
+		case <-drop:
+			return status.Error(codes.Unavailable, "stream lost")
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// waitFor polls the condition until it is met
+func waitFor(t *testing.T, msg string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for", msg)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
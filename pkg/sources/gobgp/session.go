@@ -0,0 +1,176 @@
+package gobgp
+
+import (
+	"log"
+
+	gobgpapi "github.com/osrg/gobgp/api"
+	"github.com/osrg/gobgp/pkg/packet/bgp"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/sources/gobgp/apiutil"
+)
+
+// addPathMode returns the mode of a negotiated
+// add-path capability.
+func addPathMode(local, remote bgp.BGPAddPathMode) string {
+	receive := local&bgp.BGP_ADD_PATH_RECEIVE > 0 &&
+		remote&bgp.BGP_ADD_PATH_SEND > 0
+	send := local&bgp.BGP_ADD_PATH_SEND > 0 &&
+		remote&bgp.BGP_ADD_PATH_RECEIVE > 0
+	switch {
+	case receive && send:
+		return api.AddPathSendReceive
+	case receive:
+		return api.AddPathReceive
+	case send:
+		return api.AddPathSend
+	}
+	return ""
+}
+
+// peerCapabilities returns the negotiated capabilities:
+// The capabilities advertised by both sides.
+func peerCapabilities(
+	state *gobgpapi.PeerState,
+) (*api.NeighborCapabilities, error) {
+	if len(state.RemoteCap) == 0 {
+		return nil, nil
+	}
+	local, err := apiutil.UnmarshalCapabilities(state.LocalCap)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := apiutil.UnmarshalCapabilities(state.RemoteCap)
+	if err != nil {
+		return nil, err
+	}
+
+	localCodes := map[bgp.BGPCapabilityCode]bool{}
+	localFamilies := map[bgp.RouteFamily]bool{}
+	localAddPath := map[bgp.RouteFamily]bgp.BGPAddPathMode{}
+	for _, c := range local {
+		localCodes[c.Code()] = true
+		switch c := c.(type) {
+		case *bgp.CapMultiProtocol:
+			localFamilies[c.CapValue] = true
+		case *bgp.CapAddPath:
+			for _, t := range c.Tuples {
+				localAddPath[t.RouteFamily] = t.Mode
+			}
+		}
+	}
+
+	caps := &api.NeighborCapabilities{}
+	for _, c := range remote {
+		if !localCodes[c.Code()] {
+			continue
+		}
+		switch c := c.(type) {
+		case *bgp.CapMultiProtocol:
+			if localFamilies[c.CapValue] {
+				caps.AFISAFIs = append(caps.AFISAFIs, c.CapValue.String())
+			}
+		case *bgp.CapRouteRefresh, *bgp.CapRouteRefreshCisco:
+			caps.RouteRefresh = true
+		case *bgp.CapEnhancedRouteRefresh:
+			caps.EnhancedRouteRefresh = true
+		case *bgp.CapFourOctetASNumber:
+			caps.FourOctetASN = true
+		case *bgp.CapLongLivedGracefulRestart:
+			caps.LongLivedGracefulRestart = true
+		case *bgp.CapGracefulRestart:
+			gr := &api.GracefulRestartCapability{
+				RestartTime: int(c.Time),
+				Restarting:  c.Flags&0x08 > 0,
+			}
+			for _, t := range c.Tuples {
+				gr.AFISAFIs = append(gr.AFISAFIs,
+					bgp.AfiSafiToRouteFamily(t.AFI, t.SAFI).String())
+			}
+			caps.GracefulRestart = gr
+		case *bgp.CapAddPath:
+			for _, t := range c.Tuples {
+				mode := addPathMode(localAddPath[t.RouteFamily], t.Mode)
+				if mode == "" {
+					continue
+				}
+				caps.AddPath = append(caps.AddPath, &api.AddPathCapability{
+					AFISAFI: t.RouteFamily.String(),
+					Mode:    mode,
+				})
+			}
+		}
+	}
+	return caps, nil
+}
+
+// peerLastError derives the last error of a session
+// which is not established. The API only reports the
+// number of notifications, so the error is known if
+// GoBGP shut the session down: Administratively or
+// because the prefix limit was reached.
+func peerLastError(state *gobgpapi.PeerState) *api.BGPError {
+	if state.SessionState == gobgpapi.PeerState_ESTABLISHED {
+		return nil
+	}
+	switch state.AdminState {
+	case gobgpapi.PeerState_DOWN:
+		return api.NewBGPError(api.BGPErrorSent,
+			int(bgp.BGP_ERROR_CEASE),
+			int(bgp.BGP_ERROR_SUB_ADMINISTRATIVE_SHUTDOWN))
+	case gobgpapi.PeerState_PFX_CT:
+		return api.NewBGPError(api.BGPErrorSent,
+			int(bgp.BGP_ERROR_CEASE),
+			int(bgp.BGP_ERROR_SUB_MAXIMUM_NUMBER_OF_PREFIXES_REACHED))
+	}
+	return nil
+}
+
+// peerSession creates the session details of a peer
+func peerSession(peer *gobgpapi.Peer) *api.NeighborSession {
+	session := &api.NeighborSession{}
+	if peer.State != nil {
+		caps, err := peerCapabilities(peer.State)
+		if err != nil {
+			log.Println("could not decode capabilities of peer",
+				peer.State.NeighborAddress, ":", err)
+		}
+		session.Capabilities = caps
+		session.Flaps = int(peer.State.Flops)
+		session.LastError = peerLastError(peer.State)
+	}
+
+	if peer.Timers != nil {
+		timers := &api.NeighborTimers{}
+		if c := peer.Timers.Config; c != nil {
+			timers.HoldTime = int(c.HoldTime)
+			timers.KeepaliveTime = int(c.KeepaliveInterval)
+			timers.ConnectRetry = int(c.ConnectRetry)
+		}
+		if s := peer.Timers.State; s != nil {
+			if s.NegotiatedHoldTime > 0 {
+				timers.HoldTime = int(s.NegotiatedHoldTime)
+			}
+			if s.KeepaliveInterval > 0 {
+				timers.KeepaliveTime = int(s.KeepaliveInterval)
+			}
+		}
+		session.Timers = timers
+	}
+
+	// The prefix limits are configured per AFI-SAFI
+	for _, afiSafi := range peer.AfiSafis {
+		if afiSafi.PrefixLimits == nil ||
+			afiSafi.PrefixLimits.MaxPrefixes == 0 {
+			continue
+		}
+		if session.ImportLimit == nil {
+			session.ImportLimit = &api.RouteLimit{}
+		}
+		session.ImportLimit.Limit += int(afiSafi.PrefixLimits.MaxPrefixes)
+		if afiSafi.State != nil {
+			session.ImportLimit.Routes += int(afiSafi.State.Received)
+		}
+	}
+	return session
+}
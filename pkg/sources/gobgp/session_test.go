@@ -0,0 +1,104 @@
+package gobgp
+
+import (
+	"testing"
+
+	gobgpapi "github.com/osrg/gobgp/api"
+	"github.com/osrg/gobgp/pkg/packet/bgp"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/sources/gobgp/apiutil"
+)
+
+func TestPeerSession(t *testing.T) {
+	local, err := apiutil.MarshalCapabilities([]bgp.ParameterCapabilityInterface{
+		bgp.NewCapMultiProtocol(bgp.RF_IPv4_UC),
+		bgp.NewCapMultiProtocol(bgp.RF_IPv6_UC),
+		bgp.NewCapRouteRefresh(),
+		bgp.NewCapFourOctetASNumber(65000),
+		bgp.NewCapAddPath([]*bgp.CapAddPathTuple{
+			bgp.NewCapAddPathTuple(bgp.RF_IPv4_UC, bgp.BGP_ADD_PATH_BOTH),
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := apiutil.MarshalCapabilities([]bgp.ParameterCapabilityInterface{
+		bgp.NewCapMultiProtocol(bgp.RF_IPv4_UC),
+		bgp.NewCapFourOctetASNumber(65001),
+		bgp.NewCapEnhancedRouteRefresh(),
+		bgp.NewCapAddPath([]*bgp.CapAddPathTuple{
+			bgp.NewCapAddPathTuple(bgp.RF_IPv4_UC, bgp.BGP_ADD_PATH_SEND),
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := peerSession(&gobgpapi.Peer{
+		State: &gobgpapi.PeerState{
+			LocalCap:  local,
+			RemoteCap: remote,
+			Flops:     3,
+		},
+		Timers: &gobgpapi.Timers{
+			Config: &gobgpapi.TimersConfig{
+				HoldTime:          90,
+				KeepaliveInterval: 30,
+				ConnectRetry:      120,
+			},
+			State: &gobgpapi.TimersState{
+				NegotiatedHoldTime: 60,
+			},
+		},
+		AfiSafis: []*gobgpapi.AfiSafi{{
+			PrefixLimits: &gobgpapi.PrefixLimit{MaxPrefixes: 100},
+			State:        &gobgpapi.AfiSafiState{Received: 23},
+		}},
+	})
+
+	caps := session.Capabilities
+	if len(caps.AFISAFIs) != 1 || caps.AFISAFIs[0] != api.AFISAFIIPv4Unicast {
+		t.Error("unexpected afi-safis:", caps.AFISAFIs)
+	}
+	if !caps.FourOctetASN || caps.RouteRefresh || caps.EnhancedRouteRefresh {
+		t.Error("unexpected capabilities:", caps)
+	}
+	if len(caps.AddPath) != 1 || caps.AddPath[0].Mode != api.AddPathReceive {
+		t.Error("unexpected add-path:", caps.AddPath)
+	}
+	if session.Flaps != 3 {
+		t.Error("unexpected flaps:", session.Flaps)
+	}
+	timers := session.Timers
+	if timers.HoldTime != 60 || timers.KeepaliveTime != 30 ||
+		timers.ConnectRetry != 120 {
+		t.Error("unexpected timers:", timers)
+	}
+	if session.ImportLimit.Limit != 100 || session.ImportLimit.Routes != 23 {
+		t.Error("unexpected import limit:", session.ImportLimit)
+	}
+}
+
+func TestPeerLastError(t *testing.T) {
+	state := &gobgpapi.PeerState{
+		SessionState: gobgpapi.PeerState_IDLE,
+		AdminState:   gobgpapi.PeerState_PFX_CT,
+	}
+	err := peerLastError(state)
+	if err == nil || err.Code != 6 || err.Subcode != 1 ||
+		err.Direction != api.BGPErrorSent {
+		t.Error("unexpected last error:", err)
+	}
+
+	state.AdminState = gobgpapi.PeerState_DOWN
+	if err := peerLastError(state); err == nil ||
+		err.Text != "Administrative shutdown" {
+		t.Error("unexpected last error:", err)
+	}
+
+	state.SessionState = gobgpapi.PeerState_ESTABLISHED
+	if err := peerLastError(state); err != nil {
+		t.Error("unexpected last error:", err)
+	}
+}
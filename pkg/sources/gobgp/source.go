@@ -3,15 +3,17 @@ package gobgp
 import (
 	gobgpapi "github.com/osrg/gobgp/api"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/connectivity"
 
 	api "github.com/alice-lg/alice-lg/pkg/api"
 	"github.com/alice-lg/alice-lg/pkg/caches"
 	"github.com/alice-lg/alice-lg/pkg/sources"
 
 	"context"
+	"fmt"
 	"io"
 	"log"
+	"sync"
 	"time"
 )
 
@@ -21,7 +23,13 @@ var _GoBGPSource sources.Source = &GoBGP{}
 // GoBGP is a source for Alice.
 type GoBGP struct {
 	config Config
-	client gobgpapi.GobgpApiClient
+
+	// Connection: The client is created on first use
+	dialOpts []grpc.DialOption
+	conn     *grpc.ClientConn
+	client   gobgpapi.GobgpApiClient
+	closed   bool
+	connLock sync.Mutex
 
 	// Caches: Neighbors
 	neighborsCache *caches.NeighborsCache
@@ -31,30 +39,32 @@ type GoBGP struct {
 	routesReceivedCache    *caches.RoutesCache
 	routesFilteredCache    *caches.RoutesCache
 	routesNotExportedCache *caches.RoutesCache
-}
 
-// NewGoBGP creates a new GoBGP source instance
-func NewGoBGP(config Config) *GoBGP {
-	dialOpts := make([]grpc.DialOption, 0)
-	if config.Insecure {
-		dialOpts = append(dialOpts, grpc.WithInsecure())
-	} else {
-		creds, err := credentials.NewClientTLSFromFile(
-			config.TLSCert,
-			config.TLSCommonName)
-		if err != nil {
-			log.Fatalf("could not load tls cert: %s", err)
-		}
-		dialOpts = append(dialOpts, grpc.WithTransportCredentials(creds))
-	}
+	// Streaming: The peers and received routes are
+	// maintained from the monitor streams.
+	rib       *rib
+	stopWatch context.CancelFunc
+}
 
-	conn, err := grpc.Dial(config.Host, dialOpts...)
+// NewGoBGP creates a new GoBGP source instance.
+// The TLS material is loaded, but the connection is
+// only established with the first request.
+func NewGoBGP(config Config) (*GoBGP, error) {
+	dialOpts, err := dialOptions(config)
 	if err != nil {
-		log.Fatalf("did not connect: %v", err)
+		return nil, err
 	}
+	gobgp := newGoBGP(config, nil)
+	gobgp.dialOpts = dialOpts
+	gobgp.startWatch()
+	return gobgp, nil
+}
 
-	client := gobgpapi.NewGobgpApiClient(conn)
-
+// newGoBGP creates a source instance using the client.
+// In streaming mode the monitor streams are subscribed.
+// Without a client, the connection is established
+// with the first request.
+func newGoBGP(config Config, client gobgpapi.GobgpApiClient) *GoBGP {
 	// Cache settings:
 	// TODO: Maybe read from config file
 	neighborsCacheDisable := false
@@ -73,7 +83,7 @@ func NewGoBGP(config Config) *GoBGP {
 	routesNotExportedCache := caches.NewRoutesCache(
 		routesCacheDisabled, routesCacheMaxSize)
 
-	return &GoBGP{
+	gobgp := &GoBGP{
 		config: config,
 		client: client,
 
@@ -83,7 +93,44 @@ func NewGoBGP(config Config) *GoBGP {
 		routesReceivedCache:    routesReceivedCache,
 		routesFilteredCache:    routesFilteredCache,
 		routesNotExportedCache: routesNotExportedCache,
+
+		rib: newRIB(),
+	}
+
+	if client != nil {
+		gobgp.startWatch()
+	}
+
+	return gobgp
+}
+
+// startWatch subscribes to the monitor
+// streams in streaming mode.
+func (gobgp *GoBGP) startWatch() {
+	if !gobgp.config.Streaming {
+		return
 	}
+	ctx, cancel := context.WithCancel(context.Background())
+	gobgp.stopWatch = cancel
+	go gobgp.watch(ctx)
+}
+
+// Close stops the monitor streams and closes the
+// connection. The source can not be used afterwards.
+func (gobgp *GoBGP) Close() error {
+	if gobgp.stopWatch != nil {
+		gobgp.stopWatch()
+	}
+	gobgp.connLock.Lock()
+	defer gobgp.connLock.Unlock()
+	gobgp.closed = true
+	gobgp.client = nil
+	if gobgp.conn == nil {
+		return nil
+	}
+	err := gobgp.conn.Close()
+	gobgp.conn = nil
+	return err
 }
 
 // ExpireCaches clears all local caches
@@ -105,7 +152,11 @@ func (gobgp *GoBGP) NeighborsStatus(
 	response := api.NeighborsStatusResponse{}
 	response.Neighbors = make(api.NeighborsStatus, 0)
 
-	resp, err := gobgp.client.ListPeer(ctx, &gobgpapi.ListPeerRequest{})
+	client, err := gobgp.getClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.ListPeer(ctx, &gobgpapi.ListPeerRequest{})
 	if err != nil {
 		return nil, err
 	}
@@ -115,6 +166,10 @@ func (gobgp *GoBGP) NeighborsStatus(
 			break
 		}
 
+		if !gobgp.peerInVRF(_resp.Peer) {
+			continue
+		}
+
 		ns := api.NeighborStatus{}
 		ns.ID = PeerHash(_resp.Peer)
 
@@ -131,6 +186,7 @@ func (gobgp *GoBGP) NeighborsStatus(
 				int64(_resp.Peer.Timers.State.Uptime.Nanos)))
 		}
 
+		response.Neighbors = append(response.Neighbors, &ns)
 	}
 	return &response, nil
 }
@@ -143,14 +199,26 @@ func (gobgp *GoBGP) Status(
 		ctx, time.Second*time.Duration(gobgp.config.ProcessingTimeout))
 	defer cancel()
 
-	resp, err := gobgp.client.GetBgp(ctx, &gobgpapi.GetBgpRequest{})
+	client, err := gobgp.getClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.GetBgp(ctx, &gobgpapi.GetBgpRequest{})
+	state, dialed := gobgp.connState()
 	if err != nil {
+		if dialed && state != connectivity.Ready {
+			return nil, fmt.Errorf(
+				"%w (connection %s): %w", ErrNotConnected, state, err)
+		}
 		return nil, err
 	}
 
 	response := api.StatusResponse{}
 	response.Status.RouterID = resp.Global.RouterId
 	response.Status.Backend = "gobgp"
+	if dialed {
+		response.Status.Message = "connection " + state.String()
+	}
 	return &response, nil
 }
 
@@ -162,10 +230,18 @@ func (gobgp *GoBGP) Neighbors(
 		ctx, time.Second*time.Duration(gobgp.config.ProcessingTimeout))
 	defer cancel()
 
+	if neighbors, ok := gobgp.streamNeighbors(); ok {
+		return neighbors, nil
+	}
+
 	response := api.NeighborsResponse{}
 	response.Neighbors = make(api.Neighbors, 0)
 
-	resp, err := gobgp.client.ListPeer(ctx, &gobgpapi.ListPeerRequest{EnableAdvertised: true})
+	client, err := gobgp.getClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.ListPeer(ctx, &gobgpapi.ListPeerRequest{EnableAdvertised: true})
 	if err != nil {
 		return nil, err
 	}
@@ -175,38 +251,50 @@ func (gobgp *GoBGP) Neighbors(
 			break
 		}
 
-		neigh := api.Neighbor{}
-
-		neigh.Address = _resp.Peer.State.NeighborAddress
-		neigh.ASN = int(_resp.Peer.State.PeerAs)
-		switch _resp.Peer.State.SessionState {
-		case gobgpapi.PeerState_ESTABLISHED:
-			neigh.State = "up"
-		default:
-			neigh.State = "down"
+		if !gobgp.peerInVRF(_resp.Peer) {
+			continue
 		}
-		neigh.Description = _resp.Peer.Conf.Description
-
-		neigh.ID = PeerHash(_resp.Peer)
-		neigh.RouteServerID = gobgp.config.ID
 
-		response.Neighbors = append(response.Neighbors, &neigh)
+		neigh := gobgp.peerNeighbor(_resp.Peer)
 		for _, afiSafi := range _resp.Peer.AfiSafis {
 			neigh.RoutesReceived += int(afiSafi.State.Received)
 			neigh.RoutesExported += int(afiSafi.State.Advertised)
 			neigh.RoutesAccepted += int(afiSafi.State.Accepted)
 			neigh.RoutesFiltered += (neigh.RoutesReceived - neigh.RoutesAccepted)
 		}
+		response.Neighbors = append(response.Neighbors, neigh)
+	}
 
-		if _resp.Peer.Timers.State.Uptime != nil {
-			neigh.Uptime = time.Since(time.Unix(
-				_resp.Peer.Timers.State.Uptime.Seconds,
-				int64(_resp.Peer.Timers.State.Uptime.Nanos)))
-		}
+	return &response, nil
+}
 
+// peerNeighbor creates a neighbor from a peer
+func (gobgp *GoBGP) peerNeighbor(peer *gobgpapi.Peer) *api.Neighbor {
+	neigh := &api.Neighbor{}
+
+	neigh.Address = peer.State.NeighborAddress
+	neigh.ASN = int(peer.State.PeerAs)
+	switch peer.State.SessionState {
+	case gobgpapi.PeerState_ESTABLISHED:
+		neigh.State = "up"
+	default:
+		neigh.State = "down"
+	}
+	if peer.Conf != nil {
+		neigh.Description = peer.Conf.Description
 	}
 
-	return &response, nil
+	neigh.ID = PeerHash(peer)
+	neigh.RouteServerID = gobgp.config.ID
+	neigh.Session = peerSession(peer)
+
+	if peer.Timers != nil && peer.Timers.State != nil &&
+		peer.Timers.State.Uptime != nil {
+		neigh.Uptime = time.Since(time.Unix(
+			peer.Timers.State.Uptime.Seconds,
+			int64(peer.Timers.State.Uptime.Nanos)))
+	}
+	return neigh
 }
 
 // NeighborsSummary is an alias of Neighbors for now
@@ -273,6 +361,14 @@ func (gobgp *GoBGP) RoutesReceived(
 	ctx context.Context,
 	neighborID string,
 ) (*api.RoutesResponse, error) {
+	if routes, ok, err := gobgp.streamRoutes(neighborID); ok {
+		if err != nil {
+			return nil, err
+		}
+		routes.Filtered = nil
+		return routes, nil
+	}
+
 	neigh, err := gobgp.lookupNeighbor(ctx, neighborID)
 	if err != nil {
 		return nil, err
@@ -292,6 +388,14 @@ func (gobgp *GoBGP) RoutesFiltered(
 	ctx context.Context,
 	neighborID string,
 ) (*api.RoutesResponse, error) {
+	if routes, ok, err := gobgp.streamRoutes(neighborID); ok {
+		if err != nil {
+			return nil, err
+		}
+		routes.Imported = nil
+		return routes, nil
+	}
+
 	routes, err := gobgp.getRoutes(ctx, neighborID)
 	if err != nil {
 		log.Print(err)
@@ -324,7 +428,19 @@ func (gobgp *GoBGP) RoutesNotExported(
 func (gobgp *GoBGP) AllRoutes(
 	ctx context.Context,
 ) (*api.RoutesResponse, error) {
+	if routes, ok := gobgp.streamAllRoutes(); ok {
+		return routes, nil
+	}
+
 	routes := NewRoutesResponse()
+	if gobgp.config.TableType == TableTypeLocal {
+		// All routes are read from the table at once
+		if err := gobgp.GetTableRoutes(ctx, &routes); err != nil {
+			return nil, err
+		}
+		return &routes, nil
+	}
+
 	peers, err := gobgp.GetNeighbors(ctx)
 	if err != nil {
 		return nil, err
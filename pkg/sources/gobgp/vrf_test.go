@@ -0,0 +1,131 @@
+package gobgp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gobgpapi "github.com/osrg/gobgp/api"
+)
+
+func TestVRFNeighborsAndRoutes(t *testing.T) {
+	ctx := context.Background()
+	srv, client := startTestServer(t)
+
+	peer1 := testPeer("192.0.2.1", 64500, true)
+	peer1.Conf.Vrf = "lan1"
+	peer2 := testPeer("192.0.2.2", 64501, true)
+	peer2.Conf.Vrf = "lan2"
+	srv.peers = []*gobgpapi.Peer{peer1, peer2}
+	srv.paths = []*gobgpapi.Path{
+		testPath(peer1, "10.0.0.0", 24, false),
+		testPath(peer1, "10.0.1.0", 24, true),
+		testPath(peer2, "10.0.2.0", 24, false),
+	}
+	srv.tables = map[string][]*gobgpapi.Path{
+		"lan1": {
+			testPath(peer1, "10.0.0.0", 24, false),
+			testPath(peer2, "10.0.3.0", 24, false), // leaked
+		},
+	}
+
+	gobgp := newGoBGP(Config{
+		ID:                "rs1-lan1",
+		Name:              "rs1 (lan1)",
+		ProcessingTimeout: 10,
+		VRF:               "lan1",
+	}, client)
+
+	neighbors, err := gobgp.Neighbors(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(neighbors.Neighbors) != 1 ||
+		neighbors.Neighbors[0].ID != PeerHash(peer1) {
+		t.Fatal("expected only the neighbor in the VRF:", neighbors.Neighbors)
+	}
+
+	// Received routes from the adj-in table
+	routes, err := gobgp.AllRoutes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes.Imported) != 1 || len(routes.Filtered) != 1 {
+		t.Error("unexpected routes:", routes.Imported, routes.Filtered)
+	}
+
+	// Received routes from the table of the VRF
+	gobgp.config.TableType = TableTypeLocal
+	routes, err = gobgp.AllRoutes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes.Imported) != 1 || routes.Imported[0].Network != "10.0.0.0/24" {
+		t.Error("unexpected routes:", routes.Imported)
+	}
+	received, err := gobgp.RoutesReceived(ctx, PeerHash(peer1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(received.Imported) != 1 {
+		t.Error("unexpected received routes:", received.Imported)
+	}
+}
+
+func TestVRFStreaming(t *testing.T) {
+	watchRetryMin = 10 * time.Millisecond
+	ctx := context.Background()
+	srv, client := startTestServer(t)
+
+	peer1 := testPeer("192.0.2.1", 64500, true)
+	peer1.Conf.Vrf = "lan1"
+	peer2 := testPeer("192.0.2.2", 64501, true)
+	peer2.Conf.Vrf = "lan2"
+	srv.peers = []*gobgpapi.Peer{peer1, peer2}
+	srv.paths = []*gobgpapi.Path{
+		testPath(peer1, "10.0.0.0", 24, false),
+		testPath(peer2, "10.0.2.0", 24, false),
+	}
+
+	gobgp := newGoBGP(Config{
+		ID:                "rs1-lan1",
+		Name:              "rs1 (lan1)",
+		ProcessingTimeout: 10,
+		VRF:               "lan1",
+		Streaming:         true,
+	}, client)
+	defer gobgp.stopWatch()
+	waitFor(t, "sync", gobgp.rib.isSynced)
+
+	countRoutes := func() int {
+		routes, err := gobgp.AllRoutes(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(routes.Imported) + len(routes.Filtered)
+	}
+	if n := countRoutes(); n != 1 {
+		t.Error("expected only the routes of the VRF, got:", n)
+	}
+
+	// Updates of other VRFs are ignored
+	srv.pathUpdates <- testPath(peer2, "10.0.3.0", 24, false)
+	srv.pathUpdates <- testPath(peer1, "10.0.1.0", 24, false)
+	waitFor(t, "added path", func() bool { return countRoutes() == 2 })
+
+	peer3 := testPeer("192.0.2.3", 64502, true)
+	peer3.Conf.Vrf = "lan2"
+	srv.peerUpdates <- peer3
+	down := testPeer("192.0.2.1", 64500, false)
+	down.Conf.Vrf = "lan1"
+	srv.peerUpdates <- down
+	waitFor(t, "peer down", func() bool { return countRoutes() == 0 })
+
+	neighbors, err := gobgp.Neighbors(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(neighbors.Neighbors) != 1 {
+		t.Error("expected only the neighbor in the VRF:", neighbors.Neighbors)
+	}
+}
@@ -0,0 +1,439 @@
+package gobgp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	gobgpapi "github.com/osrg/gobgp/api"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/sources/gobgp/apiutil"
+)
+
+// Reconnect backoff of the monitor streams
+var (
+	watchRetryMin = 1 * time.Second
+	watchRetryMax = 60 * time.Second
+)
+
+// ErrStreamClosed is returned when a monitor
+// stream was closed by the server.
+var ErrStreamClosed = errors.New("monitor stream closed")
+
+// pathKey identifies a received path: With add-path,
+// a peer can send multiple paths for a prefix.
+type pathKey struct {
+	prefix string
+	id     uint32
+}
+
+// peerRIB is the state of a peer and the paths
+// received from it.
+type peerRIB struct {
+	peer  *gobgpapi.Peer
+	paths map[pathKey]*gobgpapi.Path
+}
+
+// The rib holds the state of all peers as maintained
+// from the monitor streams. The peers are keyed by the
+// neighbor address.
+type rib struct {
+	peers  map[string]*peerRIB
+	synced bool
+	sync.RWMutex
+}
+
+// newRIB creates a new empty rib
+func newRIB() *rib {
+	return &rib{
+		peers: make(map[string]*peerRIB),
+	}
+}
+
+// reset replaces the state after a resync
+func (r *rib) reset(peers map[string]*peerRIB) {
+	r.Lock()
+	defer r.Unlock()
+	r.peers = peers
+	r.synced = true
+}
+
+// invalidate marks the state as out of sync
+func (r *rib) invalidate() {
+	r.Lock()
+	defer r.Unlock()
+	r.synced = false
+}
+
+// isSynced checks if the state can be used
+func (r *rib) isSynced() bool {
+	r.RLock()
+	defer r.RUnlock()
+	return r.synced
+}
+
+// hasPeer checks if the peer is part of the rib
+func (r *rib) hasPeer(addr string) bool {
+	r.RLock()
+	defer r.RUnlock()
+	_, ok := r.peers[addr]
+	return ok
+}
+
+// updatePeer applies a peer state change. The paths
+// of a peer are dropped when the session goes down.
+func (r *rib) updatePeer(peer *gobgpapi.Peer) {
+	if peer == nil || peer.State == nil {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	addr := peer.State.NeighborAddress
+	p, ok := r.peers[addr]
+	if !ok {
+		p = &peerRIB{paths: make(map[pathKey]*gobgpapi.Path)}
+		r.peers[addr] = p
+	}
+	if peer.State.SessionState != gobgpapi.PeerState_ESTABLISHED {
+		p.paths = make(map[pathKey]*gobgpapi.Path)
+	}
+	p.peer = peer
+}
+
+// updatePath applies an update or a withdraw
+// of a path received from a peer.
+func (r *rib) updatePath(path *gobgpapi.Path, family *gobgpapi.Family) error {
+	if path.Family == nil {
+		path.Family = family
+	}
+	nlri, err := apiutil.GetNativeNlri(path)
+	if err != nil {
+		return err
+	}
+	key := pathKey{prefix: nlri.String(), id: path.Identifier}
+
+	r.Lock()
+	defer r.Unlock()
+	p, ok := r.peers[path.NeighborIp]
+	if !ok {
+		// Paths of unknown peers are picked
+		// up with the next resync.
+		return nil
+	}
+	if path.IsWithdraw {
+		delete(p.paths, key)
+		return nil
+	}
+	p.paths[key] = path
+	return nil
+}
+
+// lookupPeer finds the peer by neighbor ID
+func (r *rib) lookupPeer(neighborID string) *peerRIB {
+	for _, p := range r.peers {
+		if PeerHash(p.peer) == neighborID {
+			return p
+		}
+	}
+	return nil
+}
+
+// watch subscribes to the monitor streams and keeps
+// the rib in sync. When a stream is lost, the streams
+// are subscribed again and the state is resynced.
+func (gobgp *GoBGP) watch(ctx context.Context) {
+	retry := watchRetryMin
+	for {
+		err := gobgp.watchStreams(ctx, func() {
+			retry = watchRetryMin
+		})
+		gobgp.rib.invalidate()
+		if ctx.Err() != nil {
+			return
+		}
+		log.Println(
+			"gobgp:", gobgp.config.Name, "monitor stream lost:", err,
+			"- reconnecting in", retry)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		retry *= 2
+		if retry > watchRetryMax {
+			retry = watchRetryMax
+		}
+	}
+}
+
+// watchStreams subscribes to the peer and table
+// monitor streams, resyncs the state and applies the
+// updates until a stream fails. The streams are opened
+// before the resync, so no update is missed.
+func (gobgp *GoBGP) watchStreams(ctx context.Context, synced func()) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	client, err := gobgp.getClient()
+	if err != nil {
+		return err
+	}
+	peerStream, err := client.MonitorPeer(
+		ctx, &gobgpapi.MonitorPeerRequest{})
+	if err != nil {
+		return err
+	}
+	tableStreams := make(
+		[]gobgpapi.GobgpApi_MonitorTableClient, 0, len(families))
+	for _, family := range families {
+		family := family
+		stream, err := client.MonitorTable(
+			ctx, &gobgpapi.MonitorTableRequest{
+				TableType: gobgpapi.TableType_ADJ_IN,
+				Family:    &family,
+			})
+		if err != nil {
+			return err
+		}
+		tableStreams = append(tableStreams, stream)
+	}
+
+	peers, err := gobgp.resync(ctx)
+	if err != nil {
+		return err
+	}
+	gobgp.rib.reset(peers)
+	synced()
+	log.Println(
+		"gobgp:", gobgp.config.Name, "synced", len(peers),
+		"peers from monitor streams")
+
+	errs := make(chan error, 1+len(tableStreams))
+	go func() {
+		for {
+			res, err := peerStream.Recv()
+			if err != nil {
+				errs <- streamError(err)
+				return
+			}
+			// The stream sends the peers of all VRFs
+			if res.Peer == nil || !gobgp.peerInVRF(res.Peer) {
+				continue
+			}
+			gobgp.rib.updatePeer(res.Peer)
+		}
+	}()
+	for i, stream := range tableStreams {
+		go func(stream gobgpapi.GobgpApi_MonitorTableClient, family gobgpapi.Family) {
+			for {
+				res, err := stream.Recv()
+				if err != nil {
+					errs <- streamError(err)
+					return
+				}
+				// Only the peers of the VRF are in the rib
+				if res.Path == nil || !gobgp.rib.hasPeer(res.Path.NeighborIp) {
+					continue
+				}
+				if !res.Path.IsWithdraw {
+					// A failed lookup triggers a resync
+					if err := gobgp.lookupFiltered(ctx, res.Path, &family); err != nil {
+						errs <- err
+						return
+					}
+				}
+				if err := gobgp.rib.updatePath(res.Path, &family); err != nil {
+					log.Println("gobgp: could not apply path update:", err)
+				}
+			}
+		}(stream, families[i])
+	}
+	return <-errs
+}
+
+// lookupFiltered sets the filtered flag of a path
+// received from the monitor stream. The stream sends
+// the paths before the import policy is applied, so
+// the path is looked up in the adj-in table.
+func (gobgp *GoBGP) lookupFiltered(
+	ctx context.Context,
+	path *gobgpapi.Path,
+	family *gobgpapi.Family,
+) error {
+	if path.Family == nil {
+		path.Family = family
+	}
+	nlri, err := apiutil.GetNativeNlri(path)
+	if err != nil {
+		return err
+	}
+	prefix := nlri.String()
+
+	client, err := gobgp.getClient()
+	if err != nil {
+		return err
+	}
+	stream, err := client.ListPath(ctx, &gobgpapi.ListPathRequest{
+		Name:      path.NeighborIp,
+		TableType: gobgpapi.TableType_ADJ_IN,
+		Family:    path.Family,
+		Prefixes: []*gobgpapi.TableLookupPrefix{
+			{Prefix: prefix},
+		},
+		EnableFiltered: true,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", errListPathFailed, err)
+	}
+	path.Filtered = false
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		dst := res.Destination
+		if dst == nil || dst.Prefix != prefix {
+			continue
+		}
+		for _, p := range dst.Paths {
+			if p.Identifier == path.Identifier {
+				path.Filtered = p.Filtered
+			}
+		}
+	}
+}
+
+// streamError converts the end of a stream into an error
+func streamError(err error) error {
+	if err == io.EOF {
+		return ErrStreamClosed
+	}
+	return err
+}
+
+// resync retrieves the peers and their received paths
+func (gobgp *GoBGP) resync(
+	ctx context.Context,
+) (map[string]*peerRIB, error) {
+	peers, err := gobgp.GetNeighbors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	state := make(map[string]*peerRIB, len(peers))
+	for _, peer := range peers {
+		p := &peerRIB{
+			peer:  peer,
+			paths: make(map[pathKey]*gobgpapi.Path),
+		}
+		state[peer.State.NeighborAddress] = p
+		for _, family := range families {
+			rib, err := gobgp.listDestinations(
+				ctx, peer.State.NeighborAddress,
+				gobgpapi.TableType_ADJ_IN, family)
+			if err != nil {
+				return nil, err
+			}
+			for _, dst := range rib {
+				for _, path := range dst.Paths {
+					key := pathKey{prefix: dst.Prefix, id: path.Identifier}
+					p.paths[key] = path
+				}
+			}
+		}
+	}
+	return state, nil
+}
+
+// parsePeerRoutes parses the paths of a peer into
+// the routes response.
+func (gobgp *GoBGP) parsePeerRoutes(
+	p *peerRIB,
+	response *api.RoutesResponse,
+) {
+	for key, path := range p.paths {
+		route, err := gobgp.parsePathIntoRoute(path, key.prefix)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if path.Filtered {
+			response.Filtered = append(response.Filtered, route)
+		} else {
+			response.Imported = append(response.Imported, route)
+		}
+	}
+}
+
+// streamNeighbors returns the neighbors from the rib.
+// The result is only valid if the rib is in sync.
+func (gobgp *GoBGP) streamNeighbors() (*api.NeighborsResponse, bool) {
+	gobgp.rib.RLock()
+	defer gobgp.rib.RUnlock()
+	if !gobgp.rib.synced {
+		return nil, false
+	}
+	response := &api.NeighborsResponse{
+		Neighbors: make(api.Neighbors, 0, len(gobgp.rib.peers)),
+	}
+	for _, p := range gobgp.rib.peers {
+		neigh := gobgp.peerNeighbor(p.peer)
+		for _, path := range p.paths {
+			neigh.RoutesReceived++
+			if path.Filtered {
+				neigh.RoutesFiltered++
+			}
+		}
+		neigh.RoutesAccepted = neigh.RoutesReceived - neigh.RoutesFiltered
+		for _, afiSafi := range p.peer.AfiSafis {
+			if afiSafi.State != nil {
+				neigh.RoutesExported += int(afiSafi.State.Advertised)
+			}
+		}
+		response.Neighbors = append(response.Neighbors, neigh)
+	}
+	return response, true
+}
+
+// streamRoutes returns the received and filtered
+// routes of a neighbor from the rib.
+func (gobgp *GoBGP) streamRoutes(
+	neighborID string,
+) (*api.RoutesResponse, bool, error) {
+	gobgp.rib.RLock()
+	defer gobgp.rib.RUnlock()
+	if !gobgp.rib.synced {
+		return nil, false, nil
+	}
+	p := gobgp.rib.lookupPeer(neighborID)
+	if p == nil {
+		return nil, true, fmt.Errorf("could not lookup neighbor")
+	}
+	routes := NewRoutesResponse()
+	gobgp.parsePeerRoutes(p, &routes)
+	sort.Sort(routes.Imported)
+	sort.Sort(routes.Filtered)
+	return &routes, true, nil
+}
+
+// streamAllRoutes returns the routes of all
+// neighbors from the rib.
+func (gobgp *GoBGP) streamAllRoutes() (*api.RoutesResponse, bool) {
+	gobgp.rib.RLock()
+	defer gobgp.rib.RUnlock()
+	if !gobgp.rib.synced {
+		return nil, false
+	}
+	routes := NewRoutesResponse()
+	for _, p := range gobgp.rib.peers {
+		gobgp.parsePeerRoutes(p, &routes)
+	}
+	return &routes, true
+}
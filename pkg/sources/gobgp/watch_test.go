@@ -0,0 +1,169 @@
+package gobgp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alice-lg/alice-lg/pkg/sources"
+)
+
+func TestWatchStreams(t *testing.T) {
+	watchRetryMin = 10 * time.Millisecond
+	ctx := context.Background()
+	srv, client := startTestServer(t)
+
+	peer := testPeer("192.0.2.1", 64500, true)
+	srv.peers = append(srv.peers, peer)
+	srv.paths = append(srv.paths,
+		testPath(peer, "10.0.0.0", 24, false),
+		testPath(peer, "10.0.1.0", 24, true))
+
+	gobgp := newGoBGP(Config{
+		ID:                "rs1",
+		Name:              "rs1",
+		ProcessingTimeout: 10,
+		Streaming:         true,
+	}, client)
+	defer gobgp.stopWatch()
+	waitFor(t, "sync", gobgp.rib.isSynced)
+
+	neighbors, err := gobgp.Neighbors(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(neighbors.Neighbors) != 1 {
+		t.Fatal("unexpected neighbors:", neighbors.Neighbors)
+	}
+	n := neighbors.Neighbors[0]
+	if n.RoutesReceived != 2 || n.RoutesFiltered != 1 || n.RoutesAccepted != 1 {
+		t.Error("unexpected route counts:", n)
+	}
+	neighborID := n.ID
+
+	received, err := gobgp.RoutesReceived(ctx, neighborID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(received.Imported) != 1 || received.Imported[0].Network != "10.0.0.0/24" {
+		t.Error("unexpected received routes:", received.Imported)
+	}
+	filtered, err := gobgp.RoutesFiltered(ctx, neighborID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered.Filtered) != 1 || filtered.Filtered[0].Network != "10.0.1.0/24" {
+		t.Error("unexpected filtered routes:", filtered.Filtered)
+	}
+	if _, err := gobgp.RoutesReceived(ctx, "unknown"); err == nil {
+		t.Error("expected error for unknown neighbor")
+	}
+
+	countRoutes := func() int {
+		routes, err := gobgp.AllRoutes(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(routes.Imported) + len(routes.Filtered)
+	}
+
+	// Incremental updates
+	srv.pathUpdates <- testPath(peer, "10.0.2.0", 24, false)
+	waitFor(t, "added path", func() bool { return countRoutes() == 3 })
+
+	withdraw := testPath(peer, "10.0.0.0", 24, false)
+	withdraw.IsWithdraw = true
+	srv.pathUpdates <- withdraw
+	waitFor(t, "withdrawn path", func() bool { return countRoutes() == 2 })
+
+	// Paths are withdrawn per path identifier
+	addPath := testPath(peer, "10.0.3.0", 24, false)
+	addPath.Identifier = 1
+	srv.pathUpdates <- addPath
+	addPath = testPath(peer, "10.0.3.0", 24, false)
+	addPath.Identifier = 2
+	srv.pathUpdates <- addPath
+	waitFor(t, "add-path paths", func() bool { return countRoutes() == 4 })
+
+	withdraw = testPath(peer, "10.0.3.0", 24, false)
+	withdraw.Identifier = 1
+	withdraw.IsWithdraw = true
+	srv.pathUpdates <- withdraw
+	waitFor(t, "withdrawn add-path", func() bool { return countRoutes() == 3 })
+
+	// The filtered flag is looked up, the stream
+	// sends the paths before the import policy.
+	srv.pathUpdates <- testPath(peer, "10.0.4.0", 24, true)
+	waitFor(t, "filtered path", func() bool { return countRoutes() == 4 })
+	filtered, err = gobgp.RoutesFiltered(ctx, neighborID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered.Filtered) != 2 {
+		t.Error("unexpected filtered routes:", filtered.Filtered)
+	}
+
+	// The paths are dropped when the session goes down
+	srv.peerUpdates <- testPeer("192.0.2.1", 64500, false)
+	waitFor(t, "peer down", func() bool { return countRoutes() == 0 })
+
+	// A lost stream triggers a resync
+	srv.Lock()
+	calls := srv.listPeerCalls
+	srv.peers[0] = peer
+	srv.Unlock()
+	srv.dropStreams()
+	waitFor(t, "resync", func() bool {
+		srv.Lock()
+		defer srv.Unlock()
+		return srv.listPeerCalls > calls && gobgp.rib.isSynced()
+	})
+	waitFor(t, "resynced paths", func() bool { return countRoutes() == 4 })
+}
+
+func TestWatchStopped(t *testing.T) {
+	srv, client := startTestServer(t)
+	srv.peers = append(srv.peers, testPeer("192.0.2.1", 64500, true))
+
+	gobgp := newGoBGP(Config{
+		ID:                "rs1",
+		Name:              "rs1",
+		ProcessingTimeout: 10,
+		Streaming:         true,
+	}, client)
+	waitFor(t, "sync", gobgp.rib.isSynced)
+	gobgp.stopWatch()
+	waitFor(t, "invalidation", func() bool { return !gobgp.rib.isSynced() })
+
+	// Without the streams the tables are listed
+	neighbors, err := gobgp.Neighbors(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(neighbors.Neighbors) != 1 {
+		t.Error("unexpected neighbors:", neighbors.Neighbors)
+	}
+}
+
+func TestClose(t *testing.T) {
+	srv, client := startTestServer(t)
+	srv.peers = append(srv.peers, testPeer("192.0.2.1", 64500, true))
+
+	gobgp := newGoBGP(Config{
+		ID:                "rs1",
+		Name:              "rs1",
+		ProcessingTimeout: 10,
+		Streaming:         true,
+	}, client)
+	waitFor(t, "sync", gobgp.rib.isSynced)
+	if err := gobgp.Close(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "invalidation", func() bool { return !gobgp.rib.isSynced() })
+
+	_, err := gobgp.Neighbors(context.Background())
+	if !errors.Is(err, sources.ErrSourceClosed) {
+		t.Error("expected closed source error, got:", err)
+	}
+}
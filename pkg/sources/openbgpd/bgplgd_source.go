@@ -3,11 +3,11 @@ package openbgpd
 import (
 	"context"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/alice-lg/alice-lg/pkg/api"
 	"github.com/alice-lg/alice-lg/pkg/caches"
-	"github.com/alice-lg/alice-lg/pkg/decoders"
 	"github.com/alice-lg/alice-lg/pkg/sources"
 )
 
@@ -35,6 +35,13 @@ type BgplgdSource struct {
 	routesCache         *caches.RoutesCache
 	routesReceivedCache *caches.RoutesCache
 	routesFilteredCache *caches.RoutesCache
+
+	routesNotExportedCache *caches.RoutesCache
+
+	// The best routes of the Loc-RIB are shared
+	// by the neighbors for the not exported routes.
+	bestRIBCache *caches.RoutesCache
+	bestRIBLock  sync.Mutex
 }
 
 // NewBgplgdSource creates a new source instance with a configuration.
@@ -47,6 +54,8 @@ func NewBgplgdSource(cfg *Config) *BgplgdSource {
 	rc := caches.NewRoutesCache(cacheDisabled, cfg.RoutesCacheSize)
 	rrc := caches.NewRoutesCache(cacheDisabled, cfg.RoutesCacheSize)
 	rfc := caches.NewRoutesCache(cacheDisabled, cfg.RoutesCacheSize)
+	rnc := caches.NewRoutesCache(cacheDisabled, cfg.RoutesCacheSize)
+	brc := caches.NewRoutesCache(cacheDisabled, 1)
 
 	return &BgplgdSource{
 		cfg:                   cfg,
@@ -55,12 +64,17 @@ func NewBgplgdSource(cfg *Config) *BgplgdSource {
 		routesCache:           rc,
 		routesReceivedCache:   rrc,
 		routesFilteredCache:   rfc,
+
+		routesNotExportedCache: rnc,
+		bestRIBCache:           brc,
 	}
 }
 
 // ExpireCaches ... will flush the cache.
 func (src *BgplgdSource) ExpireCaches() int {
 	totalExpired := src.routesReceivedCache.Expire()
+	totalExpired += src.routesNotExportedCache.Expire()
+	totalExpired += src.bestRIBCache.Expire()
 	return totalExpired
 }
 
@@ -91,6 +105,56 @@ func (src *BgplgdSource) ShowNeighborRIBRequest(
 	return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 }
 
+// ShowNeighborRIBInvalidRequest retrieves the routes from the
+// neighbor, which are not eligible.
+func (src *BgplgdSource) ShowNeighborRIBInvalidRequest(
+	ctx context.Context,
+	neighborID string,
+) (*http.Request, error) {
+	url := src.cfg.APIURL("/rib?neighbor=%s&invalid=1", neighborID)
+	return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+}
+
+// ShowNeighborRIBInFilteredRequest retrieves the routes from the
+// Adj-RIB-In of the neighbor, which were filtered on input.
+func (src *BgplgdSource) ShowNeighborRIBInFilteredRequest(
+	ctx context.Context,
+	neighborID string,
+) (*http.Request, error) {
+	url := src.cfg.APIURL("/rib?neighbor=%s&in=1&filtered=1", neighborID)
+	return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+}
+
+// ShowNeighborRIBOutRequest retrieves the routes from the
+// Adj-RIB-Out of the neighbor.
+func (src *BgplgdSource) ShowNeighborRIBOutRequest(
+	ctx context.Context,
+	neighborID string,
+) (*http.Request, error) {
+	url := src.cfg.APIURL("/rib?neighbor=%s&out=1", neighborID)
+	return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+}
+
+// ShowRIBInvalidRequest retrieves all routes which
+// are not eligible.
+func (src *BgplgdSource) ShowRIBInvalidRequest(ctx context.Context) (*http.Request, error) {
+	url := src.cfg.APIURL("/rib?invalid=1")
+	return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+}
+
+// ShowRIBInFilteredRequest retrieves all routes
+// filtered on input.
+func (src *BgplgdSource) ShowRIBInFilteredRequest(ctx context.Context) (*http.Request, error) {
+	url := src.cfg.APIURL("/rib?in=1&filtered=1")
+	return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+}
+
+// ShowBestRIBRequest retrieves the best routes
+func (src *BgplgdSource) ShowBestRIBRequest(ctx context.Context) (*http.Request, error) {
+	url := src.cfg.APIURL("/rib?best=1")
+	return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+}
+
 // ShowRIBRequest makes a request for retrieving all routes imported
 // from all peers
 func (src *BgplgdSource) ShowRIBRequest(ctx context.Context) (*http.Request, error) {
@@ -149,7 +213,7 @@ func (src *BgplgdSource) Neighbors(
 	if err != nil {
 		return nil, err
 	}
-	body, err := decoders.ReadJSONResponse(res)
+	body, err := readJSONResponse(res)
 	if err != nil {
 		return nil, err
 	}
@@ -202,7 +266,7 @@ func (src *BgplgdSource) NeighborsSummary(
 	if err != nil {
 		return nil, err
 	}
-	body, err := decoders.ReadJSONResponse(res)
+	body, err := readJSONResponse(res)
 	if err != nil {
 		return nil, err
 	}
@@ -243,7 +307,7 @@ func (src *BgplgdSource) NeighborsStatus(
 	}
 
 	// Read and decode response
-	body, err := decoders.ReadJSONResponse(res)
+	body, err := readJSONResponse(res)
 	if err != nil {
 		return nil, err
 	}
@@ -274,32 +338,11 @@ func (src *BgplgdSource) Routes(
 		return response, nil
 	}
 
-	// Query RIB for routes received
-	req, err := src.ShowNeighborRIBRequest(ctx, neighborID)
-	if err != nil {
-		return nil, err
-	}
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	// Read and decode response
-	body, err := decoders.ReadJSONResponse(res)
-	if err != nil {
-		return nil, err
-	}
-
-	routes, err := decodeRoutes(body)
+	received, rejected, err := src.neighborRoutes(ctx, neighborID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Filtered routes are marked with a large BGP community
-	// as defined in the reject reasons.
-	received := filterReceivedRoutes(src.cfg.RejectCommunities, routes)
-	rejected := filterRejectedRoutes(src.cfg.RejectCommunities, routes)
-
 	response = &api.RoutesResponse{
 		Response: api.Response{
 			Meta: src.makeResponseMeta(),
@@ -324,31 +367,11 @@ func (src *BgplgdSource) RoutesReceived(
 		return response, nil
 	}
 
-	// Query RIB for routes received
-	req, err := src.ShowNeighborRIBRequest(ctx, neighborID)
-	if err != nil {
-		return nil, err
-	}
-	res, err := http.DefaultClient.Do(req)
+	received, _, err := src.neighborRoutes(ctx, neighborID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Read and decode response
-	body, err := decoders.ReadJSONResponse(res)
-	if err != nil {
-		return nil, err
-	}
-
-	routes, err := decodeRoutes(body)
-	if err != nil {
-		return nil, err
-	}
-
-	// Filtered routes are marked with a large BGP community
-	// as defined in the reject reasons.
-	received := filterReceivedRoutes(src.cfg.RejectCommunities, routes)
-
 	response = &api.RoutesResponse{
 		Response: api.Response{
 			Meta: src.makeResponseMeta(),
@@ -373,31 +396,11 @@ func (src *BgplgdSource) RoutesFiltered(
 		return response, nil
 	}
 
-	// Query RIB for routes received
-	req, err := src.ShowNeighborRIBRequest(ctx, neighborID)
-	if err != nil {
-		return nil, err
-	}
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	// Read and decode response
-	body, err := decoders.ReadJSONResponse(res)
+	_, rejected, err := src.neighborRoutes(ctx, neighborID)
 	if err != nil {
 		return nil, err
 	}
 
-	routes, err := decodeRoutes(body)
-	if err != nil {
-		return nil, err
-	}
-
-	// Filtered routes are marked with a large BGP community
-	// as defined in the reject reasons.
-	rejected := filterRejectedRoutes(src.cfg.RejectCommunities, routes)
-
 	response = &api.RoutesResponse{
 		Response: api.Response{
 			Meta: src.makeResponseMeta(),
@@ -411,20 +414,77 @@ func (src *BgplgdSource) RoutesFiltered(
 	return response, nil
 }
 
+// bestRIB retrieves the best routes of the Loc-RIB.
+// The routes are requested once for all neighbors
+// until the cache expires.
+func (src *BgplgdSource) bestRIB(
+	ctx context.Context,
+) (api.Routes, error) {
+	src.bestRIBLock.Lock()
+	defer src.bestRIBLock.Unlock()
+	if response := src.bestRIBCache.Get("best"); response != nil {
+		return response.Imported, nil
+	}
+
+	req, err := src.ShowBestRIBRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	best, err := src.requestRoutes(req)
+	if err != nil {
+		return nil, err
+	}
+	src.bestRIBCache.Set("best", &api.RoutesResponse{
+		Response: api.Response{
+			Meta: src.makeResponseMeta(),
+		},
+		Imported: best,
+	})
+	return best, nil
+}
+
 // RoutesNotExported retrievs the routes not exported
-// from the rs for a neighbor.
+// from the rs for a neighbor: The best routes of the
+// Loc-RIB missing in the Adj-RIB-Out of the neighbor.
 func (src *BgplgdSource) RoutesNotExported(
 	ctx context.Context,
 	neighborID string,
 ) (*api.RoutesResponse, error) {
-	response := &api.RoutesResponse{
+	response := src.routesNotExportedCache.Get(neighborID)
+	if response != nil {
+		response.Meta.ResultFromCache = true
+		return response, nil
+	}
+
+	best, err := src.bestRIB(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := src.ShowNeighborRIBOutRequest(ctx, neighborID)
+	if err != nil {
+		return nil, err
+	}
+	exported, err := src.requestRoutes(req)
+	if err != nil {
+		return nil, err
+	}
+
+	notExported := filterNotExportedRoutes(neighborID, best, exported)
+	tagReason(
+		notExported,
+		parseReason(src.cfg.NoexportReason),
+		src.cfg.NoexportCommunities)
+
+	response = &api.RoutesResponse{
 		Response: api.Response{
 			Meta: src.makeResponseMeta(),
 		},
 		Imported:    api.Routes{},
-		NotExported: api.Routes{},
+		NotExported: notExported,
 		Filtered:    api.Routes{},
 	}
+	src.routesNotExportedCache.Set(neighborID, response)
+
 	return response, nil
 }
 
@@ -433,31 +493,24 @@ func (src *BgplgdSource) RoutesNotExported(
 func (src *BgplgdSource) AllRoutes(
 	ctx context.Context,
 ) (*api.RoutesResponse, error) {
-	req, err := src.ShowRIBRequest(ctx)
+	locRIB, err := src.ShowRIBRequest(ctx)
 	if err != nil {
 		return nil, err
 	}
-	res, err := http.DefaultClient.Do(req)
+	ineligible, err := src.ShowRIBInvalidRequest(ctx)
 	if err != nil {
 		return nil, err
 	}
-
-	// Read and decode response
-	body, err := decoders.ReadJSONResponse(res)
+	filtered, err := src.ShowRIBInFilteredRequest(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	routes, err := decodeRoutes(body)
+	received, rejected, err := src.splitRoutes(locRIB, ineligible, filtered)
 	if err != nil {
 		return nil, err
 	}
 
-	// Filtered routes are marked with a large BGP community
-	// as defined in the reject reasons.
-	received := filterReceivedRoutes(src.cfg.RejectCommunities, routes)
-	rejected := filterRejectedRoutes(src.cfg.RejectCommunities, routes)
-
 	response := &api.RoutesResponse{
 		Response: api.Response{
 			Meta: src.makeResponseMeta(),
@@ -468,3 +521,80 @@ func (src *BgplgdSource) AllRoutes(
 	}
 	return response, nil
 }
+
+// requestRoutes makes a rib request and decodes the routes
+func (src *BgplgdSource) requestRoutes(req *http.Request) (api.Routes, error) {
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if err := checkResponseStatus(res); err != nil {
+		return nil, err
+	}
+	return decodeRoutesStream(res.Body, src.cfg.streamParserThrottle())
+}
+
+// neighborRoutes retrieves the received and the
+// filtered routes of a neighbor.
+func (src *BgplgdSource) neighborRoutes(
+	ctx context.Context,
+	neighborID string,
+) (api.Routes, api.Routes, error) {
+	locRIB, err := src.ShowNeighborRIBRequest(ctx, neighborID)
+	if err != nil {
+		return nil, nil, err
+	}
+	ineligible, err := src.ShowNeighborRIBInvalidRequest(ctx, neighborID)
+	if err != nil {
+		return nil, nil, err
+	}
+	filtered, err := src.ShowNeighborRIBInFilteredRequest(ctx, neighborID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return src.splitRoutes(locRIB, ineligible, filtered)
+}
+
+// splitRoutes retrieves the routes from the Loc-RIB, the
+// routes which are not eligible and the routes filtered
+// from the Adj-RIB-In and splits them into received and
+// filtered routes.
+//
+// Filtered are the routes marked with a reject community,
+// the routes not eligible and the routes filtered by the
+// input filters. The routes without a reject community
+// are tagged with the reason of the configuration.
+func (src *BgplgdSource) splitRoutes(
+	locRIBReq *http.Request,
+	ineligibleReq *http.Request,
+	filteredReq *http.Request,
+) (api.Routes, api.Routes, error) {
+	locRIB, err := src.requestRoutes(locRIBReq)
+	if err != nil {
+		return nil, nil, err
+	}
+	ineligible, err := src.requestRoutes(ineligibleReq)
+	if err != nil {
+		return nil, nil, err
+	}
+	filtered, err := src.requestRoutes(filteredReq)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Filtered routes are marked with a large BGP community
+	// as defined in the reject reasons.
+	reasons := src.cfg.RejectCommunities
+	received := filterReceivedRoutes(reasons, locRIB)
+	received = excludeRoutes(received, ineligible)
+	rejected := filterRejectedRoutes(reasons, locRIB)
+	rejected = excludeRoutes(rejected, ineligible)
+
+	tagReason(ineligible, parseReason(src.cfg.IneligibleReason), reasons)
+	tagReason(filtered, parseReason(src.cfg.FilteredReason), reasons)
+
+	rejected = append(rejected, ineligible...)
+	rejected = append(rejected, filtered...)
+	return received, rejected, nil
+}
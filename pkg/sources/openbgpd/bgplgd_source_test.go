@@ -0,0 +1,159 @@
+package openbgpd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+)
+
+// testRIB creates a rib response
+func testRIB(neighbor string, prefixes ...string) string {
+	rib := ""
+	for i, prefix := range prefixes {
+		if i > 0 {
+			rib += ","
+		}
+		rib += `{"prefix": "` + prefix + `", "aspath": "64500",
+			"neighbor": {"remote_addr": "` + neighbor + `"},
+			"localpref": 100, "large_communities": []}`
+	}
+	return `{"rib": [` + rib + `]}`
+}
+
+func TestBgplgdFilteredAndNotExported(t *testing.T) {
+	n1, n2 := "192.0.2.1", "192.0.2.2"
+	responses := map[string]string{
+		"neighbor=" + n1:                      testRIB(n1, "10.0.0.0/24", "10.0.1.0/24"),
+		"neighbor=" + n1 + "&invalid=1":       testRIB(n1, "10.0.1.0/24"),
+		"neighbor=" + n1 + "&in=1&filtered=1": testRIB(n1, "10.0.2.0/24"),
+		"neighbor=" + n1 + "&out=1":           testRIB(n1, "10.1.0.0/24"),
+		"best=1":                              testRIB(n2, "10.1.0.0/24", "10.1.1.0/24"),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			res, ok := responses[r.URL.RawQuery]
+			if !ok {
+				t.Error("unexpected query:", r.URL.RawQuery)
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(res))
+		}))
+	defer srv.Close()
+
+	src := NewBgplgdSource(&Config{
+		ID:               "rs1",
+		API:              srv.URL,
+		FilteredReason:   "9999:666:1",
+		IneligibleReason: "9999:666:2",
+		NoexportReason:   "9999:667:1",
+	})
+	ctx := context.Background()
+
+	routes, err := src.Routes(ctx, n1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes.Imported) != 1 || routes.Imported[0].Network != "10.0.0.0/24" {
+		t.Error("unexpected imported routes:", routes.Imported)
+	}
+	if len(routes.Filtered) != 2 {
+		t.Fatal("unexpected filtered routes:", routes.Filtered)
+	}
+	reasons := map[string]api.Community{
+		"10.0.1.0/24": {9999, 666, 2},
+		"10.0.2.0/24": {9999, 666, 1},
+	}
+	for _, r := range routes.Filtered {
+		if !r.BGP.HasLargeCommunity(reasons[r.Network]) {
+			t.Error("expected reason for", r.Network, "got:", r.BGP.LargeCommunities)
+		}
+	}
+
+	notExported, err := src.RoutesNotExported(ctx, n1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notExported.NotExported) != 1 {
+		t.Fatal("unexpected not exported routes:", notExported.NotExported)
+	}
+	r := notExported.NotExported[0]
+	if r.Network != "10.1.1.0/24" || !r.BGP.HasLargeCommunity(api.Community{9999, 667, 1}) {
+		t.Error("unexpected not exported route:", r)
+	}
+}
+
+func TestBgplgdNotExportedBestRIBCache(t *testing.T) {
+	n1, n2, n3 := "192.0.2.1", "192.0.2.2", "192.0.2.3"
+	responses := map[string]string{
+		"neighbor=" + n1 + "&out=1": testRIB(n1, "10.1.0.0/24"),
+		"neighbor=" + n3 + "&out=1": testRIB(n3),
+		"best=1":                    testRIB(n2, "10.1.0.0/24", "10.1.1.0/24"),
+	}
+	bestQueries := 0
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.RawQuery == "best=1" {
+				bestQueries++
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(responses[r.URL.RawQuery]))
+		}))
+	defer srv.Close()
+
+	src := NewBgplgdSource(&Config{
+		ID:              "rs1",
+		API:             srv.URL,
+		CacheTTL:        time.Minute,
+		RoutesCacheSize: 16,
+		NoexportReason:  "9999:667:1",
+	})
+	ctx := context.Background()
+	if _, err := src.RoutesNotExported(ctx, n1); err != nil {
+		t.Fatal(err)
+	}
+	res, err := src.RoutesNotExported(ctx, n3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bestQueries != 1 {
+		t.Error("expected the best routes to be requested once, got:", bestQueries)
+	}
+
+	// The shared best routes are not modified
+	if len(res.NotExported) != 2 {
+		t.Fatal("unexpected not exported routes:", res.NotExported)
+	}
+	for _, r := range res.NotExported {
+		if len(r.BGP.LargeCommunities) != 1 {
+			t.Error("unexpected communities:", r.BGP.LargeCommunities)
+		}
+	}
+}
+
+func TestBgplgdRoutesStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "bgpctl failed", http.StatusInternalServerError)
+		}))
+	defer srv.Close()
+
+	src := NewBgplgdSource(&Config{
+		ID:  "rs1",
+		API: srv.URL,
+	})
+	_, err := src.Routes(context.Background(), "192.0.2.1")
+	if !errors.Is(err, ErrUnexpectedStatus) {
+		t.Fatal("expected unexpected status, got:", err)
+	}
+	if !strings.Contains(err.Error(), "500") {
+		t.Error("expected status in error:", err)
+	}
+}
@@ -16,9 +16,27 @@ type Config struct {
 	CacheTTL        time.Duration
 	RoutesCacheSize int
 
+	// StreamParserThrottle is the time in nanoseconds
+	// to wait after decoding a route.
+	StreamParserThrottle int
+
 	API string `ini:"api"`
 
-	RejectCommunities api.Communities
+	RejectCommunities   api.Communities
+	NoexportCommunities api.Communities
+
+	// bgpd does not tag the routes it filters. These routes
+	// are tagged with the large community of a reason from
+	// the rejection_reasons or noexport_reasons, unless they
+	// are tagged with a reason already.
+	FilteredReason   string `ini:"filtered_reason"`
+	IneligibleReason string `ini:"ineligible_reason"`
+	NoexportReason   string `ini:"noexport_reason"`
+}
+
+// streamParserThrottle returns the throttle duration
+func (cfg *Config) streamParserThrottle() time.Duration {
+	return time.Duration(cfg.StreamParserThrottle) * time.Nanosecond
 }
 
 // APIURL creates an url from the config
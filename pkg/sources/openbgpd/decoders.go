@@ -2,14 +2,18 @@ package openbgpd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/alice-lg/alice-lg/pkg/api"
 	"github.com/alice-lg/alice-lg/pkg/decoders"
 	"github.com/alice-lg/alice-lg/pkg/pools"
+	"github.com/alice-lg/alice-lg/pkg/sources"
 )
 
 // Decode the api status response from the openbgpd
@@ -53,7 +57,8 @@ func decodeNeighbor(n interface{}) (*api.Neighbor, error) {
 		RoutesExported: int(decoders.MapGet(prefixes, "sent", -1).(float64)),
 		// TODO: RoutesPreferred
 		// TODO: RoutesAccepted
-		Uptime: decoders.DurationTimeframe(decoders.MapGet(nb, "last_updown", ""), 0),
+		Uptime:  decoders.DurationTimeframe(decoders.MapGet(nb, "last_updown", ""), 0),
+		Session: decodeNeighborSession(nb),
 	}
 	return neighbor, nil
 }
@@ -126,31 +131,105 @@ func decodeNeighborStatus(nb interface{}) *api.NeighborStatus {
 	}
 }
 
-// decodeRoutes decodes a response with a rib query.
-// The toplevel element is expected to be "rib".
-func decodeRoutes(res interface{}) (api.Routes, error) {
-	r := decoders.MapGet(res, "rib", nil)
-	if r == nil {
-		// The response was a valid json but empty. So no
-		// routes are present.
-		return api.Routes{}, nil
+// ErrInvalidRIB is returned when the response of
+// a rib query can not be decoded.
+var ErrInvalidRIB = errors.New("invalid rib response")
+
+// ErrUnexpectedStatus is returned when a rib query
+// is not answered with 200 OK.
+var ErrUnexpectedStatus = errors.New("unexpected status")
+
+// checkResponseStatus checks the status of a response
+// before the body is decoded.
+func checkResponseStatus(res *http.Response) error {
+	if err := sources.CheckResponseStatus(res); err != nil {
+		return fmt.Errorf("%w: %w", ErrUnexpectedStatus, err)
 	}
-	rib, ok := r.([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("not a list of interfaces")
+	return nil
+}
+
+// readJSONResponse checks the status of the
+// response and decodes the body.
+func readJSONResponse(res *http.Response) (map[string]interface{}, error) {
+	if err := checkResponseStatus(res); err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+	return decoders.ReadJSONResponse(res)
+}
+
+// decodeRoutesStream decodes a response with a rib query.
+// The toplevel element is expected to be "rib". The routes
+// are decoded one at a time, waiting for the throttle
+// after each route.
+func decodeRoutesStream(
+	body io.Reader,
+	throttle time.Duration,
+) (api.Routes, error) {
+	dec := json.NewDecoder(body)
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
 	}
-	routes := make(api.Routes, 0, len(rib))
-	for _, details := range rib {
-		route, err := decodeRoute(details.(map[string]interface{}))
+	routes := api.Routes{}
+	for dec.More() {
+		t, err := dec.Token()
 		if err != nil {
 			return nil, err
 		}
-		routes = append(routes, route)
-	}
+		if t != "rib" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// The response was a valid json but the rib
+		// is empty. So no routes are present.
+		t, err = dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if t == nil {
+			continue
+		}
+		if t != json.Delim('[') {
+			return nil, fmt.Errorf("%w: not a list of routes", ErrInvalidRIB)
+		}
+		for dec.More() {
+			details := make(map[string]interface{})
+			if err := dec.Decode(&details); err != nil {
+				return nil, err
+			}
 
+			// Wait a bit, so our CPUs do not go up in flames.
+			time.Sleep(throttle)
+
+			route, err := decodeRoute(details)
+			if err != nil {
+				return nil, err
+			}
+			routes = append(routes, route)
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return nil, err
+		}
+	}
 	return routes, nil
 }
 
+// expectDelim reads the next token, which must be the delimiter
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if t != delim {
+		return fmt.Errorf("%w: expected %s, got %v", ErrInvalidRIB, delim, t)
+	}
+	return nil
+}
+
 // decodeRoute decodes a single route received from the source
 func decodeRoute(details map[string]interface{}) (*api.Route, error) {
 	prefix := decoders.MapGetString(details, "prefix", "")
@@ -2,8 +2,11 @@ package openbgpd
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -28,6 +31,20 @@ func TestDecodeNeighbors(t *testing.T) {
 		t.Fatal(err)
 	}
 	t.Log(n[0])
+
+	session := n[0].Session
+	if session.Timers.HoldTime != 90 || session.Timers.KeepaliveTime != 30 {
+		t.Error("unexpected timers:", session.Timers)
+	}
+	caps := session.Capabilities
+	if !caps.FourOctetASN || caps.RouteRefresh ||
+		len(caps.AFISAFIs) != 1 || caps.AFISAFIs[0] != "ipv4-unicast" {
+		t.Error("unexpected capabilities:", caps)
+	}
+	limit := session.ImportLimit
+	if limit.Limit != 577 || limit.Routes != 1 || limit.Action != "restart" {
+		t.Error("unexpected import limit:", limit)
+	}
 }
 
 func TestDecodeNeighborsStatus(t *testing.T) {
@@ -43,8 +60,12 @@ func TestDecodeNeighborsStatus(t *testing.T) {
 }
 
 func TestDecodeRoutes(t *testing.T) {
-	res := readTestData("rib.json")
-	routes, err := decodeRoutes(res)
+	f, err := os.Open("testdata/rib.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	routes, err := decodeRoutesStream(f, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -82,6 +103,40 @@ func TestDecodeRoutes(t *testing.T) {
 	t.Log(r.Age)
 }
 
+func TestDecodeRoutesStream(t *testing.T) {
+	// The rib may be preceded by other keys or be empty
+	body := `{"meta": {"rib": [1, 2]}, "rib": [
+		{"prefix": "10.0.0.0/24", "aspath": "64500", "localpref": 100}
+	], "more": true}`
+	routes, err := decodeRoutesStream(strings.NewReader(body), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 1 || routes[0].Network != "10.0.0.0/24" {
+		t.Error("unexpected routes:", routes)
+	}
+
+	routes, err = decodeRoutesStream(strings.NewReader(`{"rib": null}`), 0)
+	if err != nil || len(routes) != 0 {
+		t.Error("expected no routes, got:", routes, err)
+	}
+	routes, err = decodeRoutesStream(strings.NewReader(`{}`), 0)
+	if err != nil || len(routes) != 0 {
+		t.Error("expected no routes, got:", routes, err)
+	}
+
+	invalid := []string{`[]`, `{"rib": {}}`, `{"rib": [`}
+	for _, body := range invalid {
+		if _, err := decodeRoutesStream(strings.NewReader(body), 0); err == nil {
+			t.Error("expected error for", body)
+		}
+	}
+	_, err = decodeRoutesStream(strings.NewReader(`{"rib": 42}`), 0)
+	if !errors.Is(err, ErrInvalidRIB) {
+		t.Error("expected invalid rib error, got:", err)
+	}
+}
+
 func TestDecodeExtendedCommunities(t *testing.T) {
 	data := []interface{}{"rt 123:456", "error invalid community"}
 	comms := decodeExtendedCommunities(data)
@@ -1,7 +1,12 @@
 package openbgpd
 
 import (
+	"log"
+	"strings"
+
 	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/decoders"
+	"github.com/alice-lg/alice-lg/pkg/pools"
 )
 
 func filterReceivedRoutes(
@@ -43,3 +48,105 @@ func filterRejectedRoutes(
 	}
 	return filtered
 }
+
+// hasReason checks if the route is tagged with
+// one of the reason communities.
+func hasReason(reasons api.Communities, r *api.Route) bool {
+	for _, c := range reasons {
+		if r.BGP.HasLargeCommunity(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseReason parses the large community of a reason.
+// Invalid communities are ignored.
+func parseReason(s string) api.Community {
+	if s == "" {
+		return nil
+	}
+	tokens := strings.Split(s, ":")
+	c := decoders.IntListFromStrings(tokens)
+	if len(tokens) != 3 || len(c) != 3 {
+		log.Println("openbgpd: invalid reason community:", s)
+		return nil
+	}
+	return c
+}
+
+// tagReason adds the large community of the reason to
+// the routes, which are not tagged with a reason.
+func tagReason(routes api.Routes, reason api.Community, reasons api.Communities) {
+	if reason == nil {
+		return
+	}
+	for _, r := range routes {
+		if hasReason(reasons, r) {
+			continue
+		}
+		comms := make(api.Communities, 0, len(r.BGP.LargeCommunities)+1)
+		comms = append(comms, r.BGP.LargeCommunities...)
+		comms = append(comms, reason)
+		r.BGP.LargeCommunities = pools.LargeCommunitiesSets.Acquire(comms)
+	}
+}
+
+// routeKey identifies a route of a neighbor
+func routeKey(r *api.Route) string {
+	neighborID := ""
+	if r.NeighborID != nil {
+		neighborID = *r.NeighborID
+	}
+	return neighborID + " " + r.Network
+}
+
+// excludeRoutes returns the routes not present in
+// the excluded routes.
+func excludeRoutes(routes api.Routes, excluded api.Routes) api.Routes {
+	if len(excluded) == 0 {
+		return routes
+	}
+	keys := make(map[string]struct{}, len(excluded))
+	for _, r := range excluded {
+		keys[routeKey(r)] = struct{}{}
+	}
+	result := make(api.Routes, 0, len(routes))
+	for _, r := range routes {
+		if _, ok := keys[routeKey(r)]; !ok {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// filterNotExportedRoutes returns the best routes which
+// are not in the Adj-RIB-Out of the neighbor. Routes
+// learned from the neighbor are never exported to it.
+// The routes are copied, as the best routes are shared
+// by all neighbors.
+func filterNotExportedRoutes(
+	neighborID string,
+	best api.Routes,
+	exported api.Routes,
+) api.Routes {
+	prefixes := make(map[string]struct{}, len(exported))
+	for _, r := range exported {
+		prefixes[r.Key()] = struct{}{}
+	}
+	notExported := make(api.Routes, 0)
+	for _, r := range best {
+		if r.NeighborID != nil && *r.NeighborID == neighborID {
+			continue
+		}
+		if _, ok := prefixes[r.Key()]; !ok {
+			route := *r
+			if r.BGP != nil {
+				bgp := *r.BGP
+				route.BGP = &bgp
+			}
+			notExported = append(notExported, &route)
+		}
+	}
+	return notExported
+}
@@ -93,3 +93,51 @@ func TestFilterRejectedRoutes(t *testing.T) {
 		t.Error("unexpected route:", filtered[0])
 	}
 }
+
+func TestTagReason(t *testing.T) {
+	reasons := api.Communities{
+		api.Community{9999, 666, 1},
+		api.Community{9999, 666, 2},
+	}
+	routes := api.Routes{
+		&api.Route{
+			Network: "1.2.3.0/24",
+			BGP: &api.BGPInfo{
+				LargeCommunities: api.Communities{
+					api.Community{9999, 666, 1},
+				},
+			},
+		},
+		&api.Route{
+			Network: "5.6.7.0/24",
+			BGP:     &api.BGPInfo{},
+		},
+	}
+	tagReason(routes, parseReason("9999:666:2"), reasons)
+	if len(routes[0].BGP.LargeCommunities) != 1 {
+		t.Error("tagged route should not be tagged again")
+	}
+	if !routes[1].BGP.HasLargeCommunity(api.Community{9999, 666, 2}) {
+		t.Error("expected route to be tagged:", routes[1].BGP)
+	}
+
+	if parseReason("9999:666") != nil || parseReason("a:b:c") != nil {
+		t.Error("invalid reasons should be ignored")
+	}
+}
+
+func TestFilterNotExportedRoutes(t *testing.T) {
+	n1, n2 := "192.0.2.1", "192.0.2.2"
+	best := api.Routes{
+		&api.Route{NeighborID: &n1, Network: "10.0.0.0/24"},
+		&api.Route{NeighborID: &n2, Network: "10.0.1.0/24"},
+		&api.Route{NeighborID: &n2, Network: "10.0.2.0/24"},
+	}
+	exported := api.Routes{
+		&api.Route{NeighborID: &n1, Network: "10.0.1.0/24"},
+	}
+	notExported := filterNotExportedRoutes(n1, best, exported)
+	if len(notExported) != 1 || notExported[0].Network != "10.0.2.0/24" {
+		t.Error("unexpected not exported routes:", notExported)
+	}
+}
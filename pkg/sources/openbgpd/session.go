@@ -0,0 +1,126 @@
+package openbgpd
+
+import (
+	"strings"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/decoders"
+)
+
+// afiSafis maps the bgpctl address families
+var afiSafis = map[string]string{
+	"IPv4 unicast":  api.AFISAFIIPv4Unicast,
+	"IPv6 unicast":  api.AFISAFIIPv6Unicast,
+	"IPv4 flowspec": api.AFISAFIIPv4Flowspec,
+	"IPv6 flowspec": api.AFISAFIIPv6Flowspec,
+	"IPv4 vpn":      api.AFISAFIIPv4VPNUnicast,
+	"IPv6 vpn":      api.AFISAFIIPv6VPNUnicast,
+}
+
+// decodeAFISAFI decodes an address family like "IPv4 unicast"
+func decodeAFISAFI(family string) string {
+	if afiSafi, ok := afiSafis[family]; ok {
+		return afiSafi
+	}
+	return strings.ReplaceAll(strings.ToLower(family), " ", "-")
+}
+
+// decodeAddPathMode decodes the add-path modes
+// recv, send and recv/send.
+func decodeAddPathMode(mode string) string {
+	switch mode {
+	case "recv":
+		return api.AddPathReceive
+	case "send":
+		return api.AddPathSend
+	case "recv/send", "send/recv":
+		return api.AddPathSendReceive
+	}
+	return mode
+}
+
+// decodeCapabilities decodes the negotiated capabilities
+// of the session.
+func decodeCapabilities(c interface{}) *api.NeighborCapabilities {
+	if c == nil {
+		return nil
+	}
+	caps := &api.NeighborCapabilities{
+		RouteRefresh:         decoders.MapGetBool(c, "refresh", false),
+		EnhancedRouteRefresh: decoders.MapGetBool(c, "enhanced_refresh", false),
+		FourOctetASN:         decoders.MapGetBool(c, "as4byte", false),
+		ExtendedMessage:      decoders.MapGetBool(c, "extended_message", false),
+	}
+	for _, family := range decoders.StringList(
+		decoders.MapGet(c, "multiprotocol", nil)) {
+		caps.AFISAFIs = append(caps.AFISAFIs, decodeAFISAFI(family))
+	}
+
+	addPath, _ := decoders.MapGet(c, "add-path", nil).([]interface{})
+	for _, a := range addPath {
+		caps.AddPath = append(caps.AddPath, &api.AddPathCapability{
+			AFISAFI: decodeAFISAFI(decoders.MapGetString(a, "family", "")),
+			Mode:    decodeAddPathMode(decoders.MapGetString(a, "mode", "")),
+		})
+	}
+
+	gr := decoders.MapGet(c, "graceful_restart", nil)
+	if gr != nil {
+		caps.GracefulRestart = &api.GracefulRestartCapability{
+			Restarting: decoders.MapGetBool(gr, "restart", false),
+		}
+		protocols, _ := decoders.MapGet(gr, "protocols", nil).([]interface{})
+		for _, p := range protocols {
+			caps.GracefulRestart.AFISAFIs = append(
+				caps.GracefulRestart.AFISAFIs,
+				decodeAFISAFI(decoders.MapGetString(p, "family", "")))
+		}
+	}
+	return caps
+}
+
+// decodeRouteLimit decodes a max-prefix limit
+func decodeRouteLimit(config interface{}, key string, routes int) *api.RouteLimit {
+	limit := decoders.Int(decoders.MapGet(config, key, nil), 0)
+	if limit == 0 {
+		return nil
+	}
+	rl := &api.RouteLimit{
+		Limit:  limit,
+		Routes: routes,
+	}
+	if decoders.Int(decoders.MapGet(config, key+"_restart", nil), 0) > 0 {
+		rl.Action = "restart"
+	}
+	return rl
+}
+
+// decodeNeighborSession decodes the session details
+// of a neighbor. The timers and capabilities are only
+// present if the session is established.
+func decodeNeighborSession(nb interface{}) *api.NeighborSession {
+	config := decoders.MapGet(nb, "config", nil)
+	session := decoders.MapGet(nb, "session", nil)
+	stats := decoders.MapGet(nb, "stats", nil)
+	prefixes := decoders.MapGet(stats, "prefixes", nil)
+
+	s := &api.NeighborSession{
+		LastError: api.ParseBGPError(
+			decoders.MapGetString(nb, "last_error", "")),
+		ImportLimit: decodeRouteLimit(config, "max_prefix",
+			decoders.Int(decoders.MapGet(prefixes, "received", nil), 0)),
+		ExportLimit: decodeRouteLimit(config, "max_out_prefix",
+			decoders.Int(decoders.MapGet(prefixes, "sent", nil), 0)),
+	}
+	if session != nil {
+		s.Timers = &api.NeighborTimers{
+			HoldTime: decoders.Int(
+				decoders.MapGet(session, "holdtime", nil), 0),
+			KeepaliveTime: decoders.Int(
+				decoders.MapGet(session, "keepalive", nil), 0),
+		}
+		s.Capabilities = decodeCapabilities(
+			decoders.MapGet(session, "capabilities", nil))
+	}
+	return s
+}
@@ -7,7 +7,6 @@ import (
 
 	"github.com/alice-lg/alice-lg/pkg/api"
 	"github.com/alice-lg/alice-lg/pkg/caches"
-	"github.com/alice-lg/alice-lg/pkg/decoders"
 	"github.com/alice-lg/alice-lg/pkg/sources"
 )
 
@@ -139,7 +138,7 @@ func (src *StateServerSource) Status(
 	if err != nil {
 		return nil, err
 	}
-	body, err := decoders.ReadJSONResponse(res)
+	body, err := readJSONResponse(res)
 	if err != nil {
 		return nil, err
 	}
@@ -173,7 +172,7 @@ func (src *StateServerSource) Neighbors(
 	if err != nil {
 		return nil, err
 	}
-	body, err := decoders.ReadJSONResponse(res)
+	body, err := readJSONResponse(res)
 	if err != nil {
 		return nil, err
 	}
@@ -226,7 +225,7 @@ func (src *StateServerSource) NeighborsSummary(
 	if err != nil {
 		return nil, err
 	}
-	body, err := decoders.ReadJSONResponse(res)
+	body, err := readJSONResponse(res)
 	if err != nil {
 		return nil, err
 	}
@@ -266,7 +265,7 @@ func (src *StateServerSource) NeighborsStatus(
 	}
 
 	// Read and decode response
-	body, err := decoders.ReadJSONResponse(res)
+	body, err := readJSONResponse(res)
 	if err != nil {
 		return nil, err
 	}
@@ -302,18 +301,7 @@ func (src *StateServerSource) Routes(
 	if err != nil {
 		return nil, err
 	}
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	// Read and decode response
-	body, err := decoders.ReadJSONResponse(res)
-	if err != nil {
-		return nil, err
-	}
-
-	routes, err := decodeRoutes(body)
+	routes, err := src.requestRoutes(req)
 	if err != nil {
 		return nil, err
 	}
@@ -352,18 +340,7 @@ func (src *StateServerSource) RoutesReceived(
 	if err != nil {
 		return nil, err
 	}
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	// Read and decode response
-	body, err := decoders.ReadJSONResponse(res)
-	if err != nil {
-		return nil, err
-	}
-
-	routes, err := decodeRoutes(body)
+	routes, err := src.requestRoutes(req)
 	if err != nil {
 		return nil, err
 	}
@@ -399,18 +376,7 @@ func (src *StateServerSource) RoutesFiltered(
 	if err != nil {
 		return nil, err
 	}
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	// Read and decode response
-	body, err := decoders.ReadJSONResponse(res)
-	if err != nil {
-		return nil, err
-	}
-
-	routes, err := decodeRoutes(body)
+	routes, err := src.requestRoutes(req)
 	if err != nil {
 		return nil, err
 	}
@@ -456,18 +422,7 @@ func (src *StateServerSource) AllRoutes(
 	if err != nil {
 		return nil, err
 	}
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	// Read and decode response
-	body, err := decoders.ReadJSONResponse(res)
-	if err != nil {
-		return nil, err
-	}
-
-	routes, err := decodeRoutes(body)
+	routes, err := src.requestRoutes(req)
 	if err != nil {
 		return nil, err
 	}
@@ -487,3 +442,16 @@ func (src *StateServerSource) AllRoutes(
 	}
 	return response, nil
 }
+
+// requestRoutes makes a rib request and decodes the routes
+func (src *StateServerSource) requestRoutes(req *http.Request) (api.Routes, error) {
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if err := checkResponseStatus(res); err != nil {
+		return nil, err
+	}
+	return decodeRoutesStream(res.Body, src.cfg.streamParserThrottle())
+}
@@ -18,6 +18,14 @@ var (
 	// ErrSourceBusy is returned when a refresh is
 	// already in progress.
 	ErrSourceBusy = errors.New("source is busy")
+
+	// ErrSourceUnavailable is returned while the circuit
+	// breaker of a source is open.
+	ErrSourceUnavailable = errors.New("source is unavailable")
+
+	// ErrSourceClosed is returned by a source which
+	// was closed, because it was replaced or removed.
+	ErrSourceClosed = errors.New("source is closed")
 )
 
 // Source is a generic datasource for alice.
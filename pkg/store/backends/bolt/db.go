@@ -0,0 +1,162 @@
+package bolt
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/alice-lg/alice-lg/pkg/config"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+var (
+	// ErrPathUnconfigured will be returned, if the
+	// path of the database file is not set.
+	ErrPathUnconfigured = errors.New("database path not configured")
+)
+
+// Buckets: The values of a source are stored in a
+// nested bucket in the bucket of the kind, e.g.
+// neighbors/rs1/<seq>. Routes are stored in a bucket
+// per neighbor: routes/rs1/<neighborID>/<route key>.
+// The time of the last update is stored in
+// updated_at/<kind>/<sourceID>.
+var (
+	bucketNeighbors = []byte("neighbors")
+	bucketRoutes    = []byte("routes")
+	bucketUpdatedAt = []byte("updated_at")
+)
+
+// Open opens the database file and creates the buckets.
+func Open(opts *config.BoltConfig) (*bbolt.DB, error) {
+	if opts.Path == "" {
+		return nil, ErrPathUnconfigured
+	}
+	db, err := bbolt.Open(opts.Path, 0600, &bbolt.Options{
+		Timeout: 10 * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		updatedAt, err := tx.CreateBucketIfNotExists(bucketUpdatedAt)
+		if err != nil {
+			return err
+		}
+		for _, kind := range [][]byte{bucketNeighbors, bucketRoutes} {
+			if _, err := tx.CreateBucketIfNotExists(kind); err != nil {
+				return err
+			}
+			if _, err := updatedAt.CreateBucketIfNotExists(kind); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Private putSource replaces the values of a source
+func putSource(
+	db *bbolt.DB,
+	kind []byte,
+	sourceID string,
+	values [][]byte,
+	now time.Time,
+) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(kind)
+		key := []byte(sourceID)
+		if bucket.Bucket(key) != nil {
+			if err := bucket.DeleteBucket(key); err != nil {
+				return err
+			}
+		}
+		src, err := bucket.CreateBucket(key)
+		if err != nil {
+			return err
+		}
+		seq := make([]byte, 8)
+		for i, v := range values {
+			binary.BigEndian.PutUint64(seq, uint64(i))
+			if err := src.Put(seq, v); err != nil {
+				return err
+			}
+		}
+		return setUpdatedAt(tx, kind, sourceID, now)
+	})
+}
+
+// Private setUpdatedAt records the time of the
+// last update of a source.
+func setUpdatedAt(
+	tx *bbolt.Tx,
+	kind []byte,
+	sourceID string,
+	now time.Time,
+) error {
+	ts, err := now.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(bucketUpdatedAt).Bucket(kind).Put([]byte(sourceID), ts)
+}
+
+// Private deleteSource removes all values of a source
+func deleteSource(db *bbolt.DB, kind []byte, sourceID string) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		key := []byte(sourceID)
+		bucket := tx.Bucket(kind)
+		if bucket.Bucket(key) != nil {
+			if err := bucket.DeleteBucket(key); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(bucketUpdatedAt).Bucket(kind).Delete(key)
+	})
+}
+
+// Private loadSource calls fn with all values of a source.
+// The value is only valid during the call.
+func loadSource(
+	db *bbolt.DB,
+	kind []byte,
+	sourceID string,
+	fn func(value []byte) error,
+) error {
+	return db.View(func(tx *bbolt.Tx) error {
+		src := tx.Bucket(kind).Bucket([]byte(sourceID))
+		if src == nil {
+			return nil
+		}
+		return src.ForEach(func(_, v []byte) error {
+			return fn(v)
+		})
+	})
+}
+
+// Private lastUpdated returns the time of the last
+// update of all stored sources.
+func lastUpdated(db *bbolt.DB, kind []byte) (map[string]time.Time, error) {
+	updated := make(map[string]time.Time)
+	err := db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketUpdatedAt).Bucket(kind).ForEach(
+			func(k, v []byte) error {
+				t := time.Time{}
+				if err := t.UnmarshalBinary(v); err != nil {
+					return err
+				}
+				updated[string(k)] = t
+				return nil
+			})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
@@ -0,0 +1,96 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/store/backends/memory"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+// NeighborsBackend keeps the neighbors in memory
+// and persists the last update of each source.
+type NeighborsBackend struct {
+	*memory.NeighborsBackend
+	db *bbolt.DB
+}
+
+// NewNeighborsBackend creates a new backend
+// with a database.
+func NewNeighborsBackend(db *bbolt.DB) *NeighborsBackend {
+	return &NeighborsBackend{
+		NeighborsBackend: memory.NewNeighborsBackend(),
+		db:               db,
+	}
+}
+
+// Init restores the persisted neighbors.
+func (b *NeighborsBackend) Init(ctx context.Context) error {
+	updated, err := b.LastUpdated(ctx)
+	if err != nil {
+		return err
+	}
+	for sourceID := range updated {
+		neighbors := api.Neighbors{}
+		err := loadSource(b.db, bucketNeighbors, sourceID, func(v []byte) error {
+			neighbor := &api.Neighbor{}
+			if err := json.Unmarshal(v, neighbor); err != nil {
+				return err
+			}
+			neighbors = append(neighbors, neighbor)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if err := b.NeighborsBackend.SetNeighbors(
+			ctx, sourceID, neighbors); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetNeighbors persists the neighbors of a
+// route server and replaces them in memory.
+func (b *NeighborsBackend) SetNeighbors(
+	ctx context.Context,
+	sourceID string,
+	neighbors api.Neighbors,
+) error {
+	values := make([][]byte, 0, len(neighbors))
+	for _, n := range neighbors {
+		data, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+		values = append(values, data)
+	}
+	now := time.Now().UTC()
+	if err := putSource(b.db, bucketNeighbors, sourceID, values, now); err != nil {
+		return err
+	}
+	return b.NeighborsBackend.SetNeighbors(ctx, sourceID, neighbors)
+}
+
+// RemoveSource deletes the neighbors of a source.
+func (b *NeighborsBackend) RemoveSource(
+	ctx context.Context,
+	sourceID string,
+) error {
+	if err := deleteSource(b.db, bucketNeighbors, sourceID); err != nil {
+		return err
+	}
+	return b.NeighborsBackend.RemoveSource(ctx, sourceID)
+}
+
+// LastUpdated returns the time of the last update
+// of all sources with persisted neighbors.
+func (b *NeighborsBackend) LastUpdated(
+	ctx context.Context,
+) (map[string]time.Time, error) {
+	return lastUpdated(b.db, bucketNeighbors)
+}
@@ -0,0 +1,56 @@
+package bolt
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/config"
+)
+
+func TestNeighborsBackendRestore(t *testing.T) {
+	ctx := context.Background()
+	opts := &config.BoltConfig{
+		Path: filepath.Join(t.TempDir(), "alice.bolt"),
+	}
+	db, err := Open(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewNeighborsBackend(db)
+	neighbors := api.Neighbors{
+		{ID: "n2342", Address: "test123"},
+		{ID: "n2343", Address: "test124"},
+	}
+	if err := b.SetNeighbors(ctx, "rs1", neighbors); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetNeighbors(ctx, "rs1", neighbors[:1]); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	db, err = Open(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	b = NewNeighborsBackend(db)
+	if err := b.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	result, err := b.GetNeighborsMapAt(ctx, "rs1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 || result["n2342"].Address != "test123" {
+		t.Error("unexpected neighbors:", result)
+	}
+}
+
+func TestOpenWithoutPath(t *testing.T) {
+	if _, err := Open(&config.BoltConfig{}); err != ErrPathUnconfigured {
+		t.Error("unexpected error:", err)
+	}
+}
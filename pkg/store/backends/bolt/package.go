@@ -0,0 +1,8 @@
+// Package bolt provides backends for the neighbors and
+// routes stores, which keep the data in memory and persist
+// it in an embedded bbolt database.
+//
+// On startup the last known neighbors and routes are
+// restored, so they can be served immediately until
+// the first refresh completes.
+package bolt
@@ -0,0 +1,225 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/pools"
+	"github.com/alice-lg/alice-lg/pkg/store/backends/memory"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+// RoutesBackend keeps the routes in memory and
+// persists the last update of each source.
+type RoutesBackend struct {
+	*memory.RoutesBackend
+	db *bbolt.DB
+}
+
+// NewRoutesBackend creates a new backend
+// with a database.
+func NewRoutesBackend(db *bbolt.DB) *RoutesBackend {
+	return &RoutesBackend{
+		RoutesBackend: memory.NewRoutesBackend(),
+		db:            db,
+	}
+}
+
+// Init restores the persisted routes. The attributes
+// are acquired from the pools, so neighbor queries
+// work as with refreshed routes.
+func (b *RoutesBackend) Init(ctx context.Context) error {
+	updated, err := b.LastUpdated(ctx)
+	if err != nil {
+		return err
+	}
+	for sourceID := range updated {
+		rs := &api.LookupRouteServer{
+			ID: pools.RouteServers.Acquire(sourceID),
+		}
+		neighbors := map[string]*api.Neighbor{}
+		routes := api.LookupRoutes{}
+		err := loadRoutes(b.db, sourceID, func(v []byte) error {
+			route := &api.LookupRoute{}
+			if err := json.Unmarshal(v, route); err != nil {
+				return err
+			}
+			if route.Route == nil || route.Neighbor == nil {
+				return nil // Skip invalid routes
+			}
+			pools.AcquireRoute(route.Route)
+
+			// Share the neighbors and route server
+			neighbor, ok := neighbors[route.Neighbor.ID]
+			if !ok {
+				neighbor = route.Neighbor
+				neighbors[neighbor.ID] = neighbor
+			}
+			route.Neighbor = neighbor
+			if route.RouteServer != nil {
+				rs.Name = route.RouteServer.Name
+			}
+			route.RouteServer = rs
+
+			routes = append(routes, route)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if err := b.RoutesBackend.SetRoutes(ctx, sourceID, routes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetRoutes persists the routes of a route server
+// and replaces them in memory.
+func (b *RoutesBackend) SetRoutes(
+	ctx context.Context,
+	sourceID string,
+	routes api.LookupRoutes,
+) error {
+	if err := b.persist(sourceID, routes); err != nil {
+		return err
+	}
+	return b.RoutesBackend.SetRoutes(ctx, sourceID, routes)
+}
+
+// Private persist replaces the stored routes of
+// a route server.
+func (b *RoutesBackend) persist(
+	sourceID string,
+	routes api.LookupRoutes,
+) error {
+	now := time.Now().UTC()
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketRoutes)
+		key := []byte(sourceID)
+		if bucket.Bucket(key) != nil {
+			if err := bucket.DeleteBucket(key); err != nil {
+				return err
+			}
+		}
+		src, err := bucket.CreateBucket(key)
+		if err != nil {
+			return err
+		}
+		for _, r := range routes {
+			if err := putRoute(src, r); err != nil {
+				return err
+			}
+		}
+		return setUpdatedAt(tx, bucketRoutes, sourceID, now)
+	})
+}
+
+// UpdateRoutes applies the updates in memory and
+// persists the routes of the updated neighbors.
+func (b *RoutesBackend) UpdateRoutes(
+	ctx context.Context,
+	sourceID string,
+	updates []*api.RoutesUpdate,
+) error {
+	if err := b.RoutesBackend.UpdateRoutes(ctx, sourceID, updates); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		src, err := tx.Bucket(bucketRoutes).CreateBucketIfNotExists(
+			[]byte(sourceID))
+		if err != nil {
+			return err
+		}
+		for _, u := range updates {
+			key := []byte(u.NeighborID)
+			if len(u.Routes) == 0 {
+				if src.Bucket(key) != nil {
+					if err := src.DeleteBucket(key); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			neighbor, err := src.CreateBucketIfNotExists(key)
+			if err != nil {
+				return err
+			}
+			for _, id := range u.Withdrawn {
+				if err := neighbor.Delete([]byte(id)); err != nil {
+					return err
+				}
+			}
+			for _, r := range u.Added {
+				if err := putRoute(src, r); err != nil {
+					return err
+				}
+			}
+		}
+		return setUpdatedAt(tx, bucketRoutes, sourceID, now)
+	})
+}
+
+// Private putRoute stores a route in the bucket of
+// the neighbor by the key of the route.
+func putRoute(src *bbolt.Bucket, r *api.LookupRoute) error {
+	if r.Route == nil {
+		return nil
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	neighbor, err := src.CreateBucketIfNotExists([]byte(r.GetNeighborID()))
+	if err != nil {
+		return err
+	}
+	return neighbor.Put([]byte(r.Route.Key()), data)
+}
+
+// Private loadRoutes calls fn with all routes of a
+// source. Routes stored without a neighbor bucket by
+// previous versions are included.
+func loadRoutes(
+	db *bbolt.DB,
+	sourceID string,
+	fn func(value []byte) error,
+) error {
+	return db.View(func(tx *bbolt.Tx) error {
+		src := tx.Bucket(bucketRoutes).Bucket([]byte(sourceID))
+		if src == nil {
+			return nil
+		}
+		return src.ForEach(func(k, v []byte) error {
+			if v != nil {
+				return fn(v)
+			}
+			return src.Bucket(k).ForEach(func(_, v []byte) error {
+				return fn(v)
+			})
+		})
+	})
+}
+
+// RemoveSource deletes the routes of a source.
+func (b *RoutesBackend) RemoveSource(
+	ctx context.Context,
+	sourceID string,
+) error {
+	if err := deleteSource(b.db, bucketRoutes, sourceID); err != nil {
+		return err
+	}
+	return b.RoutesBackend.RemoveSource(ctx, sourceID)
+}
+
+// LastUpdated returns the time of the last update
+// of all sources with persisted routes.
+func (b *RoutesBackend) LastUpdated(
+	ctx context.Context,
+) (map[string]time.Time, error) {
+	return lastUpdated(b.db, bucketRoutes)
+}
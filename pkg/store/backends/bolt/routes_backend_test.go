@@ -0,0 +1,172 @@
+package bolt
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/config"
+	"github.com/alice-lg/alice-lg/pkg/pools"
+	"github.com/alice-lg/alice-lg/pkg/store/testdata"
+
+	bbolt "go.etcd.io/bbolt"
+)
+
+func TestRoutesBackendRestore(t *testing.T) {
+	ctx := context.Background()
+	opts := &config.BoltConfig{
+		Path: filepath.Join(t.TempDir(), "alice.bolt"),
+	}
+	db, err := Open(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs1 := testdata.LoadTestLookupRoutes("rs1", "routeserver1")
+	rs2 := testdata.LoadTestLookupRoutes("rs2", "routeserver2")
+
+	b := NewRoutesBackend(db)
+	if err := b.SetRoutes(ctx, "rs1", rs1); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetRoutes(ctx, "rs2", rs2); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.RemoveSource(ctx, "rs2"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	// Reopen the database and restore the routes
+	db, err = Open(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	b = NewRoutesBackend(db)
+	if err := b.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := b.LastUpdated(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := updated["rs1"]; !ok || len(updated) != 1 {
+		t.Error("unexpected last updated:", updated)
+	}
+
+	imported, filtered, err := b.CountRoutesAt(ctx, "rs1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expImported, expFiltered, _ := countRoutes(rs1)
+	if imported != expImported || filtered != expFiltered {
+		t.Error("unexpected routes count:", imported, filtered)
+	}
+	if _, _, err := b.CountRoutesAt(ctx, "rs2"); err == nil {
+		t.Error("expected rs2 to be removed")
+	}
+
+	// Neighbor queries compare pooled pointers
+	q := &api.NeighborQuery{
+		NeighborID: pools.Neighbors.Get("ID7254_AS31334"),
+		SourceID:   pools.RouteServers.Get("rs1"),
+	}
+	routes, err := b.FindByNeighbors(
+		ctx, []*api.NeighborQuery{q}, api.NewSearchFilters())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 1 {
+		t.Error("unexpected routes:", routes)
+	}
+}
+
+func TestRoutesBackendUpdateRoutes(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(&config.BoltConfig{
+		Path: filepath.Join(t.TempDir(), "alice.bolt"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rs1 := testdata.LoadTestLookupRoutes("rs1", "routeserver1")
+	b := NewRoutesBackend(db)
+	if err := b.SetRoutes(ctx, "rs1", rs1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Withdraw the first route of a neighbor
+	withdrawn := rs1[0]
+	neighborID := withdrawn.GetNeighborID()
+	remaining := api.LookupRoutes{}
+	for _, r := range rs1[1:] {
+		if r.GetNeighborID() == neighborID {
+			remaining = append(remaining, r)
+		}
+	}
+
+	// Mark the other neighbors, they must not be rewritten
+	err = db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketRoutes).Bucket([]byte("rs1")).ForEach(
+			func(k, _ []byte) error {
+				if string(k) == neighborID {
+					return nil
+				}
+				nb := tx.Bucket(bucketRoutes).Bucket([]byte("rs1")).Bucket(k)
+				return nb.Put([]byte("marker"), []byte("{}"))
+			})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	markers := len(groupNeighbors(rs1)) - 1
+
+	err = b.UpdateRoutes(ctx, "rs1", []*api.RoutesUpdate{{
+		NeighborID: neighborID,
+		Routes:     remaining,
+		Added:      api.LookupRoutes{},
+		Withdrawn:  []string{withdrawn.Network},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored := 0
+	err = loadRoutes(db, "rs1", func([]byte) error {
+		stored++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != len(rs1)-1+markers {
+		t.Error("unexpected number of stored routes:", stored)
+	}
+}
+
+// groupNeighbors returns the IDs of the neighbors
+func groupNeighbors(routes api.LookupRoutes) map[string]bool {
+	neighbors := map[string]bool{}
+	for _, r := range routes {
+		neighbors[r.GetNeighborID()] = true
+	}
+	return neighbors
+}
+
+func countRoutes(routes api.LookupRoutes) (uint, uint, error) {
+	var imported, filtered uint
+	for _, r := range routes {
+		switch r.State {
+		case api.RouteStateImported:
+			imported++
+		case api.RouteStateFiltered:
+			filtered++
+		}
+	}
+	return imported, filtered, nil
+}
@@ -3,6 +3,7 @@ package memory
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/alice-lg/alice-lg/pkg/api"
 	"github.com/alice-lg/alice-lg/pkg/sources"
@@ -15,6 +16,7 @@ type NeighborIndex map[string]*api.Neighbor
 // the neighbors store.
 type NeighborsBackend struct {
 	neighbors *sync.Map
+	updated   *sync.Map
 }
 
 // NewNeighborsBackend instanciates a new in memory
@@ -22,6 +24,7 @@ type NeighborsBackend struct {
 func NewNeighborsBackend() *NeighborsBackend {
 	return &NeighborsBackend{
 		neighbors: &sync.Map{},
+		updated:   &sync.Map{},
 	}
 }
 
@@ -39,6 +42,7 @@ func (b *NeighborsBackend) SetNeighbors(
 	}
 
 	b.neighbors.Store(sourceID, idx)
+	b.updated.Store(sourceID, time.Now().UTC())
 	return nil
 }
 
@@ -91,3 +95,32 @@ func (b *NeighborsBackend) CountNeighborsAt(
 	}
 	return len(neighbors), nil
 }
+
+// RemoveSource deletes the neighbors of a source.
+func (b *NeighborsBackend) RemoveSource(
+	ctx context.Context,
+	sourceID string,
+) error {
+	b.neighbors.Delete(sourceID)
+	b.updated.Delete(sourceID)
+	return nil
+}
+
+// LastUpdated returns the time of the last update
+// of the neighbors of all sources.
+func (b *NeighborsBackend) LastUpdated(
+	ctx context.Context,
+) (map[string]time.Time, error) {
+	return lastUpdated(b.updated), nil
+}
+
+// Private lastUpdated copies the update times
+// into a map.
+func lastUpdated(updated *sync.Map) map[string]time.Time {
+	result := make(map[string]time.Time)
+	updated.Range(func(k, v interface{}) bool {
+		result[k.(string)] = v.(time.Time)
+		return true
+	})
+	return result
+}
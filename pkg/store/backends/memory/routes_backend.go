@@ -4,21 +4,31 @@ import (
 	"context"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/alice-lg/alice-lg/pkg/api"
 	"github.com/alice-lg/alice-lg/pkg/sources"
 )
 
+// NeighborRoutes is a mapping of a neighborID to
+// the routes of the neighbor.
+type NeighborRoutes map[string]api.LookupRoutes
+
 // RoutesBackend implements an in memory backend
 // for the routes store.
 type RoutesBackend struct {
-	routes *sync.Map
+	routes  *sync.Map
+	updated *sync.Map
+
+	// Updates of a source are serialized
+	updateLock sync.Mutex
 }
 
 // NewRoutesBackend creates a new instance
 func NewRoutesBackend() *RoutesBackend {
 	return &RoutesBackend{
-		routes: &sync.Map{},
+		routes:  &sync.Map{},
+		updated: &sync.Map{},
 	}
 }
 
@@ -30,10 +40,75 @@ func (r *RoutesBackend) SetRoutes(
 	sourceID string,
 	routes api.LookupRoutes,
 ) error {
-	r.routes.Store(sourceID, routes)
+	idx := make(NeighborRoutes)
+	for _, route := range routes {
+		key := route.GetNeighborID()
+		idx[key] = append(idx[key], route)
+	}
+
+	r.updateLock.Lock()
+	defer r.updateLock.Unlock()
+	r.routes.Store(sourceID, idx)
+	r.updated.Store(sourceID, time.Now().UTC())
+	return nil
+}
+
+// UpdateRoutes replaces the routes of the updated
+// neighbors. The routes of the other neighbors are
+// not changed.
+func (r *RoutesBackend) UpdateRoutes(
+	ctx context.Context,
+	sourceID string,
+	updates []*api.RoutesUpdate,
+) error {
+	r.updateLock.Lock()
+	defer r.updateLock.Unlock()
+
+	prev, ok := r.routes.Load(sourceID)
+	if !ok {
+		return sources.ErrSourceNotFound
+	}
+
+	// Copy on write, so concurrent readers are
+	// not affected.
+	idx := make(NeighborRoutes, len(prev.(NeighborRoutes)))
+	for k, v := range prev.(NeighborRoutes) {
+		idx[k] = v
+	}
+	for _, u := range updates {
+		if len(u.Routes) == 0 {
+			delete(idx, u.NeighborID)
+			continue
+		}
+		idx[u.NeighborID] = u.Routes
+	}
+
+	r.routes.Store(sourceID, idx)
+	r.updated.Store(sourceID, time.Now().UTC())
 	return nil
 }
 
+// RoutesAt returns all routes of a source.
+func (r *RoutesBackend) RoutesAt(
+	ctx context.Context,
+	sourceID string,
+) (api.LookupRoutes, error) {
+	routes, ok := r.routes.Load(sourceID)
+	if !ok {
+		return nil, sources.ErrSourceNotFound
+	}
+	idx := routes.(NeighborRoutes)
+	n := 0
+	for _, rs := range idx {
+		n += len(rs)
+	}
+	result := make(api.LookupRoutes, 0, n)
+	for _, rs := range idx {
+		result = append(result, rs...)
+	}
+	return result, nil
+}
+
 // CountRoutesAt returns the number of filtered and imported
 // routes and implements the RoutesStoreBackend interface.
 func (r *RoutesBackend) CountRoutesAt(
@@ -50,12 +125,14 @@ func (r *RoutesBackend) CountRoutesAt(
 		filtered uint = 0
 	)
 
-	for _, route := range routes.(api.LookupRoutes) {
-		if route.State == api.RouteStateFiltered {
-			filtered++
-		}
-		if route.State == api.RouteStateImported {
-			imported++
+	for _, rs := range routes.(NeighborRoutes) {
+		for _, route := range rs {
+			if route.State == api.RouteStateFiltered {
+				filtered++
+			}
+			if route.State == api.RouteStateImported {
+				imported++
+			}
 		}
 	}
 
@@ -71,21 +148,21 @@ func (r *RoutesBackend) FindByNeighbors(
 ) (api.LookupRoutes, error) {
 	result := api.LookupRoutes{}
 
-	r.routes.Range(func(k, rs interface{}) bool {
-		for _, route := range rs.(api.LookupRoutes) {
-			for _, q := range query {
-				if !route.MatchNeighborQuery(q) {
-					continue
-				}
-				if !filters.MatchRoute(route) {
-					continue
-				}
-
-				result = append(result, route)
+	for _, q := range query {
+		if q.SourceID == nil || q.NeighborID == nil {
+			continue
+		}
+		routes, ok := r.routes.Load(*q.SourceID)
+		if !ok {
+			continue
+		}
+		for _, route := range routes.(NeighborRoutes)[*q.NeighborID] {
+			if !filters.MatchRoute(route) {
+				continue
 			}
+			result = append(result, route)
 		}
-		return true
-	})
+	}
 
 	return result, nil
 }
@@ -106,24 +183,26 @@ func (r *RoutesBackend) FindByPrefix(
 	prefix = strings.ToLower(prefix)
 	result := api.LookupRoutes{}
 	hasPrefix := prefix != ""
-	r.routes.Range(func(k, rs interface{}) bool {
+	r.routes.Range(func(k, idx interface{}) bool {
 		if limit > 0 && count >= limit {
 			limitExceeded = true
 			return false
 		}
-		for _, route := range rs.(api.LookupRoutes) {
-			// Naiive string filtering:
-			if hasPrefix && !strings.HasPrefix(strings.ToLower(route.Network), prefix) {
-				continue
-			}
-			if !filters.MatchRoute(route) {
-				continue
-			}
-			result = append(result, route)
-			count++
-			if limit > 0 && count >= limit {
-				limitExceeded = true
-				return false
+		for _, rs := range idx.(NeighborRoutes) {
+			for _, route := range rs {
+				// Naiive string filtering:
+				if hasPrefix && !strings.HasPrefix(strings.ToLower(route.Network), prefix) {
+					continue
+				}
+				if !filters.MatchRoute(route) {
+					continue
+				}
+				result = append(result, route)
+				count++
+				if limit > 0 && count >= limit {
+					limitExceeded = true
+					return false
+				}
 			}
 		}
 		return true
@@ -133,3 +212,23 @@ func (r *RoutesBackend) FindByPrefix(
 	}
 	return result, nil
 }
+
+// RemoveSource deletes the routes of a source.
+func (r *RoutesBackend) RemoveSource(
+	ctx context.Context,
+	sourceID string,
+) error {
+	r.updateLock.Lock()
+	defer r.updateLock.Unlock()
+	r.routes.Delete(sourceID)
+	r.updated.Delete(sourceID)
+	return nil
+}
+
+// LastUpdated returns the time of the last update
+// of the routes of all sources.
+func (r *RoutesBackend) LastUpdated(
+	ctx context.Context,
+) (map[string]time.Time, error) {
+	return lastUpdated(r.updated), nil
+}
@@ -80,3 +80,40 @@ func TestConcurrentRoutesAccess(t *testing.T) {
 	dt := time.Since(t0)
 	fmt.Println("finished after:", dt)
 }
+
+func TestUpdateRoutes(t *testing.T) {
+	ctx := context.Background()
+	rs1 := testdata.LoadTestLookupRoutes("rs1", "routeserver1")
+
+	b := NewRoutesBackend()
+	err := b.UpdateRoutes(ctx, "rs1", []*api.RoutesUpdate{})
+	if err == nil {
+		t.Error("expected an error for an unknown source")
+	}
+	b.SetRoutes(ctx, "rs1", rs1)
+
+	neighborID := rs1[0].GetNeighborID()
+	err = b.UpdateRoutes(ctx, "rs1", []*api.RoutesUpdate{
+		{
+			NeighborID: neighborID,
+			Routes:     api.LookupRoutes{},
+			Withdrawn:  []string{rs1[0].Network},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes, err := b.RoutesAt(ctx, "rs1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range routes {
+		if r.GetNeighborID() == neighborID {
+			t.Error("expected routes of neighbor to be removed:", r)
+		}
+	}
+	if len(routes) == 0 {
+		t.Error("expected routes of other neighbors")
+	}
+}
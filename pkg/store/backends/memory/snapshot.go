@@ -0,0 +1,499 @@
+package memory
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/pools"
+)
+
+// SnapshotVersion is the version of the snapshot format
+const SnapshotVersion = 2
+
+var (
+	// ErrSnapshotVersion is returned when the snapshot
+	// was written in an unsupported format.
+	ErrSnapshotVersion = errors.New("unsupported snapshot version")
+)
+
+// The snapshot is the serialized state of the backends.
+// Most attributes of routes are shared through the pools:
+// They are stored only once in the tables and referenced
+// by index. The index 0 is reserved for nil.
+type snapshot struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Strings        []string             `json:"strings"`
+	Lists          [][]string           `json:"lists"`
+	ASPaths        [][]int              `json:"as_paths"`
+	Communities    []api.Communities    `json:"communities"`
+	ExtCommunities []api.ExtCommunities `json:"ext_communities"`
+
+	Neighbors map[string]*snapshotNeighbors `json:"neighbors"`
+	Routes    map[string]*snapshotRoutes    `json:"routes"`
+}
+
+// The neighbors of a source
+type snapshotNeighbors struct {
+	UpdatedAt time.Time     `json:"updated_at"`
+	Neighbors api.Neighbors `json:"neighbors"`
+}
+
+// The routes of a source. The neighbors are
+// shared by the routes and referenced by index.
+type snapshotRoutes struct {
+	UpdatedAt time.Time        `json:"updated_at"`
+	Name      string           `json:"name"`
+	Neighbors api.Neighbors    `json:"neighbors"`
+	Routes    []*snapshotRoute `json:"routes"`
+}
+
+// A route with references to the tables
+type snapshotRoute struct {
+	State      int              `json:"s"`
+	Neighbor   int              `json:"nb"`
+	NeighborID int              `json:"id"`
+	Network    string           `json:"n"`
+	Interface  int              `json:"i,omitempty"`
+	Gateway    int              `json:"g,omitempty"`
+	Metric     int              `json:"m,omitempty"`
+	BGP        *snapshotBGP     `json:"b,omitempty"`
+	Age        time.Duration    `json:"a,omitempty"`
+	Type       int              `json:"t,omitempty"`
+	Primary    bool             `json:"p,omitempty"`
+	LearntFrom int              `json:"l,omitempty"`
+	Details    *json.RawMessage `json:"d,omitempty"`
+
+	Table              int                 `json:"tb,omitempty"`
+	AFISAFI            int                 `json:"af,omitempty"`
+	RouteDistinguisher string              `json:"rd,omitempty"`
+	FlowSpec           []*api.FlowSpecRule `json:"fs,omitempty"`
+}
+
+// The BGP info of a route with references to the tables
+type snapshotBGP struct {
+	Origin           int `json:"o,omitempty"`
+	ASPath           int `json:"ap,omitempty"`
+	NextHop          int `json:"nh,omitempty"`
+	Communities      int `json:"c,omitempty"`
+	LargeCommunities int `json:"lc,omitempty"`
+	ExtCommunities   int `json:"ec,omitempty"`
+	LocalPref        int `json:"lp,omitempty"`
+	Med              int `json:"med,omitempty"`
+
+	NextHopLinkLocal int    `json:"nhl,omitempty"`
+	AsSet            int    `json:"as,omitempty"`
+	Aigp             uint64 `json:"aigp,omitempty"`
+	OnlyToCustomer   int    `json:"otc,omitempty"`
+}
+
+// A table deduplicates values while encoding
+type table[T any] struct {
+	values []T
+	index  map[string]int
+}
+
+func newTable[T any]() *table[T] {
+	var null T
+	return &table[T]{
+		values: []T{null},
+		index:  make(map[string]int),
+	}
+}
+
+// ref returns the index of the value in the table
+func (t *table[T]) ref(value T) int {
+	key := fmt.Sprintf("%#v", value)
+	if i, ok := t.index[key]; ok {
+		return i
+	}
+	i := len(t.values)
+	t.values = append(t.values, value)
+	t.index[key] = i
+	return i
+}
+
+// Tables of the encoder
+type snapshotEncoder struct {
+	strings        *table[string]
+	lists          *table[[]string]
+	asPaths        *table[[]int]
+	communities    *table[api.Communities]
+	extCommunities *table[api.ExtCommunities]
+}
+
+func newSnapshotEncoder() *snapshotEncoder {
+	return &snapshotEncoder{
+		strings:        newTable[string](),
+		lists:          newTable[[]string](),
+		asPaths:        newTable[[]int](),
+		communities:    newTable[api.Communities](),
+		extCommunities: newTable[api.ExtCommunities](),
+	}
+}
+
+func (e *snapshotEncoder) str(s *string) int {
+	if s == nil {
+		return 0
+	}
+	return e.strings.ref(*s)
+}
+
+func (e *snapshotEncoder) name(s string) int {
+	if s == "" {
+		return 0
+	}
+	return e.strings.ref(s)
+}
+
+func (e *snapshotEncoder) list(l []string) int {
+	if l == nil {
+		return 0
+	}
+	return e.lists.ref(l)
+}
+
+func (e *snapshotEncoder) asPath(p []int) int {
+	if p == nil {
+		return 0
+	}
+	return e.asPaths.ref(p)
+}
+
+func (e *snapshotEncoder) comms(c api.Communities) int {
+	if c == nil {
+		return 0
+	}
+	return e.communities.ref(c)
+}
+
+func (e *snapshotEncoder) extComms(c api.ExtCommunities) int {
+	if c == nil {
+		return 0
+	}
+	return e.extCommunities.ref(c)
+}
+
+// encodeRoutes encodes the routes of a source
+func (e *snapshotEncoder) encodeRoutes(
+	routes api.LookupRoutes,
+	updatedAt time.Time,
+) *snapshotRoutes {
+	src := &snapshotRoutes{
+		UpdatedAt: updatedAt,
+		Neighbors: api.Neighbors{},
+		Routes:    make([]*snapshotRoute, 0, len(routes)),
+	}
+	neighbors := make(map[*api.Neighbor]int)
+	for _, r := range routes {
+		if r.Route == nil {
+			continue
+		}
+		if r.RouteServer != nil {
+			src.Name = r.RouteServer.Name
+		}
+		neighbor := 0
+		if r.Neighbor != nil {
+			i, ok := neighbors[r.Neighbor]
+			if !ok {
+				src.Neighbors = append(src.Neighbors, r.Neighbor)
+				i = len(src.Neighbors)
+				neighbors[r.Neighbor] = i
+			}
+			neighbor = i
+		}
+		route := &snapshotRoute{
+			State:      e.strings.ref(r.State),
+			Neighbor:   neighbor,
+			NeighborID: e.str(r.NeighborID),
+			Network:    r.Network,
+			Interface:  e.str(r.Interface),
+			Gateway:    e.str(r.Gateway),
+			Metric:     r.Metric,
+			Age:        r.Age,
+			Type:       e.list(r.Type),
+			Primary:    r.Primary,
+			LearntFrom: e.str(r.LearntFrom),
+			Details:    r.Details,
+
+			Table:              e.name(r.Table),
+			AFISAFI:            e.name(r.AFISAFI),
+			RouteDistinguisher: r.RouteDistinguisher,
+			FlowSpec:           r.FlowSpec,
+		}
+		if bgp := r.BGP; bgp != nil {
+			route.BGP = &snapshotBGP{
+				Origin:           e.str(bgp.Origin),
+				ASPath:           e.asPath(bgp.AsPath),
+				NextHop:          e.str(bgp.NextHop),
+				Communities:      e.comms(bgp.Communities),
+				LargeCommunities: e.comms(bgp.LargeCommunities),
+				ExtCommunities:   e.extComms(bgp.ExtCommunities),
+				LocalPref:        bgp.LocalPref,
+				Med:              bgp.Med,
+				NextHopLinkLocal: e.str(bgp.NextHopLinkLocal),
+				AsSet:            e.asPath(bgp.AsSet),
+				Aigp:             bgp.Aigp,
+				OnlyToCustomer:   bgp.OnlyToCustomer,
+			}
+		}
+		src.Routes = append(src.Routes, route)
+	}
+	return src
+}
+
+// WriteSnapshot serializes the neighbors and routes
+// of the backends.
+func WriteSnapshot(
+	w io.Writer,
+	neighbors *NeighborsBackend,
+	routes *RoutesBackend,
+) error {
+	enc := newSnapshotEncoder()
+	s := &snapshot{
+		Version:   SnapshotVersion,
+		CreatedAt: time.Now().UTC(),
+		Neighbors: make(map[string]*snapshotNeighbors),
+		Routes:    make(map[string]*snapshotRoutes),
+	}
+
+	neighborsUpdated := lastUpdated(neighbors.updated)
+	neighbors.neighbors.Range(func(k, v interface{}) bool {
+		sourceID := k.(string)
+		src := &snapshotNeighbors{
+			UpdatedAt: neighborsUpdated[sourceID],
+			Neighbors: make(api.Neighbors, 0, len(v.(NeighborIndex))),
+		}
+		for _, n := range v.(NeighborIndex) {
+			src.Neighbors = append(src.Neighbors, n)
+		}
+		s.Neighbors[sourceID] = src
+		return true
+	})
+
+	routesUpdated := lastUpdated(routes.updated)
+	routes.routes.Range(func(k, v interface{}) bool {
+		sourceID := k.(string)
+		rs := api.LookupRoutes{}
+		for _, neighborRoutes := range v.(NeighborRoutes) {
+			rs = append(rs, neighborRoutes...)
+		}
+		s.Routes[sourceID] = enc.encodeRoutes(rs, routesUpdated[sourceID])
+		return true
+	})
+
+	s.Strings = enc.strings.values
+	s.Lists = enc.lists.values
+	s.ASPaths = enc.asPaths.values
+	s.Communities = enc.communities.values
+	s.ExtCommunities = enc.extCommunities.values
+
+	return json.NewEncoder(w).Encode(s)
+}
+
+// Private lookup returns the table entry for a
+// reference or nil.
+func lookup[T any](values []T, i int) (T, error) {
+	var null T
+	if i < 0 || i >= len(values) {
+		return null, fmt.Errorf("invalid snapshot reference: %d", i)
+	}
+	return values[i], nil
+}
+
+// decodeRoutes restores the routes of a source.
+// The attributes are acquired from the pools.
+func (s *snapshot) decodeRoutes(
+	sourceID string,
+	src *snapshotRoutes,
+) (api.LookupRoutes, error) {
+	var err error
+	str := func(i int) *string {
+		if i == 0 || err != nil {
+			return nil
+		}
+		var v string
+		v, err = lookup(s.Strings, i)
+		return &v
+	}
+	name := func(i int) string {
+		if v := str(i); v != nil {
+			return *v
+		}
+		return ""
+	}
+
+	rs := &api.LookupRouteServer{
+		ID:   pools.RouteServers.Acquire(sourceID),
+		Name: src.Name,
+	}
+	routes := make(api.LookupRoutes, 0, len(src.Routes))
+	for _, r := range src.Routes {
+		route := &api.Route{
+			NeighborID: str(r.NeighborID),
+			Network:    r.Network,
+			Interface:  str(r.Interface),
+			Gateway:    str(r.Gateway),
+			Metric:     r.Metric,
+			Age:        r.Age,
+			Primary:    r.Primary,
+			LearntFrom: str(r.LearntFrom),
+			Details:    r.Details,
+
+			Table:              name(r.Table),
+			AFISAFI:            name(r.AFISAFI),
+			RouteDistinguisher: r.RouteDistinguisher,
+			FlowSpec:           r.FlowSpec,
+		}
+		if r.Type != 0 {
+			route.Type, err = lookup(s.Lists, r.Type)
+		}
+		if bgp := r.BGP; bgp != nil && err == nil {
+			route.BGP = &api.BGPInfo{
+				Origin:    str(bgp.Origin),
+				NextHop:   str(bgp.NextHop),
+				LocalPref: bgp.LocalPref,
+				Med:       bgp.Med,
+
+				NextHopLinkLocal: str(bgp.NextHopLinkLocal),
+				Aigp:             bgp.Aigp,
+				OnlyToCustomer:   bgp.OnlyToCustomer,
+			}
+			if bgp.ASPath != 0 && err == nil {
+				route.BGP.AsPath, err = lookup(s.ASPaths, bgp.ASPath)
+			}
+			if bgp.AsSet != 0 && err == nil {
+				route.BGP.AsSet, err = lookup(s.ASPaths, bgp.AsSet)
+			}
+			if bgp.Communities != 0 && err == nil {
+				route.BGP.Communities, err = lookup(
+					s.Communities, bgp.Communities)
+			}
+			if bgp.LargeCommunities != 0 && err == nil {
+				route.BGP.LargeCommunities, err = lookup(
+					s.Communities, bgp.LargeCommunities)
+			}
+			if bgp.ExtCommunities != 0 && err == nil {
+				route.BGP.ExtCommunities, err = lookup(
+					s.ExtCommunities, bgp.ExtCommunities)
+			}
+		}
+		state := str(r.State)
+		if err != nil {
+			return nil, err
+		}
+		pools.AcquireRoute(route)
+
+		lookupRoute := &api.LookupRoute{
+			Route:       route,
+			RouteServer: rs,
+		}
+		if state != nil {
+			lookupRoute.State = *state
+		}
+		if r.Neighbor != 0 {
+			if r.Neighbor > len(src.Neighbors) {
+				return nil, fmt.Errorf(
+					"invalid snapshot neighbor: %d", r.Neighbor)
+			}
+			lookupRoute.Neighbor = src.Neighbors[r.Neighbor-1]
+		}
+		routes = append(routes, lookupRoute)
+	}
+	return routes, nil
+}
+
+// ReadSnapshot restores the neighbors and routes
+// of the backends from a snapshot. The time of the
+// last update of each source is restored as well.
+func ReadSnapshot(
+	r io.Reader,
+	neighbors *NeighborsBackend,
+	routes *RoutesBackend,
+) error {
+	s := &snapshot{}
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return err
+	}
+	if s.Version != SnapshotVersion {
+		return ErrSnapshotVersion
+	}
+
+	for sourceID, src := range s.Neighbors {
+		idx := make(NeighborIndex)
+		for _, n := range src.Neighbors {
+			idx[n.ID] = n
+		}
+		neighbors.neighbors.Store(sourceID, idx)
+		neighbors.updated.Store(sourceID, src.UpdatedAt)
+	}
+	for sourceID, src := range s.Routes {
+		rs, err := s.decodeRoutes(sourceID, src)
+		if err != nil {
+			return fmt.Errorf("%s: %w", sourceID, err)
+		}
+		idx := make(NeighborRoutes)
+		for _, route := range rs {
+			key := route.GetNeighborID()
+			idx[key] = append(idx[key], route)
+		}
+		routes.routes.Store(sourceID, idx)
+		routes.updated.Store(sourceID, src.UpdatedAt)
+	}
+	return nil
+}
+
+// SaveSnapshot writes a compressed snapshot of the
+// backends to a file. The file is replaced atomically.
+func SaveSnapshot(
+	filename string,
+	neighbors *NeighborsBackend,
+	routes *RoutesBackend,
+) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	zw := gzip.NewWriter(tmp)
+	if err := WriteSnapshot(zw, neighbors, routes); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// LoadSnapshot restores the backends from a
+// compressed snapshot file.
+func LoadSnapshot(
+	filename string,
+	neighbors *NeighborsBackend,
+	routes *RoutesBackend,
+) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	return ReadSnapshot(zr, neighbors, routes)
+}
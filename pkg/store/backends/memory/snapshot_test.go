@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/pools"
+	"github.com/alice-lg/alice-lg/pkg/store/testdata"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	rs1 := testdata.LoadTestLookupRoutes("rs1", "routeserver1")
+	for _, r := range rs1 {
+		pools.AcquireRoute(r.Route) // Like refreshed routes
+	}
+
+	// Routes of other address families with optional attributes
+	linkLocal := "fe80::1"
+	vpn := rs1[0].Route
+	vpn.Table = "master4"
+	vpn.AFISAFI = api.AFISAFIIPv4VPNUnicast
+	vpn.RouteDistinguisher = "65000:100"
+	vpn.BGP.NextHopLinkLocal = &linkLocal
+	vpn.BGP.AsSet = []int{64500, 64501}
+	vpn.BGP.Aigp = 23
+	vpn.BGP.OnlyToCustomer = 64500
+	flow := rs1[1].Route
+	flow.AFISAFI = api.AFISAFIIPv4Flowspec
+	flow.FlowSpec = []*api.FlowSpecRule{
+		{Type: "dst", Value: flow.Network},
+		{Type: "proto", Value: "6"},
+	}
+
+	neighbors := NewNeighborsBackend()
+	neighbors.SetNeighbors(ctx, "rs1", api.Neighbors{
+		{ID: "ID163_AS31078", ASN: 31078},
+		{ID: "ID7254_AS31334", ASN: 31334},
+	})
+	routes := NewRoutesBackend()
+	routes.SetRoutes(ctx, "rs1", rs1)
+
+	buf := &bytes.Buffer{}
+	if err := WriteSnapshot(buf, neighbors, routes); err != nil {
+		t.Fatal(err)
+	}
+
+	restoredNeighbors := NewNeighborsBackend()
+	restoredRoutes := NewRoutesBackend()
+	if err := ReadSnapshot(buf, restoredNeighbors, restoredRoutes); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := restoredNeighbors.GetNeighborsMapAt(ctx, "rs1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(n) != 2 || n["ID7254_AS31334"].ASN != 31334 {
+		t.Error("unexpected neighbors:", n)
+	}
+
+	// The routes must be equal
+	result, err := restoredRoutes.RoutesAt(ctx, "rs1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != len(rs1) {
+		t.Fatal("unexpected number of routes:", len(result))
+	}
+	expected := make(map[string]*api.LookupRoute)
+	for _, r := range rs1 {
+		expected[r.Route.String()] = r
+	}
+	for _, r := range result {
+		e, ok := expected[r.Route.String()]
+		if !ok {
+			t.Error("unexpected route:", r.Route)
+			continue
+		}
+		if r.Route.RouteDistinguisher != e.Route.RouteDistinguisher ||
+			r.Route.Table != e.Route.Table ||
+			r.Route.AFISAFI != e.Route.AFISAFI ||
+			len(r.Route.FlowSpec) != len(e.Route.FlowSpec) {
+			t.Error("unexpected route attributes:", r.Route)
+		}
+		if r.State != e.State {
+			t.Error("unexpected state:", r.State)
+		}
+		if r.Neighbor.ID != e.Neighbor.ID {
+			t.Error("unexpected neighbor:", r.Neighbor)
+		}
+		if r.RouteServer.Name != "routeserver1" {
+			t.Error("unexpected route server:", r.RouteServer)
+		}
+	}
+
+	// The attributes are pooled
+	q := &api.NeighborQuery{
+		NeighborID: pools.Neighbors.Get("ID7254_AS31334"),
+		SourceID:   pools.RouteServers.Get("rs1"),
+	}
+	found, err := restoredRoutes.FindByNeighbors(
+		ctx, []*api.NeighborQuery{q}, api.NewSearchFilters())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 {
+		t.Error("unexpected routes:", found)
+	}
+
+	// The update time is restored
+	updated, _ := routes.LastUpdated(ctx)
+	restoredUpdated, _ := restoredRoutes.LastUpdated(ctx)
+	if !restoredUpdated["rs1"].Equal(updated["rs1"]) {
+		t.Error("unexpected last update:", restoredUpdated)
+	}
+}
+
+func TestSaveLoadSnapshot(t *testing.T) {
+	ctx := context.Background()
+	filename := filepath.Join(t.TempDir(), "alice.snapshot")
+
+	neighbors := NewNeighborsBackend()
+	routes := NewRoutesBackend()
+	routes.SetRoutes(ctx, "rs2", testdata.LoadTestLookupRoutes("rs2", "rs2"))
+	if err := SaveSnapshot(filename, neighbors, routes); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewRoutesBackend()
+	if err := LoadSnapshot(filename, NewNeighborsBackend(), restored); err != nil {
+		t.Fatal(err)
+	}
+	imported, filtered, err := restored.CountRoutesAt(ctx, "rs2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported == 0 || filtered == 0 {
+		t.Error("unexpected routes count:", imported, filtered)
+	}
+}
+
+func TestReadSnapshotVersion(t *testing.T) {
+	buf := bytes.NewBufferString(`{"version": 23}`)
+	err := ReadSnapshot(buf, NewNeighborsBackend(), NewRoutesBackend())
+	if err != ErrSnapshotVersion {
+		t.Error("unexpected error:", err)
+	}
+}
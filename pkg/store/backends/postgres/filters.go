@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+)
+
+// The routeAttributes are stored in indexed columns
+// of the routes table. Communities are stored as text,
+// e.g. '23:42' or 'ro:23:42'.
+type routeAttributes struct {
+	originASN        *int
+	asPath           []int
+	communities      []string
+	largeCommunities []string
+	extCommunities   []string
+}
+
+// newRouteAttributes extracts the attributes of a route
+func newRouteAttributes(route *api.LookupRoute) *routeAttributes {
+	attrs := &routeAttributes{
+		asPath:           []int{},
+		communities:      []string{},
+		largeCommunities: []string{},
+		extCommunities:   []string{},
+	}
+	if route.Route == nil || route.Route.BGP == nil {
+		return attrs
+	}
+	bgp := route.Route.BGP
+	if len(bgp.AsPath) > 0 {
+		attrs.asPath = bgp.AsPath
+		origin := bgp.AsPath[len(bgp.AsPath)-1]
+		attrs.originASN = &origin
+	}
+	for _, c := range bgp.Communities {
+		attrs.communities = append(attrs.communities, c.String())
+	}
+	for _, c := range bgp.LargeCommunities {
+		attrs.largeCommunities = append(attrs.largeCommunities, c.String())
+	}
+	for _, c := range bgp.ExtCommunities {
+		attrs.extCommunities = append(attrs.extCommunities, extCommunityText(c))
+	}
+	return attrs
+}
+
+// extCommunityText joins the components of an
+// extended community.
+func extCommunityText(c api.ExtCommunity) string {
+	parts := make([]string, len(c))
+	for i, v := range c {
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, ":")
+}
+
+// matchSource checks if the source is included
+// in the sources filter. Routes of excluded sources
+// are not queried at all.
+func matchSource(filters *api.SearchFilters, sourceID string) bool {
+	group := filters.GetGroupByKey(api.SearchKeySources)
+	if group == nil || len(group.Filters) == 0 {
+		return true
+	}
+	for _, f := range group.Filters {
+		if id, ok := f.Value.(string); ok && id == sourceID {
+			return true
+		}
+	}
+	return false
+}
+
+// filtersCondition translates the search filters
+// into a condition for the indexed columns. The values
+// are appended to the query parameters. Like with
+// MatchRoute, any of the ASNs and all communities
+// must match.
+func filtersCondition(
+	filters *api.SearchFilters,
+	vals []interface{},
+) (string, []interface{}) {
+	cond := ""
+	param := func(v interface{}) string {
+		vals = append(vals, v)
+		return fmt.Sprintf("$%d", len(vals))
+	}
+
+	if group := filters.GetGroupByKey(api.SearchKeyASNS); group != nil {
+		asns := []int{}
+		for _, f := range group.Filters {
+			if asn, ok := f.Value.(int); ok {
+				asns = append(asns, asn)
+			}
+		}
+		if len(group.Filters) > 0 {
+			cond += " AND neighbor_asn = ANY(" + param(asns) + ")"
+		}
+	}
+
+	columns := []struct {
+		key    string
+		column string
+	}{
+		{api.SearchKeyCommunities, "communities"},
+		{api.SearchKeyLargeCommunities, "large_communities"},
+		{api.SearchKeyExtCommunities, "ext_communities"},
+	}
+	for _, c := range columns {
+		group := filters.GetGroupByKey(c.key)
+		if group == nil || len(group.Filters) == 0 {
+			continue
+		}
+		communities := []string{}
+		for _, f := range group.Filters {
+			switch v := f.Value.(type) {
+			case api.Community:
+				communities = append(communities, v.String())
+			case api.ExtCommunity:
+				communities = append(communities, extCommunityText(v))
+			}
+		}
+		cond += " AND " + c.column + " @> " + param(communities) + "::text[]"
+	}
+
+	return cond, vals
+}
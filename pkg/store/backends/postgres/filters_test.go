@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+)
+
+func TestNewRouteAttributes(t *testing.T) {
+	r := &api.LookupRoute{
+		Route: &api.Route{
+			BGP: &api.BGPInfo{
+				AsPath:           []int{2342, 23, 42},
+				Communities:      api.Communities{{23, 42}},
+				LargeCommunities: api.Communities{{23, 42, 1}},
+				ExtCommunities:   api.ExtCommunities{{"ro", 23, 42}},
+			},
+		},
+	}
+	attrs := newRouteAttributes(r)
+	if *attrs.originASN != 42 {
+		t.Error("unexpected origin asn:", *attrs.originASN)
+	}
+	if !reflect.DeepEqual(attrs.communities, []string{"23:42"}) {
+		t.Error("unexpected communities:", attrs.communities)
+	}
+	if !reflect.DeepEqual(attrs.largeCommunities, []string{"23:42:1"}) {
+		t.Error("unexpected large communities:", attrs.largeCommunities)
+	}
+	if !reflect.DeepEqual(attrs.extCommunities, []string{"ro:23:42"}) {
+		t.Error("unexpected ext communities:", attrs.extCommunities)
+	}
+
+	// Routes without BGP info
+	attrs = newRouteAttributes(&api.LookupRoute{Route: &api.Route{}})
+	if attrs.originASN != nil || len(attrs.asPath) != 0 {
+		t.Error("unexpected attributes:", attrs)
+	}
+}
+
+func TestFiltersCondition(t *testing.T) {
+	filters, err := api.FiltersFromTokens([]string{
+		"#23:42", "#23:42:1", "#ro:23:42",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filters.GetGroupByKey(api.SearchKeyASNS).AddFilter(&api.SearchFilter{
+		Value: 2342,
+	})
+
+	cond, vals := filtersCondition(filters, []interface{}{"1.2.%"})
+	expected := " AND neighbor_asn = ANY($2)" +
+		" AND communities @> $3::text[]" +
+		" AND large_communities @> $4::text[]" +
+		" AND ext_communities @> $5::text[]"
+	if cond != expected {
+		t.Error("unexpected condition:", cond)
+	}
+	expectedVals := []interface{}{
+		"1.2.%",
+		[]int{2342},
+		[]string{"23:42"},
+		[]string{"23:42:1"},
+		[]string{"ro:23:42"},
+	}
+	if !reflect.DeepEqual(vals, expectedVals) {
+		t.Error("unexpected values:", vals)
+	}
+
+	// Without filters there is no condition
+	cond, vals = filtersCondition(api.NewSearchFilters(), nil)
+	if cond != "" || len(vals) != 0 {
+		t.Error("unexpected condition:", cond, vals)
+	}
+}
+
+func TestMatchSource(t *testing.T) {
+	filters := api.NewSearchFilters()
+	if !matchSource(filters, "rs1") {
+		t.Error("all sources should match without filter")
+	}
+	filters.GetGroupByKey(api.SearchKeySources).AddFilter(&api.SearchFilter{
+		Value: "rs2",
+	})
+	if matchSource(filters, "rs1") {
+		t.Error("rs1 should not match")
+	}
+	if !matchSource(filters, "rs2") {
+		t.Error("rs2 should match")
+	}
+}
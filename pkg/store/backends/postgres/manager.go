@@ -6,28 +6,39 @@ import (
 	"log"
 	"time"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
-// Include the schema through embedding
+// Include the reset script through embedding
 //
-//go:embed schema.sql
-var schema string
+//go:embed reset.sql
+var resetSchema string
 
 // CurrentSchemaVersion is the current version of the schema
-const CurrentSchemaVersion = 1
-
-var (
-// ErrNotInitialized is returned when the database
-// schema is not migrated yet
-)
+const CurrentSchemaVersion = 3
+
+// The migrationLockID identifies the advisory lock held
+// while migrating, so only one instance migrates the schema.
+const migrationLockID = 0x616c696365 // alice
+
+// The meta table is created before the migrations
+// are applied.
+const metaTable = `
+	CREATE TABLE IF NOT EXISTS __meta__ (
+		version     INTEGER   NOT NULL  UNIQUE,
+		description TEXT      NOT NULL,
+		applied_at  TIMESTAMP NOT NULL  DEFAULT CURRENT_TIMESTAMP
+	)
+`
 
 // Status is the database / store status
 type Status struct {
-	Migrated        bool      `json:"migrated"`
-	SchemaVersion   int       `json:"schema_version"`
-	SchemaAppliedAt time.Time `json:"schema_applied_at"`
-	Error           error     `json:"error"`
+	Migrated          bool         `json:"migrated"`
+	SchemaVersion     int          `json:"schema_version"`
+	SchemaAppliedAt   time.Time    `json:"schema_applied_at"`
+	PendingMigrations []*Migration `json:"pending_migrations"`
+	Error             error        `json:"error"`
 }
 
 // Log writes the status into the log
@@ -37,6 +48,10 @@ func (s *Status) Log() {
 	log.Println(
 		"Schema version:", s.SchemaVersion,
 		"applied at:", s.SchemaAppliedAt)
+	for _, m := range s.PendingMigrations {
+		log.Println(
+			"Pending migration:", m.Version, m.Description)
+	}
 }
 
 // The Manager supervises the database. It can migrate the
@@ -62,19 +77,25 @@ func (m *Manager) Start(ctx context.Context) {
 // it will be included in the result.
 func (m *Manager) Status(ctx context.Context) *Status {
 	status := &Status{}
+	migrations, err := loadMigrations()
+	if err != nil {
+		status.Error = err
+		return status
+	}
 	qry := `
 		SELECT version, applied_at FROM __meta__
 		 ORDER BY version DESC
 		 LIMIT 1
 	`
-	err := m.pool.QueryRow(ctx, qry).Scan(
+	err = m.pool.QueryRow(ctx, qry).Scan(
 		&status.SchemaVersion,
 		&status.SchemaAppliedAt,
 	)
-	if err != nil {
+	if err != nil && err != pgx.ErrNoRows {
 		status.Error = err
-		return status
 	}
+	status.PendingMigrations = pendingMigrations(
+		migrations, status.SchemaVersion)
 
 	// Is the database migrated?
 	status.Migrated = CurrentSchemaVersion == status.SchemaVersion
@@ -82,22 +103,104 @@ func (m *Manager) Status(ctx context.Context) *Status {
 	return status
 }
 
-// Migrate applies the database intialisation script if required.
+// Migrate applies all migrations newer than the current
+// schema version. Each migration is applied in a transaction.
+// An advisory lock is held while migrating, so concurrently
+// started instances wait for the migration to complete.
 func (m *Manager) Migrate(ctx context.Context) error {
-	s := m.Status(ctx)
-	if s.Migrated {
-		return nil
-	}
-
-	return m.Initialize(ctx)
+	return m.withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		return m.migrate(ctx, conn)
+	})
 }
 
 // Initialize will apply the database schema. This will clear the
 // database. However for now we treat the state as disposable.
 func (m *Manager) Initialize(ctx context.Context) error {
-	_, err := m.pool.Exec(ctx, schema)
+	return m.withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		if _, err := conn.Exec(ctx, resetSchema); err != nil {
+			return err
+		}
+		return m.migrate(ctx, conn)
+	})
+}
+
+// Private withMigrationLock acquires the advisory lock
+// on a connection and calls fn.
+func (m *Manager) withMigrationLock(
+	ctx context.Context,
+	fn func(conn *pgxpool.Conn) error,
+) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(
+		ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return err
+	}
+	defer func() {
+		_, err := conn.Exec(
+			context.Background(),
+			"SELECT pg_advisory_unlock($1)", migrationLockID)
+		if err != nil {
+			log.Println("could not release migration lock:", err)
+		}
+	}()
+
+	return fn(conn)
+}
+
+// Private migrate applies the pending migrations.
+// The migration lock must be held.
+func (m *Manager) migrate(ctx context.Context, conn *pgxpool.Conn) error {
+	migrations, err := loadMigrations()
 	if err != nil {
 		return err
 	}
+	if _, err := conn.Exec(ctx, metaTable); err != nil {
+		return err
+	}
+
+	var version int
+	qry := `SELECT COALESCE(MAX(version), 0) FROM __meta__`
+	if err := conn.QueryRow(ctx, qry).Scan(&version); err != nil {
+		return err
+	}
+	if version > CurrentSchemaVersion {
+		return ErrSchemaTooNew
+	}
+
+	for _, mig := range pendingMigrations(migrations, version) {
+		log.Println(
+			"Applying schema migration:", mig.Version, mig.Description)
+		if err := m.apply(ctx, conn, mig); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// Private apply runs a migration and records the
+// version in the meta table.
+func (m *Manager) apply(
+	ctx context.Context,
+	conn *pgxpool.Conn,
+	mig *Migration,
+) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, mig.script); err != nil {
+		return err
+	}
+	qry := `INSERT INTO __meta__ (version, description) VALUES ($1, $2)`
+	if _, err := tx.Exec(ctx, qry, mig.Version, mig.Description); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	p := ConnectTest() // Initializes the database
+	m := NewManager(p)
+
+	// Migrating a migrated database does nothing
+	if err := m.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	s := m.Status(ctx)
+	if s.Error != nil {
+		t.Fatal(s.Error)
+	}
+	if !s.Migrated || len(s.PendingMigrations) != 0 {
+		t.Error("unexpected status:", s)
+	}
+
+	// Migrations are applied from the recorded version
+	if _, err := p.Exec(ctx, `
+		DROP INDEX idx_routes_neighbor_asn, idx_routes_origin_asn,
+			idx_routes_as_path, idx_routes_communities,
+			idx_routes_large_communities, idx_routes_ext_communities;
+		ALTER TABLE routes
+			DROP COLUMN neighbor_asn, DROP COLUMN origin_asn,
+			DROP COLUMN as_path, DROP COLUMN communities,
+			DROP COLUMN large_communities, DROP COLUMN ext_communities;
+		DELETE FROM __meta__ WHERE version = 2;
+	`); err != nil {
+		t.Fatal(err)
+	}
+	s = m.Status(ctx)
+	if s.Migrated || len(s.PendingMigrations) != 1 {
+		t.Error("expected a pending migration:", s)
+	}
+	if err := m.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s := m.Status(ctx); !s.Migrated {
+		t.Error("expected database to be migrated:", s)
+	}
+}
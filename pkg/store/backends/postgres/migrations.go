@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The migrations are SQL scripts named by version and
+// description, e.g. 002_route_attributes.sql. Migrations
+// are forward-only: A released migration must never be
+// changed, the schema is changed by adding a migration.
+//
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+var (
+	// ErrSchemaTooNew is returned when the database schema
+	// was migrated by a newer version of alice.
+	ErrSchemaTooNew = errors.New(
+		"database schema is newer than the supported schema")
+)
+
+// Migration is a versioned change of the schema
+type Migration struct {
+	Version     int    `json:"version"`
+	Description string `json:"description"`
+
+	script string
+}
+
+// parseMigrationName extracts the version and the
+// description from the file name.
+func parseMigrationName(name string) (int, string, error) {
+	name = strings.TrimSuffix(path.Base(name), ".sql")
+	tokens := strings.SplitN(name, "_", 2)
+	if len(tokens) != 2 {
+		return 0, "", fmt.Errorf("invalid migration name: %s", name)
+	}
+	version, err := strconv.Atoi(tokens[0])
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid migration version: %s", name)
+	}
+	return version, strings.ReplaceAll(tokens[1], "_", " "), nil
+}
+
+// loadMigrations reads the embedded migrations
+// ordered by version.
+func loadMigrations() ([]*Migration, error) {
+	files, err := migrationsFS.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+	migrations := make([]*Migration, 0, len(files))
+	for _, f := range files {
+		version, description, err := parseMigrationName(f.Name())
+		if err != nil {
+			return nil, err
+		}
+		script, err := migrationsFS.ReadFile("migrations/" + f.Name())
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, &Migration{
+			Version:     version,
+			Description: description,
+			script:      string(script),
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf(
+				"missing or duplicate migration: %d", i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// pendingMigrations returns the migrations
+// after the version.
+func pendingMigrations(
+	migrations []*Migration,
+	version int,
+) []*Migration {
+	pending := []*Migration{}
+	for _, m := range migrations {
+		if m.Version > version {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
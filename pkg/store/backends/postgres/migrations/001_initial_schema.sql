@@ -0,0 +1,54 @@
+
+--
+-- ----------------------
+-- AliceLG schema v.1.0.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Apply alice-lg db schema.
+--
+
+-- Neighbors
+CREATE TABLE neighbors (
+    id    VARCHAR(255) NOT NULL,
+
+    -- Indexed attributes
+    rs_id VARCHAR(255) NOT NULL,
+
+    -- JSON serialized neighbor
+    neighbor     jsonb NOT NULL,
+
+    -- Timestamps
+    updated_at  TIMESTAMP  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Constraints
+    PRIMARY KEY(id, rs_id)
+);
+
+CREATE INDEX idx_neighbors_rs_id 
+          ON neighbors             USING HASH (rs_id);
+CREATE INDEX idx_neighbors_updated_at 
+          ON neighbors ( updated_at );
+
+-- Routes
+CREATE TABLE routes (
+    id            VARCHAR(255) NOT NULL,
+    rs_id         VARCHAR(255) NOT NULL,
+    neighbor_id   VARCHAR(255) NOT NULL,
+
+    -- Indexed attributes 
+    network       VARCHAR(50)  NOT NULL,
+   
+    -- JSON serialized route
+    route         jsonb        NOT NULL,
+
+    -- Timestamps
+    updated_at  TIMESTAMP  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- Constraints
+    PRIMARY KEY(id, rs_id, neighbor_id)
+);
+
+CREATE INDEX idx_routes_network    ON routes ( network );
+CREATE INDEX idx_neighbor_id       ON routes ( neighbor_id );
+CREATE INDEX idx_routes_updated_at ON routes ( updated_at );
@@ -0,0 +1,26 @@
+--
+-- %% Description: Store route attributes in indexed columns.
+--
+-- The routes tables of the sources are created
+-- like the routes table and inherit the columns
+-- and indexes.
+--
+
+ALTER TABLE routes
+    ADD COLUMN neighbor_asn      INTEGER,
+    ADD COLUMN origin_asn        INTEGER,
+    ADD COLUMN as_path           INTEGER[] NOT NULL DEFAULT '{}',
+    ADD COLUMN communities       TEXT[]    NOT NULL DEFAULT '{}',
+    ADD COLUMN large_communities TEXT[]    NOT NULL DEFAULT '{}',
+    ADD COLUMN ext_communities   TEXT[]    NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_routes_neighbor_asn ON routes ( neighbor_asn );
+CREATE INDEX idx_routes_origin_asn   ON routes ( origin_asn );
+CREATE INDEX idx_routes_as_path
+          ON routes USING GIN ( as_path );
+CREATE INDEX idx_routes_communities
+          ON routes USING GIN ( communities );
+CREATE INDEX idx_routes_large_communities
+          ON routes USING GIN ( large_communities );
+CREATE INDEX idx_routes_ext_communities
+          ON routes USING GIN ( ext_communities );
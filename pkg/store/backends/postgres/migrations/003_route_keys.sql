@@ -0,0 +1,10 @@
+--
+-- %% Description: Identify routes by the route key.
+--
+-- The key of VPN routes and flowspec rules includes
+-- the route distinguisher and the rules, which can
+-- exceed the length of the network.
+--
+
+ALTER TABLE routes
+    ALTER COLUMN id TYPE TEXT;
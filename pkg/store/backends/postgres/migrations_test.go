@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"testing"
+)
+
+func TestParseMigrationName(t *testing.T) {
+	version, description, err := parseMigrationName(
+		"migrations/002_route_attributes.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 2 {
+		t.Error("unexpected version:", version)
+	}
+	if description != "route attributes" {
+		t.Error("unexpected description:", description)
+	}
+
+	if _, _, err := parseMigrationName("routes.sql"); err == nil {
+		t.Error("expected an error")
+	}
+	if _, _, err := parseMigrationName("two_routes.sql"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := migrations[len(migrations)-1]
+	if last.Version != CurrentSchemaVersion {
+		t.Error("the last migration should be the current version:",
+			last.Version)
+	}
+
+	pending := pendingMigrations(migrations, 1)
+	if len(pending) != len(migrations)-1 || pending[0].Version != 2 {
+		t.Error("unexpected pending migrations:", pending)
+	}
+	if len(pendingMigrations(migrations, CurrentSchemaVersion)) != 0 {
+		t.Error("expected no pending migrations")
+	}
+}
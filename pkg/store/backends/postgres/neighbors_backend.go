@@ -183,3 +183,21 @@ func (b *NeighborsBackend) CountNeighborsAt(
 	}
 	return count, nil
 }
+
+// RemoveSource deletes all neighbors of a route server.
+func (b *NeighborsBackend) RemoveSource(
+	ctx context.Context,
+	sourceID string,
+) error {
+	tx, err := b.pool.BeginTx(ctx, pgx.TxOptions{
+		IsoLevel: pgx.ReadCommitted,
+	})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := b.clear(ctx, tx, sourceID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,8 @@
+--
+-- %% Description: Clear the database. The schema
+--                  is applied by the migrations.
+--
+
+DROP TABLE IF EXISTS routes;
+DROP TABLE IF EXISTS neighbors;
+DROP TABLE IF EXISTS __meta__;
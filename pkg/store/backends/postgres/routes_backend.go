@@ -4,11 +4,14 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/alice-lg/alice-lg/pkg/api"
 	"github.com/alice-lg/alice-lg/pkg/config"
+	"github.com/alice-lg/alice-lg/pkg/sources"
 
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -22,8 +25,12 @@ var (
 
 // RoutesBackend implements a postgres store for routes.
 type RoutesBackend struct {
-	pool    *pgxpool.Pool
-	sources []*config.SourceConfig
+	pool *pgxpool.Pool
+
+	// The source IDs with a routes table. Sources
+	// can be added and removed at runtime.
+	sources map[string]bool
+	sync.Mutex
 }
 
 // NewRoutesBackend creates a new instance with a postgres
@@ -32,10 +39,27 @@ func NewRoutesBackend(
 	pool *pgxpool.Pool,
 	sources []*config.SourceConfig,
 ) *RoutesBackend {
+	ids := make(map[string]bool)
+	for _, src := range sources {
+		ids[src.ID] = true
+	}
 	return &RoutesBackend{
 		pool:    pool,
-		sources: sources,
+		sources: ids,
+	}
+}
+
+// Private sourceIDs returns the sorted list of
+// sources with a routes table.
+func (b *RoutesBackend) sourceIDs() []string {
+	b.Lock()
+	defer b.Unlock()
+	ids := make([]string, 0, len(b.sources))
+	for id := range b.sources {
+		ids = append(ids, id)
 	}
+	sort.Strings(ids)
+	return ids
 }
 
 // Init will initialize all the route tables
@@ -48,8 +72,8 @@ func (b *RoutesBackend) Init(ctx context.Context) error {
 	}
 	defer tx.Rollback(ctx)
 
-	for _, src := range b.sources {
-		if err := b.initTable(ctx, tx, src.ID); err != nil {
+	for _, id := range b.sourceIDs() {
+		if err := b.initTable(ctx, tx, id); err != nil {
 			return err
 		}
 	}
@@ -89,9 +113,71 @@ func (b *RoutesBackend) SetRoutes(
 	if err := tx.Commit(ctx); err != nil {
 		return err
 	}
+
+	// The table exists now and can be queried
+	b.Lock()
+	b.sources[sourceID] = true
+	b.Unlock()
+
 	return nil
 }
 
+// UpdateRoutes applies the changes of the routes per
+// neighbor: Withdrawn routes are deleted and added
+// routes are upserted.
+func (b *RoutesBackend) UpdateRoutes(
+	ctx context.Context,
+	sourceID string,
+	updates []*api.RoutesUpdate,
+) error {
+	b.Lock()
+	known := b.sources[sourceID]
+	b.Unlock()
+	if !known {
+		return sources.ErrSourceNotFound
+	}
+
+	now := time.Now().UTC()
+	tx, err := b.pool.BeginTx(ctx, pgx.TxOptions{
+		IsoLevel: pgx.ReadCommitted,
+	})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tbl := b.routesTable(sourceID)
+	for _, u := range updates {
+		if len(u.Withdrawn) > 0 {
+			qry := `DELETE FROM ` + tbl + `
+					 WHERE neighbor_id = $1 AND id = ANY($2)`
+			if _, err := tx.Exec(ctx, qry, u.NeighborID, u.Withdrawn); err != nil {
+				return err
+			}
+		}
+		for _, r := range u.Added {
+			if err := b.persist(ctx, tx, sourceID, r, now); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// RemoveSource drops the routes table of a source.
+func (b *RoutesBackend) RemoveSource(
+	ctx context.Context,
+	sourceID string,
+) error {
+	b.Lock()
+	delete(b.sources, sourceID)
+	b.Unlock()
+
+	qry := `DROP TABLE IF EXISTS ` + b.routesTable(sourceID)
+	_, err := b.pool.Exec(ctx, qry)
+	return err
+}
+
 // Private routesTable returns the name of the routes table
 // for a sourceID
 func (b *RoutesBackend) routesTable(sourceID string) string {
@@ -115,7 +201,7 @@ func (b *RoutesBackend) initTable(
 	return err
 }
 
-// Private persist route in database
+// Private persist upserts a route in the database
 func (b *RoutesBackend) persist(
 	ctx context.Context,
 	tx pgx.Tx,
@@ -130,19 +216,42 @@ func (b *RoutesBackend) persist(
 				rs_id,
 				neighbor_id,
 				network,
+				neighbor_asn,
+				origin_asn,
+				as_path,
+				communities,
+				large_communities,
+				ext_communities,
 				route,
 				updated_at
 			) VALUES (
-				$1, $2, $3, $4, $5, $6
+				$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
 			)
+		ON CONFLICT (id, rs_id, neighbor_id) DO UPDATE SET
+				network = EXCLUDED.network,
+				neighbor_asn = EXCLUDED.neighbor_asn,
+				origin_asn = EXCLUDED.origin_asn,
+				as_path = EXCLUDED.as_path,
+				communities = EXCLUDED.communities,
+				large_communities = EXCLUDED.large_communities,
+				ext_communities = EXCLUDED.ext_communities,
+				route = EXCLUDED.route,
+				updated_at = EXCLUDED.updated_at
 	`
+	attrs := newRouteAttributes(route)
 	_, err := tx.Exec(
 		ctx,
 		qry,
-		route.Route.Network,
+		route.Route.Key(),
 		sourceID,
 		route.Neighbor.ID,
 		route.Route.Network,
+		route.Neighbor.ASN,
+		attrs.originASN,
+		attrs.asPath,
+		attrs.communities,
+		attrs.largeCommunities,
+		attrs.extCommunities,
 		route,
 		now)
 	return err
@@ -224,22 +333,24 @@ func (b *RoutesBackend) FindByNeighbors(
 	}
 	defer tx.Rollback(ctx)
 
-	vals := make([]interface{}, 0, len(neighbors))
-	vars := 0
-
+	cond, vals := filtersCondition(filters, []interface{}{})
 	qrys := []string{}
 
 	for _, neighborQuery := range neighbors {
+		if !matchSource(filters, *neighborQuery.SourceID) {
+			continue
+		}
 		tbl := b.routesTable(*neighborQuery.SourceID)
-		param := fmt.Sprintf("$%d", vars+1)
+		param := fmt.Sprintf("$%d", len(vals)+1)
 		vals = append(vals, *neighborQuery.NeighborID)
 
 		qry := `
 			SELECT route FROM ` + tbl + `
-			 WHERE neighbor_id = ` + param
+			 WHERE neighbor_id = ` + param + cond
 		qrys = append(qrys, qry)
-
-		vars++
+	}
+	if len(qrys) == 0 {
+		return api.LookupRoutes{}, nil
 	}
 
 	qry := strings.Join(qrys, " UNION ")
@@ -249,7 +360,7 @@ func (b *RoutesBackend) FindByNeighbors(
 		return nil, err
 	}
 
-	return fetchRoutes(rows, filters, 0)
+	return fetchRoutes(rows, 0)
 }
 
 // FindByPrefix will return the prefixes matching a pattern
@@ -267,27 +378,36 @@ func (b *RoutesBackend) FindByPrefix(
 	}
 	defer tx.Rollback(ctx)
 	// We are searching route.Network
+	ids := []string{}
+	for _, id := range b.sourceIDs() {
+		if matchSource(filters, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return api.LookupRoutes{}, nil
+	}
+	cond, vals := filtersCondition(filters, []interface{}{prefix + "%"})
 	qrys := []string{}
-	for _, src := range b.sources {
-		tbl := b.routesTable(src.ID)
+	for _, id := range ids {
+		tbl := b.routesTable(id)
 		qry := `
 			SELECT route FROM ` + tbl + `
-			 WHERE network ILIKE $1
-		`
+			 WHERE network ILIKE $1` + cond
 		qrys = append(qrys, qry)
 	}
 	qry := strings.Join(qrys, " UNION ")
-	rows, err := tx.Query(ctx, qry, prefix+"%")
+	rows, err := tx.Query(ctx, qry, vals...)
 	if err != nil {
 		return nil, err
 	}
-	return fetchRoutes(rows, filters, limit)
+	return fetchRoutes(rows, limit)
 }
 
-// Private fetchRoutes will load the queried result set
+// Private fetchRoutes will load the queried result set.
+// The routes are filtered by the query.
 func fetchRoutes(
 	rows pgx.Rows,
-	filters *api.SearchFilters,
 	limit uint,
 ) (api.LookupRoutes, error) {
 	var count uint
@@ -298,9 +418,6 @@ func fetchRoutes(
 		if err := rows.Scan(&route); err != nil {
 			return nil, err
 		}
-		if !filters.MatchRoute(route) {
-			continue
-		}
 		results = append(results, route)
 		count++
 		if limit > 0 && count >= limit {
@@ -79,13 +79,10 @@ func TestFindByNeighbors(t *testing.T) {
 		t.Fatal(err)
 	}
 	defer tx.Rollback(ctx)
-	b := &RoutesBackend{
-		pool: pool,
-		sources: []*config.SourceConfig{
-			{ID: "rs1"},
-			{ID: "rs2"},
-		},
-	}
+	b := NewRoutesBackend(pool, []*config.SourceConfig{
+		{ID: "rs1"},
+		{ID: "rs2"},
+	})
 	r := &api.LookupRoute{
 		State: "filtered",
 		Neighbor: &api.Neighbor{
@@ -145,13 +142,10 @@ func TestFindByPrefix(t *testing.T) {
 		t.Fatal(err)
 	}
 	defer tx.Rollback(ctx)
-	b := &RoutesBackend{
-		pool: pool,
-		sources: []*config.SourceConfig{
-			{ID: "rs1"},
-			{ID: "rs2"},
-		},
-	}
+	b := NewRoutesBackend(pool, []*config.SourceConfig{
+		{ID: "rs1"},
+		{ID: "rs2"},
+	})
 	r := &api.LookupRoute{
 		State: "filtered",
 		Neighbor: &api.Neighbor{
@@ -193,3 +187,81 @@ func TestFindByPrefix(t *testing.T) {
 	routes, _ = b.FindByPrefix(ctx, "5.5.", api.NewSearchFilters(), 0)
 	t.Log(routes)
 }
+
+func TestFindByPrefixFilters(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	pool := ConnectTest()
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback(ctx)
+	b := NewRoutesBackend(pool, []*config.SourceConfig{
+		{ID: "rs1"},
+		{ID: "rs2"},
+	})
+	b.initTable(ctx, tx, "rs1")
+	b.initTable(ctx, tx, "rs2")
+
+	r := &api.LookupRoute{
+		State: "imported",
+		Neighbor: &api.Neighbor{
+			ID:  "n23",
+			ASN: 2342,
+		},
+		Route: &api.Route{
+			Network: "1.2.3.0/24",
+			BGP: &api.BGPInfo{
+				Communities:    api.Communities{{23, 42}, {42, 23}},
+				ExtCommunities: api.ExtCommunities{{"ro", 23, 42}},
+			},
+		},
+	}
+	b.persist(ctx, tx, "rs1", r, now)
+
+	r.Route.Network = "1.2.4.0/24"
+	r.Route.BGP.Communities = api.Communities{{23, 42}}
+	b.persist(ctx, tx, "rs2", r, now)
+
+	r.Route.Network = "1.2.5.0/24"
+	r.Neighbor.ASN = 2343
+	b.persist(ctx, tx, "rs2", r, now)
+
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	filters, _ := api.FiltersFromTokens([]string{"#23:42"})
+	routes, err := b.FindByPrefix(ctx, "1.2.", filters, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 3 {
+		t.Error("unexpected routes:", routes)
+	}
+
+	filters, _ = api.FiltersFromTokens([]string{"#23:42", "#42:23"})
+	routes, _ = b.FindByPrefix(ctx, "1.2.", filters, 0)
+	if len(routes) != 1 {
+		t.Error("unexpected routes:", routes)
+	}
+
+	filters, _ = api.FiltersFromTokens([]string{"#ro:23:42"})
+	filters.GetGroupByKey(api.SearchKeyASNS).AddFilter(&api.SearchFilter{
+		Value: 2343,
+	})
+	routes, _ = b.FindByPrefix(ctx, "1.2.", filters, 0)
+	if len(routes) != 1 {
+		t.Error("unexpected routes:", routes)
+	}
+
+	filters = api.NewSearchFilters()
+	filters.GetGroupByKey(api.SearchKeySources).AddFilter(&api.SearchFilter{
+		Value: "rs2",
+	})
+	routes, _ = b.FindByPrefix(ctx, "1.2.", filters, 0)
+	if len(routes) != 2 {
+		t.Error("unexpected routes:", routes)
+	}
+}
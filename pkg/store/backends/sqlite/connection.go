@@ -0,0 +1,44 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+
+	"github.com/alice-lg/alice-lg/pkg/config"
+
+	_ "modernc.org/sqlite" // pure go sqlite driver
+)
+
+var (
+	// ErrPathUnconfigured will be returned, if the
+	// path of the database file is not set.
+	ErrPathUnconfigured = errors.New("database path not configured")
+)
+
+// Open opens the database file and configures the
+// connection. The file is created if it does not exist.
+func Open(ctx context.Context, opts *config.SqliteConfig) (*sql.DB, error) {
+	if opts.Path == "" {
+		return nil, ErrPathUnconfigured
+	}
+
+	// Concurrent readers are possible with the write
+	// ahead log. Writers wait for the lock.
+	params := url.Values{}
+	params.Add("_pragma", "busy_timeout(10000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "synchronous(NORMAL)")
+	params.Add("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", "file:"+opts.Path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,51 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/alice-lg/alice-lg/pkg/config"
+)
+
+// OpenTest creates a new database in a temporary
+// directory and applies the schema.
+func OpenTest(t *testing.T) *sql.DB {
+	ctx := context.Background()
+	db, err := Open(ctx, &config.SqliteConfig{
+		Path: filepath.Join(t.TempDir(), "alice.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	m := NewManager(db)
+	if err := m.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestInitialize(t *testing.T) {
+	db := OpenTest(t)
+	m := NewManager(db)
+	s := m.Status(context.Background())
+	if s.Error != nil {
+		t.Error(s.Error)
+	}
+	if s.Migrated == false {
+		t.Error("schema is not migrated, current:", s.SchemaVersion)
+	}
+	if s.SchemaAppliedAt.IsZero() {
+		t.Error("expected schema applied at")
+	}
+}
+
+func TestOpenWithoutPath(t *testing.T) {
+	_, err := Open(context.Background(), &config.SqliteConfig{})
+	if err != ErrPathUnconfigured {
+		t.Error("unexpected error:", err)
+	}
+}
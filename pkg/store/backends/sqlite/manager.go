@@ -0,0 +1,94 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	_ "embed" // embed schema
+	"log"
+	"time"
+)
+
+// Include the schema through embedding
+//
+//go:embed schema.sql
+var schema string
+
+// CurrentSchemaVersion is the current version of the schema
+const CurrentSchemaVersion = 1
+
+// Status is the database / store status
+type Status struct {
+	Migrated        bool      `json:"migrated"`
+	SchemaVersion   int       `json:"schema_version"`
+	SchemaAppliedAt time.Time `json:"schema_applied_at"`
+	Error           error     `json:"error"`
+}
+
+// Log writes the status into the log
+func (s *Status) Log() {
+	log.Println(
+		"Database migrated:", s.Migrated)
+	log.Println(
+		"Schema version:", s.SchemaVersion,
+		"applied at:", s.SchemaAppliedAt)
+}
+
+// The Manager supervises the database. It can migrate the
+// schema and retrieve a status.
+type Manager struct {
+	db *sql.DB
+}
+
+// NewManager creates a new database manager
+func NewManager(db *sql.DB) *Manager {
+	return &Manager{
+		db: db,
+	}
+}
+
+// Start the background jobs for database management
+func (m *Manager) Start(ctx context.Context) {
+	m.Status(ctx).Log()
+}
+
+// Status retrieves the current schema version
+// and checks if migrated. In case an error occures,
+// it will be included in the result.
+func (m *Manager) Status(ctx context.Context) *Status {
+	status := &Status{}
+	qry := `
+		SELECT version, applied_at FROM __meta__
+		 ORDER BY version DESC
+		 LIMIT 1
+	`
+	err := m.db.QueryRowContext(ctx, qry).Scan(
+		&status.SchemaVersion,
+		&status.SchemaAppliedAt,
+	)
+	if err != nil {
+		status.Error = err
+		return status
+	}
+
+	// Is the database migrated?
+	status.Migrated = CurrentSchemaVersion == status.SchemaVersion
+
+	return status
+}
+
+// Migrate applies the database intialisation script if required.
+func (m *Manager) Migrate(ctx context.Context) error {
+	s := m.Status(ctx)
+	if s.Migrated {
+		return nil
+	}
+
+	return m.Initialize(ctx)
+}
+
+// Initialize will apply the database schema. This will clear the
+// database. However for now we treat the state as disposable.
+func (m *Manager) Initialize(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, schema)
+	return err
+}
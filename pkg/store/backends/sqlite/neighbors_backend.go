@@ -0,0 +1,180 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+)
+
+// NeighborsBackend implements a neighbors store
+// using a sqlite database
+type NeighborsBackend struct {
+	db *sql.DB
+}
+
+// NewNeighborsBackend initializes the backend
+// with a database.
+func NewNeighborsBackend(db *sql.DB) *NeighborsBackend {
+	b := &NeighborsBackend{
+		db: db,
+	}
+	return b
+}
+
+// SetNeighbors updates the current neighbors of
+// a route server identified by sourceID
+func (b *NeighborsBackend) SetNeighbors(
+	ctx context.Context,
+	sourceID string,
+	neighbors api.Neighbors,
+) error {
+	now := time.Now().UTC()
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Clear current neighbors
+	if err := b.clear(ctx, tx, sourceID); err != nil {
+		return err
+	}
+
+	// Set neighbors
+	for _, n := range neighbors {
+		if err := b.persist(ctx, tx, sourceID, n, now); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Private persist saves a neighbor to the database
+func (b *NeighborsBackend) persist(
+	ctx context.Context,
+	tx *sql.Tx,
+	sourceID string,
+	neighbor *api.Neighbor,
+	now time.Time,
+) error {
+	data, err := json.Marshal(neighbor)
+	if err != nil {
+		return err
+	}
+	qry := `
+	  INSERT INTO neighbors (
+	  		id, rs_id, neighbor, updated_at
+		) VALUES ( ?, ?, ?, ? )
+	`
+	_, err = tx.ExecContext(ctx, qry, neighbor.ID, sourceID, data, now)
+	return err
+}
+
+// Private clear removes all neighbors for a RS
+func (b *NeighborsBackend) clear(
+	ctx context.Context,
+	tx *sql.Tx,
+	sourceID string,
+) error {
+	qry := `
+	  DELETE FROM neighbors WHERE rs_id = ?
+	`
+	_, err := tx.ExecContext(ctx, qry, sourceID)
+	return err
+}
+
+// Private queryNeighborsAt selects all neighbors
+// for a given sourceID
+func (b *NeighborsBackend) queryNeighborsAt(
+	ctx context.Context,
+	sourceID string,
+) (api.Neighbors, error) {
+	qry := `
+		SELECT neighbor
+		  FROM neighbors
+		 WHERE rs_id = ?
+	`
+	rows, err := b.db.QueryContext(ctx, qry, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := api.Neighbors{}
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		neighbor := &api.Neighbor{}
+		if err := json.Unmarshal(data, neighbor); err != nil {
+			return nil, err
+		}
+		results = append(results, neighbor)
+	}
+	return results, rows.Err()
+}
+
+// GetNeighborsAt retrieves all neighbors associated
+// with a route server (source).
+func (b *NeighborsBackend) GetNeighborsAt(
+	ctx context.Context,
+	sourceID string,
+) (api.Neighbors, error) {
+	return b.queryNeighborsAt(ctx, sourceID)
+}
+
+// GetNeighborsMapAt retrieve a neighbor map for a route server.
+// The Neighbor is identified by ID.
+func (b *NeighborsBackend) GetNeighborsMapAt(
+	ctx context.Context,
+	sourceID string,
+) (map[string]*api.Neighbor, error) {
+	neighbors, err := b.queryNeighborsAt(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	results := make(map[string]*api.Neighbor)
+	for _, neighbor := range neighbors {
+		results[neighbor.ID] = neighbor
+	}
+	return results, nil
+}
+
+// CountNeighborsAt retrieves the current number of
+// stored neighbors.
+func (b *NeighborsBackend) CountNeighborsAt(
+	ctx context.Context,
+	sourceID string,
+) (int, error) {
+	qry := `
+		SELECT COUNT(1) FROM neighbors WHERE rs_id = ?
+	`
+	count := 0
+	err := b.db.QueryRowContext(ctx, qry, sourceID).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// RemoveSource deletes all neighbors of a route server.
+func (b *NeighborsBackend) RemoveSource(
+	ctx context.Context,
+	sourceID string,
+) error {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := b.clear(ctx, tx, sourceID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,7 @@
+// Package sqlite is an implementation of a routes and neighbors
+// store using an embedded sqlite database.
+//
+// The schema follows the postgres backend: Routes and neighbors
+// are stored as json with indexes for query performance.
+// This is intended for single node deployments.
+package sqlite
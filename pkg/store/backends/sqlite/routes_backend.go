@@ -0,0 +1,315 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+)
+
+// RoutesBackend implements a sqlite store for routes.
+// Unlike the postgres backend, the routes of all sources
+// are stored in a single table.
+type RoutesBackend struct {
+	db *sql.DB
+}
+
+// NewRoutesBackend creates a new instance with a
+// database.
+func NewRoutesBackend(db *sql.DB) *RoutesBackend {
+	return &RoutesBackend{
+		db: db,
+	}
+}
+
+// SetRoutes implements the RoutesStoreBackend interface
+// function for setting all routes of a source identified
+// by ID.
+func (b *RoutesBackend) SetRoutes(
+	ctx context.Context,
+	sourceID string,
+	routes api.LookupRoutes,
+) error {
+	now := time.Now().UTC()
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Remove the previous routes
+	if err := b.clear(ctx, tx, sourceID); err != nil {
+		return err
+	}
+
+	// persist all routes
+	stmt, err := b.prepare(ctx, tx)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range routes {
+		if err := b.persist(ctx, stmt, sourceID, r, now); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UpdateRoutes applies the changes of the routes per
+// neighbor: Withdrawn routes are deleted and added
+// routes are replaced.
+func (b *RoutesBackend) UpdateRoutes(
+	ctx context.Context,
+	sourceID string,
+	updates []*api.RoutesUpdate,
+) error {
+	now := time.Now().UTC()
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	del, err := tx.PrepareContext(ctx, `
+		DELETE FROM routes
+		 WHERE rs_id = ? AND neighbor_id = ? AND id = ?
+	`)
+	if err != nil {
+		return err
+	}
+	defer del.Close()
+
+	stmt, err := b.prepare(ctx, tx)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, u := range updates {
+		for _, id := range u.Withdrawn {
+			_, err := del.ExecContext(ctx, sourceID, u.NeighborID, id)
+			if err != nil {
+				return err
+			}
+		}
+		for _, r := range u.Added {
+			if err := b.persist(ctx, stmt, sourceID, r, now); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// Private prepare creates the insert statement
+// for persisting routes.
+func (b *RoutesBackend) prepare(
+	ctx context.Context,
+	tx *sql.Tx,
+) (*sql.Stmt, error) {
+	qry := `
+		INSERT OR REPLACE INTO routes (
+				id,
+				rs_id,
+				neighbor_id,
+				network,
+				route,
+				updated_at
+			) VALUES (
+				?, ?, ?, ?, ?, ?
+			)
+	`
+	return tx.PrepareContext(ctx, qry)
+}
+
+// Private persist route in database
+func (b *RoutesBackend) persist(
+	ctx context.Context,
+	stmt *sql.Stmt,
+	sourceID string,
+	route *api.LookupRoute,
+	now time.Time,
+) error {
+	data, err := json.Marshal(route)
+	if err != nil {
+		return err
+	}
+	_, err = stmt.ExecContext(
+		ctx,
+		route.Route.Key(),
+		sourceID,
+		route.Neighbor.ID,
+		route.Route.Network,
+		data,
+		now)
+	return err
+}
+
+// Private clear removes all routes of a source.
+func (b *RoutesBackend) clear(
+	ctx context.Context,
+	tx *sql.Tx,
+	sourceID string,
+) error {
+	qry := `
+	  DELETE FROM routes WHERE rs_id = ?
+	`
+	_, err := tx.ExecContext(ctx, qry, sourceID)
+	return err
+}
+
+// CountRoutesAt returns the number of filtered and imported
+// routes and implements the RoutesStoreBackend interface.
+func (b *RoutesBackend) CountRoutesAt(
+	ctx context.Context,
+	sourceID string,
+) (uint, uint, error) {
+	qry := `
+		SELECT json_extract(route, '$.state'), COUNT(1)
+		  FROM routes
+		 WHERE rs_id = ?
+		 GROUP BY 1
+	`
+	rows, err := b.db.QueryContext(ctx, qry, sourceID)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+
+	var (
+		imported uint
+		filtered uint
+	)
+	for rows.Next() {
+		var (
+			state string
+			count uint
+		)
+		if err := rows.Scan(&state, &count); err != nil {
+			return 0, 0, err
+		}
+		switch state {
+		case api.RouteStateImported:
+			imported = count
+		case api.RouteStateFiltered:
+			filtered = count
+		}
+	}
+	return imported, filtered, rows.Err()
+}
+
+// FindByNeighbors will return the prefixes for a
+// list of neighbors identified by ID.
+func (b *RoutesBackend) FindByNeighbors(
+	ctx context.Context,
+	neighbors []*api.NeighborQuery,
+	filters *api.SearchFilters,
+) (api.LookupRoutes, error) {
+	if len(neighbors) == 0 {
+		return api.LookupRoutes{}, nil
+	}
+
+	vals := make([]interface{}, 0, 2*len(neighbors))
+	conds := make([]string, 0, len(neighbors))
+	for _, neighborQuery := range neighbors {
+		conds = append(conds, "(rs_id = ? AND neighbor_id = ?)")
+		vals = append(vals,
+			*neighborQuery.SourceID,
+			*neighborQuery.NeighborID)
+	}
+
+	qry := `
+		SELECT route FROM routes
+		 WHERE ` + strings.Join(conds, " OR ")
+
+	rows, err := b.db.QueryContext(ctx, qry, vals...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return fetchRoutes(rows, filters, 0)
+}
+
+// Search the routes by the prefix of the network. LIKE is
+// case insensitive and uses the NOCASE index on the network.
+const findByPrefixQuery = `
+	SELECT route FROM routes
+	 WHERE network LIKE ? ESCAPE '\'
+`
+
+// Escape the wildcards of a LIKE pattern
+var likeEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`%`, `\%`,
+	`_`, `\_`,
+)
+
+// FindByPrefix will return the prefixes matching a pattern
+func (b *RoutesBackend) FindByPrefix(
+	ctx context.Context,
+	prefix string,
+	filters *api.SearchFilters,
+	limit uint,
+) (api.LookupRoutes, error) {
+	rows, err := b.db.QueryContext(
+		ctx, findByPrefixQuery, likeEscaper.Replace(prefix)+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return fetchRoutes(rows, filters, limit)
+}
+
+// RemoveSource deletes all routes of a source.
+func (b *RoutesBackend) RemoveSource(
+	ctx context.Context,
+	sourceID string,
+) error {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := b.clear(ctx, tx, sourceID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Private fetchRoutes will load the queried result set
+func fetchRoutes(
+	rows *sql.Rows,
+	filters *api.SearchFilters,
+	limit uint,
+) (api.LookupRoutes, error) {
+	var count uint
+	results := api.LookupRoutes{}
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		route := &api.LookupRoute{}
+		if err := json.Unmarshal(data, route); err != nil {
+			return nil, err
+		}
+		if !filters.MatchRoute(route) {
+			continue
+		}
+		results = append(results, route)
+		count++
+		if limit > 0 && count >= limit {
+			return nil, api.ErrTooManyRoutes
+		}
+	}
+	return results, rows.Err()
+}
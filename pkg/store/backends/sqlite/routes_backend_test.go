@@ -0,0 +1,324 @@
+package sqlite
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/pools"
+)
+
+func TestCountRoutesAt(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	db := OpenTest(t)
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	b := NewRoutesBackend(db)
+	stmt, err := b.prepare(ctx, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &api.LookupRoute{
+		State: "filtered",
+		Neighbor: &api.Neighbor{
+			ID: "n23",
+		},
+		Route: &api.Route{
+			Network: "1.2.3.0/24",
+		},
+	}
+	if err := b.persist(ctx, stmt, "rs1", r, now); err != nil {
+		t.Fatal(err)
+	}
+
+	r.Route.Network = "1.2.6.1/24"
+	if err := b.persist(ctx, stmt, "rs1", r, now); err != nil {
+		t.Fatal(err)
+	}
+
+	r.State = "imported"
+	r.Route.Network = "1.2.5.5/24"
+	if err := b.persist(ctx, stmt, "rs1", r, now); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	imported, filtered, err := b.CountRoutesAt(ctx, "rs1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 1 {
+		t.Error("unexpected imported:", imported)
+	}
+	if filtered != 2 {
+		t.Error("unexpected filtered:", imported)
+	}
+}
+
+func TestFindByNeighbors(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	db := OpenTest(t)
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	b := NewRoutesBackend(db)
+	stmt, err := b.prepare(ctx, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &api.LookupRoute{
+		State: "filtered",
+		Neighbor: &api.Neighbor{
+			ID: "n23",
+		},
+		Route: &api.Route{
+			Network:    "1.2.3.0/24",
+			NeighborID: pools.Neighbors.Acquire("n23"),
+		},
+	}
+	b.persist(ctx, stmt, "rs1", r, now)
+
+	r.Network = "1.4.5.0/24"
+	b.persist(ctx, stmt, "rs1", r, now)
+
+	r.Neighbor.ID = "n24"
+	b.persist(ctx, stmt, "rs1", r, now)
+
+	r.Neighbor.ID = "n25"
+	b.persist(ctx, stmt, "rs2", r, now)
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	nq1 := &api.NeighborQuery{
+		NeighborID: pools.Neighbors.Acquire("n24"),
+		SourceID:   pools.RouteServers.Acquire("rs1"),
+	}
+	nq2 := &api.NeighborQuery{
+		NeighborID: pools.Neighbors.Acquire("n25"),
+		SourceID:   pools.RouteServers.Acquire("rs2"),
+	}
+
+	routes, err := b.FindByNeighbors(
+		ctx,
+		[]*api.NeighborQuery{nq1, nq2},
+		api.NewSearchFilters())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(routes) != 2 {
+		t.Error("unexpected routes:", routes)
+	}
+	t.Log(routes)
+}
+
+func TestFindByPrefix(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	db := OpenTest(t)
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	b := NewRoutesBackend(db)
+	stmt, err := b.prepare(ctx, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &api.LookupRoute{
+		State: "filtered",
+		Neighbor: &api.Neighbor{
+			ID: "n23",
+		},
+		Route: &api.Route{
+			Network: "1.2.3.0/24",
+		},
+	}
+
+	b.persist(ctx, stmt, "rs1", r, now)
+
+	r.Route.Network = "1.2.4.0/24"
+	b.persist(ctx, stmt, "rs1", r, now)
+
+	r.Route.Network = "1.2.5.0/24"
+	r.Neighbor.ID = "n24"
+	b.persist(ctx, stmt, "rs2", r, now)
+
+	r.Route.Network = "5.5.5.0/24"
+	r.Neighbor.ID = "n25"
+	b.persist(ctx, stmt, "rs1", r, now)
+
+	r.Route.Network = "2001:DB8::/32"
+	b.persist(ctx, stmt, "rs1", r, now)
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	routes, err := b.FindByPrefix(ctx, "1.2.", api.NewSearchFilters(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(routes) != 3 {
+		t.Error("unexpected routes:", routes)
+	}
+
+	// The lookup is case insensitive
+	routes, _ = b.FindByPrefix(ctx, "2001:db8", api.NewSearchFilters(), 0)
+	if len(routes) != 1 {
+		t.Error("unexpected routes:", routes)
+	}
+
+	// Wildcards are matched literally
+	routes, _ = b.FindByPrefix(ctx, "1_2", api.NewSearchFilters(), 0)
+	if len(routes) != 0 {
+		t.Error("unexpected routes:", routes)
+	}
+	routes, _ = b.FindByPrefix(ctx, "%", api.NewSearchFilters(), 0)
+	if len(routes) != 0 {
+		t.Error("unexpected routes:", routes)
+	}
+
+	// The limit is exceeded
+	_, err = b.FindByPrefix(ctx, "1.2.", api.NewSearchFilters(), 2)
+	if err != api.ErrTooManyRoutes {
+		t.Error("unexpected error:", err)
+	}
+}
+
+func TestFindByPrefixUsesIndex(t *testing.T) {
+	db := OpenTest(t)
+	rows, err := db.Query("EXPLAIN QUERY PLAN "+findByPrefixQuery, "1.2.%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	plan := ""
+	for rows.Next() {
+		var id, parent, notused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+			t.Fatal(err)
+		}
+		plan += detail + "\n"
+	}
+	if !strings.Contains(plan, "USING INDEX idx_routes_network") {
+		t.Error("the network index is not used:", plan)
+	}
+}
+
+func TestSetRoutes(t *testing.T) {
+	ctx := context.Background()
+	db := OpenTest(t)
+	b := NewRoutesBackend(db)
+
+	routes := api.LookupRoutes{
+		{
+			State:    "imported",
+			Neighbor: &api.Neighbor{ID: "n23"},
+			Route:    &api.Route{Network: "1.2.3.0/24"},
+		},
+		{
+			State:    "imported",
+			Neighbor: &api.Neighbor{ID: "n23"},
+			Route:    &api.Route{Network: "1.2.4.0/24"},
+		},
+	}
+	if err := b.SetRoutes(ctx, "rs1", routes); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SetRoutes(ctx, "rs2", routes); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replace the routes
+	if err := b.SetRoutes(ctx, "rs1", routes[:1]); err != nil {
+		t.Fatal(err)
+	}
+	imported, _, err := b.CountRoutesAt(ctx, "rs1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 1 {
+		t.Error("unexpected imported:", imported)
+	}
+
+	if err := b.RemoveSource(ctx, "rs2"); err != nil {
+		t.Fatal(err)
+	}
+	imported, _, _ = b.CountRoutesAt(ctx, "rs2")
+	if imported != 0 {
+		t.Error("unexpected imported:", imported)
+	}
+}
+
+func TestUpdateRoutes(t *testing.T) {
+	ctx := context.Background()
+	db := OpenTest(t)
+	b := NewRoutesBackend(db)
+
+	routes := api.LookupRoutes{
+		{
+			State:    "imported",
+			Neighbor: &api.Neighbor{ID: "n23"},
+			Route:    &api.Route{Network: "1.2.3.0/24"},
+		},
+		{
+			State:    "imported",
+			Neighbor: &api.Neighbor{ID: "n23"},
+			Route:    &api.Route{Network: "1.2.4.0/24"},
+		},
+		{
+			State:    "imported",
+			Neighbor: &api.Neighbor{ID: "n42"},
+			Route:    &api.Route{Network: "1.2.3.0/24"},
+		},
+	}
+	if err := b.SetRoutes(ctx, "rs1", routes); err != nil {
+		t.Fatal(err)
+	}
+
+	added := &api.LookupRoute{
+		State:    "filtered",
+		Neighbor: &api.Neighbor{ID: "n23"},
+		Route:    &api.Route{Network: "1.2.5.0/24"},
+	}
+	err := b.UpdateRoutes(ctx, "rs1", []*api.RoutesUpdate{{
+		NeighborID: "n23",
+		Routes:     api.LookupRoutes{routes[1], added},
+		Added:      api.LookupRoutes{added},
+		Withdrawn:  []string{"1.2.3.0/24"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imported, filtered, err := b.CountRoutesAt(ctx, "rs1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The route of n42 with the same network is kept
+	if imported != 2 {
+		t.Error("unexpected imported:", imported)
+	}
+	if filtered != 1 {
+		t.Error("unexpected filtered:", filtered)
+	}
+}
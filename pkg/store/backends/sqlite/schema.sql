@@ -1,11 +1,9 @@
-
 --
 -- ----------------------
 -- AliceLG schema v.1.0.0
 -- ----------------------
 --
--- %% Author:      annika
--- %% Description: Apply alice-lg db schema.
+-- %% Description: Apply alice-lg sqlite schema.
 --
 
 -- Clear state
@@ -21,7 +19,7 @@ CREATE TABLE neighbors (
     rs_id VARCHAR(255) NOT NULL,
 
     -- JSON serialized neighbor
-    neighbor     jsonb NOT NULL,
+    neighbor     TEXT NOT NULL,
 
     -- Timestamps
     updated_at  TIMESTAMP  NOT NULL DEFAULT CURRENT_TIMESTAMP,
@@ -30,9 +28,9 @@ CREATE TABLE neighbors (
     PRIMARY KEY(id, rs_id)
 );
 
-CREATE INDEX idx_neighbors_rs_id 
-          ON neighbors             USING HASH (rs_id);
-CREATE INDEX idx_neighbors_updated_at 
+CREATE INDEX idx_neighbors_rs_id
+          ON neighbors ( rs_id );
+CREATE INDEX idx_neighbors_updated_at
           ON neighbors ( updated_at );
 
 -- Routes
@@ -41,11 +39,11 @@ CREATE TABLE routes (
     rs_id         VARCHAR(255) NOT NULL,
     neighbor_id   VARCHAR(255) NOT NULL,
 
-    -- Indexed attributes 
+    -- Indexed attributes
     network       VARCHAR(50)  NOT NULL,
-   
+
     -- JSON serialized route
-    route         jsonb        NOT NULL,
+    route         TEXT         NOT NULL,
 
     -- Timestamps
     updated_at  TIMESTAMP  NOT NULL DEFAULT CURRENT_TIMESTAMP,
@@ -54,8 +52,9 @@ CREATE TABLE routes (
     PRIMARY KEY(id, rs_id, neighbor_id)
 );
 
-CREATE INDEX idx_routes_network    ON routes ( network );
-CREATE INDEX idx_neighbor_id       ON routes ( neighbor_id );
+CREATE INDEX idx_routes_rs_id      ON routes ( rs_id );
+CREATE INDEX idx_routes_network    ON routes ( network COLLATE NOCASE );
+CREATE INDEX idx_neighbor_id       ON routes ( rs_id, neighbor_id );
 CREATE INDEX idx_routes_updated_at ON routes ( updated_at );
 
 -- The meta table stores information about the schema
@@ -68,4 +67,3 @@ CREATE TABLE __meta__ (
 
 INSERT INTO __meta__ (version, description)
      VALUES (1, 'initial schema');
-
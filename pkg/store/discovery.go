@@ -0,0 +1,193 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alice-lg/alice-lg/pkg/config"
+)
+
+// SourceDiscovery periodically reads an inventory of
+// route servers and adds, updates or removes the sources
+// in the configuration and the stores.
+type SourceDiscovery struct {
+	cfg       *config.Config
+	neighbors *NeighborsStore
+	routes    *RoutesStore
+	client    *http.Client
+
+	// Sources from the configuration file
+	// are never changed by the discovery.
+	static map[string]bool
+}
+
+// NewSourceDiscovery creates a new source discovery
+// for the inventory in the configuration.
+func NewSourceDiscovery(
+	cfg *config.Config,
+	neighbors *NeighborsStore,
+	routes *RoutesStore,
+) *SourceDiscovery {
+	static := make(map[string]bool)
+	for _, src := range cfg.GetSources() {
+		static[src.ID] = true
+	}
+	return &SourceDiscovery{
+		cfg:       cfg,
+		neighbors: neighbors,
+		routes:    routes,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		static: static,
+	}
+}
+
+// Start reads the inventory periodically until
+// the context is done.
+func (d *SourceDiscovery) Start(ctx context.Context) {
+	interval := time.Duration(d.cfg.Discovery.Interval) * time.Minute
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	log.Println("Discovering sources from", d.cfg.Discovery.Inventory,
+		"every", interval)
+
+	for {
+		if err := d.Discover(ctx); err != nil {
+			log.Println("Source discovery failed:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Discover reads the inventory and reconciles
+// the sources.
+func (d *SourceDiscovery) Discover(ctx context.Context) error {
+	data, err := d.fetchInventory(ctx)
+	if err != nil {
+		return err
+	}
+	discovered, err := d.cfg.ParseInventory(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", d.cfg.Discovery.Inventory, err)
+	}
+	return d.Reconcile(ctx, discovered)
+}
+
+// Private fetchInventory reads the inventory
+// from a file or url.
+func (d *SourceDiscovery) fetchInventory(
+	ctx context.Context,
+) ([]byte, error) {
+	inventory := d.cfg.Discovery.Inventory
+	if !strings.HasPrefix(inventory, "http://") &&
+		!strings.HasPrefix(inventory, "https://") {
+		return os.ReadFile(inventory)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, inventory, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(
+			"%s: unexpected status: %s", inventory, res.Status)
+	}
+	return io.ReadAll(res.Body)
+}
+
+// Reconcile updates the sources to match the discovered
+// sources: New sources are added, changed sources are
+// updated and sources missing in the inventory are removed
+// together with their neighbors and routes.
+// Failing updates do not stop the reconciliation,
+// the errors are returned when all sources are processed.
+func (d *SourceDiscovery) Reconcile(
+	ctx context.Context,
+	discovered []*config.SourceConfig,
+) error {
+	current := d.cfg.GetSources()
+	known := make(map[string]*config.SourceConfig)
+	sources := make([]*config.SourceConfig, 0, len(discovered))
+	for _, src := range current {
+		known[src.ID] = src
+		if d.static[src.ID] {
+			sources = append(sources, src)
+		}
+	}
+
+	errs := []error{}
+	seen := make(map[string]bool)
+	replaced := []*config.SourceConfig{}
+	for _, src := range discovered {
+		if d.static[src.ID] {
+			log.Println("Ignoring discovered source", src.ID,
+				"already present in", d.cfg.File)
+			continue
+		}
+		seen[src.ID] = true
+
+		prev, ok := known[src.ID]
+		if !ok {
+			log.Println("Adding discovered source:", src.ID)
+			d.neighbors.AddSource(src)
+			d.routes.AddSource(src)
+		} else if prev.Matches(src) {
+			src = prev // Keep the instance
+		} else {
+			log.Println("Updating discovered source:", src.ID)
+			if err := d.neighbors.UpdateSource(src); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", src.ID, err))
+			}
+			if err := d.routes.UpdateSource(src); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", src.ID, err))
+			}
+			replaced = append(replaced, prev)
+		}
+		src.Order = len(sources)
+		sources = append(sources, src)
+	}
+	d.cfg.SetSources(sources)
+
+	// Release the connections of the replaced instances
+	for _, src := range replaced {
+		if err := src.CloseInstance(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", src.ID, err))
+		}
+	}
+
+	// Remove sources no longer present in the inventory
+	for _, src := range current {
+		if d.static[src.ID] || seen[src.ID] {
+			continue
+		}
+		log.Println("Removing source:", src.ID)
+		if err := d.routes.RemoveSource(ctx, src.ID); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", src.ID, err))
+		}
+		if err := d.neighbors.RemoveSource(ctx, src.ID); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", src.ID, err))
+		}
+		if err := src.CloseInstance(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", src.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,234 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/config"
+	"github.com/alice-lg/alice-lg/pkg/sources"
+	"github.com/alice-lg/alice-lg/pkg/sources/gobgp"
+	"github.com/alice-lg/alice-lg/pkg/store/backends/memory"
+)
+
+// Make stores with a single static source
+func makeTestDiscovery(inventory string) (
+	*SourceDiscovery,
+	*memory.NeighborsBackend,
+) {
+	cfg := &config.Config{
+		Discovery: config.DiscoveryConfig{
+			Inventory: inventory,
+		},
+		Sources: []*config.SourceConfig{
+			{ID: "rs1", Name: "rs1"},
+		},
+	}
+	neighborsBackend := memory.NewNeighborsBackend()
+	neighbors := NewNeighborsStore(cfg, neighborsBackend)
+	routes := NewRoutesStore(neighbors, cfg, memory.NewRoutesBackend())
+	return NewSourceDiscovery(cfg, neighbors, routes), neighborsBackend
+}
+
+func TestSourceDiscoveryReconcile(t *testing.T) {
+	ctx := context.Background()
+	d, backend := makeTestDiscovery("")
+
+	err := d.Reconcile(ctx, []*config.SourceConfig{
+		{ID: "rs1", Name: "rs1 from inventory"},
+		{ID: "rs2", Name: "rs2"},
+		{ID: "rs3", Name: "rs3"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := d.cfg.GetSources()
+	if len(sources) != 3 {
+		t.Fatal("expected 3 sources, got:", len(sources))
+	}
+	// Static sources are not changed
+	if d.cfg.SourceByID("rs1").Name != "rs1" {
+		t.Error("static source was changed")
+	}
+	if d.cfg.SourceByID("rs3").Order != 2 {
+		t.Error("unexpected order:", d.cfg.SourceByID("rs3").Order)
+	}
+	if d.routes.sources.Get("rs2") == nil || d.neighbors.sources.Get("rs2") == nil {
+		t.Error("expected rs2 in stores")
+	}
+
+	// Update rs2 and remove rs3
+	backend.SetNeighbors(ctx, "rs3", api.Neighbors{{ID: "n1"}})
+	d.neighbors.sources.RefreshSuccess("rs2")
+	err = d.Reconcile(ctx, []*config.SourceConfig{
+		{ID: "rs2", Name: "rs2 updated"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.cfg.GetSources()) != 2 {
+		t.Error("unexpected sources:", d.cfg.GetSources())
+	}
+	if d.cfg.SourceByID("rs3") != nil {
+		t.Error("rs3 should be removed")
+	}
+	if name := d.neighbors.sources.GetName("rs2"); name != "rs2 updated" {
+		t.Error("unexpected name:", name)
+	}
+	if !d.neighbors.sources.ShouldRefresh("rs2") {
+		t.Error("updated source should be refreshed")
+	}
+	if _, err := d.routes.sources.GetStatus("rs3"); err == nil {
+		t.Error("rs3 should be removed from the routes store")
+	}
+	if _, err := backend.GetNeighborsAt(ctx, "rs3"); err == nil {
+		t.Error("neighbors of rs3 should be removed")
+	}
+}
+
+func TestSourceDiscoveryDiscover(t *testing.T) {
+	inventory := `[{
+		"id": "rs2",
+		"name": "rs2",
+		"openbgpd-state-server": {"api": "http://rs2.example.net/api"}
+	}]`
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(inventory))
+		}))
+	defer srv.Close()
+
+	d, _ := makeTestDiscovery(srv.URL)
+	if err := d.Discover(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	src := d.cfg.SourceByID("rs2")
+	if src == nil {
+		t.Fatal("expected rs2 to be discovered")
+	}
+	if src.OpenBGPD.API != "http://rs2.example.net/api" {
+		t.Error("unexpected api:", src.OpenBGPD.API)
+	}
+
+	// The instance is kept if nothing changed
+	instance := src.GetInstance()
+	if err := d.Discover(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if d.cfg.SourceInstanceByID("rs2") != instance {
+		t.Error("expected the instance to be kept")
+	}
+
+	// Errors do not modify the sources
+	inventory = `{"invalid": true}`
+	if err := d.Discover(context.Background()); err == nil {
+		t.Error("expected an error")
+	}
+	if d.cfg.SourceByID("rs2") == nil {
+		t.Error("rs2 should not be removed")
+	}
+}
+
+func TestSourceDiscoveryReconcileOrder(t *testing.T) {
+	ctx := context.Background()
+	d, _ := makeTestDiscovery("")
+
+	err := d.Reconcile(ctx, []*config.SourceConfig{
+		{ID: "rs2", Name: "rs2"},
+		{ID: "rs3", Name: "rs3"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs3 := d.cfg.SourceByID("rs3")
+
+	// Reordering the inventory keeps the sources
+	err = d.Reconcile(ctx, []*config.SourceConfig{
+		{ID: "rs3", Name: "rs3"},
+		{ID: "rs2", Name: "rs2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.cfg.SourceByID("rs3") != rs3 {
+		t.Error("reordered source should be kept")
+	}
+	if rs3.Order != 1 || d.cfg.SourceByID("rs2").Order != 2 {
+		t.Error("unexpected order:", rs3.Order,
+			d.cfg.SourceByID("rs2").Order)
+	}
+}
+
+func TestSourceDiscoveryReconcileErrors(t *testing.T) {
+	ctx := context.Background()
+	d, _ := makeTestDiscovery("")
+
+	err := d.Reconcile(ctx, []*config.SourceConfig{
+		{ID: "rs2", Name: "rs2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The update of rs2 fails, rs3 is added anyway
+	d.routes.sources.RemoveSource("rs2")
+	err = d.Reconcile(ctx, []*config.SourceConfig{
+		{ID: "rs2", Name: "rs2 updated"},
+		{ID: "rs3", Name: "rs3"},
+	})
+	if err == nil {
+		t.Error("expected an error")
+	}
+	if d.cfg.SourceByID("rs3") == nil || d.routes.sources.Get("rs3") == nil {
+		t.Error("expected rs3 to be added")
+	}
+	if name := d.neighbors.sources.GetName("rs2"); name != "rs2 updated" {
+		t.Error("unexpected name:", name)
+	}
+}
+
+func TestSourceDiscoveryReconcileClose(t *testing.T) {
+	ctx := context.Background()
+	d, _ := makeTestDiscovery("")
+
+	gobgpSource := func(id, name string) *config.SourceConfig {
+		return &config.SourceConfig{
+			ID:      id,
+			Name:    name,
+			Backend: config.SourceBackendGoBGP,
+			GoBGP: gobgp.Config{
+				Host:              "127.0.0.1:1",
+				Insecure:          true,
+				ProcessingTimeout: 1,
+			},
+		}
+	}
+	err := d.Reconcile(ctx, []*config.SourceConfig{
+		gobgpSource("rs2", "rs2"),
+		gobgpSource("rs3", "rs3"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs2 := d.cfg.SourceInstanceByID("rs2")
+	rs3 := d.cfg.SourceInstanceByID("rs3")
+
+	// Update rs2 and remove rs3
+	err = d.Reconcile(ctx, []*config.SourceConfig{
+		gobgpSource("rs2", "rs2 updated"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range []sources.Source{rs2, rs3} {
+		if _, err := src.Status(ctx); !errors.Is(err, sources.ErrSourceClosed) {
+			t.Error("expected the instance to be closed, got:", err)
+		}
+	}
+	if d.cfg.SourceInstanceByID("rs2") == rs2 {
+		t.Error("expected a new instance")
+	}
+}
@@ -10,9 +10,17 @@ import (
 	"github.com/alice-lg/alice-lg/pkg/config"
 )
 
+// HousekeepingTask is an additional task, which
+// is run after each housekeeping cycle.
+type HousekeepingTask func(ctx context.Context) error
+
 // StartHousekeeping is a background task flushing
 // memory and expireing caches.
-func StartHousekeeping(ctx context.Context, cfg *config.Config) {
+func StartHousekeeping(
+	ctx context.Context,
+	cfg *config.Config,
+	tasks ...HousekeepingTask,
+) {
 
 	for {
 		if cfg.Housekeeping.Interval > 0 {
@@ -25,7 +33,7 @@ func StartHousekeeping(ctx context.Context, cfg *config.Config) {
 
 		// Expire the caches
 		log.Println("Expiring caches")
-		for _, source := range cfg.Sources {
+		for _, source := range cfg.GetSources() {
 			count := source.GetInstance().ExpireCaches()
 			log.Println("Expired", count, "entries for source", source.Name)
 		}
@@ -36,6 +44,12 @@ func StartHousekeeping(ctx context.Context, cfg *config.Config) {
 			debug.FreeOSMemory()
 		}
 
+		for _, task := range tasks {
+			if err := task(ctx); err != nil {
+				log.Println("Housekeeping task failed:", err)
+			}
+		}
+
 		// Check if our services are still required
 		select {
 		case <-ctx.Done():
@@ -4,9 +4,9 @@ import (
 	"context"
 	"errors"
 	"log"
-	"math/rand"
 	"regexp"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/alice-lg/alice-lg/pkg/api"
@@ -48,6 +48,13 @@ type NeighborsStoreBackend interface {
 		ctx context.Context,
 		sourceID string,
 	) (int, error)
+
+	// RemoveSource deletes all neighbors of a
+	// route server, when the source is removed.
+	RemoveSource(
+		ctx context.Context,
+		sourceID string,
+	) error
 }
 
 // NeighborsStore is queryable for neighbor information
@@ -56,6 +63,10 @@ type NeighborsStore struct {
 	sources *SourcesStore
 
 	forceNeighborRefresh bool
+
+	// Refreshes can only be scheduled while
+	// the store is running.
+	running atomic.Bool
 }
 
 // NewNeighborsStore creates a new store for neighbors
@@ -81,6 +92,13 @@ func NewNeighborsStore(
 	// Store refresh information per store
 	sources := NewSourcesStore(cfg, refreshInterval, refreshParallelism)
 
+	// Use the persisted data from a previous run
+	if b, ok := backend.(PersistentBackend); ok {
+		if err := sources.RestoreStatus(context.Background(), b); err != nil {
+			log.Println("Could not restore neighbors status:", err)
+		}
+	}
+
 	// Neighbors will be refreshed on every GetNeighborsAt
 	// invocation. Why? I (Annika) don't know. I have to ask Patrick.
 	// TODO: This feels wrong here. Figure out reason why it
@@ -99,6 +117,8 @@ func NewNeighborsStore(
 // Start the store's housekeeping.
 func (s *NeighborsStore) Start(ctx context.Context) {
 	log.Println("Starting local neighbors store")
+	s.running.Store(true)
+	defer s.running.Store(false)
 	for {
 		if err := ctx.Err(); err != nil {
 			return // Context invalid
@@ -108,6 +128,45 @@ func (s *NeighborsStore) Start(ctx context.Context) {
 	}
 }
 
+// AddSource registers a source discovered at runtime.
+func (s *NeighborsStore) AddSource(src *config.SourceConfig) {
+	s.sources.AddSource(src)
+}
+
+// UpdateSource replaces the configuration of a source.
+func (s *NeighborsStore) UpdateSource(src *config.SourceConfig) error {
+	return s.sources.UpdateSource(src)
+}
+
+// RefreshSource schedules the refresh of the neighbors
+// of a source. ErrSourceBusy is returned if a refresh
+// is in progress.
+func (s *NeighborsStore) RefreshSource(sourceID string) error {
+	if !s.running.Load() {
+		return ErrStoreNotRunning
+	}
+	return s.sources.RequestRefresh(sourceID)
+}
+
+// CanRefreshSource checks if a refresh of the neighbors
+// of a source can be scheduled.
+func (s *NeighborsStore) CanRefreshSource(sourceID string) error {
+	if !s.running.Load() {
+		return ErrStoreNotRunning
+	}
+	return s.sources.CheckRefresh(sourceID)
+}
+
+// RemoveSource unregisters a source and deletes
+// its neighbors from the backend.
+func (s *NeighborsStore) RemoveSource(
+	ctx context.Context,
+	sourceID string,
+) error {
+	s.sources.RemoveSource(sourceID)
+	return s.backend.RemoveSource(ctx, sourceID)
+}
+
 // GetStatus retrievs the status for a route server
 // identified by sourceID.
 func (s *NeighborsStore) GetStatus(sourceID string) (*Status, error) {
@@ -138,7 +197,12 @@ func (s *NeighborsStore) updateSource(
 		return err
 	}
 
-	return s.sources.RefreshSuccess(srcID)
+	err = s.sources.RefreshSuccess(srcID)
+	if errors.Is(err, sources.ErrSourceNotFound) {
+		// The source was removed during the refresh
+		return s.backend.RemoveSource(ctx, srcID)
+	}
+	return err
 }
 
 // safeUpdateSource will try to update a source but
@@ -155,11 +219,13 @@ func (s *NeighborsStore) safeUpdateSource(ctx context.Context, id string) {
 	}
 
 	// Apply jitter so, we do not hit everything at once.
-	// TODO: Make configurable
-	time.Sleep(time.Duration(rand.Intn(30)) * time.Second)
+	time.Sleep(s.sources.Jitter(id))
 
 	src := s.sources.GetInstance(id)
 	srcName := s.sources.GetName(id)
+	if src == nil {
+		return // The source was removed
+	}
 
 	// Prepare for impact.
 	defer func() {
@@ -229,6 +295,27 @@ func (s *NeighborsStore) GetNeighborsAt(
 	return s.backend.GetNeighborsAt(ctx, sourceID)
 }
 
+// GetStoredNeighborsAt returns the neighbors of a source
+// from the store without refreshing them and the time
+// of the last refresh.
+func (s *NeighborsStore) GetStoredNeighborsAt(
+	ctx context.Context,
+	sourceID string,
+) (api.Neighbors, time.Time, error) {
+	status, err := s.sources.GetStatus(sourceID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if !status.Initialized {
+		return nil, time.Time{}, ErrSourceNotInitialized
+	}
+	neighbors, err := s.backend.GetNeighborsAt(ctx, sourceID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return neighbors, status.LastRefresh, nil
+}
+
 // GetNeighborsMapAt looks up a neighbor on a RS by ID.
 func (s *NeighborsStore) GetNeighborsMapAt(
 	ctx context.Context,
@@ -366,6 +453,7 @@ func (s *NeighborsStore) Status(ctx context.Context) *api.StoreStatus {
 			LastRefresh:     s.LastRefresh,
 			State:           s.State.String(),
 			Initialized:     s.Initialized,
+			Stale:           s.Stale,
 		}
 	}
 
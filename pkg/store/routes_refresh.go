@@ -0,0 +1,287 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/config"
+	"github.com/alice-lg/alice-lg/pkg/pools"
+	"github.com/alice-lg/alice-lg/pkg/sources"
+)
+
+// ErrNeighborsRefreshFailed is returned when the routes
+// of some neighbors of a source could not be refreshed.
+var ErrNeighborsRefreshFailed = errors.New(
+	"refresh of neighbor routes failed")
+
+// neighborStatus is the refresh status of the
+// routes of a single neighbor.
+type neighborStatus struct {
+	State       State
+	LastRefresh time.Time
+	LastError   error
+}
+
+// neighborsStatus holds the status of the neighbors
+// per source.
+type neighborsStatus struct {
+	status map[string]map[string]*neighborStatus
+	sync.Mutex
+}
+
+// newNeighborsStatus creates an empty neighbors status
+func newNeighborsStatus() *neighborsStatus {
+	return &neighborsStatus{
+		status: make(map[string]map[string]*neighborStatus),
+	}
+}
+
+// Get the status of the neighbors of a source
+func (s *neighborsStatus) Get(sourceID string) map[string]*neighborStatus {
+	s.Lock()
+	defer s.Unlock()
+	return s.status[sourceID]
+}
+
+// Set replaces the status of the neighbors of a source
+func (s *neighborsStatus) Set(
+	sourceID string,
+	status map[string]*neighborStatus,
+) {
+	s.Lock()
+	defer s.Unlock()
+	if status == nil {
+		delete(s.status, sourceID)
+		return
+	}
+	s.status[sourceID] = status
+}
+
+// neighborRoutes is the result of fetching the
+// routes of a neighbor.
+type neighborRoutes struct {
+	Imported api.Routes
+	Filtered api.Routes
+	Err      error
+}
+
+// neighborsForRefresh selects the neighbors, which were
+// not refreshed successfully within the refresh interval.
+func neighborsForRefresh(
+	neighbors map[string]*api.Neighbor,
+	status map[string]*neighborStatus,
+	refreshInterval time.Duration,
+	now time.Time,
+) []string {
+	ids := make([]string, 0, len(neighbors))
+	for id := range neighbors {
+		s, ok := status[id]
+		if ok && s.State == StateReady &&
+			now.Before(s.LastRefresh.Add(refreshInterval)) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// fetchNeighborRoutes retrieves the received and
+// filtered routes of a neighbor.
+func fetchNeighborRoutes(
+	ctx context.Context,
+	rs sources.Source,
+	neighborID string,
+) (res *neighborRoutes) {
+	res = &neighborRoutes{}
+	defer func() {
+		if err := recover(); err != nil {
+			res.Err = fmt.Errorf("%v", err)
+		}
+	}()
+	if err := ctx.Err(); err != nil {
+		res.Err = err
+		return
+	}
+	received, err := rs.RoutesReceived(ctx, neighborID)
+	if err != nil {
+		res.Err = err
+		return
+	}
+	filtered, err := rs.RoutesFiltered(ctx, neighborID)
+	if err != nil {
+		res.Err = err
+		return
+	}
+	res.Imported = received.Imported
+	res.Filtered = filtered.Filtered
+	return
+}
+
+// fetchNeighborsRoutes retrieves the routes of the
+// neighbors. At most parallelism neighbors are fetched
+// at the same time.
+func fetchNeighborsRoutes(
+	ctx context.Context,
+	rs sources.Source,
+	neighborIDs []string,
+	parallelism int,
+) map[string]*neighborRoutes {
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+	results := make(map[string]*neighborRoutes, len(neighborIDs))
+	slots := make(chan struct{}, parallelism)
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, id := range neighborIDs {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(id string) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			res := fetchNeighborRoutes(ctx, rs, id)
+			lock.Lock()
+			results[id] = res
+			lock.Unlock()
+		}(id)
+	}
+	wg.Wait()
+	return results
+}
+
+// updateSourceNeighbors refreshes the routes of a
+// source neighbor by neighbor. The routes of neighbors
+// which could not be fetched are kept.
+func (s *RoutesStore) updateSourceNeighbors(
+	ctx context.Context,
+	src *config.SourceConfig,
+	rs sources.Source,
+) error {
+	if err := s.awaitNeighborStore(ctx, src.ID); err != nil {
+		return err
+	}
+	neighbors, err := s.neighbors.GetNeighborsMapAt(ctx, src.ID)
+	if err != nil {
+		return err
+	}
+
+	srcStatus, err := s.sources.GetStatus(src.ID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	prev := s.neighborsStatus.Get(src.ID)
+	pending := neighborsForRefresh(
+		neighbors, prev, srcStatus.RefreshInterval, now)
+
+	log.Println(
+		"[routes store] refreshing routes of", len(pending),
+		"of", len(neighbors), "neighbors of", src.Name)
+
+	results := fetchNeighborsRoutes(
+		ctx, rs, pending, s.neighborParallelism)
+
+	srcRS := &api.LookupRouteServer{
+		ID:   pools.RouteServers.Acquire(src.ID),
+		Name: src.Name,
+	}
+	status := make(map[string]*neighborStatus, len(neighbors))
+	routes := api.LookupRoutes{}
+	keep := []*api.NeighborQuery{}
+	failed := 0
+	for id := range neighbors {
+		res, ok := results[id]
+		if ok && res.Err == nil {
+			status[id] = &neighborStatus{
+				State:       StateReady,
+				LastRefresh: now,
+			}
+			routes = append(routes,
+				res.Imported.ToLookupRoutes("imported", srcRS, neighbors)...)
+			routes = append(routes,
+				res.Filtered.ToLookupRoutes("filtered", srcRS, neighbors)...)
+			continue
+		}
+		if ok {
+			failed++
+			log.Println(
+				"[routes store] refreshing routes of neighbor", id,
+				"of", src.Name, "failed:", res.Err)
+			st := &neighborStatus{
+				State:     StateError,
+				LastError: res.Err,
+			}
+			if p, ok := prev[id]; ok {
+				st.LastRefresh = p.LastRefresh
+			}
+			status[id] = st
+		} else {
+			status[id] = prev[id]
+		}
+
+		// Keep the routes from the previous refresh
+		if q := newNeighborQuery(id, src.ID); q != nil {
+			keep = append(keep, q)
+		}
+	}
+
+	if len(keep) > 0 {
+		kept, err := s.backend.FindByNeighbors(
+			ctx, keep, api.NewSearchFilters())
+		if err != nil {
+			return err
+		}
+		routes = append(routes, kept...)
+	}
+
+	log.Println("[routes store] importing", len(routes), "into store from", src.Name)
+	if err := s.importRoutes(ctx, src.ID, routes); err != nil {
+		return err
+	}
+	s.neighborsStatus.Set(src.ID, status)
+
+	if failed > 0 {
+		return fmt.Errorf(
+			"%w: %d of %d neighbors",
+			ErrNeighborsRefreshFailed, failed, len(pending))
+	}
+
+	err = s.sources.RefreshSuccess(src.ID)
+	if errors.Is(err, sources.ErrSourceNotFound) {
+		// The source was removed during the refresh
+		s.neighborsStatus.Set(src.ID, nil)
+		return s.backend.RemoveSource(ctx, src.ID)
+	}
+	return err
+}
+
+// neighborsRefreshStatus converts the status of the
+// neighbors of a source for the API.
+func (s *RoutesStore) neighborsRefreshStatus(
+	sourceID string,
+) map[string]*api.NeighborRefreshStatus {
+	status := s.neighborsStatus.Get(sourceID)
+	if status == nil {
+		return nil
+	}
+	result := make(map[string]*api.NeighborRefreshStatus, len(status))
+	for id, st := range status {
+		res := &api.NeighborRefreshStatus{
+			LastRefresh: st.LastRefresh,
+			State:       st.State.String(),
+		}
+		if st.LastError != nil {
+			res.Error = st.LastError.Error()
+		}
+		result[id] = res
+	}
+	return result
+}
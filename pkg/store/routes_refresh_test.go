@@ -0,0 +1,122 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/pools"
+	"github.com/alice-lg/alice-lg/pkg/sources"
+)
+
+// The neighborsTestSource returns a route for each
+// neighbor, unless the neighbor is failing.
+type neighborsTestSource struct {
+	sources.Source
+
+	failing map[string]bool
+	fetched map[string]int
+	sync.Mutex
+}
+
+func (src *neighborsTestSource) RoutesReceived(
+	ctx context.Context,
+	neighborID string,
+) (*api.RoutesResponse, error) {
+	src.Lock()
+	defer src.Unlock()
+	src.fetched[neighborID]++
+	if src.failing[neighborID] {
+		return nil, errors.New("timeout")
+	}
+	return &api.RoutesResponse{
+		Imported: api.Routes{
+			{
+				NeighborID: pools.Neighbors.Acquire(neighborID),
+				Network:    "10.23.0.0/16",
+				BGP:        &api.BGPInfo{},
+			},
+		},
+	}, nil
+}
+
+func (src *neighborsTestSource) RoutesFiltered(
+	ctx context.Context,
+	neighborID string,
+) (*api.RoutesResponse, error) {
+	return &api.RoutesResponse{}, nil
+}
+
+func TestNeighborsForRefresh(t *testing.T) {
+	now := time.Now().UTC()
+	neighbors := map[string]*api.Neighbor{
+		"n1": {ID: "n1"},
+		"n2": {ID: "n2"},
+		"n3": {ID: "n3"},
+		"n4": {ID: "n4"},
+	}
+	status := map[string]*neighborStatus{
+		"n1": {State: StateReady, LastRefresh: now},
+		"n2": {State: StateError, LastRefresh: now},
+		"n3": {State: StateReady, LastRefresh: now.Add(-time.Hour)},
+	}
+	ids := neighborsForRefresh(neighbors, status, time.Minute, now)
+	if len(ids) != 3 {
+		t.Error("unexpected neighbors:", ids)
+	}
+	for _, id := range ids {
+		if id == "n1" {
+			t.Error("n1 should not be refreshed")
+		}
+	}
+}
+
+func TestUpdateSourceNeighbors(t *testing.T) {
+	ctx := context.Background()
+	s := makeTestRoutesStore()
+	src := s.sources.Get("rs2")
+	rs := &neighborsTestSource{
+		failing: map[string]bool{"ID2233_AS4223": true},
+		fetched: map[string]int{},
+	}
+
+	err := s.updateSourceNeighbors(ctx, src, rs)
+	if !errors.Is(err, ErrNeighborsRefreshFailed) {
+		t.Fatal("expected refresh to fail partially, got:", err)
+	}
+	routes, err := s.LookupPrefix(ctx, "10.23.", api.NewSearchFilters())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 1 {
+		t.Error("expected routes of the other neighbor:", routes)
+	}
+	status := s.Status(ctx).Sources["rs2"].Neighbors
+	if status["ID2233_AS4223"].State != "ERROR" ||
+		status["ID2233_AS4223"].Error != "timeout" {
+		t.Error("unexpected status:", status["ID2233_AS4223"])
+	}
+	if status["ID2233_AS2342"].State != "READY" {
+		t.Error("unexpected status:", status["ID2233_AS2342"])
+	}
+
+	// Only the failed neighbor is retried
+	rs.failing = map[string]bool{}
+	if err := s.updateSourceNeighbors(ctx, src, rs); err != nil {
+		t.Fatal(err)
+	}
+	if rs.fetched["ID2233_AS2342"] != 1 || rs.fetched["ID2233_AS4223"] != 2 {
+		t.Error("unexpected fetches:", rs.fetched)
+	}
+	routes, _ = s.LookupPrefix(ctx, "10.23.", api.NewSearchFilters())
+	if len(routes) != 2 {
+		t.Error("expected routes of both neighbors:", routes)
+	}
+	status = s.Status(ctx).Sources["rs2"].Neighbors
+	if status["ID2233_AS4223"].State != "READY" {
+		t.Error("unexpected status:", status["ID2233_AS4223"])
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"log"
-	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/alice-lg/alice-lg/pkg/api"
@@ -62,6 +64,13 @@ type RoutesStoreBackend interface {
 		filters *api.SearchFilters,
 		limit uint,
 	) (api.LookupRoutes, error)
+
+	// RemoveSource deletes all routes of a route
+	// server, when the source is removed.
+	RemoveSource(
+		ctx context.Context,
+		sourceID string,
+	) error
 }
 
 // The RoutesStore holds a mapping of routes,
@@ -72,6 +81,21 @@ type RoutesStore struct {
 	sources   *SourcesStore
 	neighbors *NeighborsStore
 	limit     uint
+
+	// Refresh the routes of a source all at once
+	// or neighbor by neighbor.
+	refreshStrategy     string
+	neighborParallelism int
+	neighborsStatus     *neighborsStatus
+
+	// The fingerprints of the routes of the last
+	// refresh, if the backend supports updates.
+	fingerprints     map[string]routeFingerprints
+	fingerprintsLock sync.Mutex
+
+	// Refreshes can only be scheduled while
+	// the store is running.
+	running atomic.Bool
 }
 
 // NewRoutesStore makes a new store instance
@@ -97,20 +121,88 @@ func NewRoutesStore(
 	log.Println("Routes refresh parallelism:", refreshParallelism)
 	log.Println("Routes store query limit:", cfg.Server.RoutesStoreQueryLimit)
 
+	refreshStrategy := cfg.Server.RoutesStoreRefreshStrategy
+	if refreshStrategy == "" {
+		refreshStrategy = config.RoutesRefreshStrategyAll
+	}
+	neighborParallelism := cfg.Server.RoutesStoreNeighborParallelism
+	if neighborParallelism <= 0 {
+		neighborParallelism = config.DefaultRoutesStoreNeighborRefreshParallelism
+	}
+	log.Println("Routes refresh strategy:", refreshStrategy)
+
 	// Store refresh information per store
 	sources := NewSourcesStore(cfg, refreshInterval, refreshParallelism)
+
+	// Use the persisted data from a previous run
+	if b, ok := backend.(PersistentBackend); ok {
+		if err := sources.RestoreStatus(context.Background(), b); err != nil {
+			log.Println("Could not restore routes status:", err)
+		}
+	}
 	store := &RoutesStore{
 		backend:   backend,
 		sources:   sources,
 		neighbors: neighbors,
 		limit:     cfg.Server.RoutesStoreQueryLimit,
+
+		refreshStrategy:     refreshStrategy,
+		neighborParallelism: neighborParallelism,
+		neighborsStatus:     newNeighborsStatus(),
+
+		fingerprints: make(map[string]routeFingerprints),
 	}
 	return store
 }
 
+// AddSource registers a source discovered at runtime.
+func (s *RoutesStore) AddSource(src *config.SourceConfig) {
+	s.sources.AddSource(src)
+}
+
+// UpdateSource replaces the configuration of a source.
+func (s *RoutesStore) UpdateSource(src *config.SourceConfig) error {
+	s.setFingerprints(src.ID, nil)
+	s.neighborsStatus.Set(src.ID, nil)
+	return s.sources.UpdateSource(src)
+}
+
+// RefreshSource schedules the refresh of the routes
+// of a source. ErrSourceBusy is returned if a refresh
+// is in progress.
+func (s *RoutesStore) RefreshSource(sourceID string) error {
+	if !s.running.Load() {
+		return ErrStoreNotRunning
+	}
+	return s.sources.RequestRefresh(sourceID)
+}
+
+// CanRefreshSource checks if a refresh of the routes
+// of a source can be scheduled.
+func (s *RoutesStore) CanRefreshSource(sourceID string) error {
+	if !s.running.Load() {
+		return ErrStoreNotRunning
+	}
+	return s.sources.CheckRefresh(sourceID)
+}
+
+// RemoveSource unregisters a source and deletes
+// its routes from the backend.
+func (s *RoutesStore) RemoveSource(
+	ctx context.Context,
+	sourceID string,
+) error {
+	s.sources.RemoveSource(sourceID)
+	s.setFingerprints(sourceID, nil)
+	s.neighborsStatus.Set(sourceID, nil)
+	return s.backend.RemoveSource(ctx, sourceID)
+}
+
 // Start starts the routes store
 func (s *RoutesStore) Start(ctx context.Context) {
 	log.Println("Starting local routes store")
+	s.running.Store(true)
+	defer s.running.Store(false)
 
 	// Periodically trigger updates
 	for {
@@ -147,11 +239,13 @@ func (s *RoutesStore) safeUpdateSource(ctx context.Context, id string) {
 	}
 
 	// Apply jitter so, we do not hit everything at once.
-	// TODO: Make configurable
-	time.Sleep(time.Duration(rand.Intn(30)) * time.Second)
+	time.Sleep(s.sources.Jitter(id))
 
 	src := s.sources.Get(id)
 	srcName := s.sources.GetName(id)
+	if src == nil {
+		return // The source was removed
+	}
 
 	log.Println("[routes store] begin routes refresh of:", srcName)
 
@@ -165,7 +259,17 @@ func (s *RoutesStore) safeUpdateSource(ctx context.Context, id string) {
 		}
 	}()
 
-	if err := s.updateSource(ctx, src); err != nil {
+	var err error
+	if s.refreshStrategy == config.RoutesRefreshStrategyNeighbors {
+		err = s.updateSourceNeighbors(ctx, src, src.GetInstance())
+	} else {
+		err = s.updateSource(ctx, src)
+	}
+	if errors.Is(err, ErrNeighborsRefreshFailed) {
+		log.Println(
+			"Refreshing routes of", src.Name, "is incomplete:", err)
+		s.sources.RefreshIncomplete(id, err)
+	} else if err != nil {
 		log.Println(
 			"Refeshing routes of", src.Name, "failed:", err)
 		s.sources.RefreshError(id, err)
@@ -215,12 +319,82 @@ func (s *RoutesStore) updateSource(
 	lookupRoutes := append(imported, filtered...)
 
 	log.Println("[routes store] importing", len(lookupRoutes), "into store from", src.Name)
-	if err = s.backend.SetRoutes(ctx, src.ID, lookupRoutes); err != nil {
+	if err = s.importRoutes(ctx, src.ID, lookupRoutes); err != nil {
 		return err
 	}
 	log.Println("[routes store] import success")
 
-	return s.sources.RefreshSuccess(src.ID)
+	err = s.sources.RefreshSuccess(src.ID)
+	if errors.Is(err, sources.ErrSourceNotFound) {
+		// The source was removed during the refresh
+		return s.backend.RemoveSource(ctx, src.ID)
+	}
+	return err
+}
+
+// importRoutes stores the routes of a source. If the
+// backend supports updates, only the changes since the
+// last refresh are applied.
+func (s *RoutesStore) importRoutes(
+	ctx context.Context,
+	sourceID string,
+	routes api.LookupRoutes,
+) error {
+	backend, ok := s.backend.(RoutesUpdateBackend)
+	if !ok {
+		return s.backend.SetRoutes(ctx, sourceID, routes)
+	}
+
+	groups := groupRoutes(routes)
+	fingerprints := fingerprintRoutes(groups)
+	prev := s.getFingerprints(sourceID)
+	s.setFingerprints(sourceID, nil) // In case of an error
+
+	if prev == nil {
+		if err := s.backend.SetRoutes(ctx, sourceID, routes); err != nil {
+			return err
+		}
+	} else {
+		updates := diffRoutes(prev, fingerprints, groups)
+		added, withdrawn := 0, 0
+		for _, u := range updates {
+			added += len(u.Added)
+			withdrawn += len(u.Withdrawn)
+		}
+		log.Println(
+			"[routes store] updating", len(updates), "neighbors of", sourceID,
+			"with", added, "added and", withdrawn, "withdrawn routes")
+		if err := backend.UpdateRoutes(ctx, sourceID, updates); err != nil {
+			return err
+		}
+	}
+
+	s.setFingerprints(sourceID, fingerprints)
+	return nil
+}
+
+// getFingerprints returns the fingerprints of the
+// last refresh of a source or nil.
+func (s *RoutesStore) getFingerprints(sourceID string) routeFingerprints {
+	s.fingerprintsLock.Lock()
+	defer s.fingerprintsLock.Unlock()
+	return s.fingerprints[sourceID]
+}
+
+// setFingerprints replaces the fingerprints of a source.
+// Without fingerprints all routes are replaced on the
+// next refresh.
+func (s *RoutesStore) setFingerprints(
+	sourceID string,
+	fingerprints routeFingerprints,
+) {
+	s.fingerprintsLock.Lock()
+	defer s.fingerprintsLock.Unlock()
+	if fingerprints == nil {
+		delete(s.fingerprints, sourceID)
+		return
+	}
+	s.fingerprints[sourceID] = fingerprints
 }
 
 // awaitNeighborStore polls the neighbor store state
@@ -234,7 +408,11 @@ func (s *RoutesStore) awaitNeighborStore(
 			return err
 		}
 
-		if s.neighbors.IsInitialized(srcID) {
+		ready, err := s.neighbors.sources.IsInitialized(srcID)
+		if err != nil {
+			return err // The source was removed
+		}
+		if ready {
 			return nil
 		}
 
@@ -248,15 +426,17 @@ func (s *RoutesStore) Status(ctx context.Context) *api.StoreStatus {
 	sources := s.sources.GetSourcesStatus()
 	status := make(map[string]*api.SourceStatus)
 
-	for _, s := range sources {
-		if !s.Initialized {
+	for _, src := range sources {
+		if !src.Initialized {
 			initialized = false
 		}
-		status[s.SourceID] = &api.SourceStatus{
-			RefreshInterval: s.RefreshInterval,
-			LastRefresh:     s.LastRefresh,
-			State:           s.State.String(),
-			Initialized:     s.Initialized,
+		status[src.SourceID] = &api.SourceStatus{
+			RefreshInterval: src.RefreshInterval,
+			LastRefresh:     src.LastRefresh,
+			State:           src.State.String(),
+			Initialized:     src.Initialized,
+			Stale:           src.Stale,
+			Neighbors:       s.neighborsRefreshStatus(src.SourceID),
 		}
 	}
 
@@ -282,6 +462,9 @@ func (s *RoutesStore) Stats(ctx context.Context) *api.RoutesStoreStats {
 		}
 
 		src := s.sources.Get(sourceID)
+		if src == nil {
+			continue // The source was removed
+		}
 		nImported, nFiltered, err := s.backend.CountRoutesAt(ctx, sourceID)
 		if err != nil {
 			if !errors.Is(err, sources.ErrSourceNotFound) {
@@ -359,3 +542,45 @@ func (s *RoutesStore) LookupPrefixForNeighbors(
 	}
 	return s.backend.FindByNeighbors(ctx, query, filters)
 }
+
+// GetStoredRoutesAt returns the routes of a neighbor
+// with the state (imported or filtered) from the store
+// and the time of their last refresh. The source is
+// not queried.
+func (s *RoutesStore) GetStoredRoutesAt(
+	ctx context.Context,
+	sourceID string,
+	neighborID string,
+	state string,
+) (api.Routes, time.Time, error) {
+	status, err := s.sources.GetStatus(sourceID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if !status.Initialized {
+		return nil, time.Time{}, ErrSourceNotInitialized
+	}
+	cachedAt := status.LastRefresh
+	if st := s.neighborsStatus.Get(sourceID)[neighborID]; st != nil && !st.LastRefresh.IsZero() {
+		cachedAt = st.LastRefresh
+	}
+
+	routes := api.Routes{}
+	q := newNeighborQuery(neighborID, sourceID)
+	if q == nil {
+		return routes, cachedAt, nil
+	}
+	stored, err := s.backend.FindByNeighbors(
+		ctx, []*api.NeighborQuery{q}, api.NewSearchFilters())
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	for _, r := range stored {
+		if r.State != state {
+			continue
+		}
+		routes = append(routes, r.Route)
+	}
+	sort.Sort(routes)
+	return routes, cachedAt, nil
+}
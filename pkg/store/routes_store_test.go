@@ -184,3 +184,43 @@ func TestLookupPrefixForNeighbors(t *testing.T) {
 
 	testCheckPrefixesPresence(presence, resultset, t)
 }
+
+func TestGetStoredRoutesAt(t *testing.T) {
+	ctx := context.Background()
+	s := makeTestRoutesStore()
+
+	imported, cachedAt, err := s.GetStoredRoutesAt(
+		ctx, "rs1", "ID163_AS31078", "imported")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported) == 0 {
+		t.Error("expected imported routes")
+	}
+	for _, r := range imported {
+		if r.NeighborID == nil || *r.NeighborID != "ID163_AS31078" {
+			t.Error("unexpected neighbor:", r)
+		}
+	}
+	if cachedAt.IsZero() {
+		t.Error("expected the time of the last refresh")
+	}
+	filtered, _, err := s.GetStoredRoutesAt(
+		ctx, "rs1", "ID163_AS31078", "filtered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range filtered {
+		for _, i := range imported {
+			if r == i {
+				t.Error("filtered route in imported routes:", r)
+			}
+		}
+	}
+
+	// The source rs2 was never refreshed
+	if _, _, err := s.GetStoredRoutesAt(
+		ctx, "rs2", "ID163_AS31078", "imported"); err != ErrSourceNotInitialized {
+		t.Error("expected source not initialized, got:", err)
+	}
+}
@@ -0,0 +1,214 @@
+package store
+
+import (
+	"context"
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+)
+
+// RoutesUpdateBackend is implemented by backends, which
+// can apply the changes of the routes of the neighbors
+// instead of replacing all routes of a source.
+type RoutesUpdateBackend interface {
+	UpdateRoutes(
+		ctx context.Context,
+		sourceID string,
+		updates []*api.RoutesUpdate,
+	) error
+}
+
+// The routeFingerprints of a source map the neighbor ID
+// and the route ID (the route key) to a hash of the route.
+type routeFingerprints map[string]map[string]uint64
+
+// routeHash writes the fields of a route into
+// the hash. Strings are terminated and lists are
+// prefixed with their length, so adjacent fields
+// can not be confused.
+type routeHash struct {
+	hash.Hash64
+	buf [8]byte
+}
+
+// Private writeString adds a string
+func (h *routeHash) writeString(s string) {
+	h.Write([]byte(s))
+	h.Write([]byte{0})
+}
+
+// Private writeStringPtr adds an optional string
+func (h *routeHash) writeStringPtr(s *string) {
+	h.writeBool(s != nil)
+	if s != nil {
+		h.writeString(*s)
+	}
+}
+
+// Private writeBool adds a flag
+func (h *routeHash) writeBool(b bool) {
+	if b {
+		h.Write([]byte{1})
+	} else {
+		h.Write([]byte{0})
+	}
+}
+
+// Private writeInt adds an integer
+func (h *routeHash) writeInt(i uint64) {
+	binary.LittleEndian.PutUint64(h.buf[:8], i)
+	h.Write(h.buf[:])
+}
+
+// Private writeInts adds a list of integers
+func (h *routeHash) writeInts(ints []int) {
+	h.writeInt(uint64(len(ints)))
+	for _, i := range ints {
+		h.writeInt(uint64(i))
+	}
+}
+
+// Private writeBGP adds the BGP attributes
+func (h *routeHash) writeBGP(bgp *api.BGPInfo) {
+	h.writeBool(bgp != nil)
+	if bgp == nil {
+		return
+	}
+	h.writeStringPtr(bgp.Origin)
+	h.writeInts(bgp.AsPath)
+	h.writeStringPtr(bgp.NextHop)
+	h.writeInt(uint64(len(bgp.Communities)))
+	for _, c := range bgp.Communities {
+		h.writeInts(c)
+	}
+	h.writeInt(uint64(len(bgp.LargeCommunities)))
+	for _, c := range bgp.LargeCommunities {
+		h.writeInts(c)
+	}
+	h.writeInt(uint64(len(bgp.ExtCommunities)))
+	for _, c := range bgp.ExtCommunities {
+		h.writeString(c.String())
+	}
+	h.writeInt(uint64(bgp.LocalPref))
+	h.writeInt(uint64(bgp.Med))
+	h.writeStringPtr(bgp.NextHopLinkLocal)
+	h.writeInts(bgp.AsSet)
+	h.writeInt(bgp.Aigp)
+	h.writeInt(uint64(bgp.OnlyToCustomer))
+}
+
+// hashRoute calculates the hash of a route. The
+// attributes of the neighbor shown in lookups are
+// included, the volatile counters are not. The age
+// of the route is ignored as well.
+func hashRoute(r *api.LookupRoute) uint64 {
+	h := &routeHash{Hash64: fnv.New64a()}
+	h.writeString(r.State)
+	if route := r.Route; route != nil {
+		h.writeString(route.Key())
+		h.writeStringPtr(route.NeighborID)
+		h.writeStringPtr(route.Interface)
+		h.writeStringPtr(route.Gateway)
+		h.writeInt(uint64(route.Metric))
+		h.writeBGP(route.BGP)
+		h.writeInt(uint64(len(route.Type)))
+		for _, t := range route.Type {
+			h.writeString(t)
+		}
+		h.writeBool(route.Primary)
+		h.writeStringPtr(route.LearntFrom)
+		h.writeString(route.Table)
+		h.writeString(route.AFISAFI)
+		h.writeBool(route.Details != nil)
+		if route.Details != nil {
+			h.Write(*route.Details)
+		}
+	}
+	if n := r.Neighbor; n != nil {
+		h.writeString(n.ID)
+		h.writeString(n.Address)
+		h.writeInt(uint64(n.ASN))
+		h.writeString(n.Description)
+	}
+	return h.Sum64()
+}
+
+// groupRoutes groups the routes by neighbor
+func groupRoutes(routes api.LookupRoutes) map[string]api.LookupRoutes {
+	groups := make(map[string]api.LookupRoutes)
+	for _, r := range routes {
+		id := r.GetNeighborID()
+		groups[id] = append(groups[id], r)
+	}
+	return groups
+}
+
+// fingerprintRoutes calculates the fingerprints of
+// the grouped routes.
+func fingerprintRoutes(
+	groups map[string]api.LookupRoutes,
+) routeFingerprints {
+	fingerprints := make(routeFingerprints, len(groups))
+	for neighborID, routes := range groups {
+		hashes := make(map[string]uint64, len(routes))
+		for _, r := range routes {
+			hashes[r.Key()] = hashRoute(r)
+		}
+		fingerprints[neighborID] = hashes
+	}
+	return fingerprints
+}
+
+// diffRoutes compares the routes with the previous
+// refresh and returns the updates of the changed neighbors.
+func diffRoutes(
+	prev routeFingerprints,
+	next routeFingerprints,
+	groups map[string]api.LookupRoutes,
+) []*api.RoutesUpdate {
+	updates := []*api.RoutesUpdate{}
+	for neighborID, routes := range groups {
+		prevHashes := prev[neighborID]
+		hashes := next[neighborID]
+		update := &api.RoutesUpdate{
+			NeighborID: neighborID,
+			Routes:     routes,
+			Added:      api.LookupRoutes{},
+			Withdrawn:  []string{},
+		}
+		for _, r := range routes {
+			key := r.Key()
+			if h, ok := prevHashes[key]; !ok || h != hashes[key] {
+				update.Added = append(update.Added, r)
+			}
+		}
+		for id := range prevHashes {
+			if _, ok := hashes[id]; !ok {
+				update.Withdrawn = append(update.Withdrawn, id)
+			}
+		}
+		if len(update.Added) > 0 || len(update.Withdrawn) > 0 {
+			updates = append(updates, update)
+		}
+	}
+
+	// Withdraw all routes of neighbors without routes
+	for neighborID, prevHashes := range prev {
+		if _, ok := groups[neighborID]; ok {
+			continue
+		}
+		update := &api.RoutesUpdate{
+			NeighborID: neighborID,
+			Routes:     api.LookupRoutes{},
+			Added:      api.LookupRoutes{},
+			Withdrawn:  make([]string, 0, len(prevHashes)),
+		}
+		for id := range prevHashes {
+			update.Withdrawn = append(update.Withdrawn, id)
+		}
+		updates = append(updates, update)
+	}
+	return updates
+}
@@ -0,0 +1,201 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alice-lg/alice-lg/pkg/api"
+	"github.com/alice-lg/alice-lg/pkg/store/testdata"
+)
+
+// Change the routes of the first neighbor: The first
+// route is withdrawn, the second is changed and a new
+// route is added.
+func changeTestRoutes(routes api.LookupRoutes) (api.LookupRoutes, string) {
+	neighborID := routes[0].GetNeighborID()
+	result := api.LookupRoutes{}
+	changed := 0
+	for _, r := range routes {
+		if r.GetNeighborID() != neighborID {
+			result = append(result, r)
+			continue
+		}
+		changed++
+		switch changed {
+		case 1:
+			continue // withdrawn
+		case 2:
+			route := *r.Route
+			bgp := *route.BGP
+			bgp.Med = 2342
+			route.BGP = &bgp
+			r = &api.LookupRoute{
+				Route:       &route,
+				State:       r.State,
+				Neighbor:    r.Neighbor,
+				RouteServer: r.RouteServer,
+			}
+		}
+		result = append(result, r)
+	}
+	added := *routes[0]
+	addedRoute := *added.Route
+	addedRoute.Network = "23.42.0.0/16"
+	added.Route = &addedRoute
+	return append(result, &added), neighborID
+}
+
+func TestDiffRoutes(t *testing.T) {
+	routes := testdata.LoadTestLookupRoutes("rs1", "rs1")
+	groups := groupRoutes(routes)
+	prev := fingerprintRoutes(groups)
+
+	// Nothing changed
+	if updates := diffRoutes(prev, prev, groups); len(updates) != 0 {
+		t.Error("unexpected updates:", updates)
+	}
+
+	changed, neighborID := changeTestRoutes(routes)
+	groups = groupRoutes(changed)
+	next := fingerprintRoutes(groups)
+	updates := diffRoutes(prev, next, groups)
+	if len(updates) != 1 {
+		t.Fatal("expected one neighbor to be updated:", updates)
+	}
+	u := updates[0]
+	if u.NeighborID != neighborID {
+		t.Error("unexpected neighbor:", u.NeighborID)
+	}
+	if len(u.Added) != 2 {
+		t.Error("expected a changed and an added route:", u.Added)
+	}
+	if len(u.Withdrawn) != 1 || u.Withdrawn[0] != routes[0].Network {
+		t.Error("unexpected withdrawn routes:", u.Withdrawn)
+	}
+	if len(u.Routes) != len(groups[neighborID]) {
+		t.Error("expected all routes of the neighbor")
+	}
+
+	// All routes of a neighbor are withdrawn
+	delete(groups, neighborID)
+	updates = diffRoutes(prev, fingerprintRoutes(groups), groups)
+	if len(updates) != 1 || len(updates[0].Routes) != 0 ||
+		len(updates[0].Withdrawn) != len(prev[neighborID]) {
+		t.Error("unexpected updates:", updates)
+	}
+}
+
+func TestDiffRoutesKeys(t *testing.T) {
+	routes := testdata.LoadTestLookupRoutes("rs1", "rs1")
+	neighborID := routes[0].GetNeighborID()
+	groups := groupRoutes(routes)
+	prev := fingerprintRoutes(groups)
+
+	// A VPN route with the same network in another VRF
+	vpn := *routes[0]
+	vpnRoute := *vpn.Route
+	vpnRoute.RouteDistinguisher = "65000:100"
+	vpn.Route = &vpnRoute
+	groups[neighborID] = append(groups[neighborID], &vpn)
+
+	updates := diffRoutes(prev, fingerprintRoutes(groups), groups)
+	if len(updates) != 1 || len(updates[0].Added) != 1 ||
+		len(updates[0].Withdrawn) != 0 {
+		t.Fatal("expected the vpn route to be added:", updates)
+	}
+	if updates[0].Added[0].Key() != "65000:100 "+routes[0].Network {
+		t.Error("unexpected route key:", updates[0].Added[0].Key())
+	}
+}
+
+func TestImportRoutesUpdate(t *testing.T) {
+	ctx := context.Background()
+	s := makeTestRoutesStore()
+	routes := testdata.LoadTestLookupRoutes("rs2", "rs2")
+
+	// The first import replaces all routes
+	if err := s.importRoutes(ctx, "rs2", routes); err != nil {
+		t.Fatal(err)
+	}
+	if s.getFingerprints("rs2") == nil {
+		t.Fatal("expected fingerprints")
+	}
+
+	changed, _ := changeTestRoutes(routes)
+	if err := s.importRoutes(ctx, "rs2", changed); err != nil {
+		t.Fatal(err)
+	}
+	imported, filtered, err := s.backend.CountRoutesAt(ctx, "rs2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(imported+filtered) != len(changed) {
+		t.Error("unexpected number of routes:", imported, filtered)
+	}
+
+	result, err := s.LookupPrefix(ctx, "23.42.", api.NewSearchFilters())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 {
+		t.Error("expected the added route:", result)
+	}
+	result, _ = s.LookupPrefix(ctx, routes[0].Network, api.NewSearchFilters())
+	for _, r := range result {
+		if *r.RouteServer.ID == "rs2" && r.GetNeighborID() == routes[0].GetNeighborID() {
+			t.Error("expected route to be withdrawn:", r)
+		}
+	}
+
+	// Removing the source drops the fingerprints
+	if err := s.RemoveSource(ctx, "rs2"); err != nil {
+		t.Fatal(err)
+	}
+	if s.getFingerprints("rs2") != nil {
+		t.Error("unexpected fingerprints")
+	}
+}
+
+func TestHashRoute(t *testing.T) {
+	routes := testdata.LoadTestLookupRoutes("rs1", "rs1")
+	r := routes[0]
+	hash := hashRoute(r)
+
+	// Copy the route: equal values yield the same hash,
+	// the age is ignored.
+	copyRoute := func() (*api.LookupRoute, *api.BGPInfo) {
+		route := *r.Route
+		bgp := *route.BGP
+		bgp.Communities = append(api.Communities{}, bgp.Communities...)
+		route.BGP = &bgp
+		route.Age += 42
+		return &api.LookupRoute{
+			Route:       &route,
+			State:       r.State,
+			Neighbor:    r.Neighbor,
+			RouteServer: r.RouteServer,
+		}, &bgp
+	}
+	same, _ := copyRoute()
+	if hashRoute(same) != hash {
+		t.Error("expected equal routes to have the same hash")
+	}
+
+	changes := []func(*api.LookupRoute, *api.BGPInfo){
+		func(r *api.LookupRoute, _ *api.BGPInfo) { r.State = "filtered" },
+		func(r *api.LookupRoute, _ *api.BGPInfo) { r.RouteDistinguisher = "1:1" },
+		func(r *api.LookupRoute, _ *api.BGPInfo) { r.Primary = !r.Primary },
+		func(_ *api.LookupRoute, bgp *api.BGPInfo) {
+			bgp.Communities = append(bgp.Communities, api.Community{23, 42})
+		},
+		func(_ *api.LookupRoute, bgp *api.BGPInfo) { bgp.AsSet = []int{64500} },
+		func(_ *api.LookupRoute, bgp *api.BGPInfo) { bgp.LocalPref++ },
+	}
+	for i, change := range changes {
+		changed, bgp := copyRoute()
+		change(changed, bgp)
+		if hashRoute(changed) == hash {
+			t.Error("expected change", i, "to modify the hash")
+		}
+	}
+}
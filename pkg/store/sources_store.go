@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"log"
+	"math/rand"
 	"sort"
 	"sync"
 	"time"
@@ -17,6 +18,21 @@ import (
 var ErrSourceNotInitialized = errors.New(
 	"source is not initialized")
 
+// ErrSourceMaintenance is returned if a refresh is
+// requested within a maintenance window of the source.
+var ErrSourceMaintenance = errors.New(
+	"source is in maintenance")
+
+// ErrStoreNotRunning is returned if a refresh is
+// requested from a store which was not started.
+var ErrStoreNotRunning = errors.New(
+	"store is not running")
+
+// After a failed refresh, the refresh is retried with
+// an exponential backoff starting at the minimum. The backoff
+// is limited by the refresh interval of the source.
+const refreshBackoffMin = 10 * time.Second
+
 // Store State Constants
 const (
 	StateInit = iota
@@ -50,11 +66,22 @@ type Status struct {
 	LastRefresh         time.Time     `json:"last_refresh"`
 	LastRefreshDuration time.Duration `json:"-"`
 	LastError           interface{}   `json:"-"`
+	FailedRefreshes     int           `json:"-"`
 	State               State         `json:"state"`
 	Initialized         bool          `json:"initialized"`
+	Stale               bool          `json:"stale"`
 	SourceID            string        `json:"source_id"`
 
 	lastRefreshStart time.Time
+	refreshRequested bool
+}
+
+// A PersistentBackend keeps the data of the sources
+// across restarts.
+type PersistentBackend interface {
+	// LastUpdated returns the time of the last update
+	// of all sources with persisted data.
+	LastUpdated(ctx context.Context) (map[string]time.Time, error)
 }
 
 // SourceStatusList is a sortable list of source status
@@ -80,6 +107,7 @@ func (l SourceStatusList) Swap(i, j int) {
 type SourcesStore struct {
 	refreshInterval    time.Duration
 	refreshParallelism int
+	refreshJitter      time.Duration
 	status             map[string]*Status
 	sources            map[string]*config.SourceConfig
 	sync.Mutex
@@ -94,12 +122,16 @@ func NewSourcesStore(
 	status := make(map[string]*Status)
 	sources := make(map[string]*config.SourceConfig)
 
-	// Add sources from config
-	for _, src := range cfg.Sources {
+	// Add sources from config. Composite sources are
+	// not stored, their sources are stored instead.
+	for _, src := range cfg.GetSources() {
+		if src.Backend == config.SourceBackendComposite {
+			continue
+		}
 		sourceID := src.ID
 		sources[sourceID] = src
 		status[sourceID] = &Status{
-			RefreshInterval: refreshInterval,
+			RefreshInterval: sourceRefreshInterval(src, refreshInterval),
 			SourceID:        sourceID,
 		}
 	}
@@ -109,7 +141,93 @@ func NewSourcesStore(
 		sources:            sources,
 		refreshInterval:    refreshInterval,
 		refreshParallelism: refreshParallelism,
+		refreshJitter: time.Duration(
+			cfg.Server.RefreshJitter) * time.Second,
+	}
+}
+
+// sourceRefreshInterval returns the refresh interval
+// of the source or the default.
+func sourceRefreshInterval(
+	src *config.SourceConfig,
+	refreshInterval time.Duration,
+) time.Duration {
+	if src.RefreshInterval > 0 {
+		return src.RefreshInterval
+	}
+	return refreshInterval
+}
+
+// AddSource registers a new source. The source
+// will be refreshed with the next update.
+func (s *SourcesStore) AddSource(src *config.SourceConfig) {
+	if src.Backend == config.SourceBackendComposite {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.sources[src.ID] = src
+	if status, ok := s.status[src.ID]; ok {
+		// The source is already known
+		status.RefreshInterval = sourceRefreshInterval(src, s.refreshInterval)
+		return
+	}
+	s.status[src.ID] = &Status{
+		RefreshInterval: sourceRefreshInterval(src, s.refreshInterval),
+		SourceID:        src.ID,
+	}
+}
+
+// UpdateSource replaces the configuration of a
+// source. The data is kept until the next refresh,
+// which is scheduled immediately.
+func (s *SourcesStore) UpdateSource(src *config.SourceConfig) error {
+	if src.Backend == config.SourceBackendComposite {
+		return nil
+	}
+	s.Lock()
+	defer s.Unlock()
+	status, err := s.getStatus(src.ID)
+	if err != nil {
+		return err
 	}
+	s.sources[src.ID] = src
+	status.RefreshInterval = sourceRefreshInterval(src, s.refreshInterval)
+	status.LastRefresh = time.Time{}
+	return nil
+}
+
+// RemoveSource unregisters a source.
+func (s *SourcesStore) RemoveSource(sourceID string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.sources, sourceID)
+	delete(s.status, sourceID)
+}
+
+// RestoreStatus marks the sources with persisted data
+// as initialized, but stale. The data can be used until
+// the first refresh succeeds.
+func (s *SourcesStore) RestoreStatus(
+	ctx context.Context,
+	backend PersistentBackend,
+) error {
+	updated, err := backend.LastUpdated(ctx)
+	if err != nil {
+		return err
+	}
+	s.Lock()
+	defer s.Unlock()
+	for sourceID, lastRefresh := range updated {
+		status, ok := s.status[sourceID]
+		if !ok {
+			continue // The source is no longer configured
+		}
+		status.Initialized = true
+		status.Stale = true
+		status.LastRefresh = lastRefresh
+	}
+	return nil
 }
 
 // GetSourcesStatus will retrieve the status for all sources
@@ -164,7 +282,7 @@ func (s *SourcesStore) NextRefresh(
 
 	for _, status := range s.status {
 		nextRefresh := status.LastRefresh.Add(
-			s.refreshInterval)
+			s.getRefreshInterval(status))
 		if nextRefresh.After(t) {
 			t = nextRefresh
 		}
@@ -177,31 +295,125 @@ func (s *SourcesStore) NextRefresh(
 func (s *SourcesStore) ShouldRefresh(
 	sourceID string,
 ) bool {
-	status, err := s.GetStatus(sourceID)
+	s.Lock()
+	defer s.Unlock()
+	status, err := s.getStatus(sourceID)
 	if err != nil {
 		log.Println("get status error:", err)
 		return false
 	}
+	return s.shouldRefresh(status, time.Now().UTC())
+}
 
-	nextRefresh := status.LastRefresh.Add(s.refreshInterval)
-
+// Internal shouldRefresh checks if the refresh of
+// the source is due.
+func (s *SourcesStore) shouldRefresh(
+	status *Status,
+	now time.Time,
+) bool {
 	if status.State == StateBusy {
 		return false // Source is busy
 	}
+	if src, ok := s.sources[status.SourceID]; ok && src.InMaintenance(now) {
+		return false // Refresh is paused
+	}
+	if status.refreshRequested {
+		return true // Refresh now
+	}
+	if status.Stale && status.State == StateInit {
+		return true // Replace the restored data
+	}
+
+	refreshInterval := s.getRefreshInterval(status)
+	nextRefresh := status.LastRefresh.Add(refreshInterval)
 	if status.State == StateError {
 		// The refresh interval in the config is ok if the
 		// success case. When an error occures it is desirable
 		// to retry sooner, without spamming the server.
-		nextRefresh = status.LastRefresh.Add(10 * time.Second)
+		nextRefresh = status.LastRefresh.Add(
+			refreshBackoff(status.FailedRefreshes, refreshInterval))
 	}
 
-	if time.Now().UTC().Before(nextRefresh) {
+	if now.Before(nextRefresh) {
 		return false // Too soon
 	}
 
 	return true // Go for it
 }
 
+// Internal getRefreshInterval returns the refresh
+// interval of the source.
+func (s *SourcesStore) getRefreshInterval(status *Status) time.Duration {
+	if status.RefreshInterval > 0 {
+		return status.RefreshInterval
+	}
+	return s.refreshInterval
+}
+
+// refreshBackoff calculates the delay before the next
+// retry after a number of failed refreshes.
+func refreshBackoff(failed int, limit time.Duration) time.Duration {
+	backoff := refreshBackoffMin
+	for i := 1; i < failed && backoff < limit; i++ {
+		backoff *= 2
+	}
+	if limit > refreshBackoffMin && backoff > limit {
+		backoff = limit
+	}
+	return backoff
+}
+
+// RequestRefresh schedules the refresh of a source
+// independent of the refresh interval. A source which
+// is busy or in maintenance can not be refreshed.
+func (s *SourcesStore) RequestRefresh(sourceID string) error {
+	s.Lock()
+	defer s.Unlock()
+	status, err := s.checkRefresh(sourceID)
+	if err != nil {
+		return err
+	}
+	status.refreshRequested = true
+	return nil
+}
+
+// CheckRefresh returns the error RequestRefresh would
+// return, without scheduling the refresh.
+func (s *SourcesStore) CheckRefresh(sourceID string) error {
+	s.Lock()
+	defer s.Unlock()
+	_, err := s.checkRefresh(sourceID)
+	return err
+}
+
+// Private checkRefresh checks if a refresh of the
+// source can be requested. The lock must be held.
+func (s *SourcesStore) checkRefresh(sourceID string) (*Status, error) {
+	status, err := s.getStatus(sourceID)
+	if err != nil {
+		return nil, err
+	}
+	if status.State == StateBusy {
+		return nil, sources.ErrSourceBusy
+	}
+	if src, ok := s.sources[sourceID]; ok && src.InMaintenance(time.Now()) {
+		return nil, ErrSourceMaintenance
+	}
+	return status, nil
+}
+
+// Jitter returns a random delay for the refresh
+// of the source. Requested refreshes are not delayed.
+func (s *SourcesStore) Jitter(sourceID string) time.Duration {
+	s.Lock()
+	defer s.Unlock()
+	status, err := s.getStatus(sourceID)
+	if err != nil || status.refreshRequested || s.refreshJitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(s.refreshJitter)))
+}
+
 // CachedAt retrievs the oldest refresh time
 // from all sources. All data is then guaranteed to be older
 // than the CachedAt date.
@@ -222,14 +434,22 @@ func (s *SourcesStore) CachedAt(ctx context.Context) time.Time {
 func (s *SourcesStore) GetInstance(sourceID string) sources.Source {
 	s.Lock()
 	defer s.Unlock()
-	return s.sources[sourceID].GetInstance()
+	src, ok := s.sources[sourceID]
+	if !ok {
+		return nil
+	}
+	return src.GetInstance()
 }
 
 // GetName retrieves a source name by ID
 func (s *SourcesStore) GetName(sourceID string) string {
 	s.Lock()
 	defer s.Unlock()
-	return s.sources[sourceID].Name
+	src, ok := s.sources[sourceID]
+	if !ok {
+		return ""
+	}
+	return src.Name
 }
 
 // Get retrieves the source
@@ -259,12 +479,15 @@ func (s *SourcesStore) GetSourceIDsForRefresh() []string {
 	defer s.Unlock()
 
 	locked := 0
+	now := time.Now().UTC()
 	sources := make(SourceStatusList, 0, len(s.status))
 	for _, status := range s.status {
-		sources = append(sources, status)
 		if status.State == StateBusy {
 			locked++
 		}
+		if s.shouldRefresh(status, now) {
+			sources = append(sources, status)
+		}
 	}
 
 	// Sort by refresh start time ascending
@@ -316,7 +539,10 @@ func (s *SourcesStore) RefreshSuccess(sourceID string) error {
 	status.LastRefresh = time.Now().UTC()
 	status.LastRefreshDuration = time.Since(status.lastRefreshStart)
 	status.LastError = nil
+	status.FailedRefreshes = 0
+	status.refreshRequested = false
 	status.Initialized = true // We now have data
+	status.Stale = false
 	return nil
 }
 
@@ -336,4 +562,29 @@ func (s *SourcesStore) RefreshError(
 	status.LastRefresh = time.Now().UTC()
 	status.LastRefreshDuration = time.Since(status.lastRefreshStart)
 	status.LastError = sourceErr
+	status.FailedRefreshes++
+	status.refreshRequested = false
+}
+
+// RefreshIncomplete indicates that the refresh has
+// failed partially. The data of the source can be
+// used, but the refresh will be retried.
+func (s *SourcesStore) RefreshIncomplete(
+	sourceID string,
+	sourceErr interface{},
+) {
+	s.Lock()
+	defer s.Unlock()
+	status, err := s.getStatus(sourceID)
+	if err != nil {
+		log.Println("error getting source status:", err)
+		return
+	}
+	status.State = StateError
+	status.LastRefresh = time.Now().UTC()
+	status.LastRefreshDuration = time.Since(status.lastRefreshStart)
+	status.LastError = sourceErr
+	status.FailedRefreshes++
+	status.refreshRequested = false
+	status.Initialized = true
 }
@@ -1,8 +1,13 @@
 package store
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
+
+	"github.com/alice-lg/alice-lg/pkg/config"
+	"github.com/alice-lg/alice-lg/pkg/sources"
 )
 
 func TestGetSourceIDsForRefreshSequential(t *testing.T) {
@@ -102,3 +107,197 @@ func TestGetSourceIDsForRefreshParallel(t *testing.T) {
 		t.Error("expected src3 to be least refreshed")
 	}
 }
+
+type lastUpdatedBackend map[string]time.Time
+
+func (b lastUpdatedBackend) LastUpdated(
+	_ context.Context,
+) (map[string]time.Time, error) {
+	return b, nil
+}
+
+func TestRestoreStatus(t *testing.T) {
+	s := &SourcesStore{
+		refreshInterval: time.Hour,
+		status: map[string]*Status{
+			"src1": {
+				SourceID: "src1",
+			},
+		},
+	}
+	lastRefresh := time.Now().UTC().Add(-time.Minute)
+	backend := lastUpdatedBackend{
+		"src1":    lastRefresh,
+		"removed": lastRefresh,
+	}
+	if err := s.RestoreStatus(context.Background(), backend); err != nil {
+		t.Fatal(err)
+	}
+
+	status, _ := s.GetStatus("src1")
+	if !status.Initialized || !status.Stale {
+		t.Error("expected source to be initialized and stale")
+	}
+	if !status.LastRefresh.Equal(lastRefresh) {
+		t.Error("unexpected last refresh:", status.LastRefresh)
+	}
+	if _, err := s.GetStatus("removed"); err == nil {
+		t.Error("removed source should not be restored")
+	}
+
+	// The restored data should be replaced
+	if !s.ShouldRefresh("src1") {
+		t.Error("expected stale source to be refreshed")
+	}
+	if err := s.LockSource("src1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RefreshSuccess("src1"); err != nil {
+		t.Fatal(err)
+	}
+	status, _ = s.GetStatus("src1")
+	if status.Stale {
+		t.Error("expected source to be fresh")
+	}
+	if s.ShouldRefresh("src1") {
+		t.Error("source was just refreshed")
+	}
+}
+
+func TestRefreshBackoff(t *testing.T) {
+	tests := []struct {
+		failed   int
+		expected time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{4, 80 * time.Second},
+		{10, 5 * time.Minute},
+	}
+	for _, test := range tests {
+		backoff := refreshBackoff(test.failed, 5*time.Minute)
+		if backoff != test.expected {
+			t.Error("unexpected backoff after", test.failed, "errors:", backoff)
+		}
+	}
+}
+
+func TestShouldRefreshSchedule(t *testing.T) {
+	weekly, _ := config.ParseMaintenanceWindow("Sun 02:00-04:00")
+	cfg := &config.Config{
+		Sources: []*config.SourceConfig{
+			{ID: "src1", RefreshInterval: time.Hour},
+			{ID: "src2"},
+			{ID: "src3", Maintenance: []*config.MaintenanceWindow{weekly}},
+		},
+	}
+	s := NewSourcesStore(cfg, time.Minute, 1)
+	for _, id := range []string{"src1", "src2"} {
+		s.LockSource(id)
+		s.RefreshSuccess(id)
+	}
+
+	// The per source interval is used
+	now := time.Now().UTC().Add(2 * time.Minute)
+	if s.shouldRefresh(s.status["src1"], now) {
+		t.Error("src1 should not be refreshed")
+	}
+	if !s.shouldRefresh(s.status["src2"], now) {
+		t.Error("src2 should be refreshed")
+	}
+
+	// Failed refreshes are retried with a backoff
+	s.LockSource("src2")
+	s.RefreshError("src2", errors.New("timeout"))
+	s.LockSource("src2")
+	s.RefreshError("src2", errors.New("timeout"))
+	now = time.Now().UTC()
+	if s.shouldRefresh(s.status["src2"], now.Add(15*time.Second)) {
+		t.Error("src2 should be retried after 20 seconds")
+	}
+	if !s.shouldRefresh(s.status["src2"], now.Add(21*time.Second)) {
+		t.Error("src2 should be retried")
+	}
+
+	// No refresh within the maintenance window
+	sunday := time.Date(2023, 1, 1, 3, 0, 0, 0, time.UTC)
+	if s.shouldRefresh(s.status["src3"], sunday) {
+		t.Error("src3 is in maintenance")
+	}
+	if !s.shouldRefresh(s.status["src3"], sunday.Add(time.Hour)) {
+		t.Error("src3 should be refreshed")
+	}
+}
+
+func TestRequestRefresh(t *testing.T) {
+	cfg := &config.Config{
+		Sources: []*config.SourceConfig{{ID: "src1"}},
+	}
+	s := NewSourcesStore(cfg, time.Hour, 1)
+	s.LockSource("src1")
+	if err := s.RequestRefresh("src1"); !errors.Is(err, sources.ErrSourceBusy) {
+		t.Error("expected source to be busy, got:", err)
+	}
+	s.RefreshSuccess("src1")
+	if s.ShouldRefresh("src1") {
+		t.Error("source was just refreshed")
+	}
+
+	if err := s.RequestRefresh("src1"); err != nil {
+		t.Fatal(err)
+	}
+	if !s.ShouldRefresh("src1") {
+		t.Error("expected requested refresh")
+	}
+	if ids := s.GetSourceIDsForRefresh(); len(ids) != 1 {
+		t.Error("expected src1 to be refreshed:", ids)
+	}
+	s.LockSource("src1")
+	s.RefreshSuccess("src1")
+	if s.ShouldRefresh("src1") {
+		t.Error("the request should be reset after the refresh")
+	}
+	if err := s.RequestRefresh("unknown"); !errors.Is(err, sources.ErrSourceNotFound) {
+		t.Error("expected unknown source, got:", err)
+	}
+}
+
+func TestCheckRefresh(t *testing.T) {
+	cfg := &config.Config{
+		Sources: []*config.SourceConfig{{ID: "src1"}},
+	}
+	s := NewSourcesStore(cfg, time.Hour, 1)
+	s.LockSource("src1")
+	if err := s.CheckRefresh("src1"); !errors.Is(err, sources.ErrSourceBusy) {
+		t.Error("expected source to be busy, got:", err)
+	}
+	s.RefreshSuccess("src1")
+	if err := s.CheckRefresh("src1"); err != nil {
+		t.Fatal(err)
+	}
+	if s.ShouldRefresh("src1") {
+		t.Error("the check should not request a refresh")
+	}
+}
+
+func TestSourcesStoreSkipsComposite(t *testing.T) {
+	cfg := &config.Config{
+		Sources: []*config.SourceConfig{
+			{ID: "rs1-v4", Backend: config.SourceBackendBirdwatcher},
+			{ID: "rs1", Backend: config.SourceBackendComposite},
+		},
+	}
+	s := NewSourcesStore(cfg, time.Minute, 1)
+	if _, ok := s.status["rs1"]; ok {
+		t.Error("composite source should not be stored")
+	}
+	if _, ok := s.status["rs1-v4"]; !ok {
+		t.Error("expected source rs1-v4")
+	}
+
+	s.AddSource(&config.SourceConfig{
+		ID: "rs2", Backend: config.SourceBackendComposite})
+	if _, ok := s.status["rs2"]; ok {
+		t.Error("composite source should not be added")
+	}
+}
@@ -65,6 +65,11 @@ const RouteDetailsModal = () => {
             <tr>
              <th>Next Hop:</th><td>{attrs.next_hop}</td>
             </tr>
+            {attrs.next_hop_link_local &&
+              <tr>
+                <th>Link Local Next Hop:</th>
+                <td>{attrs.next_hop_link_local}</td>
+              </tr>}
             <tr>
                 <th>MED:</th>
                 <td>{attrs.med}</td>
@@ -74,6 +79,21 @@ const RouteDetailsModal = () => {
                   <th>AS Path:</th>
                   <td><AsPath asns={attrs.as_path} /></td>
                 </tr>}
+            {attrs.as_set &&
+                <tr>
+                  <th>AS Set:</th>
+                  <td>{`{${attrs.as_set.join(", ")}}`}</td>
+                </tr>}
+            {attrs.otc &&
+                <tr>
+                  <th>Only To Customer:</th>
+                  <td>{attrs.otc}</td>
+                </tr>}
+            {attrs.aigp &&
+                <tr>
+                  <th>AIGP:</th>
+                  <td>{attrs.aigp}</td>
+                </tr>}
             {communities.length > 0 &&
                 <tr>
                   <th>Communities:</th>
@@ -181,7 +181,9 @@ const decodeExtCommunity = (community) => {
   if (comm.length !== 3) {
     return comm; 
   }
-  return [comm[0], ...comm.slice(1).map((c) => parseInt(c, 10))];
+  // The administrator can be an IPv4 address
+  return [comm[0], ...comm.slice(1).map((c) =>
+    /^\d+$/.test(c) ? parseInt(c, 10) : c)];
 }
 
 const decodeCommunities = (value) =>